@@ -1,13 +1,29 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Time zones of calendar data, also where the system has none
+	"todoListChallenge/internal/apidocs"
+	"todoListChallenge/internal/auth"
 	"todoListChallenge/internal/db"
+	"todoListChallenge/internal/events"
 	"todoListChallenge/internal/handlers"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/openapi"
 	"todoListChallenge/internal/repository"
 	"todoListChallenge/internal/routes"
 	"todoListChallenge/internal/services"
+	"todoListChallenge/internal/storage"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -27,14 +43,75 @@ func main() {
 	// Initialize repositories
 	todoRepo := repository.NewTodoRepository(db.DB)
 	categoryRepo := repository.NewCategoryRepository(db.DB)
+	tagRepo := repository.NewTagRepository(db.DB)
+	userRepo := repository.NewUserRepository(db.DB)
+	webhookRepo := repository.NewWebhookRepository(db.DB)
+	auditRepo := repository.NewAuditRepository(db.DB)
+	commentRepo := repository.NewCommentRepository(db.DB)
+	attachmentRepo := repository.NewAttachmentRepository(db.DB)
+	reminderRepo := repository.NewReminderRepository(db.DB)
+	calendarRepo := repository.NewCalendarRepository(db.DB)
+	caldavRepo := repository.NewCalDAVRepository(db.DB)
+	transferRepo := repository.NewTransferRepository(db.DB)
+
+	// Initialize token signing
+	tokens := auth.NewTokenManager(jwtSecret(), getDuration("ACCESS_TOKEN_TTL", 15*time.Minute))
+
+	// Initialize the change feed
+	bus := events.NewBus(events.DefaultHistorySize)
 
 	// Initialize services
-	todoService := services.NewTodoService(todoRepo)
-	categoryService := services.NewCategoryService(categoryRepo)
+	todoService := services.NewTodoService(todoRepo, bus)
+	categoryService := services.NewCategoryService(categoryRepo, bus)
+	tagService := services.NewTagService(tagRepo)
+	authService := services.NewAuthService(userRepo, tokens, getDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour))
+	webhookService := services.NewWebhookService(webhookRepo, webhookClient())
+	auditService := services.NewAuditService(auditRepo)
+	commentService := services.NewCommentService(commentRepo, bus)
+	attachmentService := services.NewAttachmentService(attachmentRepo, attachmentStorage(), services.AttachmentLimits{
+		MaxSize: getInt64("ATTACHMENT_MAX_SIZE", services.DefaultMaxAttachmentSize),
+		Types:   getList("ATTACHMENT_TYPES", services.DefaultAttachmentTypes),
+	})
+	reminderService := services.NewReminderService(reminderRepo, bus, notifiers(webhookService))
+	calendarService := services.NewCalendarService(calendarRepo, todoService)
+	caldavService := services.NewCalDAVService(caldavRepo, todoService, categoryService)
+	transferService := services.NewTransferService(transferRepo, todoService, categoryService)
+
+	// Deliver webhooks in the background
+	go webhookService.Run(context.Background(), bus)
+
+	// Empty the trash of anything older than the retention; 0 keeps it forever
+	if retention := getDuration("TRASH_RETENTION", 30*24*time.Hour); retention > 0 {
+		go services.RunTrashPurge(context.Background(), retention, todoService, categoryService)
+	}
+
+	// Remove stored files that no attachment uses any more
+	go attachmentService.RunAttachmentCleanup(context.Background())
+
+	// Fire reminders and deliver their notifications
+	go reminderService.Run(context.Background())
 
 	// Initialize handlers
-	todoHandler := handlers.NewTodoHandler(todoService)
+	todoHandler := handlers.NewTodoHandler(todoService, transferService)
 	categoryHandler := handlers.NewCategoryHandler(categoryService)
+	tagHandler := handlers.NewTagHandler(tagService)
+	authHandler := handlers.NewAuthHandler(authService)
+	eventHandler := handlers.NewEventHandler(bus)
+	webhookHandler := handlers.NewWebhookHandler(webhookService)
+	trashHandler := handlers.NewTrashHandler(todoService, categoryService)
+	auditHandler := handlers.NewAuditHandler(auditService, todoService)
+	commentHandler := handlers.NewCommentHandler(commentService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService)
+	reminderHandler := handlers.NewReminderHandler(reminderService)
+	calendarHandler := handlers.NewCalendarHandler(calendarService)
+	caldavHandler := handlers.NewCalDAVHandler(caldavService, authService)
+	transferHandler := handlers.NewTransferHandler(transferService)
+	spec, err := apidocs.JSON()
+	if err != nil {
+		log.Fatal("Failed to load API documentation:", err)
+	}
+	docsHandler := handlers.NewDocsHandler(spec)
+	contract := apiContract()
 
 	// Setup Gin router
 	router := gin.Default()
@@ -43,17 +120,24 @@ func main() {
 	config := cors.DefaultConfig()
 	config.AllowOrigins = []string{"http://localhost:5173", "http://localhost:3000"} // React dev server
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
-	config.ExposeHeaders = []string{"Content-Length"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "Last-Event-ID", "If-Match", "If-None-Match"}
+	config.ExposeHeaders = []string{"Content-Length", "ETag"}
 	config.AllowCredentials = true
 	router.Use(cors.New(config))
 
+	// Check responses against the API documentation while developing
+	if getEnv("VALIDATE_RESPONSES", "false") == "true" {
+		router.Use(middleware.ValidateResponses(contract, func(c *gin.Context, err error) {
+			log.Printf("Response of %s %s does not match the API documentation: %v", c.Request.Method, c.Request.URL.Path, err)
+		}))
+	}
+
 	// Setup routes
-	routes.SetupRoutes(router, todoHandler, categoryHandler)
+	routes.SetupRoutes(router, todoHandler, categoryHandler, tagHandler, authHandler, eventHandler, webhookHandler, trashHandler, auditHandler, commentHandler, attachmentHandler, reminderHandler, calendarHandler, caldavHandler, transferHandler, docsHandler, middleware.RequireAuth(tokens), caldavAuth(authService), middleware.ValidateRequests(contract))
 
 	// Get port from environment or use default
 	port := getEnv("PORT", "8080")
-	
+
 	log.Printf("Server is running on port %s", port)
 	if err := router.Run(":" + port); err != nil {
 		log.Fatal("Failed to start server:", err)
@@ -65,4 +149,145 @@ func getEnv(key, defaultValue string) string {
 		return value
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+		log.Printf("Invalid duration %q for %s, using %s", value, key, defaultValue)
+	}
+	return defaultValue
+}
+
+func getInt64(key string, defaultValue int64) int64 {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+		log.Printf("Invalid number %q for %s, using %d", value, key, defaultValue)
+	}
+	return defaultValue
+}
+
+// getList reads a comma-separated list, ignoring blank entries
+func getList(key string, defaultValue []string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	if len(list) == 0 {
+		return defaultValue
+	}
+	return list
+}
+
+// attachmentStorage returns the store for attachment files selected by
+// STORAGE_BACKEND: the local directory STORAGE_PATH by default, or an
+// S3-compatible bucket with "s3"
+func attachmentStorage() storage.Storage {
+	switch backend := getEnv("STORAGE_BACKEND", "local"); backend {
+	case "local":
+		store, err := storage.NewLocalStorage(getEnv("STORAGE_PATH", "./data/attachments"))
+		if err != nil {
+			log.Fatal("Failed to open attachment storage:", err)
+		}
+		return store
+	case "s3":
+		store, err := storage.NewS3Storage(storage.S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PathStyle: getEnv("S3_PATH_STYLE", "false") == "true",
+		}, &http.Client{Timeout: 5 * time.Minute})
+		if err != nil {
+			log.Fatal("Failed to configure attachment storage:", err)
+		}
+		return store
+	default:
+		log.Fatalf("Unknown STORAGE_BACKEND %q, expected local or s3", backend)
+		return nil
+	}
+}
+
+// caldavAuth authenticates CalDAV clients with the email and password of the account
+func caldavAuth(authService *services.AuthService) gin.HandlerFunc {
+	return middleware.RequireBasicAuth("Todo List", func(email, password string) (uint, error) {
+		user, err := authService.CheckPassword(email, password)
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return user.ID, nil
+	})
+}
+
+// webhookClient returns the client webhooks are sent with. It only connects
+// to public addresses unless WEBHOOK_ALLOW_PRIVATE is true, e.g. for a
+// receiver on the same machine during development.
+func webhookClient() *http.Client {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if getEnv("WEBHOOK_ALLOW_PRIVATE", "false") == "true" {
+		return client
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: services.PublicAddressesOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // A proxy would be the address checked instead of the receiver
+	transport.DialContext = dialer.DialContext
+	client.Transport = transport
+	return client
+}
+
+// notifiers returns the notifiers for reminders: webhooks always, and email
+// when SMTP_HOST is set
+func notifiers(webhooks *services.WebhookService) map[string]services.Notifier {
+	notifiers := map[string]services.Notifier{models.ChannelWebhook: services.NewWebhookNotifier(webhooks)}
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		email, err := services.NewSMTPNotifier(services.SMTPConfig{
+			Host:     host,
+			Port:     int(getInt64("SMTP_PORT", 25)),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     getEnv("SMTP_FROM", "Todo List <noreply@localhost>"),
+		})
+		if err != nil {
+			log.Fatal("Failed to configure email notifications:", err)
+		}
+		notifiers[models.ChannelEmail] = email
+	}
+	return notifiers
+}
+
+// apiContract loads the API documentation that requests are checked against
+func apiContract() *openapi.Document {
+	spec, err := apidocs.Spec()
+	if err != nil {
+		log.Fatal("Failed to load API documentation:", err)
+	}
+	doc, err := openapi.New(spec)
+	if err != nil {
+		log.Fatal("Failed to load API documentation:", err)
+	}
+	return doc
+}
+
+// jwtSecret returns the access token signing key from JWT_SECRET, falling back
+// to a random key so development setups work without configuration
+func jwtSecret() []byte {
+	if secret := os.Getenv("JWT_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	log.Println("JWT_SECRET is not set, using a random key; access tokens will not survive restarts")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatal("Failed to generate JWT secret:", err)
+	}
+	return secret
+}
@@ -0,0 +1,34 @@
+// Package apidocs holds the OpenAPI document of the HTTP API.
+package apidocs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// specYAML is the OpenAPI document as it is written, in YAML
+//
+//go:embed openapi.yaml
+var specYAML []byte
+
+// Spec returns the OpenAPI document as a tree of maps, slices and scalars,
+// the way encoding/json decodes a JSON document
+func Spec() (map[string]interface{}, error) {
+	var spec map[string]interface{}
+	if err := yaml.Unmarshal(specYAML, &spec); err != nil {
+		return nil, fmt.Errorf("parse OpenAPI document: %w", err)
+	}
+	return spec, nil
+}
+
+// JSON returns the OpenAPI document converted to JSON
+func JSON() ([]byte, error) {
+	spec, err := Spec()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(spec)
+}
@@ -0,0 +1,110 @@
+package apidocs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON(t *testing.T) {
+	doc, err := JSON()
+	if !assert.NoError(t, err) {
+		return
+	}
+	var spec map[string]interface{}
+	assert.NoError(t, json.Unmarshal(doc, &spec))
+	assert.Equal(t, "3.1.0", spec["openapi"])
+}
+
+func TestSpec_RefsResolve(t *testing.T) {
+	spec, err := Spec()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch node := node.(type) {
+		case map[string]interface{}:
+			if ref, ok := node["$ref"].(string); ok {
+				assert.NotNil(t, resolve(spec, ref), "%s does not resolve", ref)
+			}
+			for _, child := range node {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range node {
+				walk(child)
+			}
+		}
+	}
+	walk(spec)
+}
+
+func TestSpec_ModelSchemas(t *testing.T) {
+	spec, err := Spec()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Every field a model is served with is in its schema
+	now := time.Now()
+	categoryID := uint(1)
+	count := int64(1)
+	for name, model := range map[string]interface{}{
+		"Todo": models.Todo{
+			CategoryID: &categoryID, ParentID: &categoryID, DueDate: &now,
+			Category: &models.Category{}, Subtasks: []models.Todo{{}}, Progress: &models.Progress{},
+			Tags: []models.Tag{{}}, Search: &models.SearchMatch{}, CommentCount: &count,
+		},
+		"Category": models.Category{Todos: []models.Todo{{}}},
+		"Tag":      models.Tag{},
+		"User":     models.User{},
+	} {
+		body, _ := json.Marshal(model)
+		var fields map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &fields))
+
+		schema, _ := resolve(spec, "#/components/schemas/"+name).(map[string]interface{})
+		properties, _ := schema["properties"].(map[string]interface{})
+		for field := range fields {
+			assert.Contains(t, properties, field, "%s.%s is missing from the schema", name, field)
+		}
+	}
+}
+
+func TestSpec_WebhookEventTypes(t *testing.T) {
+	spec, err := Spec()
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// Webhooks can subscribe to every event type and only those
+	schema, _ := resolve(spec, "#/components/schemas/WebhookEventType").(map[string]interface{})
+	want := []interface{}{"*"}
+	for _, eventType := range events.Types {
+		want = append(want, eventType)
+	}
+	assert.Equal(t, want, schema["enum"])
+}
+
+// resolve returns the node a local $ref points to, or nil
+func resolve(spec map[string]interface{}, ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var node interface{} = spec
+	for _, key := range strings.Split(ref[2:], "/") {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = object[strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~")]
+	}
+	return node
+}
@@ -0,0 +1,108 @@
+// Package auth issues and verifies the tokens used to authenticate API requests.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is returned for access tokens that are malformed, expired or wrongly signed
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// issuer is the iss claim of every access token
+const issuer = "todoListChallenge"
+
+// TokenManager signs and verifies HS256 JWT access tokens
+type TokenManager struct {
+	secret    []byte
+	accessTTL time.Duration
+	now       func() time.Time
+}
+
+// NewTokenManager creates a new TokenManager
+func NewTokenManager(secret []byte, accessTTL time.Duration) *TokenManager {
+	return &TokenManager{secret: secret, accessTTL: accessTTL, now: time.Now}
+}
+
+// AccessTTL returns how long access tokens stay valid
+func (m *TokenManager) AccessTTL() time.Duration {
+	return m.accessTTL
+}
+
+// IssueAccessToken creates a signed access token for a user
+func (m *TokenManager) IssueAccessToken(userID uint) (string, error) {
+	now := m.now()
+	claims := jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   strconv.FormatUint(uint64(userID), 10),
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(m.accessTTL)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+// ParseAccessToken verifies an access token and returns the user ID it was issued for
+func (m *TokenManager) ParseAccessToken(token string) (uint, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(m.now),
+	)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+	return uint(userID), nil
+}
+
+// NewRefreshToken generates a random opaque refresh token and the hash to store for it
+func NewRefreshToken() (token, hash string, err error) {
+	return newOpaqueToken("refresh token")
+}
+
+// HashRefreshToken returns the stored form of a refresh token
+func HashRefreshToken(token string) string {
+	return hashToken(token)
+}
+
+// NewFeedToken generates a random calendar feed token and the hash to store for it
+func NewFeedToken() (token, hash string, err error) {
+	return newOpaqueToken("feed token")
+}
+
+// HashFeedToken returns the stored form of a calendar feed token
+func HashFeedToken(token string) string {
+	return hashToken(token)
+}
+
+// newOpaqueToken generates 256 random bits, URL-safe encoded, and their hash
+func newOpaqueToken(kind string) (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate %s: %w", kind, err)
+	}
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	return token, hashToken(token), nil
+}
+
+// hashToken returns the hex SHA-256 of an opaque token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
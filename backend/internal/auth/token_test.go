@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenManager(t *testing.T) {
+	manager := NewTokenManager([]byte("secret"), time.Minute)
+
+	t.Run("round trip", func(t *testing.T) {
+		token, err := manager.IssueAccessToken(42)
+		assert.NoError(t, err)
+
+		userID, err := manager.ParseAccessToken(token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, uint(42), userID)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		token, _ := manager.IssueAccessToken(42)
+		expired := NewTokenManager([]byte("secret"), time.Minute)
+		expired.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+		_, err := expired.ParseAccessToken(token)
+
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		token, _ := NewTokenManager([]byte("other"), time.Minute).IssueAccessToken(42)
+
+		_, err := manager.ParseAccessToken(token)
+
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("refresh tokens are hashed", func(t *testing.T) {
+		token, hash, err := NewRefreshToken()
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, token, hash)
+		assert.Equal(t, hash, HashRefreshToken(token))
+	})
+
+	t.Run("feed tokens are hashed", func(t *testing.T) {
+		token, hash, err := NewFeedToken()
+		other, _, _ := NewFeedToken()
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, token, other)
+		assert.Equal(t, hash, HashFeedToken(token))
+	})
+}
@@ -0,0 +1,241 @@
+// Package caldav reads the XML bodies of WebDAV and CalDAV requests (RFC
+// 4918, RFC 4791, RFC 6578) and writes multistatus and error responses.
+package caldav
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// XML namespaces of the properties and reports served
+const (
+	NamespaceDAV            = "DAV:"
+	NamespaceCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	NamespaceCalendarServer = "http://calendarserver.org/ns/"
+	NamespaceAppleICal      = "http://apple.com/ns/ical/"
+)
+
+// prefixes are the namespace prefixes declared on every response
+var prefixes = []struct{ prefix, space string }{
+	{"d", NamespaceDAV},
+	{"c", NamespaceCalDAV},
+	{"cs", NamespaceCalendarServer},
+	{"ic", NamespaceAppleICal},
+}
+
+// DAV returns the name of an element in the DAV: namespace
+func DAV(local string) xml.Name {
+	return xml.Name{Space: NamespaceDAV, Local: local}
+}
+
+// CalDAV returns the name of an element in the CalDAV namespace
+func CalDAV(local string) xml.Name {
+	return xml.Name{Space: NamespaceCalDAV, Local: local}
+}
+
+// Node is an element of a request body
+type Node struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Nodes    []Node     `xml:",any"`
+	CharData string     `xml:",chardata"`
+}
+
+// Parse reads a request body. An empty body is returned as a nil Node.
+func Parse(r io.Reader) (*Node, error) {
+	var node Node
+	if err := xml.NewDecoder(r).Decode(&node); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &node, nil
+}
+
+// Is reports whether the node is the named element
+func (n *Node) Is(name xml.Name) bool {
+	return n != nil && n.XMLName == name
+}
+
+// Child returns the first child element with the given name, or nil
+func (n *Node) Child(name xml.Name) *Node {
+	if n == nil {
+		return nil
+	}
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName == name {
+			return &n.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// Children returns every child element with the given name
+func (n *Node) Children(name xml.Name) []*Node {
+	if n == nil {
+		return nil
+	}
+	var children []*Node
+	for i := range n.Nodes {
+		if n.Nodes[i].XMLName == name {
+			children = append(children, &n.Nodes[i])
+		}
+	}
+	return children
+}
+
+// Attr returns the value of an attribute without namespace, or ""
+func (n *Node) Attr(local string) string {
+	if n == nil {
+		return ""
+	}
+	for _, attr := range n.Attrs {
+		if attr.Name.Space == "" && attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// Text returns the trimmed character data of the node
+func (n *Node) Text() string {
+	if n == nil {
+		return ""
+	}
+	return strings.TrimSpace(n.CharData)
+}
+
+// PropNames returns the names of the properties a propfind or report asks
+// for, and nil when it asks for all of them
+func (n *Node) PropNames() []xml.Name {
+	prop := n.Child(DAV("prop"))
+	if prop == nil {
+		return nil
+	}
+	names := make([]xml.Name, len(prop.Nodes))
+	for i, node := range prop.Nodes {
+		names[i] = node.XMLName
+	}
+	return names
+}
+
+// Prop is a property value. XML is the content of the property element,
+// already escaped.
+type Prop struct {
+	Name xml.Name
+	XML  string
+}
+
+// Response is the result for one resource in a multistatus. A resource that
+// does not exist has a Status and no properties.
+type Response struct {
+	Href    string
+	Status  int
+	Props   []Prop
+	Missing []xml.Name // Requested properties the resource does not have
+}
+
+// Multistatus writes a 207 Multi-Status body. A non-empty syncToken is
+// added for sync-collection reports.
+func Multistatus(w io.Writer, responses []Response, syncToken string) error {
+	b := bufio.NewWriter(w)
+	b.WriteString(xml.Header)
+	b.WriteString("<d:multistatus")
+	for _, p := range prefixes {
+		fmt.Fprintf(b, ` xmlns:%s="%s"`, p.prefix, p.space)
+	}
+	b.WriteString(">")
+	for _, response := range responses {
+		b.WriteString("<d:response><d:href>" + Escape(response.Href) + "</d:href>")
+		if response.Status != 0 {
+			b.WriteString("<d:status>" + statusLine(response.Status) + "</d:status>")
+		}
+		if len(response.Props) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, prop := range response.Props {
+				writeElement(b, prop.Name, prop.XML)
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusOK) + "</d:status></d:propstat>")
+		}
+		if len(response.Missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, name := range response.Missing {
+				writeElement(b, name, "")
+			}
+			b.WriteString("</d:prop><d:status>" + statusLine(http.StatusNotFound) + "</d:status></d:propstat>")
+		}
+		b.WriteString("</d:response>")
+	}
+	if syncToken != "" {
+		b.WriteString("<d:sync-token>" + Escape(syncToken) + "</d:sync-token>")
+	}
+	b.WriteString("</d:multistatus>")
+	return b.Flush()
+}
+
+// Error writes the body of a failed precondition, such as CalDAV's
+// valid-calendar-data, with an optional human readable description
+func Error(w io.Writer, condition xml.Name, description string) error {
+	b := bufio.NewWriter(w)
+	b.WriteString(xml.Header)
+	b.WriteString("<d:error")
+	for _, p := range prefixes {
+		fmt.Fprintf(b, ` xmlns:%s="%s"`, p.prefix, p.space)
+	}
+	b.WriteString(">")
+	writeElement(b, condition, "")
+	if description != "" {
+		writeElement(b, DAV("responsedescription"), Escape(description))
+	}
+	b.WriteString("</d:error>")
+	return b.Flush()
+}
+
+// Href returns the content of a property holding one href
+func Href(path string) string {
+	return "<d:href>" + Escape(path) + "</d:href>"
+}
+
+// Escape escapes text for use in element content
+func Escape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+// writeElement writes an element, declaring its namespace inline unless it
+// has one of the usual prefixes
+func writeElement(b *bufio.Writer, name xml.Name, content string) {
+	tag, declaration := name.Local, ""
+	if prefix := prefixOf(name.Space); prefix != "" {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		tag = "x:" + name.Local
+		declaration = ` xmlns:x="` + Escape(name.Space) + `"`
+	}
+	if content == "" {
+		b.WriteString("<" + tag + declaration + "/>")
+		return
+	}
+	b.WriteString("<" + tag + declaration + ">" + content + "</" + tag + ">")
+}
+
+// prefixOf returns the declared prefix of a namespace, or ""
+func prefixOf(space string) string {
+	for _, p := range prefixes {
+		if p.space == space {
+			return p.prefix
+		}
+	}
+	return ""
+}
+
+// statusLine returns the HTTP status line of a code
+func statusLine(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
@@ -0,0 +1,96 @@
+package caldav
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("propfind", func(t *testing.T) {
+		body := `<?xml version="1.0" encoding="utf-8"?>
+<propfind xmlns="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <prop><displayname/><C:supported-calendar-component-set/><getetag/></prop>
+</propfind>`
+
+		node, err := Parse(strings.NewReader(body))
+
+		assert.NoError(t, err)
+		assert.True(t, node.Is(DAV("propfind")))
+		assert.Equal(t, []xml.Name{DAV("displayname"), CalDAV("supported-calendar-component-set"), DAV("getetag")}, node.PropNames())
+	})
+
+	t.Run("allprop", func(t *testing.T) {
+		node, err := Parse(strings.NewReader(`<d:propfind xmlns:d="DAV:"><d:allprop/></d:propfind>`))
+
+		assert.NoError(t, err)
+		assert.Nil(t, node.PropNames())
+	})
+
+	t.Run("empty body", func(t *testing.T) {
+		node, err := Parse(strings.NewReader(""))
+
+		assert.NoError(t, err)
+		assert.Nil(t, node)
+		assert.Nil(t, node.PropNames())
+		assert.Empty(t, node.Child(DAV("prop")).Text())
+	})
+
+	t.Run("calendar query filter", func(t *testing.T) {
+		body := `<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/></d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VTODO">
+    <c:prop-filter name="COMPLETED"><c:is-not-defined/></c:prop-filter>
+  </c:comp-filter></c:comp-filter></c:filter>
+</c:calendar-query>`
+
+		node, err := Parse(strings.NewReader(body))
+
+		assert.NoError(t, err)
+		todo := node.Child(CalDAV("filter")).Child(CalDAV("comp-filter")).Child(CalDAV("comp-filter"))
+		assert.Equal(t, "VTODO", todo.Attr("name"))
+		assert.Len(t, todo.Children(CalDAV("prop-filter")), 1)
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		_, err := Parse(strings.NewReader("<d:propfind xmlns:d=\"DAV:\">"))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestMultistatus(t *testing.T) {
+	var out strings.Builder
+	err := Multistatus(&out, []Response{
+		{
+			Href:    "/dav/calendars/1/",
+			Props:   []Prop{{Name: DAV("displayname"), XML: Escape("Work & Home")}, {Name: xml.Name{Space: "http://example.com/ns", Local: "custom"}, XML: "1"}},
+			Missing: []xml.Name{CalDAV("calendar-timezone")},
+		},
+		{Href: "/dav/calendars/1/gone.ics", Status: http.StatusNotFound},
+	}, "http://todolist/ns/sync/1")
+
+	assert.NoError(t, err)
+	body := out.String()
+	assert.Contains(t, body, `<d:displayname>Work &amp; Home</d:displayname>`)
+	assert.Contains(t, body, `<x:custom xmlns:x="http://example.com/ns">1</x:custom>`)
+	assert.Contains(t, body, `<d:prop><c:calendar-timezone/></d:prop><d:status>HTTP/1.1 404 Not Found</d:status>`)
+	assert.Contains(t, body, `<d:href>/dav/calendars/1/gone.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`)
+	assert.Contains(t, body, `<d:sync-token>http://todolist/ns/sync/1</d:sync-token></d:multistatus>`)
+
+	// The output is well-formed and reads back
+	node, err := Parse(strings.NewReader(body))
+	assert.NoError(t, err)
+	assert.Len(t, node.Children(DAV("response")), 2)
+}
+
+func TestError(t *testing.T) {
+	var out strings.Builder
+	err := Error(&out, CalDAV("no-uid-conflict"), "UID <abc> is taken")
+
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `<c:no-uid-conflict/><d:responsedescription>UID &lt;abc&gt; is taken</d:responsedescription></d:error>`)
+}
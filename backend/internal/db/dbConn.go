@@ -30,8 +30,9 @@ func InitDB() {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
 		host, user, password, dbname, port, sslmode)
 
-	// Connect to database
-	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// Connect to database; TranslateError turns constraint violations into
+	// gorm errors the services recognise
+	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
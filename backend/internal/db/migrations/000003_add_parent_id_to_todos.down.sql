@@ -0,0 +1,3 @@
+-- Drop parent_id from todos
+DROP INDEX IF EXISTS idx_todos_parent_id;
+ALTER TABLE todos DROP COLUMN IF EXISTS parent_id;
\ No newline at end of file
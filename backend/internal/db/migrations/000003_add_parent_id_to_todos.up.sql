@@ -0,0 +1,5 @@
+-- Add parent_id to todos for subtasks
+ALTER TABLE todos ADD COLUMN parent_id INTEGER REFERENCES todos(id) ON DELETE CASCADE;
+
+-- Create index on parent_id for loading subtasks
+CREATE INDEX idx_todos_parent_id ON todos(parent_id);
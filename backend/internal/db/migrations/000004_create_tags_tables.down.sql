@@ -0,0 +1,3 @@
+-- Drop tags tables
+DROP TABLE IF EXISTS todo_tags;
+DROP TABLE IF EXISTS tags;
\ No newline at end of file
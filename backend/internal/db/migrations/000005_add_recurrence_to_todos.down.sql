@@ -0,0 +1,2 @@
+-- Drop recurrence from todos
+ALTER TABLE todos DROP COLUMN IF EXISTS recurrence;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Add recurrence rule (RFC 5545 RRULE value) to todos
+ALTER TABLE todos ADD COLUMN recurrence VARCHAR(255);
@@ -0,0 +1,15 @@
+-- Remove ownership from todos, categories and tags
+DROP INDEX IF EXISTS idx_tags_user_name;
+ALTER TABLE tags DROP COLUMN IF EXISTS user_id;
+ALTER TABLE tags ADD CONSTRAINT tags_name_key UNIQUE (name);
+
+DROP INDEX IF EXISTS idx_categories_user_name;
+ALTER TABLE categories DROP COLUMN IF EXISTS user_id;
+ALTER TABLE categories ADD CONSTRAINT categories_name_key UNIQUE (name);
+
+DROP INDEX IF EXISTS idx_todos_user_id;
+ALTER TABLE todos DROP COLUMN IF EXISTS user_id;
+
+-- Drop users tables
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS users;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Remove full-text search from todos
+DROP INDEX IF EXISTS idx_todos_search_vector;
+ALTER TABLE todos DROP COLUMN IF EXISTS search_vector;
\ No newline at end of file
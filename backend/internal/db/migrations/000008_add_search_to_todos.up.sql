@@ -0,0 +1,7 @@
+-- Full-text search over todos: titles weigh more than descriptions
+ALTER TABLE todos ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'B')
+) STORED;
+
+CREATE INDEX idx_todos_search_vector ON todos USING GIN (search_vector);
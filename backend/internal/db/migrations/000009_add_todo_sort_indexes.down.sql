@@ -0,0 +1,6 @@
+-- Remove keyset pagination indexes
+DROP INDEX IF EXISTS idx_todos_user_priority;
+DROP INDEX IF EXISTS idx_todos_user_title;
+DROP INDEX IF EXISTS idx_todos_user_due_date;
+DROP INDEX IF EXISTS idx_todos_user_updated_at;
+DROP INDEX IF EXISTS idx_todos_user_created_at;
\ No newline at end of file
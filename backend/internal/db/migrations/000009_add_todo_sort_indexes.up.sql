@@ -0,0 +1,10 @@
+-- Indexes for keyset pagination: each sort key followed by the id tie-breaker
+CREATE INDEX idx_todos_user_created_at ON todos (user_id, created_at, id);
+CREATE INDEX idx_todos_user_updated_at ON todos (user_id, updated_at, id);
+CREATE INDEX idx_todos_user_due_date ON todos (user_id, due_date, id);
+CREATE INDEX idx_todos_user_title ON todos (user_id, title, id);
+CREATE INDEX idx_todos_user_priority ON todos (
+    user_id,
+    (CASE priority WHEN 'low' THEN 1 WHEN 'medium' THEN 2 WHEN 'high' THEN 3 ELSE 0 END),
+    id
+);
@@ -0,0 +1,3 @@
+-- Remove versions from todos and categories
+ALTER TABLE categories DROP COLUMN IF EXISTS version;
+ALTER TABLE todos DROP COLUMN IF EXISTS version;
\ No newline at end of file
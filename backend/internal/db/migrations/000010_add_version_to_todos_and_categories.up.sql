@@ -0,0 +1,3 @@
+-- Versions for optimistic concurrency control, served as ETags
+ALTER TABLE todos ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE categories ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
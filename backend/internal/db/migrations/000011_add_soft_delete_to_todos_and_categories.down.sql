@@ -0,0 +1,13 @@
+-- Remove soft deletion; everything in the trash is deleted for good
+DELETE FROM todos WHERE deleted_at IS NOT NULL;
+DELETE FROM categories WHERE deleted_at IS NOT NULL;
+
+DROP INDEX idx_categories_user_name;
+CREATE UNIQUE INDEX idx_categories_user_name ON categories(user_id, name);
+
+DROP INDEX IF EXISTS idx_categories_deleted_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_at;
+
+DROP INDEX IF EXISTS idx_todos_deleted_at;
+ALTER TABLE todos DROP COLUMN IF EXISTS trashed_category_id;
+ALTER TABLE todos DROP COLUMN IF EXISTS deleted_at;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- Soft deletion: deleted todos and categories stay in the trash until they are purged
+ALTER TABLE todos ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE todos ADD COLUMN trashed_category_id INTEGER REFERENCES categories(id) ON DELETE SET NULL;
+CREATE INDEX idx_todos_deleted_at ON todos(deleted_at);
+
+ALTER TABLE categories ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX idx_categories_deleted_at ON categories(deleted_at);
+
+-- Category names only need to be unique outside the trash
+DROP INDEX idx_categories_user_name;
+CREATE UNIQUE INDEX idx_categories_user_name ON categories(user_id, name) WHERE deleted_at IS NULL;
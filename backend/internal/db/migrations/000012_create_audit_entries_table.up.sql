@@ -0,0 +1,17 @@
+-- Audit log of changes to todos and categories
+CREATE TABLE audit_entries (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id INTEGER NOT NULL,
+    entity_type VARCHAR(20) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    version INTEGER,
+    changes TEXT NOT NULL,
+    state TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_entries_user_id ON audit_entries(user_id);
+CREATE INDEX idx_audit_entries_entity ON audit_entries(entity_type, entity_id);
+CREATE INDEX idx_audit_entries_created_at ON audit_entries(created_at);
@@ -0,0 +1,13 @@
+-- Create comments table for discussions on todos
+CREATE TABLE comments (
+    id SERIAL PRIMARY KEY,
+    todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    mentions TEXT,
+    edited_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_comments_todo_id ON comments(todo_id);
+CREATE INDEX idx_comments_user_id ON comments(user_id);
@@ -0,0 +1,3 @@
+-- Drop attachment tables
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS attachment_blobs;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- Drop reminder tables
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS reminders;
\ No newline at end of file
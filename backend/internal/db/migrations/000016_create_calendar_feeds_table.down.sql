@@ -0,0 +1,2 @@
+-- Drop calendar feeds table
+DROP TABLE IF EXISTS calendar_feeds;
\ No newline at end of file
@@ -0,0 +1,2 @@
+-- Drop CalDAV objects table
+DROP TABLE IF EXISTS caldav_objects;
\ No newline at end of file
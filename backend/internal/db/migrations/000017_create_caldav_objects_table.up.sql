@@ -0,0 +1,11 @@
+-- Create CalDAV objects table, the names and UIDs clients gave the todos they created
+CREATE TABLE caldav_objects (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    todo_id INTEGER NOT NULL UNIQUE REFERENCES todos(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    uid VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_caldav_objects_user_name ON caldav_objects(user_id, name);
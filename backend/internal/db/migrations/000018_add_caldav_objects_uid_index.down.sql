@@ -0,0 +1,2 @@
+-- Drop the unique index of CalDAV UIDs
+DROP INDEX IF EXISTS idx_caldav_objects_user_uid;
@@ -0,0 +1,6 @@
+-- Give every UID to one todo per user. Where several todos share one, the
+-- newest object keeps it and the others fall back to their default UID.
+DELETE FROM caldav_objects
+WHERE id NOT IN (SELECT MAX(id) FROM caldav_objects GROUP BY user_id, uid);
+
+CREATE UNIQUE INDEX idx_caldav_objects_user_uid ON caldav_objects(user_id, uid);
@@ -0,0 +1,198 @@
+// Package events provides the in-process event bus that services publish
+// changes to and that the real-time change feed reads from.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published by the services
+const (
+	TodoCreated      = "todo.created"
+	TodoUpdated      = "todo.updated"
+	TodoDeleted      = "todo.deleted"
+	TodoToggled      = "todo.toggled"
+	TodoRestored     = "todo.restored"
+	CategoryCreated  = "category.created"
+	CategoryUpdated  = "category.updated"
+	CategoryDeleted  = "category.deleted"
+	CategoryRestored = "category.restored"
+	CommentCreated   = "comment.created"
+	CommentUpdated   = "comment.updated"
+	CommentDeleted   = "comment.deleted"
+
+	// ReminderDue is sent to webhooks when a reminder with the webhook
+	// channel fires; NotificationCreated carries in-app notifications
+	ReminderDue         = "reminder.due"
+	NotificationCreated = "notification.created"
+
+	// Reset tells a resuming client that missed events are no longer
+	// available and it should reload its data
+	Reset = "reset"
+)
+
+// Types lists the event types webhooks can subscribe to
+var Types = []string{
+	TodoCreated, TodoUpdated, TodoDeleted, TodoToggled, TodoRestored,
+	CategoryCreated, CategoryUpdated, CategoryDeleted, CategoryRestored,
+	CommentCreated, CommentUpdated, CommentDeleted,
+	ReminderDue, NotificationCreated,
+}
+
+// DefaultHistorySize is how many recent events are kept for resuming clients
+const DefaultHistorySize = 1000
+
+// subscriberBuffer is how many undelivered events a subscriber may fall behind
+// before it is dropped
+const subscriberBuffer = 64
+
+// Event is a change that happened to one user's data
+type Event struct {
+	ID     uint64      `json:"id"`
+	Type   string      `json:"type"`
+	UserID uint        `json:"-"`
+	Data   interface{} `json:"data"`
+	Time   time.Time   `json:"time"`
+}
+
+// Deleted is the data of a delete event
+type Deleted struct {
+	ID uint `json:"id"`
+	// IDs lists every removed todo when subtasks were deleted along with it
+	IDs []uint `json:"ids,omitempty"`
+}
+
+// Subscription receives the events of one user until it is closed
+type Subscription struct {
+	// Events is closed when the subscription ends, either through Close or
+	// because the subscriber fell too far behind
+	Events <-chan Event
+
+	bus    *Bus
+	ch     chan Event
+	userID uint
+	all    bool
+}
+
+// Bus fans published events out to subscribers and keeps a short history so
+// clients can resume after reconnecting
+type Bus struct {
+	mu          sync.Mutex
+	nextID      uint64
+	history     []Event
+	historySize int
+	subscribers map[*Subscription]struct{}
+	now         func() time.Time
+}
+
+// NewBus creates a new Bus that keeps the last historySize events
+func NewBus(historySize int) *Bus {
+	return &Bus{
+		historySize: historySize,
+		subscribers: make(map[*Subscription]struct{}),
+		now:         time.Now,
+	}
+}
+
+// Publish sends an event to every subscriber of the user. Publishing on a nil
+// Bus is a no-op so services can be used without a change feed.
+func (b *Bus) Publish(userID uint, eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event := Event{ID: b.nextID, Type: eventType, UserID: userID, Data: data, Time: b.now()}
+	if b.historySize > 0 {
+		if len(b.history) == b.historySize {
+			b.history = append(b.history[:0], b.history[1:]...)
+		}
+		b.history = append(b.history, event)
+	}
+
+	for sub := range b.subscribers {
+		if !sub.all && sub.userID != userID {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			// A subscriber that cannot keep up is dropped; it can resume
+			// from its last event ID when it reconnects
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe starts receiving the events of a user. When lastEventID is not
+// zero, the events published after it are returned for replay. If some of them
+// are no longer in the history, the replay is a single Reset event instead.
+func (b *Bus) Subscribe(userID uint, lastEventID uint64) (*Subscription, []Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Event
+	if lastEventID > 0 {
+		var ok bool
+		if replay, ok = b.since(userID, lastEventID); !ok {
+			replay = []Event{{ID: b.nextID, Type: Reset, UserID: userID, Time: b.now()}}
+		}
+	}
+	return b.add(userID, false), replay
+}
+
+// SubscribeAll receives the events of every user, for server-side consumers
+func (b *Bus) SubscribeAll() *Subscription {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.add(0, true)
+}
+
+// Close ends the subscription
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
+
+func (b *Bus) add(userID uint, all bool) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{Events: ch, bus: b, ch: ch, userID: userID, all: all}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// remove must be called with b.mu held
+func (b *Bus) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// since returns the user's events after lastEventID. It reports false when the
+// history no longer reaches back that far or the ID was never issued, e.g.
+// because the server restarted.
+func (b *Bus) since(userID uint, lastEventID uint64) ([]Event, bool) {
+	if lastEventID > b.nextID {
+		return nil, false
+	}
+	if lastEventID == b.nextID {
+		return nil, true
+	}
+	if len(b.history) == 0 || b.history[0].ID > lastEventID+1 {
+		return nil, false
+	}
+
+	var events []Event
+	for _, event := range b.history {
+		if event.ID > lastEventID && event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	return events, true
+}
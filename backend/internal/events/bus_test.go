@@ -0,0 +1,112 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func receive(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event, ok := <-sub.Events:
+		assert.True(t, ok, "subscription closed")
+		return event
+	default:
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestBus(t *testing.T) {
+	t.Run("Delivers events only to the user's subscribers", func(t *testing.T) {
+		bus := NewBus(10)
+		alice, _ := bus.Subscribe(1, 0)
+		bob, _ := bus.Subscribe(2, 0)
+
+		bus.Publish(1, TodoCreated, "payload")
+
+		event := receive(t, alice)
+		assert.Equal(t, uint64(1), event.ID)
+		assert.Equal(t, TodoCreated, event.Type)
+		assert.Equal(t, "payload", event.Data)
+		assert.Empty(t, bob.Events)
+	})
+
+	t.Run("SubscribeAll receives every user's events", func(t *testing.T) {
+		bus := NewBus(10)
+		all := bus.SubscribeAll()
+
+		bus.Publish(1, TodoCreated, nil)
+		bus.Publish(2, CategoryDeleted, nil)
+
+		assert.Equal(t, uint(1), receive(t, all).UserID)
+		assert.Equal(t, uint(2), receive(t, all).UserID)
+	})
+
+	t.Run("Replays missed events after the last event ID", func(t *testing.T) {
+		bus := NewBus(10)
+		bus.Publish(1, TodoCreated, "a")
+		bus.Publish(2, TodoCreated, "other user")
+		bus.Publish(1, TodoUpdated, "b")
+		bus.Publish(1, TodoDeleted, "c")
+
+		_, replay := bus.Subscribe(1, 1)
+		assert.Len(t, replay, 2)
+		assert.Equal(t, uint64(3), replay[0].ID)
+		assert.Equal(t, uint64(4), replay[1].ID)
+
+		_, replay = bus.Subscribe(1, 4)
+		assert.Empty(t, replay)
+	})
+
+	t.Run("Asks for a reset when the history no longer covers the last event ID", func(t *testing.T) {
+		bus := NewBus(2)
+		for i := 0; i < 5; i++ {
+			bus.Publish(1, TodoUpdated, i)
+		}
+
+		_, replay := bus.Subscribe(1, 1)
+		assert.Len(t, replay, 1)
+		assert.Equal(t, Reset, replay[0].Type)
+		assert.Equal(t, uint64(5), replay[0].ID)
+
+		_, replay = bus.Subscribe(1, 3)
+		assert.Len(t, replay, 2)
+		assert.Equal(t, TodoUpdated, replay[0].Type)
+
+		// An ID from before a restart is unknown to the new bus
+		_, replay = bus.Subscribe(1, 99)
+		assert.Equal(t, Reset, replay[0].Type)
+	})
+
+	t.Run("Close ends the subscription", func(t *testing.T) {
+		bus := NewBus(10)
+		sub, _ := bus.Subscribe(1, 0)
+		sub.Close()
+		sub.Close()
+
+		bus.Publish(1, TodoCreated, nil)
+		_, ok := <-sub.Events
+		assert.False(t, ok)
+	})
+
+	t.Run("Drops subscribers that fall behind", func(t *testing.T) {
+		bus := NewBus(0)
+		sub, _ := bus.Subscribe(1, 0)
+		for i := 0; i <= subscriberBuffer; i++ {
+			bus.Publish(1, TodoUpdated, i)
+		}
+
+		received := 0
+		for range sub.Events {
+			received++
+		}
+		assert.Equal(t, subscriberBuffer, received)
+	})
+
+	t.Run("Publishing on a nil bus is a no-op", func(t *testing.T) {
+		var bus *Bus
+		assert.NotPanics(t, func() { bus.Publish(1, TodoCreated, nil) })
+	})
+}
@@ -0,0 +1,328 @@
+package filter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// kind is the type of a filterable field
+type kind int
+
+const (
+	kindText     kind = iota // Free text, compared case-insensitively
+	kindBool                 // true or false; a bare field means true
+	kindPriority             // low < medium < high
+	kindDate                 // Dates and times; a bare field means the date is set
+	kindCategory             // Category name; "none" means no category
+	kindTag                  // Tag name; "none" means no tags
+)
+
+// field is a whitelisted field and the SQL it is compiled against
+type field struct {
+	kind   kind
+	column string
+	ops    []Op
+}
+
+// todoFields are the fields a todo filter may use
+var todoFields = map[string]field{
+	"title":       {kind: kindText, column: "todos.title", ops: []Op{OpEq, OpContains}},
+	"description": {kind: kindText, column: "todos.description", ops: []Op{OpEq, OpContains}},
+	"completed":   {kind: kindBool, column: "todos.completed", ops: []Op{OpPresent, OpEq}},
+	"recurring":   {kind: kindBool, column: "todos.recurrence", ops: []Op{OpPresent, OpEq}},
+	"priority":    {kind: kindPriority, column: "todos.priority", ops: []Op{OpEq, OpLt, OpLte, OpGt, OpGte}},
+	"due":         {kind: kindDate, column: "todos.due_date", ops: []Op{OpPresent, OpEq, OpLt, OpLte, OpGt, OpGte}},
+	"created":     {kind: kindDate, column: "todos.created_at", ops: []Op{OpEq, OpLt, OpLte, OpGt, OpGte}},
+	"updated":     {kind: kindDate, column: "todos.updated_at", ops: []Op{OpEq, OpLt, OpLte, OpGt, OpGte}},
+	"category":    {kind: kindCategory, column: "todos.category_id", ops: []Op{OpPresent, OpEq, OpContains}},
+	"tag":         {kind: kindTag, column: "todos.id", ops: []Op{OpPresent, OpEq, OpContains}},
+}
+
+// priorities in ascending order
+var priorities = []string{"low", "medium", "high"}
+
+// dateLayouts are the accepted date and time formats
+var dateLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"}
+
+// Fields returns the names of the fields a filter may use
+func Fields() []string {
+	names := make([]string, 0, len(todoFields))
+	for name := range todoFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Env is what a filter is evaluated against
+type Env struct {
+	// UserID owns the categories and tags that names refer to
+	UserID uint
+	// Now resolves relative dates such as "today"
+	Now time.Time
+}
+
+// compiler turns a validated AST into SQL
+type compiler struct {
+	env Env
+}
+
+// Compile checks a parsed filter against the whitelisted fields and returns
+// it as a GORM clause for the todos table
+func Compile(node Node, env Env) (clause.Expr, error) {
+	c := &compiler{env: env}
+	sql, vars, err := c.compile(node)
+	if err != nil {
+		return clause.Expr{}, err
+	}
+	return clause.Expr{SQL: sql, Vars: vars}, nil
+}
+
+func (c *compiler) compile(node Node) (string, []interface{}, error) {
+	switch n := node.(type) {
+	case *And:
+		return c.join(n.Terms, " AND ")
+	case *Or:
+		return c.join(n.Terms, " OR ")
+	case *Not:
+		sql, vars, err := c.compile(n.Term)
+		if err != nil {
+			return "", nil, err
+		}
+		// Comparisons on NULL columns (no category, no due date) are unknown
+		// rather than false, so treat them as false before negating
+		return "NOT COALESCE(" + sql + ", FALSE)", vars, nil
+	case *Condition:
+		return c.condition(n)
+	}
+	return "", nil, fmt.Errorf("unknown filter node %T", node)
+}
+
+func (c *compiler) join(terms []Node, sep string) (string, []interface{}, error) {
+	parts := make([]string, len(terms))
+	var vars []interface{}
+	for i, term := range terms {
+		sql, termVars, err := c.compile(term)
+		if err != nil {
+			return "", nil, err
+		}
+		parts[i] = "(" + sql + ")"
+		vars = append(vars, termVars...)
+	}
+	return strings.Join(parts, sep), vars, nil
+}
+
+func (c *compiler) condition(cond *Condition) (string, []interface{}, error) {
+	f, ok := todoFields[cond.Field]
+	if !ok {
+		return "", nil, conditionError(cond, fmt.Sprintf("unknown field %q; use one of %s", cond.Field, strings.Join(Fields(), ", ")))
+	}
+	if !hasOp(f.ops, cond.Op) {
+		if cond.Op == OpPresent {
+			return "", nil, conditionError(cond, fmt.Sprintf("%s needs an operator and a value", cond.Field))
+		}
+		return "", nil, conditionError(cond, fmt.Sprintf("operator %q is not supported for %s", cond.Op, cond.Field))
+	}
+
+	switch f.kind {
+	case kindText:
+		return textCondition(f.column, cond)
+	case kindBool:
+		return c.boolCondition(f, cond)
+	case kindPriority:
+		return c.priorityCondition(f.column, cond)
+	case kindDate:
+		return c.dateCondition(f.column, cond)
+	case kindCategory:
+		return c.categoryCondition(cond)
+	default:
+		return c.tagCondition(cond)
+	}
+}
+
+func textCondition(column string, cond *Condition) (string, []interface{}, error) {
+	value := strings.ToLower(cond.Value)
+	if cond.Op == OpContains {
+		return "LOWER(" + column + ") LIKE ? ESCAPE '\\'", []interface{}{"%" + escapeLike(value) + "%"}, nil
+	}
+	return "LOWER(" + column + ") = ?", []interface{}{value}, nil
+}
+
+func (c *compiler) boolCondition(f field, cond *Condition) (string, []interface{}, error) {
+	value := true
+	if cond.Op == OpEq {
+		var err error
+		if value, err = parseBool(cond.Value); err != nil {
+			return "", nil, valueError(cond, "expected true or false")
+		}
+	}
+	if cond.Field == "recurring" {
+		if value {
+			return "COALESCE(" + f.column + ", '') <> ''", nil, nil
+		}
+		return "COALESCE(" + f.column + ", '') = ''", nil, nil
+	}
+	return f.column + " = ?", []interface{}{value}, nil
+}
+
+func (c *compiler) priorityCondition(column string, cond *Condition) (string, []interface{}, error) {
+	rank := -1
+	for i, p := range priorities {
+		if strings.EqualFold(cond.Value, p) {
+			rank = i
+		}
+	}
+	if rank < 0 {
+		return "", nil, valueError(cond, "expected low, medium or high")
+	}
+
+	var matching []string
+	for i, p := range priorities {
+		if (cond.Op == OpEq && i == rank) || (cond.Op == OpLt && i < rank) || (cond.Op == OpLte && i <= rank) ||
+			(cond.Op == OpGt && i > rank) || (cond.Op == OpGte && i >= rank) {
+			matching = append(matching, p)
+		}
+	}
+	if len(matching) == 0 {
+		return "1 = 0", nil, nil
+	}
+	return column + " IN ?", []interface{}{matching}, nil
+}
+
+func (c *compiler) dateCondition(column string, cond *Condition) (string, []interface{}, error) {
+	if cond.Op == OpPresent {
+		return column + " IS NOT NULL", nil, nil
+	}
+	if strings.EqualFold(cond.Value, "none") {
+		if cond.Op != OpEq || column != todoFields["due"].column {
+			return "", nil, valueError(cond, "none can only be used as due:none")
+		}
+		return column + " IS NULL", nil, nil
+	}
+
+	// A date covers [start, end); a date-time is a single instant
+	start, end, err := c.parseDate(cond.Value)
+	if err != nil {
+		return "", nil, valueError(cond, "expected a date like 2026-11-01, a date-time like 2026-11-01T09:00:00Z, today, tomorrow or yesterday")
+	}
+	switch cond.Op {
+	case OpLt:
+		return column + " < ?", []interface{}{start}, nil
+	case OpLte:
+		if end.Equal(start) {
+			return column + " <= ?", []interface{}{start}, nil
+		}
+		return column + " < ?", []interface{}{end}, nil
+	case OpGt:
+		if end.Equal(start) {
+			return column + " > ?", []interface{}{start}, nil
+		}
+		return column + " >= ?", []interface{}{end}, nil
+	case OpGte:
+		return column + " >= ?", []interface{}{start}, nil
+	}
+	if end.Equal(start) {
+		return column + " = ?", []interface{}{start}, nil
+	}
+	return column + " >= ? AND " + column + " < ?", []interface{}{start, end}, nil
+}
+
+// parseDate returns the range covered by a date value. For a date-time the
+// range is the single instant.
+func (c *compiler) parseDate(value string) (time.Time, time.Time, error) {
+	now := c.env.Now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch strings.ToLower(value) {
+	case "today":
+		return today, today.AddDate(0, 0, 1), nil
+	case "tomorrow":
+		return today.AddDate(0, 0, 1), today.AddDate(0, 0, 2), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+	}
+
+	for _, layout := range dateLayouts {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			continue
+		}
+		if layout == "2006-01-02" {
+			return t, t.AddDate(0, 0, 1), nil
+		}
+		return t.UTC(), t.UTC(), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+func (c *compiler) categoryCondition(cond *Condition) (string, []interface{}, error) {
+	switch {
+	case cond.Op == OpPresent:
+		return "todos.category_id IS NOT NULL", nil, nil
+	case cond.Op == OpEq && strings.EqualFold(cond.Value, "none"):
+		return "todos.category_id IS NULL", nil, nil
+	}
+	match, vars := nameMatch("name", cond)
+	return "todos.category_id IN (SELECT id FROM categories WHERE user_id = ? AND " + match + ")",
+		append([]interface{}{c.env.UserID}, vars...), nil
+}
+
+func (c *compiler) tagCondition(cond *Condition) (string, []interface{}, error) {
+	switch {
+	case cond.Op == OpPresent:
+		return "todos.id IN (SELECT todo_id FROM todo_tags)", nil, nil
+	case cond.Op == OpEq && strings.EqualFold(cond.Value, "none"):
+		return "todos.id NOT IN (SELECT todo_id FROM todo_tags)", nil, nil
+	}
+	match, vars := nameMatch("tags.name", cond)
+	return "todos.id IN (SELECT todo_tags.todo_id FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id " +
+			"WHERE tags.user_id = ? AND " + match + ")",
+		append([]interface{}{c.env.UserID}, vars...), nil
+}
+
+// nameMatch compares the name column of categories or tags with the value
+func nameMatch(column string, cond *Condition) (string, []interface{}) {
+	value := strings.ToLower(cond.Value)
+	if cond.Op == OpContains {
+		return "LOWER(" + column + ") LIKE ? ESCAPE '\\'", []interface{}{"%" + escapeLike(value) + "%"}
+	}
+	return "LOWER(" + column + ") = ?", []interface{}{value}
+}
+
+// conditionError reports a condition that cannot be used
+func conditionError(cond *Condition, msg string) *Error {
+	return &Error{Pos: cond.Start, Token: cond.Text, Msg: msg}
+}
+
+// valueError reports an invalid value in a condition
+func valueError(cond *Condition, msg string) *Error {
+	return &Error{Pos: cond.ValuePos, Token: cond.Value, Msg: msg}
+}
+
+func hasOp(ops []Op, op Op) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// escapeLike escapes the LIKE wildcards in a value
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
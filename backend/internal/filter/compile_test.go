@@ -0,0 +1,85 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testEnv = Env{UserID: 7, Now: time.Date(2026, 10, 18, 15, 30, 0, 0, time.UTC)}
+
+func compile(t *testing.T, input string) (string, []interface{}, error) {
+	t.Helper()
+	node, err := Parse(input)
+	if !assert.NoError(t, err, input) {
+		return "", nil, err
+	}
+	expr, err := Compile(node, testEnv)
+	return expr.SQL, expr.Vars, err
+}
+
+func TestCompile(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+
+	cases := []struct {
+		input string
+		sql   string
+		vars  []interface{}
+	}{
+		{"completed", "todos.completed = ?", []interface{}{true}},
+		{"completed:no", "todos.completed = ?", []interface{}{false}},
+		{"-recurring", "NOT COALESCE(COALESCE(todos.recurrence, '') <> '', FALSE)", nil},
+		{"priority:HIGH", "todos.priority IN ?", []interface{}{[]string{"high"}}},
+		{"priority>=medium", "todos.priority IN ?", []interface{}{[]string{"medium", "high"}}},
+		{"priority<low", "1 = 0", nil},
+		{"title~50%_off", `LOWER(todos.title) LIKE ? ESCAPE '\'`, []interface{}{`%50\%\_off%`}},
+		{`description:"Call Bob"`, "LOWER(todos.description) = ?", []interface{}{"call bob"}},
+		{"due", "todos.due_date IS NOT NULL", nil},
+		{"due:none", "todos.due_date IS NULL", nil},
+		{"due:2026-10-20", "todos.due_date >= ? AND todos.due_date < ?", []interface{}{day(20), day(21)}},
+		{"due<2026-10-20", "todos.due_date < ?", []interface{}{day(20)}},
+		{"due<=2026-10-20", "todos.due_date < ?", []interface{}{day(21)}},
+		{"due>2026-10-20", "todos.due_date >= ?", []interface{}{day(21)}},
+		{"due>=today", "todos.due_date >= ?", []interface{}{day(18)}},
+		{"due<tomorrow", "todos.due_date < ?", []interface{}{day(19)}},
+		{"created>2026-10-20T08:00:00+02:00", "todos.created_at > ?", []interface{}{time.Date(2026, 10, 20, 6, 0, 0, 0, time.UTC)}},
+		{"category:none", "todos.category_id IS NULL", nil},
+		{`category:"Work"`, "todos.category_id IN (SELECT id FROM categories WHERE user_id = ? AND LOWER(name) = ?)", []interface{}{uint(7), "work"}},
+		{"tag~ops", `todos.id IN (SELECT todo_tags.todo_id FROM todo_tags JOIN tags ON tags.id = todo_tags.tag_id WHERE tags.user_id = ? AND LOWER(tags.name) LIKE ? ESCAPE '\')`, []interface{}{uint(7), "%ops%"}},
+		{"completed OR priority:high", "(todos.completed = ?) OR (todos.priority IN ?)", []interface{}{true, []string{"high"}}},
+		{"-(completed due)", "NOT COALESCE((todos.completed = ?) AND (todos.due_date IS NOT NULL), FALSE)", []interface{}{true}},
+	}
+	for _, tc := range cases {
+		sql, vars, err := compile(t, tc.input)
+		assert.NoError(t, err, tc.input)
+		assert.Equal(t, tc.sql, sql, tc.input)
+		assert.Equal(t, tc.vars, vars, tc.input)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := []struct {
+		input string
+		pos   int
+		token string
+		msg   string
+	}{
+		{"completed owner:me", 10, "owner:me", `unknown field "owner"`},
+		{"title<b", 0, "title<b", `operator "<" is not supported for title`},
+		{"priority", 0, "priority", "priority needs an operator and a value"},
+		{"priority:urgent", 9, "urgent", "expected low, medium or high"},
+		{"completed:maybe", 10, "maybe", "expected true or false"},
+		{"due<next-week", 4, "next-week", "expected a date"},
+		{"created:none", 8, "none", "none can only be used as due:none"},
+	}
+	for _, tc := range cases {
+		_, _, err := compile(t, tc.input)
+		var filterErr *Error
+		if assert.ErrorAs(t, err, &filterErr, tc.input) {
+			assert.Equal(t, tc.pos, filterErr.Pos, tc.input)
+			assert.Equal(t, tc.token, filterErr.Token, tc.input)
+			assert.Contains(t, filterErr.Msg, tc.msg, tc.input)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+// Package filter implements the `q` filter language of the todo list:
+//
+//	priority:high due<2026-11-01 -completed category:"Work" title~deploy
+//
+// Conditions are field, operator and value. Conditions next to each other
+// must all hold; OR, parentheses and a leading '-' or NOT combine them
+// further. A field without operator and value tests a boolean field or
+// whether an optional field is set. Parse turns a filter into an AST and
+// Compile checks it against the whitelisted fields and turns it into a
+// GORM clause.
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxDepth bounds the nesting of parentheses and negations
+const maxDepth = 20
+
+// maxLength bounds the length of a filter in characters
+const maxLength = 1000
+
+// Op is a comparison operator
+type Op string
+
+const (
+	OpEq       Op = ":"
+	OpContains Op = "~"
+	OpLt       Op = "<"
+	OpLte      Op = "<="
+	OpGt       Op = ">"
+	OpGte      Op = ">="
+	// OpPresent is the operator of a bare field such as `completed`
+	OpPresent Op = ""
+)
+
+// Node is a node of the filter AST
+type Node interface {
+	// Pos is the position in characters where the node starts in the filter
+	Pos() int
+}
+
+// And holds when all of its terms hold
+type And struct {
+	Terms []Node
+}
+
+// Or holds when any of its terms holds
+type Or struct {
+	Terms []Node
+}
+
+// Not holds when its term does not
+type Not struct {
+	Term  Node
+	Start int
+}
+
+// Condition compares a field with a value
+type Condition struct {
+	Field    string
+	Op       Op
+	Value    string
+	Start    int
+	ValuePos int
+	// Text is the condition as written, for error messages
+	Text string
+}
+
+func (n *And) Pos() int       { return n.Terms[0].Pos() }
+func (n *Or) Pos() int        { return n.Terms[0].Pos() }
+func (n *Not) Pos() int       { return n.Start }
+func (n *Condition) Pos() int { return n.Start }
+
+// Error reports an invalid filter together with the position and text of the
+// offending token
+type Error struct {
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("invalid filter at position %d: %s", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("invalid filter at position %d near %q: %s", e.Pos, e.Token, e.Msg)
+}
+
+// parser is a recursive descent parser over the runes of a filter
+type parser struct {
+	input []rune
+	pos   int
+	depth int
+}
+
+// Parse parses a filter. It returns nil when the filter is empty.
+func Parse(input string) (Node, error) {
+	p := &parser{input: []rune(input)}
+	if len(p.input) > maxLength {
+		return nil, &Error{Pos: maxLength, Msg: "filter is too long"}
+	}
+	p.skipSpace()
+	if p.eof() {
+		return nil, nil
+	}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.eof() {
+		// Only an unmatched ')' stops parseOr before the end
+		return nil, p.errorf(p.pos, "unexpected ')'")
+	}
+	return node, nil
+}
+
+// parseOr parses terms separated by OR
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Node{first}
+	for p.keyword("OR") {
+		p.pos += 2
+		term, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return &Or{Terms: terms}, nil
+}
+
+// parseAnd parses terms next to each other, optionally separated by AND
+func (p *parser) parseAnd() (Node, error) {
+	var terms []Node
+	for {
+		p.skipSpace()
+		if p.eof() || p.peek() == ')' || p.keyword("OR") {
+			break
+		}
+		if p.keyword("AND") {
+			if len(terms) == 0 {
+				return nil, p.errorf(p.pos, "expected a condition before AND")
+			}
+			p.pos += 3
+			p.skipSpace()
+			if p.eof() || p.peek() == ')' || p.keyword("OR") {
+				return nil, p.errorf(p.pos, "expected a condition after AND")
+			}
+			continue
+		}
+		term, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+
+	switch len(terms) {
+	case 0:
+		return nil, p.errorf(p.pos, "expected a condition")
+	case 1:
+		return terms[0], nil
+	}
+	return &And{Terms: terms}, nil
+}
+
+// parseUnary parses a term with an optional '-' or NOT in front
+func (p *parser) parseUnary() (Node, error) {
+	start := p.pos
+	negated := false
+	switch {
+	case p.peek() == '-':
+		p.pos++
+		negated = true
+	case p.keyword("NOT"):
+		p.pos += 3
+		p.skipSpace()
+		negated = true
+	}
+	if !negated {
+		return p.parsePrimary()
+	}
+
+	if p.eof() || unicode.IsSpace(p.peek()) {
+		return nil, p.errorf(start, "expected a condition after negation")
+	}
+	if p.depth++; p.depth > maxDepth {
+		return nil, p.errorf(start, "filter is nested too deeply")
+	}
+	term, err := p.parseUnary()
+	p.depth--
+	if err != nil {
+		return nil, err
+	}
+	return &Not{Term: term, Start: start}, nil
+}
+
+// parsePrimary parses a parenthesized filter or a condition
+func (p *parser) parsePrimary() (Node, error) {
+	if p.peek() != '(' {
+		return p.parseCondition()
+	}
+
+	open := p.pos
+	if p.depth++; p.depth > maxDepth {
+		return nil, p.errorf(open, "filter is nested too deeply")
+	}
+	p.pos++
+	node, err := p.parseOr()
+	p.depth--
+	if err != nil {
+		return nil, err
+	}
+	if p.eof() {
+		return nil, p.errorf(open, "missing closing parenthesis")
+	}
+	p.pos++ // ')'
+	return node, nil
+}
+
+// parseCondition parses field, operator and value, or a bare field
+func (p *parser) parseCondition() (Node, error) {
+	start := p.pos
+	for !p.eof() && (unicode.IsLetter(p.peek()) || unicode.IsDigit(p.peek()) || p.peek() == '_') {
+		p.pos++
+	}
+	if p.pos == start {
+		return nil, p.errorf(start, "expected a field name")
+	}
+	cond := &Condition{Field: strings.ToLower(string(p.input[start:p.pos])), Start: start}
+
+	switch {
+	case p.eof() || unicode.IsSpace(p.peek()) || p.peek() == ')':
+		cond.Op = OpPresent
+		cond.Text = string(p.input[start:p.pos])
+		return cond, nil
+	case p.hasPrefix("<="), p.hasPrefix(">="):
+		cond.Op = Op(p.input[p.pos : p.pos+2])
+		p.pos += 2
+	case strings.ContainsRune(":~<>", p.peek()):
+		cond.Op = Op(p.input[p.pos : p.pos+1])
+		p.pos++
+	default:
+		return nil, p.errorf(start, "expected an operator (:, ~, <, <=, >, >=) after the field name")
+	}
+
+	cond.ValuePos = p.pos
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, p.errorf(start, fmt.Sprintf("expected a value after %q", cond.Op))
+	}
+	cond.Value = value
+	cond.Text = string(p.input[start:p.pos])
+	return cond, nil
+}
+
+// parseValue parses a quoted string or a word running up to whitespace or ')'
+func (p *parser) parseValue() (string, error) {
+	if p.peek() != '"' {
+		start := p.pos
+		for !p.eof() && !unicode.IsSpace(p.peek()) && p.peek() != ')' {
+			p.pos++
+		}
+		return string(p.input[start:p.pos]), nil
+	}
+
+	open := p.pos
+	p.pos++
+	var b strings.Builder
+	for !p.eof() {
+		r := p.peek()
+		p.pos++
+		switch {
+		case r == '\\' && !p.eof():
+			b.WriteRune(p.peek())
+			p.pos++
+		case r == '"':
+			if b.Len() == 0 {
+				return "", p.errorf(open, "quoted value is empty")
+			}
+			return b.String(), nil
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return "", p.errorf(open, "unterminated quoted value")
+}
+
+func (p *parser) eof() bool { return p.pos >= len(p.input) }
+
+func (p *parser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// hasPrefix reports whether s starts at the current position, comparing in
+// place since it is called for every token
+func (p *parser) hasPrefix(s string) bool {
+	i := p.pos
+	for _, r := range s {
+		if i >= len(p.input) || p.input[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+// keyword reports whether the upper-case keyword starts at the current
+// position as a word of its own
+func (p *parser) keyword(kw string) bool {
+	if !p.hasPrefix(kw) {
+		return false
+	}
+	end := p.pos + len(kw)
+	return end == len(p.input) || unicode.IsSpace(p.input[end]) || p.input[end] == '('
+}
+
+// errorf returns an Error for the token starting at pos
+func (p *parser) errorf(pos int, msg string) *Error {
+	return &Error{Pos: pos, Token: tokenAt(p.input, pos), Msg: msg}
+}
+
+// tokenAt returns the text from pos up to the next whitespace
+func tokenAt(input []rune, pos int) string {
+	end := pos
+	for end < len(input) && !unicode.IsSpace(input[end]) {
+		end++
+	}
+	return string(input[pos:end])
+}
@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("conditions next to each other are combined with AND", func(t *testing.T) {
+		node, err := Parse(`priority:high due<2026-11-01 -completed category:"Work & Life" title~deploy`)
+		assert.NoError(t, err)
+
+		and, ok := node.(*And)
+		assert.True(t, ok)
+		assert.Len(t, and.Terms, 5)
+		assert.Equal(t, &Condition{Field: "priority", Op: OpEq, Value: "high", Start: 0, ValuePos: 9, Text: "priority:high"}, and.Terms[0])
+		assert.Equal(t, &Condition{Field: "due", Op: OpLt, Value: "2026-11-01", Start: 14, ValuePos: 18, Text: "due<2026-11-01"}, and.Terms[1])
+		assert.Equal(t, &Not{Term: &Condition{Field: "completed", Op: OpPresent, Start: 30, Text: "completed"}, Start: 29}, and.Terms[2])
+		assert.Equal(t, "Work & Life", and.Terms[3].(*Condition).Value)
+		assert.Equal(t, OpContains, and.Terms[4].(*Condition).Op)
+	})
+
+	t.Run("OR, AND, NOT and parentheses", func(t *testing.T) {
+		node, err := Parse(`(priority:high OR due<=today) AND NOT tag:later`)
+		assert.NoError(t, err)
+
+		and := node.(*And)
+		or := and.Terms[0].(*Or)
+		assert.Len(t, or.Terms, 2)
+		assert.Equal(t, OpLte, or.Terms[1].(*Condition).Op)
+		assert.Equal(t, "tag", and.Terms[1].(*Not).Term.(*Condition).Field)
+	})
+
+	t.Run("values may contain colons and escaped quotes", func(t *testing.T) {
+		node, err := Parse(`due>=2026-11-01T09:00:00Z title:"say \"hi\""`)
+		assert.NoError(t, err)
+		terms := node.(*And).Terms
+		assert.Equal(t, "2026-11-01T09:00:00Z", terms[0].(*Condition).Value)
+		assert.Equal(t, `say "hi"`, terms[1].(*Condition).Value)
+	})
+
+	t.Run("empty filter", func(t *testing.T) {
+		node, err := Parse("  ")
+		assert.NoError(t, err)
+		assert.Nil(t, node)
+	})
+
+	t.Run("errors point at the offending token", func(t *testing.T) {
+		cases := []struct {
+			input string
+			pos   int
+			token string
+			msg   string
+		}{
+			{`priority:high title:"deploy`, 20, `"deploy`, "unterminated quoted value"},
+			{`priority: high`, 0, "priority:", "expected a value"},
+			{`priority=high`, 0, "priority=high", "expected an operator"},
+			{`(priority:high OR completed`, 0, "(priority:high", "missing closing parenthesis"},
+			{`completed)`, 9, ")", "unexpected ')'"},
+			{`completed OR`, 12, "", "expected a condition"},
+			{`- completed`, 0, "-", "expected a condition after negation"},
+			{`AND completed`, 0, "AND", "expected a condition before AND"},
+			{`:high`, 0, ":high", "expected a field name"},
+		}
+		for _, tc := range cases {
+			_, err := Parse(tc.input)
+			var filterErr *Error
+			if assert.ErrorAs(t, err, &filterErr, tc.input) {
+				assert.Equal(t, tc.pos, filterErr.Pos, tc.input)
+				assert.Equal(t, tc.token, filterErr.Token, tc.input)
+				assert.Contains(t, filterErr.Msg, tc.msg, tc.input)
+			}
+		}
+	})
+
+	t.Run("nesting is bounded", func(t *testing.T) {
+		input := ""
+		for i := 0; i < maxDepth+1; i++ {
+			input += "("
+		}
+		_, err := Parse(input + "completed")
+		assert.ErrorContains(t, err, "nested too deeply")
+	})
+
+	t.Run("length is bounded", func(t *testing.T) {
+		_, err := Parse(strings.Repeat("a", maxLength))
+		assert.NoError(t, err)
+
+		_, err = Parse(strings.Repeat("a", maxLength+1))
+		var filterErr *Error
+		assert.ErrorAs(t, err, &filterErr)
+		assert.Equal(t, "filter is too long", filterErr.Msg)
+	})
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/problem"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is the room left for multipart headers and boundaries
+// on top of the largest accepted file
+const multipartOverhead = 64 << 10
+
+// AttachmentHandler handles HTTP requests for Attachment
+type AttachmentHandler struct {
+	service *services.AttachmentService
+}
+
+// NewAttachmentHandler creates a new AttachmentHandler
+func NewAttachmentHandler(service *services.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{service: service}
+}
+
+// GetAttachments handles GET /todos/:id/attachments
+func (h *AttachmentHandler) GetAttachments(c *gin.Context) {
+	todoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	attachments, err := h.service.GetAttachments(middleware.UserID(c), uint(todoID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+// CreateAttachment handles POST /todos/:id/attachments with the file in the
+// "file" field of a multipart/form-data body
+func (h *AttachmentHandler) CreateAttachment(c *gin.Context) {
+	todoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.service.MaxSize()+multipartOverhead)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondTooLarge(c)
+			return
+		}
+		respondProblem(c, http.StatusBadRequest, "a multipart file field named file is required")
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.service.CreateAttachment(c.Request.Context(), middleware.UserID(c), uint(todoID), header.Filename, file)
+	if err != nil {
+		if errors.Is(err, services.ErrAttachmentTooLarge) {
+			h.respondTooLarge(c)
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// GetAttachment handles GET /todos/:id/attachments/:attachmentId
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	todoID, id, ok := attachmentIDs(c)
+	if !ok {
+		return
+	}
+
+	attachment, err := h.service.GetAttachmentByID(middleware.UserID(c), todoID, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attachment)
+}
+
+// DownloadAttachment handles GET /todos/:id/attachments/:attachmentId/content
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	todoID, id, ok := attachmentIDs(c)
+	if !ok {
+		return
+	}
+
+	attachment, err := h.service.GetAttachmentByID(middleware.UserID(c), todoID, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// The content never changes, so its hash is a strong entity tag
+	tag := `"` + attachment.SHA256 + `"`
+	if c.GetHeader("If-None-Match") == tag {
+		c.Header("ETag", tag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	content, err := h.service.OpenAttachment(c.Request.Context(), attachment)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer content.Close()
+
+	c.Header("ETag", tag)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	c.DataFromReader(http.StatusOK, attachment.Size, attachment.ContentType, io.NopCloser(content), nil)
+}
+
+// DeleteAttachment handles DELETE /todos/:id/attachments/:attachmentId
+func (h *AttachmentHandler) DeleteAttachment(c *gin.Context) {
+	todoID, id, ok := attachmentIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteAttachment(c.Request.Context(), middleware.UserID(c), todoID, id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// respondTooLarge responds 413 Request Entity Too Large with the largest
+// file accepted
+func (h *AttachmentHandler) respondTooLarge(c *gin.Context) {
+	problem.Respond(c, http.StatusRequestEntityTooLarge, services.ErrAttachmentTooLarge.Error(), gin.H{"max_size": h.service.MaxSize()})
+}
+
+// attachmentIDs parses the todo and attachment IDs of an attachment URL,
+// responding with 400 Bad Request when one is invalid
+func attachmentIDs(c *gin.Context) (uint, uint, bool) {
+	todoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("attachmentId"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid attachment id")
+		return 0, 0, false
+	}
+	return uint(todoID), uint(id), true
+}
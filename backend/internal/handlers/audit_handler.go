@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler handles HTTP requests for the audit log
+type AuditHandler struct {
+	audit *services.AuditService
+	todos *services.TodoService
+}
+
+// NewAuditHandler creates a new AuditHandler
+func NewAuditHandler(audit *services.AuditService, todos *services.TodoService) *AuditHandler {
+	return &AuditHandler{audit: audit, todos: todos}
+}
+
+// GetAuditLog handles GET /audit?entity_type=&entity_id=&action=&actor_id=&since=&until=
+func (h *AuditHandler) GetAuditLog(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	var filter repository.AuditFilter
+	switch entityType := c.Query("entity_type"); entityType {
+	case "", models.AuditTodo, models.AuditCategory:
+		filter.EntityType = entityType
+	default:
+		respondProblem(c, http.StatusBadRequest, "entity_type must be todo or category")
+		return
+	}
+	filter.Action = c.Query("action")
+
+	for key, target := range map[string]*uint{"entity_id": &filter.EntityID, "actor_id": &filter.ActorID} {
+		if value := c.Query(key); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				respondProblem(c, http.StatusBadRequest, "invalid "+key)
+				return
+			}
+			*target = uint(id)
+		}
+	}
+
+	// Time range as RFC 3339 timestamps
+	for key, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := c.Query(key); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				respondProblem(c, http.StatusBadRequest, key+" must be an RFC 3339 timestamp")
+				return
+			}
+			*target = &t
+		}
+	}
+
+	entries, total, err := h.audit.GetAuditLog(middleware.UserID(c), filter, page, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	respondAuditPage(c, entries, total, page, limit)
+}
+
+// GetTodoHistory handles GET /todos/:id/history
+func (h *AuditHandler) GetTodoHistory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	entries, total, err := h.audit.GetTodoHistory(middleware.UserID(c), uint(id), page, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	// Todos in the trash or purged still have their history
+	if total == 0 {
+		if _, err := h.todos.GetTodoByID(middleware.UserID(c), uint(id)); err != nil {
+			respondError(c, err)
+			return
+		}
+	}
+	respondAuditPage(c, entries, total, page, limit)
+}
+
+// respondAuditPage responds with a page of audit entries
+func respondAuditPage(c *gin.Context, entries []models.AuditEntry, total int64, page, limit int) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": entries,
+		"pagination": gin.H{
+			"current_page": page,
+			"per_page":     limit,
+			"total":        total,
+			"total_pages":  (int(total) + limit - 1) / limit,
+		},
+	})
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthHandler handles HTTP requests for accounts and tokens
+type AuthHandler struct {
+	service *services.AuthService
+}
+
+// NewAuthHandler creates a new AuthHandler
+func NewAuthHandler(service *services.AuthService) *AuthHandler {
+	return &AuthHandler{service: service}
+}
+
+type registerRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Name     string `json:"name"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type loginRequest struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// authResponse is returned after registering or logging in
+type authResponse struct {
+	User *models.User `json:"user"`
+	*services.TokenPair
+}
+
+// Register handles POST /auth/register
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req registerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, tokens, err := h.service.Register(req.Email, req.Name, req.Password)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, authResponse{User: user, TokenPair: tokens})
+}
+
+// Login handles POST /auth/login
+func (h *AuthHandler) Login(c *gin.Context) {
+	var req loginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, tokens, err := h.service.Login(req.Email, req.Password)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, authResponse{User: user, TokenPair: tokens})
+}
+
+// Refresh handles POST /auth/refresh
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, err := h.service.Refresh(req.RefreshToken)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// Logout handles POST /auth/logout
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.Logout(req.RefreshToken); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// Me handles GET /auth/me
+func (h *AuthHandler) Me(c *gin.Context) {
+	user, err := h.service.GetUser(middleware.UserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
@@ -0,0 +1,559 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"todoListChallenge/internal/caldav"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalDAV paths: the principal of the signed-in user, the home holding its
+// calendars, and below that one collection per calendar
+const (
+	davPrincipalPath = "/dav/"
+	davHomePath      = "/dav/calendars/"
+)
+
+// maxDAVBody is the largest request body accepted, 1 MiB
+const maxDAVBody = 1 << 20
+
+// davMethods are the methods the CalDAV server supports
+const davMethods = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT"
+
+// calendarContentType is the media type of a calendar object resource
+const calendarContentType = "text/calendar; charset=utf-8; component=vtodo"
+
+// davResource identifies what a CalDAV path points at
+type davResource struct {
+	kind       int    // One of the dav* kinds below
+	collection string // Collection name for collections and objects
+	object     string // Resource name for objects
+}
+
+// Kinds of CalDAV resources
+const (
+	davPrincipal = iota
+	davHome
+	davCollection
+	davObject
+)
+
+// CalDAVHandler serves categories and todos to calendar apps over CalDAV
+type CalDAVHandler struct {
+	service *services.CalDAVService
+	users   *services.AuthService
+}
+
+// NewCalDAVHandler creates a new CalDAVHandler
+func NewCalDAVHandler(service *services.CalDAVService, users *services.AuthService) *CalDAVHandler {
+	return &CalDAVHandler{service: service, users: users}
+}
+
+// WellKnown handles /.well-known/caldav, where clients look for the server
+func (h *CalDAVHandler) WellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, davPrincipalPath)
+}
+
+// Options handles OPTIONS /dav/*path
+func (h *CalDAVHandler) Options(c *gin.Context) {
+	c.Header("DAV", "1, 3, calendar-access")
+	c.Header("Allow", davMethods)
+	c.Status(http.StatusOK)
+}
+
+// Propfind handles PROPFIND /dav/*path. A Depth of 1 or infinity also
+// describes the members of a collection, one level deep.
+func (h *CalDAVHandler) Propfind(c *gin.Context) {
+	resource, ok := parseDAVPath(c.Request.URL.Path)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	req, err := caldav.Parse(http.MaxBytesReader(c.Writer, c.Request.Body, maxDAVBody))
+	if err != nil || (req != nil && !req.Is(caldav.DAV("propfind"))) {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	names := req.PropNames()
+	members := c.GetHeader("Depth") != "0"
+	userID := middleware.UserID(c)
+
+	var responses []caldav.Response
+	switch resource.kind {
+	case davPrincipal:
+		user, err := h.users.GetUser(userID)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		responses = append(responses, respondProps(davPrincipalPath, names, []caldav.Prop{
+			{Name: caldav.DAV("resourcetype"), XML: "<d:collection/><d:principal/>"},
+			{Name: caldav.DAV("displayname"), XML: caldav.Escape(user.Name)},
+			{Name: caldav.DAV("current-user-principal"), XML: caldav.Href(davPrincipalPath)},
+			{Name: caldav.DAV("principal-URL"), XML: caldav.Href(davPrincipalPath)},
+			{Name: caldav.DAV("current-user-privilege-set"), XML: "<d:privilege><d:read/></d:privilege>"},
+			{Name: caldav.CalDAV("calendar-home-set"), XML: caldav.Href(davHomePath)},
+			{Name: caldav.CalDAV("calendar-user-address-set"), XML: caldav.Href("mailto:" + user.Email)},
+		}))
+		if members {
+			responses = append(responses, homeResponse(names))
+		}
+
+	case davHome:
+		responses = append(responses, homeResponse(names))
+		if members {
+			collections, err := h.service.Collections(userID)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			token, err := h.service.SyncToken(userID)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			for i := range collections {
+				responses = append(responses, collectionResponse(&collections[i], token, names))
+			}
+		}
+
+	case davCollection:
+		collection, ok := h.collection(c, userID, resource.collection)
+		if !ok {
+			return
+		}
+		token, err := h.service.SyncToken(userID)
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		responses = append(responses, collectionResponse(collection, token, names))
+		if members {
+			objects, err := h.service.Objects(userID, collection, nil)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			for i := range objects {
+				responses = append(responses, objectResponse(collection, &objects[i], names))
+			}
+		}
+
+	case davObject:
+		collection, object, ok := h.object(c, userID, resource)
+		if !ok {
+			return
+		}
+		responses = append(responses, objectResponse(collection, object, names))
+	}
+
+	multistatus(c, responses, "")
+}
+
+// Report handles REPORT /dav/calendars/:collection/ for the
+// calendar-multiget, calendar-query and sync-collection reports
+func (h *CalDAVHandler) Report(c *gin.Context) {
+	resource, ok := parseDAVPath(c.Request.URL.Path)
+	if !ok || resource.kind != davCollection {
+		davError(c, http.StatusForbidden, caldav.DAV("supported-report"), "reports are supported on calendar collections")
+		return
+	}
+	userID := middleware.UserID(c)
+	collection, ok := h.collection(c, userID, resource.collection)
+	if !ok {
+		return
+	}
+	req, err := caldav.Parse(http.MaxBytesReader(c.Writer, c.Request.Body, maxDAVBody))
+	if err != nil || req == nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	names := req.PropNames()
+
+	var responses []caldav.Response
+	switch {
+	case req.Is(caldav.CalDAV("calendar-multiget")):
+		for _, href := range req.Children(caldav.DAV("href")) {
+			responses = append(responses, h.multigetResponse(userID, collection, href.Text(), names))
+		}
+		multistatus(c, responses, "")
+
+	case req.Is(caldav.CalDAV("calendar-query")):
+		todos, completed := queryFilter(req.Child(caldav.CalDAV("filter")))
+		if todos {
+			objects, err := h.service.Objects(userID, collection, completed)
+			if err != nil {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+			for i := range objects {
+				responses = append(responses, objectResponse(collection, &objects[i], names))
+			}
+		}
+		multistatus(c, responses, "")
+
+	case req.Is(caldav.DAV("sync-collection")):
+		changed, removed, token, err := h.service.Changes(userID, collection, req.Child(caldav.DAV("sync-token")).Text())
+		if errors.Is(err, services.ErrInvalidSyncToken) {
+			davError(c, http.StatusForbidden, caldav.DAV("valid-sync-token"), err.Error())
+			return
+		}
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		for i := range changed {
+			responses = append(responses, objectResponse(collection, &changed[i], names))
+		}
+		for _, name := range removed {
+			responses = append(responses, caldav.Response{Href: objectHref(collection, name), Status: http.StatusNotFound})
+		}
+		multistatus(c, responses, token)
+
+	default:
+		davError(c, http.StatusForbidden, caldav.DAV("supported-report"), "unsupported report")
+	}
+}
+
+// Get handles GET and HEAD /dav/calendars/:collection/:object
+func (h *CalDAVHandler) Get(c *gin.Context) {
+	resource, ok := parseDAVPath(c.Request.URL.Path)
+	if !ok || resource.kind != davObject {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+	_, object, ok := h.object(c, middleware.UserID(c), resource)
+	if !ok {
+		return
+	}
+	if notModified(c, object.Todo.Version) {
+		return
+	}
+
+	var data bytes.Buffer
+	if err := services.WriteObject(&data, object); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	setETag(c, object.Todo.Version)
+	c.Data(http.StatusOK, calendarContentType, data.Bytes())
+}
+
+// Put handles PUT /dav/calendars/:collection/:object, creating or replacing
+// a todo. No ETag is returned, since the todo is not stored octet for octet
+// as sent; clients fetch it again.
+func (h *CalDAVHandler) Put(c *gin.Context) {
+	resource, ok := parseDAVPath(c.Request.URL.Path)
+	if !ok || resource.kind != davObject {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+	userID := middleware.UserID(c)
+	collection, err := h.service.Collection(userID, resource.collection)
+	if err != nil {
+		c.Status(http.StatusConflict) // The parent collection must exist
+		return
+	}
+	existing, err := h.service.FindObject(userID, collection, resource.object)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// If-None-Match: * creates only; If-Match replaces only that version
+	if existing != nil && strings.TrimSpace(c.GetHeader("If-None-Match")) == "*" {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+	if header := c.GetHeader("If-Match"); header != "" && (existing == nil || !matchETag(header, existing.Todo.Version, false)) {
+		c.Status(http.StatusPreconditionFailed)
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxDAVBody)
+	if _, err := h.service.PutObject(userID, collection, resource.object, existing, body); err != nil {
+		respondCalDAVError(c, err)
+		return
+	}
+
+	if existing == nil {
+		c.Status(http.StatusCreated)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Delete handles DELETE /dav/calendars/:collection/:object, moving the todo
+// to the trash. Collections are categories and are deleted in the app.
+func (h *CalDAVHandler) Delete(c *gin.Context) {
+	resource, ok := parseDAVPath(c.Request.URL.Path)
+	if !ok || resource.kind != davObject {
+		c.Header("Allow", "OPTIONS, PROPFIND, REPORT")
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+	userID := middleware.UserID(c)
+	_, object, ok := h.object(c, userID, resource)
+	if !ok {
+		return
+	}
+	var version uint
+	if header := c.GetHeader("If-Match"); header != "" {
+		if !matchETag(header, object.Todo.Version, false) {
+			c.Status(http.StatusPreconditionFailed)
+			return
+		}
+		version = object.Todo.Version
+	}
+
+	if err := h.service.DeleteObject(userID, object, version); err != nil {
+		respondCalDAVError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// collection gets a user's collection, responding with 404 Not Found when
+// there is no such collection
+func (h *CalDAVHandler) collection(c *gin.Context, userID uint, name string) (*services.CalDAVCollection, bool) {
+	collection, err := h.service.Collection(userID, name)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return nil, false
+	}
+	return collection, true
+}
+
+// object gets the resource a path points at, responding with 404 Not Found
+// when there is no such resource
+func (h *CalDAVHandler) object(c *gin.Context, userID uint, resource davResource) (*services.CalDAVCollection, *services.CalDAVObject, bool) {
+	collection, ok := h.collection(c, userID, resource.collection)
+	if !ok {
+		return nil, nil, false
+	}
+	object, err := h.service.Object(userID, collection, resource.object)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return nil, nil, false
+	}
+	return collection, object, true
+}
+
+// multigetResponse returns the response for one href of a calendar-multiget
+func (h *CalDAVHandler) multigetResponse(userID uint, collection *services.CalDAVCollection, href string, names []xml.Name) caldav.Response {
+	notFound := caldav.Response{Href: href, Status: http.StatusNotFound}
+	target, err := url.Parse(href)
+	if err != nil {
+		return notFound
+	}
+	resource, ok := parseDAVPath(target.Path)
+	if !ok || resource.kind != davObject || resource.collection != collection.Name {
+		return notFound
+	}
+	object, err := h.service.Object(userID, collection, resource.object)
+	if err != nil {
+		return notFound
+	}
+	return objectResponse(collection, object, names)
+}
+
+// homeResponse describes the calendar home
+func homeResponse(names []xml.Name) caldav.Response {
+	return respondProps(davHomePath, names, []caldav.Prop{
+		{Name: caldav.DAV("resourcetype"), XML: "<d:collection/>"},
+		{Name: caldav.DAV("displayname"), XML: "Calendars"},
+		{Name: caldav.DAV("current-user-principal"), XML: caldav.Href(davPrincipalPath)},
+		{Name: caldav.DAV("current-user-privilege-set"), XML: "<d:privilege><d:read/></d:privilege>"},
+	})
+}
+
+// collectionResponse describes a collection. token is the sync token of the
+// user's todos, which also serves as the getctag clients poll for changes.
+func collectionResponse(collection *services.CalDAVCollection, token string, names []xml.Name) caldav.Response {
+	props := []caldav.Prop{
+		{Name: caldav.DAV("resourcetype"), XML: "<d:collection/><c:calendar/>"},
+		{Name: caldav.DAV("displayname"), XML: caldav.Escape(collection.DisplayName)},
+		{Name: caldav.DAV("current-user-principal"), XML: caldav.Href(davPrincipalPath)},
+		{Name: caldav.DAV("owner"), XML: caldav.Href(davPrincipalPath)},
+		{Name: caldav.DAV("current-user-privilege-set"), XML: "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>"},
+		{Name: caldav.DAV("supported-report-set"), XML: "<d:supported-report><d:report><c:calendar-multiget/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><c:calendar-query/></d:report></d:supported-report>" +
+			"<d:supported-report><d:report><d:sync-collection/></d:report></d:supported-report>"},
+		{Name: caldav.DAV("sync-token"), XML: caldav.Escape(token)},
+		{Name: xml.Name{Space: caldav.NamespaceCalendarServer, Local: "getctag"}, XML: caldav.Escape(token)},
+		{Name: caldav.CalDAV("supported-calendar-component-set"), XML: `<c:comp name="VTODO"/>`},
+		{Name: caldav.CalDAV("supported-calendar-data"), XML: `<c:calendar-data content-type="text/calendar" version="2.0"/>`},
+	}
+	if collection.Color != "" {
+		props = append(props, caldav.Prop{Name: xml.Name{Space: caldav.NamespaceAppleICal, Local: "calendar-color"}, XML: caldav.Escape(collection.Color)})
+	}
+	return respondProps(collectionHref(collection), names, props)
+}
+
+// objectResponse describes a calendar object resource. Its calendar data is
+// only included when asked for by name.
+func objectResponse(collection *services.CalDAVCollection, object *services.CalDAVObject, names []xml.Name) caldav.Response {
+	props := []caldav.Prop{
+		{Name: caldav.DAV("resourcetype")},
+		{Name: caldav.DAV("getetag"), XML: caldav.Escape(etag(object.Todo.Version))},
+		{Name: caldav.DAV("getcontenttype"), XML: calendarContentType},
+		{Name: caldav.DAV("getlastmodified"), XML: object.Todo.UpdatedAt.UTC().Format(http.TimeFormat)},
+	}
+	for _, name := range names {
+		if name == caldav.CalDAV("calendar-data") {
+			var data bytes.Buffer
+			if err := services.WriteObject(&data, object); err == nil {
+				props = append(props, caldav.Prop{Name: name, XML: caldav.Escape(data.String())})
+			}
+		}
+	}
+	return respondProps(objectHref(collection, object.Name), names, props)
+}
+
+// respondProps returns the response for a resource with the available
+// properties, limited to the requested names unless names is nil
+func respondProps(href string, names []xml.Name, available []caldav.Prop) caldav.Response {
+	response := caldav.Response{Href: href}
+	if names == nil {
+		response.Props = available
+		return response
+	}
+	for _, name := range names {
+		found := false
+		for _, prop := range available {
+			if prop.Name == name {
+				response.Props = append(response.Props, prop)
+				found = true
+				break
+			}
+		}
+		if !found {
+			response.Missing = append(response.Missing, name)
+		}
+	}
+	return response
+}
+
+// queryFilter reads the filter of a calendar-query: whether it can match
+// todos at all, and the completion status it asks for, if any. Time ranges
+// and other property filters are not applied, so a query may return more
+// todos than it asked for.
+func queryFilter(filter *caldav.Node) (bool, *bool) {
+	calendar := filter.Child(caldav.CalDAV("comp-filter"))
+	if calendar == nil {
+		return true, nil
+	}
+	if !strings.EqualFold(calendar.Attr("name"), "VCALENDAR") {
+		return false, nil
+	}
+	component := calendar.Child(caldav.CalDAV("comp-filter"))
+	if component == nil {
+		return true, nil
+	}
+	if !strings.EqualFold(component.Attr("name"), "VTODO") || component.Child(caldav.CalDAV("is-not-defined")) != nil {
+		return false, nil
+	}
+
+	var completed *bool
+	for _, prop := range component.Children(caldav.CalDAV("prop-filter")) {
+		switch strings.ToUpper(prop.Attr("name")) {
+		case "COMPLETED":
+			defined := prop.Child(caldav.CalDAV("is-not-defined")) == nil
+			completed = &defined
+		case "STATUS":
+			match := prop.Child(caldav.CalDAV("text-match"))
+			if match != nil && strings.EqualFold(match.Text(), "COMPLETED") {
+				done := match.Attr("negate-condition") != "yes"
+				completed = &done
+			}
+		}
+	}
+	return true, completed
+}
+
+// parseDAVPath parses a path below /dav/
+func parseDAVPath(path string) (davResource, bool) {
+	rest, ok := strings.CutPrefix(path, davPrincipalPath)
+	if !ok {
+		return davResource{}, false
+	}
+	if rest == "" {
+		return davResource{kind: davPrincipal}, true
+	}
+	segments := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+	if segments[0] != "calendars" {
+		return davResource{}, false
+	}
+	switch len(segments) {
+	case 1:
+		return davResource{kind: davHome}, true
+	case 2:
+		return davResource{kind: davCollection, collection: segments[1]}, segments[1] != ""
+	case 3:
+		if strings.HasSuffix(rest, "/") || segments[2] == "" {
+			return davResource{}, false
+		}
+		return davResource{kind: davObject, collection: segments[1], object: segments[2]}, segments[1] != ""
+	}
+	return davResource{}, false
+}
+
+// collectionHref returns the path of a collection
+func collectionHref(collection *services.CalDAVCollection) string {
+	return davHomePath + url.PathEscape(collection.Name) + "/"
+}
+
+// objectHref returns the path of a resource in a collection
+func objectHref(collection *services.CalDAVCollection, name string) string {
+	return collectionHref(collection) + url.PathEscape(name)
+}
+
+// multistatus responds with 207 Multi-Status
+func multistatus(c *gin.Context, responses []caldav.Response, syncToken string) {
+	c.Header("Content-Type", "application/xml; charset=utf-8")
+	c.Status(http.StatusMultiStatus)
+	if err := caldav.Multistatus(c.Writer, responses, syncToken); err != nil {
+		c.Error(err)
+	}
+}
+
+// davError responds with a failed precondition or postcondition
+func davError(c *gin.Context, status int, condition xml.Name, description string) {
+	c.Header("Content-Type", "application/xml; charset=utf-8")
+	c.Status(status)
+	if err := caldav.Error(c.Writer, condition, description); err != nil {
+		c.Error(err)
+	}
+}
+
+// respondCalDAVError responds to an error storing or deleting a resource,
+// naming the CalDAV precondition it failed where there is one
+func respondCalDAVError(c *gin.Context, err error) {
+	var validationErr *services.ValidationError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &validationErr) && validationErr.Field == "calendar_data":
+		davError(c, http.StatusForbidden, caldav.CalDAV("valid-calendar-data"), validationErr.Msg)
+	case errors.As(err, &validationErr):
+		davError(c, http.StatusForbidden, caldav.CalDAV("valid-calendar-object-resource"), validationErr.Msg)
+	case errors.Is(err, services.ErrUnsupportedComponent):
+		davError(c, http.StatusForbidden, caldav.CalDAV("supported-calendar-component"), err.Error())
+	case errors.Is(err, services.ErrUIDConflict):
+		davError(c, http.StatusForbidden, caldav.CalDAV("no-uid-conflict"), err.Error())
+	case errors.Is(err, services.ErrVersionMismatch):
+		c.Status(http.StatusPreconditionFailed)
+	case errors.As(err, &maxBytesErr):
+		davError(c, http.StatusRequestEntityTooLarge, caldav.CalDAV("max-resource-size"), "calendar data is too large")
+	default:
+		c.Status(http.StatusInternalServerError)
+	}
+}
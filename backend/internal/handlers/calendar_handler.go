@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CalendarHandler handles HTTP requests for the iCalendar feed of todos
+type CalendarHandler struct {
+	service *services.CalendarService
+}
+
+// NewCalendarHandler creates a new CalendarHandler
+func NewCalendarHandler(service *services.CalendarService) *CalendarHandler {
+	return &CalendarHandler{service: service}
+}
+
+// GetCalendar handles GET /todos.ics
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	h.writeCalendar(c, middleware.UserID(c))
+}
+
+// GetFeed handles GET /calendar/:token, the address calendar apps subscribe
+// to. The token stands in for a login, since they cannot send one.
+func (h *CalendarHandler) GetFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	userID, err := h.service.FeedUser(token)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	h.writeCalendar(c, userID)
+}
+
+// CreateFeedToken handles POST /calendar/token. It replaces any earlier
+// token and returns the new one along with the feed address.
+func (h *CalendarHandler) CreateFeedToken(c *gin.Context) {
+	token, err := h.service.CreateFeedToken(middleware.UserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"token": token, "url": feedURL(c, token)})
+}
+
+// DeleteFeedToken handles DELETE /calendar/token
+func (h *CalendarHandler) DeleteFeedToken(c *gin.Context) {
+	if err := h.service.DeleteFeedToken(middleware.UserID(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// writeCalendar streams a user's todos as an iCalendar file, filtered by
+// the same query parameters as GET /todos
+func (h *CalendarHandler) writeCalendar(c *gin.Context, userID uint) {
+	c.Header("Content-Type", "text/calendar; charset=utf-8")
+	c.Header("Content-Disposition", `inline; filename="todos.ics"`)
+
+	err := h.service.WriteCalendar(c.Writer, userID, c.Query("search"), todoFilters(c))
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		c.Error(err) // Too late to change the response; leave it to the log
+		return
+	}
+	c.Header("Content-Type", "")
+	c.Header("Content-Disposition", "")
+	respondError(c, err)
+}
+
+// feedURL returns the absolute address of a calendar feed as seen by the client
+func feedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + "/api/calendar/" + token + ".ics"
+}
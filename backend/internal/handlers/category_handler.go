@@ -3,6 +3,7 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"todoListChallenge/internal/middleware"
 	"todoListChallenge/internal/models"
 	"todoListChallenge/internal/services"
 
@@ -23,23 +24,24 @@ func NewCategoryHandler(service *services.CategoryService) *CategoryHandler {
 func (h *CategoryHandler) CreateCategory(c *gin.Context) {
 	var category models.Category
 	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondProblem(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := h.service.CreateCategory(&category); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.CreateCategory(middleware.UserID(c), &category); err != nil {
+		respondError(c, err)
 		return
 	}
 
+	setETag(c, category.Version)
 	c.JSON(http.StatusCreated, category)
 }
 
 // GetCategories handles GET /categories
 func (h *CategoryHandler) GetCategories(c *gin.Context) {
-	categories, err := h.service.GetCategories()
+	categories, err := h.service.GetCategories(middleware.UserID(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -50,16 +52,20 @@ func (h *CategoryHandler) GetCategories(c *gin.Context) {
 func (h *CategoryHandler) GetCategory(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(c, http.StatusBadRequest, "invalid id")
 		return
 	}
 
-	category, err := h.service.GetCategoryByID(uint(id))
+	category, err := h.service.GetCategoryByID(middleware.UserID(c), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		respondError(c, err)
 		return
 	}
 
+	if notModified(c, category.Version) {
+		return
+	}
+	setETag(c, category.Version)
 	c.JSON(http.StatusOK, category)
 }
 
@@ -67,22 +73,62 @@ func (h *CategoryHandler) GetCategory(c *gin.Context) {
 func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(c, http.StatusBadRequest, "invalid id")
 		return
 	}
 
 	var category models.Category
 	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondProblem(c, http.StatusBadRequest, err.Error())
 		return
 	}
 	category.ID = uint(id)
 
-	if err := h.service.UpdateCategory(&category); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	version, ok := ifMatch(c, h.categoryVersion(c, uint(id)))
+	if !ok {
+		return
+	}
+	category.Version = version
+
+	if err := h.service.UpdateCategory(middleware.UserID(c), &category); err != nil {
+		respondError(c, err)
 		return
 	}
 
+	setETag(c, category.Version)
+	c.JSON(http.StatusOK, category)
+}
+
+// PatchCategory handles PATCH /categories/:id with a merge patch or JSON Patch
+func (h *CategoryHandler) PatchCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	apply, ok := readPatch(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.service.GetCategoryByID(middleware.UserID(c), uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	version, ok := ifMatch(c, h.categoryVersion(c, uint(id)))
+	if !ok {
+		return
+	}
+
+	category, err := h.service.PatchCategory(middleware.UserID(c), uint(id), version, apply)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, category.Version)
 	c.JSON(http.StatusOK, category)
 }
 
@@ -90,14 +136,30 @@ func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
 func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	version, ok := ifMatch(c, h.categoryVersion(c, uint(id)))
+	if !ok {
 		return
 	}
 
-	if err := h.service.DeleteCategory(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.DeleteCategory(middleware.UserID(c), uint(id), version); err != nil {
+		respondError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusNoContent, nil)
-}
\ No newline at end of file
+}
+
+// categoryVersion returns a loader for the current version of a category, for ifMatch
+func (h *CategoryHandler) categoryVersion(c *gin.Context, id uint) func() (uint, error) {
+	return func() (uint, error) {
+		category, err := h.service.GetCategoryByID(middleware.UserID(c), id)
+		if err != nil {
+			return 0, err
+		}
+		return category.Version, nil
+	}
+}
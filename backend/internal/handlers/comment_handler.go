@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CommentHandler handles HTTP requests for Comment
+type CommentHandler struct {
+	service *services.CommentService
+}
+
+// NewCommentHandler creates a new CommentHandler
+func NewCommentHandler(service *services.CommentService) *CommentHandler {
+	return &CommentHandler{service: service}
+}
+
+// commentRequest is the body of a comment create or edit
+type commentRequest struct {
+	Body string `json:"body"`
+}
+
+// GetComments handles GET /todos/:id/comments
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	todoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	comments, err := h.service.GetComments(middleware.UserID(c), uint(todoID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// CreateComment handles POST /todos/:id/comments
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	todoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req commentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comment := models.Comment{Body: req.Body}
+	if err := h.service.CreateComment(middleware.UserID(c), uint(todoID), &comment); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// GetComment handles GET /todos/:id/comments/:commentId
+func (h *CommentHandler) GetComment(c *gin.Context) {
+	todoID, id, ok := commentIDs(c)
+	if !ok {
+		return
+	}
+
+	comment, err := h.service.GetCommentByID(middleware.UserID(c), todoID, id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// UpdateComment handles PUT /todos/:id/comments/:commentId
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	todoID, id, ok := commentIDs(c)
+	if !ok {
+		return
+	}
+
+	var req commentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	comment, err := h.service.UpdateComment(middleware.UserID(c), todoID, id, req.Body)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment handles DELETE /todos/:id/comments/:commentId
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	todoID, id, ok := commentIDs(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteComment(middleware.UserID(c), todoID, id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// commentIDs parses the todo and comment IDs of a comment URL, responding
+// with 400 Bad Request when one is invalid
+func commentIDs(c *gin.Context) (uint, uint, bool) {
+	todoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return 0, 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("commentId"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid comment id")
+		return 0, 0, false
+	}
+	return uint(todoID), uint(id), true
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"todoListChallenge/internal/filter"
+	"todoListChallenge/internal/patch"
+	"todoListChallenge/internal/problem"
+	"todoListChallenge/internal/search"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondProblem responds with a problem details body for an error the
+// handler found itself, like an invalid path parameter
+func respondProblem(c *gin.Context, status int, detail string) {
+	problem.Respond(c, status, detail, nil)
+}
+
+// respondInvalid responds 422 Unprocessable Entity to an invalid field the
+// handler found itself
+func respondInvalid(c *gin.Context, field, msg string) {
+	problem.Respond(c, http.StatusUnprocessableEntity, msg, fieldMembers(field, msg))
+}
+
+// respondError responds to an error of the services with the status for its
+// kind:
+//   - 400 for a malformed search, filter, cursor or patch
+//   - 401 for wrong credentials or refresh tokens
+//   - 404 for a *services.NotFoundError
+//   - 409 for a *services.ConflictError or a patch that does not apply
+//   - 412 for services.ErrVersionMismatch
+//   - 415 for services.ErrUnsupportedAttachment
+//   - 422 for a *services.ValidationError or a *services.ReferenceError
+//
+// Anything else is an unexpected failure: it is left to the log and the
+// client gets 500 without the details.
+func respondError(c *gin.Context, err error) {
+	var validationErr *services.ValidationError
+	var notFound *services.NotFoundError
+	var conflict *services.ConflictError
+	var reference *services.ReferenceError
+	var patchErr *patch.Error
+	var syntaxErr *search.SyntaxError
+	var filterErr *filter.Error
+	switch {
+	case errors.As(err, &validationErr):
+		respondInvalid(c, validationErr.Field, validationErr.Msg)
+	case errors.As(err, &notFound):
+		respondProblem(c, http.StatusNotFound, notFound.Error())
+	case errors.As(err, &conflict) && conflict.Field != "":
+		problem.Respond(c, http.StatusConflict, conflict.Msg, gin.H{"field": conflict.Field})
+	case errors.As(err, &conflict):
+		respondProblem(c, http.StatusConflict, conflict.Msg)
+	case errors.As(err, &reference):
+		respondProblem(c, http.StatusUnprocessableEntity, reference.Msg)
+	case errors.Is(err, services.ErrVersionMismatch):
+		preconditionFailed(c)
+	case errors.As(err, &patchErr) && patchErr.Malformed:
+		respondProblem(c, http.StatusBadRequest, patchErr.Error())
+	case errors.As(err, &patchErr):
+		respondProblem(c, http.StatusConflict, patchErr.Error())
+	case errors.As(err, &syntaxErr):
+		problem.Respond(c, http.StatusBadRequest, syntaxErr.Error(), gin.H{"position": syntaxErr.Pos})
+	case errors.As(err, &filterErr):
+		problem.Respond(c, http.StatusBadRequest, filterErr.Error(), gin.H{"position": filterErr.Pos, "token": filterErr.Token})
+	case errors.Is(err, services.ErrInvalidCursor):
+		respondProblem(c, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrInvalidCredentials), errors.Is(err, services.ErrInvalidRefreshToken):
+		respondProblem(c, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, services.ErrUnsupportedAttachment):
+		respondProblem(c, http.StatusUnsupportedMediaType, err.Error())
+	default:
+		c.Error(err)
+		respondProblem(c, http.StatusInternalServerError, "internal server error")
+	}
+}
+
+// fieldMembers returns the problem members naming an invalid field, in the
+// same shape as the errors found by checking requests against the OpenAPI
+// document
+func fieldMembers(field, msg string) gin.H {
+	if field == "" {
+		return nil
+	}
+	return gin.H{"field": field, "errors": []gin.H{{"field": field, "error": msg}}}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag returns the entity tag of a resource version
+func etag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// setETag sets the ETag header of a response
+func setETag(c *gin.Context, version uint) {
+	c.Header("ETag", etag(version))
+}
+
+// notModified responds with 304 Not Modified when If-None-Match matches the
+// version and reports whether it did
+func notModified(c *gin.Context, version uint) bool {
+	header := c.GetHeader("If-None-Match")
+	if header == "" || !matchETag(header, version, true) {
+		return false
+	}
+	setETag(c, version)
+	c.Status(http.StatusNotModified)
+	return true
+}
+
+// ifMatch evaluates the If-Match precondition of a change. current loads the
+// resource's version and is only called when the request has If-Match; a
+// resource that does not exist fails the precondition. It returns the version
+// the change must apply to, 0 without a precondition, and false after
+// responding with 412 Precondition Failed or with the error of the lookup.
+func ifMatch(c *gin.Context, current func() (uint, error)) (uint, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return 0, true
+	}
+	version, err := current()
+	var notFound *services.NotFoundError
+	if errors.As(err, &notFound) {
+		preconditionFailed(c)
+		return 0, false
+	}
+	if err != nil {
+		respondError(c, err)
+		return 0, false
+	}
+	if !matchETag(header, version, false) {
+		preconditionFailed(c)
+		return 0, false
+	}
+	return version, true
+}
+
+// preconditionFailed responds with 412 Precondition Failed
+func preconditionFailed(c *gin.Context) {
+	respondProblem(c, http.StatusPreconditionFailed, "resource has been changed, fetch it again and retry")
+}
+
+// matchETag reports whether a list of entity tags like `"3", W/"4"` or `*`
+// matches the version. If-Match compares strongly, so weak tags never match;
+// If-None-Match compares weakly.
+func matchETag(header string, version uint, weak bool) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	want := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if tag == want {
+			return true
+		}
+	}
+	return false
+}
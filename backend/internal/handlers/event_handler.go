@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// heartbeatInterval keeps idle SSE connections open through proxies
+const heartbeatInterval = 25 * time.Second
+
+// EventHandler streams the change feed to clients
+type EventHandler struct {
+	bus *events.Bus
+}
+
+// NewEventHandler creates a new EventHandler
+func NewEventHandler(bus *events.Bus) *EventHandler {
+	return &EventHandler{bus: bus}
+}
+
+// Stream handles GET /events as a Server-Sent Events stream. A reconnecting
+// client resumes after the Last-Event-ID header or last_event_id parameter.
+func (h *EventHandler) Stream(c *gin.Context) {
+	lastEventID, err := parseLastEventID(c, c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sub, replay := h.bus.Subscribe(middleware.UserID(c), lastEventID)
+	defer sub.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, event := range replay {
+		if err := writeSSE(c, event); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return // Dropped for falling behind; the client reconnects and resumes
+			}
+			if err := writeSSE(c, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// WebSocket handles GET /events/ws, sending each event as a JSON text message.
+// A reconnecting client resumes after the last_event_id parameter.
+func (h *EventHandler) WebSocket(c *gin.Context) {
+	lastEventID, err := parseLastEventID(c, "")
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	userID := middleware.UserID(c)
+
+	server := websocket.Server{
+		// Clients are authenticated by their access token, so any origin may connect
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+
+			sub, replay := h.bus.Subscribe(userID, lastEventID)
+			defer sub.Close()
+
+			// Incoming messages are ignored; reading only detects the client going away
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				var msg string
+				for websocket.Message.Receive(conn, &msg) == nil {
+				}
+			}()
+
+			for _, event := range replay {
+				if err := websocket.JSON.Send(conn, event); err != nil {
+					return
+				}
+			}
+			for {
+				select {
+				case <-closed:
+					return
+				case event, ok := <-sub.Events:
+					if !ok {
+						return
+					}
+					if err := websocket.JSON.Send(conn, event); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// writeSSE writes one event in the text/event-stream format
+func writeSSE(c *gin.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(c.Writer, "id: %d\ndata: %s\n\n", event.ID, data)
+	return err
+}
+
+// parseLastEventID reads the last_event_id query parameter, falling back to
+// the given header value
+func parseLastEventID(c *gin.Context, header string) (uint64, error) {
+	value := c.DefaultQuery("last_event_id", header)
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid last event ID")
+	}
+	return id, nil
+}
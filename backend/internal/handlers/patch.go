@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"todoListChallenge/internal/patch"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readPatch reads the body of a PATCH request as a patch for its content
+// type: application/merge-patch+json or application/json for an RFC 7396
+// merge patch and application/json-patch+json for an RFC 6902 JSON Patch. It
+// responds with an error and returns false for anything else.
+func readPatch(c *gin.Context) (services.Patch, bool) {
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+
+	var apply func(doc, p []byte) ([]byte, error)
+	switch mediaType {
+	case "application/merge-patch+json", "application/json":
+		apply = patch.MergePatch
+	case "application/json-patch+json":
+		apply = patch.Apply
+	default:
+		c.Header("Accept-Patch", "application/merge-patch+json, application/json-patch+json")
+		respondProblem(c, http.StatusUnsupportedMediaType, "content type must be application/merge-patch+json or application/json-patch+json")
+		return nil, false
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return func(doc []byte) ([]byte, error) {
+		return apply(doc, body)
+	}, true
+}
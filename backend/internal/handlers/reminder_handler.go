@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReminderHandler handles HTTP requests for Reminder and Notification
+type ReminderHandler struct {
+	service *services.ReminderService
+}
+
+// NewReminderHandler creates a new ReminderHandler
+func NewReminderHandler(service *services.ReminderService) *ReminderHandler {
+	return &ReminderHandler{service: service}
+}
+
+// reminderRequest is the body of a reminder create
+type reminderRequest struct {
+	RemindAt      *time.Time `json:"remind_at"`
+	OffsetMinutes *int       `json:"offset_minutes"`
+	Channels      []string   `json:"channels"`
+}
+
+// snoozeRequest is the body of a snooze; without either field the reminder
+// is snoozed for services.DefaultSnooze
+type snoozeRequest struct {
+	Minutes int        `json:"minutes"`
+	Until   *time.Time `json:"until"`
+}
+
+// GetReminders handles GET /todos/:id/reminders
+func (h *ReminderHandler) GetReminders(c *gin.Context) {
+	todoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	reminders, err := h.service.GetReminders(middleware.UserID(c), uint(todoID))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reminders)
+}
+
+// CreateReminder handles POST /todos/:id/reminders
+func (h *ReminderHandler) CreateReminder(c *gin.Context) {
+	todoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req reminderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	reminder := models.Reminder{RemindAt: req.RemindAt, OffsetMinutes: req.OffsetMinutes, Channels: req.Channels}
+	if err := h.service.CreateReminder(middleware.UserID(c), uint(todoID), &reminder); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reminder)
+}
+
+// DeleteReminder handles DELETE /todos/:id/reminders/:reminderId
+func (h *ReminderHandler) DeleteReminder(c *gin.Context) {
+	todoID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("reminderId"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid reminder id")
+		return
+	}
+
+	if err := h.service.DeleteReminder(middleware.UserID(c), uint(todoID), uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// GetUpcomingReminders handles GET /reminders
+func (h *ReminderHandler) GetUpcomingReminders(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+
+	reminders, err := h.service.GetUpcomingReminders(middleware.UserID(c), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reminders)
+}
+
+// SnoozeReminder handles POST /reminders/:id/snooze
+func (h *ReminderHandler) SnoozeReminder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req snoozeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	until := time.Now().Add(services.DefaultSnooze)
+	switch {
+	case req.Until != nil && req.Minutes != 0:
+		respondInvalid(c, "until", "minutes and until cannot both be set")
+		return
+	case req.Until != nil:
+		until = *req.Until
+	case req.Minutes < 0:
+		respondInvalid(c, "minutes", "minutes must be positive")
+		return
+	case req.Minutes > 0:
+		until = time.Now().Add(time.Duration(req.Minutes) * time.Minute)
+	}
+
+	reminder, err := h.service.SnoozeReminder(middleware.UserID(c), uint(id), until)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reminder)
+}
+
+// DismissReminder handles POST /reminders/:id/dismiss
+func (h *ReminderHandler) DismissReminder(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	reminder, err := h.service.DismissReminder(middleware.UserID(c), uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reminder)
+}
+
+// GetNotifications handles GET /notifications
+func (h *ReminderHandler) GetNotifications(c *gin.Context) {
+	unread := c.Query("unread") == "true"
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+
+	notifications, err := h.service.GetNotifications(middleware.UserID(c), unread, limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// ReadNotification handles POST /notifications/:id/read
+func (h *ReminderHandler) ReadNotification(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.service.ReadNotification(middleware.UserID(c), uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TagHandler handles HTTP requests for Tag
+type TagHandler struct {
+	service *services.TagService
+}
+
+// NewTagHandler creates a new TagHandler
+func NewTagHandler(service *services.TagService) *TagHandler {
+	return &TagHandler{service: service}
+}
+
+// CreateTag handles POST /tags
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	var tag models.Tag
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.CreateTag(middleware.UserID(c), &tag); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// GetTags handles GET /tags
+func (h *TagHandler) GetTags(c *gin.Context) {
+	tags, err := h.service.GetTags(middleware.UserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// GetTag handles GET /tags/:id
+func (h *TagHandler) GetTag(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	tag, err := h.service.GetTagByID(middleware.UserID(c), uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// UpdateTag handles PUT /tags/:id
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var tag models.Tag
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	tag.ID = uint(id)
+
+	if err := h.service.UpdateTag(middleware.UserID(c), &tag); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag handles DELETE /tags/:id
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.service.DeleteTag(middleware.UserID(c), uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
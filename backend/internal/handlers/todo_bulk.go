@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bulkRequest is the body of POST /todos/bulk: either a list of operations,
+// or a filter and the operation to run on every todo it selects
+type bulkRequest struct {
+	Mode       services.BulkMode        `json:"mode"`
+	Operations []services.BulkOperation `json:"operations"`
+	Filter     *struct {
+		Q      string `json:"q"`
+		Search string `json:"search"`
+	} `json:"filter"`
+	Operation *services.BulkOperation `json:"operation"`
+}
+
+// BulkTodos handles POST /todos/bulk
+func (h *TodoHandler) BulkTodos(c *gin.Context) {
+	var req bulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var result *services.BulkResult
+	var err error
+	switch {
+	case req.Filter != nil && req.Operation != nil && req.Operations == nil:
+		result, err = h.service.BulkSelect(middleware.UserID(c), req.Filter.Search, req.Filter.Q, *req.Operation, req.Mode)
+	case req.Filter == nil && req.Operation == nil:
+		result, err = h.service.Bulk(middleware.UserID(c), req.Operations, req.Mode)
+	default:
+		respondProblem(c, http.StatusBadRequest, "send either operations, or a filter and an operation")
+		return
+	}
+
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// An atomic request that failed saved nothing
+	status := http.StatusOK
+	if !result.Committed {
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, result)
+}
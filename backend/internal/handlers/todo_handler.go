@@ -3,47 +3,95 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"todoListChallenge/internal/middleware"
 	"todoListChallenge/internal/models"
 	"todoListChallenge/internal/services"
+	"todoListChallenge/internal/transfer"
 
 	"github.com/gin-gonic/gin"
 )
 
 // TodoHandler handles HTTP requests for Todo
 type TodoHandler struct {
-	service *services.TodoService
+	service  *services.TodoService
+	transfer *services.TransferService
 }
 
-// NewTodoHandler creates a new TodoHandler
-func NewTodoHandler(service *services.TodoService) *TodoHandler {
-	return &TodoHandler{service: service}
+// NewTodoHandler creates a new TodoHandler. The TransferService renders
+// todo lists in text formats.
+func NewTodoHandler(service *services.TodoService, transfer *services.TransferService) *TodoHandler {
+	return &TodoHandler{service: service, transfer: transfer}
 }
 
 // CreateTodo handles POST /todos
 func (h *TodoHandler) CreateTodo(c *gin.Context) {
 	var todo models.Todo
 	if err := c.ShouldBindJSON(&todo); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondProblem(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if err := h.service.CreateTodo(&todo); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.CreateTodo(middleware.UserID(c), &todo); err != nil {
+		respondError(c, err)
 		return
 	}
 
+	setETag(c, todo.Version)
 	c.JSON(http.StatusCreated, todo)
 }
 
-// GetTodos handles GET /todos
+// GetTodos handles GET /todos. With a format parameter of markdown or
+// todotxt, or an Accept header of text/markdown or text/plain, it returns
+// every matching todo as text, without pagination, instead of a page of
+// JSON.
 func (h *TodoHandler) GetTodos(c *gin.Context) {
+	c.Header("Vary", "Accept")
+	if format := negotiateExport(c, transfer.FormatJSON, transfer.FormatMarkdown, transfer.FormatTodoTxt); format != transfer.FormatJSON {
+		writeExport(c, h.transfer, format, false)
+		return
+	}
+
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	search := c.Query("search")
+	searchText := c.Query("search")
 	sortBy := c.DefaultQuery("sort_by", "created_at")
+	if _, ok := c.GetQuery("sort_by"); !ok && searchText != "" {
+		sortBy = "relevance" // Best matches first unless another order is asked for
+	}
 	sortOrder := c.DefaultQuery("sort_order", "desc")
 
-	// Build filters from query parameters
+	filters := todoFilters(c)
+
+	// Cursor pagination: ?cursor=... or, for the first page, ?pagination=cursor
+	if cursor, ok := c.GetQuery("cursor"); ok || c.Query("pagination") == "cursor" {
+		h.getTodoPage(c, cursor, limit, searchText, sortBy, sortOrder, filters)
+		return
+	}
+
+	todos, total, err := h.service.GetTodos(middleware.UserID(c), page, limit, searchText, sortBy, sortOrder, filters)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	totalPages := (int(total) + limit - 1) / limit
+	pagination := gin.H{
+		"current_page": page,
+		"per_page":     limit,
+		"total":        total,
+		"total_pages":  totalPages,
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       todos,
+		"pagination": pagination,
+	})
+}
+
+// todoFilters builds the filters of a todo list from the query parameters
+func todoFilters(c *gin.Context) map[string]interface{} {
 	filters := make(map[string]interface{})
 
 	// Filter by completion status
@@ -67,18 +115,55 @@ func (h *TodoHandler) GetTodos(c *gin.Context) {
 		filters["priority"] = priority
 	}
 
-	todos, total, err := h.service.GetTodos(page, limit, search, sortBy, sortOrder, filters)
+	// Filter by parent todo
+	if parentIDStr := c.Query("parent_id"); parentIDStr != "" {
+		parentID, err := strconv.ParseUint(parentIDStr, 10, 32)
+		if err == nil {
+			filters["parent_id"] = uint(parentID)
+		}
+	}
+
+	// Filter by tags: comma-separated tag IDs
+	for _, key := range []string{"tags_any", "tags_all", "tags_none"} {
+		if tagIDs := parseIDList(c.Query(key)); len(tagIDs) > 0 {
+			filters[key] = tagIDs
+		}
+	}
+
+	// Only top-level todos
+	if topLevelStr := c.Query("top_level"); topLevelStr != "" {
+		topLevel, err := strconv.ParseBool(topLevelStr)
+		if err == nil {
+			filters["top_level"] = topLevel
+		}
+	}
+
+	// Filter language, e.g. q=priority:high -completed due<2026-11-01
+	if q := c.Query("q"); q != "" {
+		filters["q"] = q
+	}
+
+	return filters
+}
+
+// getTodoPage responds with a cursor-paginated page of todos
+func (h *TodoHandler) getTodoPage(c *gin.Context, cursor string, limit int, searchText, sortBy, sortOrder string, filters map[string]interface{}) {
+	todos, next, prev, err := h.service.GetTodoPage(middleware.UserID(c), cursor, limit, searchText, sortBy, sortOrder, filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
-	totalPages := (int(total) + limit - 1) / limit
 	pagination := gin.H{
-		"current_page": page,
-		"per_page":     limit,
-		"total":        total,
-		"total_pages":  totalPages,
+		"per_page":    limit,
+		"next_cursor": nil,
+		"prev_cursor": nil,
+	}
+	if next != "" {
+		pagination["next_cursor"] = next
+	}
+	if prev != "" {
+		pagination["prev_cursor"] = prev
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -91,16 +176,20 @@ func (h *TodoHandler) GetTodos(c *gin.Context) {
 func (h *TodoHandler) GetTodo(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(c, http.StatusBadRequest, "invalid id")
 		return
 	}
 
-	todo, err := h.service.GetTodoByID(uint(id))
+	todo, err := h.service.GetTodoByID(middleware.UserID(c), uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "todo not found"})
+		respondError(c, err)
 		return
 	}
 
+	if notModified(c, todo.Version) {
+		return
+	}
+	setETag(c, todo.Version)
 	c.JSON(http.StatusOK, todo)
 }
 
@@ -108,35 +197,122 @@ func (h *TodoHandler) GetTodo(c *gin.Context) {
 func (h *TodoHandler) UpdateTodo(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(c, http.StatusBadRequest, "invalid id")
 		return
 	}
 
 	var todo models.Todo
 	if err := c.ShouldBindJSON(&todo); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondProblem(c, http.StatusBadRequest, err.Error())
 		return
 	}
 	todo.ID = uint(id)
 
-	if err := h.service.UpdateTodo(&todo); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	version, ok := ifMatch(c, h.todoVersion(c, uint(id)))
+	if !ok {
+		return
+	}
+	todo.Version = version
+
+	if err := h.service.UpdateTodo(middleware.UserID(c), &todo); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, todo.Version)
+	c.JSON(http.StatusOK, todo)
+}
+
+// PatchTodo handles PATCH /todos/:id with a merge patch or JSON Patch
+func (h *TodoHandler) PatchTodo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	apply, ok := readPatch(c)
+	if !ok {
+		return
+	}
+
+	if _, err := h.service.GetTodoByID(middleware.UserID(c), uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	version, ok := ifMatch(c, h.todoVersion(c, uint(id)))
+	if !ok {
+		return
+	}
+
+	todo, err := h.service.PatchTodo(middleware.UserID(c), uint(id), version, apply)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, todo.Version)
+	c.JSON(http.StatusOK, todo)
+}
+
+// RevertTodo handles POST /todos/:id/revert with the audit entry to go back to
+func (h *TodoHandler) RevertTodo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req struct {
+		EntryID uint `json:"entry_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if _, err := h.service.GetTodoByID(middleware.UserID(c), uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	version, ok := ifMatch(c, h.todoVersion(c, uint(id)))
+	if !ok {
+		return
+	}
+
+	todo, err := h.service.RevertTodo(middleware.UserID(c), uint(id), req.EntryID, version)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
+	setETag(c, todo.Version)
 	c.JSON(http.StatusOK, todo)
 }
 
-// DeleteTodo handles DELETE /todos/:id
+// DeleteTodo handles DELETE /todos/:id?subtasks=cascade|reparent
 func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	mode := services.DeleteMode(c.DefaultQuery("subtasks", string(services.DeleteCascade)))
+	if mode != services.DeleteCascade && mode != services.DeleteReparent {
+		respondProblem(c, http.StatusBadRequest, "subtasks must be cascade or reparent")
 		return
 	}
 
-	if err := h.service.DeleteTodo(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	version, ok := ifMatch(c, h.todoVersion(c, uint(id)))
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteTodo(middleware.UserID(c), uint(id), mode, version); err != nil {
+		respondError(c, err)
 		return
 	}
 
@@ -147,20 +323,167 @@ func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 func (h *TodoHandler) ToggleComplete(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	version, ok := ifMatch(c, h.todoVersion(c, uint(id)))
+	if !ok {
 		return
 	}
 
-	if err := h.service.ToggleComplete(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.service.ToggleComplete(middleware.UserID(c), uint(id), version); err != nil {
+		respondError(c, err)
 		return
 	}
 
-	todo, err := h.service.GetTodoByID(uint(id))
+	todo, err := h.service.GetTodoByID(middleware.UserID(c), uint(id))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve updated todo"})
+		respondError(c, err)
 		return
 	}
 
+	setETag(c, todo.Version)
 	c.JSON(http.StatusOK, todo)
-}
\ No newline at end of file
+}
+
+// GetSubtasks handles GET /todos/:id/subtasks
+func (h *TodoHandler) GetSubtasks(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	subtasks, err := h.service.GetSubtasks(middleware.UserID(c), uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, subtasks)
+}
+
+// CreateSubtask handles POST /todos/:id/subtasks
+func (h *TodoHandler) CreateSubtask(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var todo models.Todo
+	if err := c.ShouldBindJSON(&todo); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.service.CreateSubtask(middleware.UserID(c), uint(id), &todo); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, todo)
+}
+
+// AddTag handles PUT /todos/:id/tags/:tagId
+func (h *TodoHandler) AddTag(c *gin.Context) {
+	h.changeTag(c, h.service.AddTag)
+}
+
+// RemoveTag handles DELETE /todos/:id/tags/:tagId
+func (h *TodoHandler) RemoveTag(c *gin.Context) {
+	h.changeTag(c, h.service.RemoveTag)
+}
+
+// changeTag parses the todo and tag IDs, applies change and returns the updated todo
+func (h *TodoHandler) changeTag(c *gin.Context, change func(userID, todoID, tagID uint) error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	tagID, err := strconv.ParseUint(c.Param("tagId"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid tag id")
+		return
+	}
+
+	if err := change(middleware.UserID(c), uint(id), uint(tagID)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	todo, err := h.service.GetTodoByID(middleware.UserID(c), uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, todo)
+}
+
+// todoVersion returns a loader for the current version of a todo, for ifMatch
+func (h *TodoHandler) todoVersion(c *gin.Context, id uint) func() (uint, error) {
+	return func() (uint, error) {
+		todo, err := h.service.GetTodoByID(middleware.UserID(c), id)
+		if err != nil {
+			return 0, err
+		}
+		return todo.Version, nil
+	}
+}
+
+// parseIDList parses a comma-separated list of IDs, skipping invalid entries
+func parseIDList(value string) []uint {
+	var ids []uint
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32)
+		if err == nil {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// GetOccurrences handles GET /todos/:id/occurrences?count=N
+func (h *TodoHandler) GetOccurrences(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+	count, _ := strconv.Atoi(c.DefaultQuery("count", "10"))
+
+	occurrences, err := h.service.GetOccurrences(middleware.UserID(c), uint(id), count)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"occurrences": occurrences})
+}
+
+// previewRecurrenceRequest is the body of POST /recurrence/preview
+type previewRecurrenceRequest struct {
+	Rule  string    `json:"rule" binding:"required"`
+	Start time.Time `json:"start" binding:"required"`
+	Count int       `json:"count"`
+}
+
+// PreviewRecurrence handles POST /recurrence/preview
+func (h *TodoHandler) PreviewRecurrence(c *gin.Context) {
+	var req previewRecurrenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondProblem(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	occurrences, err := h.service.PreviewRecurrence(req.Rule, req.Start, req.Count)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"occurrences": occurrences})
+}
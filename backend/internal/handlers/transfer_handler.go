@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/problem"
+	"todoListChallenge/internal/services"
+	"todoListChallenge/internal/transfer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBody is the largest import file accepted, 10 MiB
+const maxImportBody = 10 << 20
+
+// exportTypes are the media types and file names of the export formats
+var exportTypes = map[string]struct{ mediaType, fileName string }{
+	transfer.FormatJSON:     {"application/json", "todos.json"},
+	transfer.FormatCSV:      {"text/csv", "todos.csv"},
+	transfer.FormatMarkdown: {"text/markdown", "todos.md"},
+	transfer.FormatTodoTxt:  {"text/plain", "todo.txt"},
+}
+
+// TransferHandler handles HTTP requests for exporting and importing todos
+type TransferHandler struct {
+	service *services.TransferService
+}
+
+// NewTransferHandler creates a new TransferHandler
+func NewTransferHandler(service *services.TransferService) *TransferHandler {
+	return &TransferHandler{service: service}
+}
+
+// Export handles GET /export, filtered by the same query parameters as
+// GET /todos. The format is taken from the format parameter, else from the
+// Accept header, and is JSON by default.
+func (h *TransferHandler) Export(c *gin.Context) {
+	format := negotiateExport(c, transfer.FormatJSON, transfer.FormatCSV, transfer.FormatMarkdown, transfer.FormatTodoTxt)
+	writeExport(c, h.service, format, true)
+}
+
+// Import handles POST /import with the file either as the body, or in the
+// "file" field of a multipart/form-data body. The format is taken from the
+// format parameter, else from the file name or content type.
+func (h *TransferHandler) Import(c *gin.Context) {
+	h.runImport(c, h.service.Import)
+}
+
+// Preview handles POST /import/preview, which takes the same file and
+// parameters as POST /import and returns what it would import
+func (h *TransferHandler) Preview(c *gin.Context) {
+	h.runImport(c, h.service.Preview)
+}
+
+// runImport reads the file and options of an import request and responds
+// with the result of passing them to run
+func (h *TransferHandler) runImport(c *gin.Context, run func(userID uint, file io.Reader, opts services.ImportOptions) (*services.ImportResult, error)) {
+	opts := services.ImportOptions{
+		Format:           c.Query("format"),
+		Mapping:          c.QueryMap("map"),
+		OnDuplicate:      services.DuplicateMode(c.Query("on_duplicate")),
+		CreateCategories: true,
+	}
+	for name, value := range map[string]*bool{"dry_run": &opts.DryRun, "create_categories": &opts.CreateCategories} {
+		if s := c.Query(name); s != "" {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				respondProblem(c, http.StatusBadRequest, "invalid "+name+" value")
+				return
+			}
+			*value = b
+		}
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBody+multipartOverhead)
+	var file io.Reader = c.Request.Body
+	contentType, _, _ := mime.ParseMediaType(c.ContentType())
+	if contentType == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				respondImportTooLarge(c)
+				return
+			}
+			respondProblem(c, http.StatusBadRequest, "a multipart file field named file is required")
+			return
+		}
+		upload, err := header.Open()
+		if err != nil {
+			respondProblem(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		defer upload.Close()
+		file = upload
+		opts.FileName = header.Filename
+		contentType, _, _ = mime.ParseMediaType(header.Header.Get("Content-Type"))
+	}
+	if opts.Format == "" {
+		opts.Format = importFormat(contentType, opts.FileName)
+	}
+
+	result, err := run(middleware.UserID(c), file, opts)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondImportTooLarge(c)
+			return
+		}
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// negotiateExport returns the export format of the format parameter, else
+// the one of formats the Accept header prefers, which is the first when it
+// prefers none
+func negotiateExport(c *gin.Context, formats ...string) string {
+	if format := c.Query("format"); format != "" {
+		return format
+	}
+	offered := make([]string, len(formats))
+	for i, format := range formats {
+		offered[i] = exportTypes[format].mediaType
+	}
+	mediaType := c.NegotiateFormat(offered...)
+	for _, format := range formats {
+		if exportTypes[format].mediaType == mediaType {
+			return format
+		}
+	}
+	return formats[0]
+}
+
+// writeExport streams a user's todos, filtered by the same query parameters
+// as GET /todos, in an export format. As an attachment, the response is
+// saved as a file by browsers.
+func writeExport(c *gin.Context, service *services.TransferService, format string, attachment bool) {
+	if exportType, ok := exportTypes[format]; ok {
+		c.Header("Content-Type", exportType.mediaType+"; charset=utf-8")
+		if attachment {
+			c.Header("Content-Disposition", `attachment; filename="`+exportType.fileName+`"`)
+		}
+	}
+
+	err := service.Export(c.Writer, middleware.UserID(c), format, c.Query("search"), todoFilters(c))
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		c.Error(err) // Too late to change the response; leave it to the log
+		return
+	}
+	c.Header("Content-Type", "")
+	c.Header("Content-Disposition", "")
+	respondError(c, err)
+}
+
+// respondImportTooLarge responds 413 Request Entity Too Large with the
+// largest file an import accepts
+func respondImportTooLarge(c *gin.Context) {
+	problem.Respond(c, http.StatusRequestEntityTooLarge, "the file is too large", gin.H{"max_size": maxImportBody})
+}
+
+// importFormat returns the import format of a file by the extension of its
+// name, else by its media type, or "" when neither is known. The exports of
+// Todoist and Trello are JSON, so they need the format parameter.
+func importFormat(contentType, fileName string) string {
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".json":
+		return transfer.FormatJSON
+	case ".csv":
+		return transfer.FormatCSV
+	case ".txt":
+		return transfer.FormatTodoTxt
+	case ".zip":
+		return transfer.FormatTodoist
+	}
+	switch {
+	case contentType == "text/csv":
+		return transfer.FormatCSV
+	case contentType == "application/json", strings.HasSuffix(contentType, "+json"):
+		return transfer.FormatJSON
+	case contentType == "text/plain":
+		return transfer.FormatTodoTxt
+	case contentType == "application/zip":
+		return transfer.FormatTodoist
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashHandler handles HTTP requests for deleted todos and categories
+type TrashHandler struct {
+	todos      *services.TodoService
+	categories *services.CategoryService
+}
+
+// NewTrashHandler creates a new TrashHandler
+func NewTrashHandler(todos *services.TodoService, categories *services.CategoryService) *TrashHandler {
+	return &TrashHandler{todos: todos, categories: categories}
+}
+
+// GetTrash handles GET /trash
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	todos, err := h.todos.GetTrash(middleware.UserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	categories, err := h.categories.GetTrash(middleware.UserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"todos": todos, "categories": categories})
+}
+
+// EmptyTrash handles DELETE /trash
+func (h *TrashHandler) EmptyTrash(c *gin.Context) {
+	if err := h.todos.EmptyTrash(middleware.UserID(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := h.categories.EmptyTrash(middleware.UserID(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// RestoreTodo handles POST /trash/todos/:id/restore
+func (h *TrashHandler) RestoreTodo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	todo, err := h.todos.RestoreTodo(middleware.UserID(c), uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, todo.Version)
+	c.JSON(http.StatusOK, todo)
+}
+
+// PurgeTodo handles DELETE /trash/todos/:id
+func (h *TrashHandler) PurgeTodo(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.todos.PurgeTodo(middleware.UserID(c), uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// RestoreCategory handles POST /trash/categories/:id/restore
+func (h *TrashHandler) RestoreCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	category, err := h.categories.RestoreCategory(middleware.UserID(c), uint(id))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	setETag(c, category.Version)
+	c.JSON(http.StatusOK, category)
+}
+
+// PurgeCategory handles DELETE /trash/categories/:id
+func (h *TrashHandler) PurgeCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondProblem(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	if err := h.categories.PurgeCategory(middleware.UserID(c), uint(id)); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
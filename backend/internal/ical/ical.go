@@ -0,0 +1,113 @@
+// Package ical reads and writes iCalendar data as defined by RFC 5545:
+// content lines folded at 75 octets, escaped text values and date-times.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineLength is the longest content line in octets, without the line break
+const maxLineLength = 75
+
+// dateTimeFormat is the form of a DATE-TIME value in UTC
+const dateTimeFormat = "20060102T150405Z"
+
+// Writer writes the content lines of an iCalendar stream. The first write
+// error is kept and returned by Flush; later writes do nothing.
+type Writer struct {
+	w   *bufio.Writer
+	err error
+}
+
+// NewWriter creates a Writer that writes to w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+// Begin starts a component such as VCALENDAR or VTODO
+func (w *Writer) Begin(component string) {
+	w.Line("BEGIN", component)
+}
+
+// End ends a component
+func (w *Writer) End(component string) {
+	w.Line("END", component)
+}
+
+// Text writes a property with a TEXT value, escaping it
+func (w *Writer) Text(name, value string) {
+	w.Line(name, EscapeText(value))
+}
+
+// List writes a property with a list of TEXT values, such as CATEGORIES
+func (w *Writer) List(name string, values []string) {
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = EscapeText(value)
+	}
+	w.Line(name, strings.Join(escaped, ","))
+}
+
+// Time writes a property with a DATE-TIME value in UTC
+func (w *Writer) Time(name string, t time.Time) {
+	w.Line(name, FormatTime(t))
+}
+
+// Line writes a property whose value is already in iCalendar form. name may
+// carry parameters, as in "REFRESH-INTERVAL;VALUE=DURATION".
+func (w *Writer) Line(name, value string) {
+	if w.err != nil {
+		return
+	}
+	line := name + ":" + value
+	limit := maxLineLength
+	for len(line) > limit {
+		// Fold before the limit without splitting a UTF-8 sequence
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		if _, w.err = w.w.WriteString(line[:cut] + "\r\n "); w.err != nil {
+			return
+		}
+		line = line[cut:]
+		limit = maxLineLength - 1 // Continuation lines start with a space
+	}
+	_, w.err = w.w.WriteString(line + "\r\n")
+}
+
+// Err returns the first write error, if any
+func (w *Writer) Err() error {
+	return w.err
+}
+
+// Flush writes any buffered data and returns the first error
+func (w *Writer) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	return w.w.Flush()
+}
+
+// FormatTime formats a DATE-TIME value in UTC
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeFormat)
+}
+
+// textEscaper escapes the characters with a meaning in TEXT values
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// EscapeText escapes a TEXT value. Line breaks become \n; other control
+// characters except tabs cannot be part of TEXT and are dropped.
+func EscapeText(value string) string {
+	return strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\t') || r == 0x7f {
+			return -1
+		}
+		return r
+	}, textEscaper.Replace(value))
+}
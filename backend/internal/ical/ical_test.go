@@ -0,0 +1,49 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	t.Run("component with escaped values", func(t *testing.T) {
+		var b bytes.Buffer
+		w := NewWriter(&b)
+
+		w.Begin("VTODO")
+		w.Text("SUMMARY", "Buy milk, eggs; bread\\butter\nand jam\x00")
+		w.List("CATEGORIES", []string{"Home, garden", "Errands"})
+		w.Time("DUE", time.Date(2026, 11, 2, 10, 30, 0, 0, time.FixedZone("CET", 3600)))
+		w.End("VTODO")
+
+		assert.NoError(t, w.Flush())
+		assert.Equal(t, "BEGIN:VTODO\r\n"+
+			`SUMMARY:Buy milk\, eggs\; bread\\butter\nand jam`+"\r\n"+
+			`CATEGORIES:Home\, garden,Errands`+"\r\n"+
+			"DUE:20261102T093000Z\r\n"+
+			"END:VTODO\r\n", b.String())
+	})
+
+	t.Run("long lines are folded at 75 octets", func(t *testing.T) {
+		var b bytes.Buffer
+		w := NewWriter(&b)
+
+		w.Text("DESCRIPTION", strings.Repeat("a", 100)+strings.Repeat("ä", 60))
+
+		assert.NoError(t, w.Flush())
+		lines := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+		assert.Len(t, lines, 4)
+		for i, line := range lines {
+			assert.LessOrEqual(t, len(line), 75)
+			if i > 0 {
+				assert.True(t, strings.HasPrefix(line, " "))
+			}
+		}
+		unfolded := strings.ReplaceAll(b.String(), "\r\n ", "")
+		assert.Equal(t, "DESCRIPTION:"+strings.Repeat("a", 100)+strings.Repeat("ä", 60)+"\r\n", unfolded)
+	})
+}
@@ -0,0 +1,211 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Component is a parsed component such as VCALENDAR or VTODO
+type Component struct {
+	Name       string
+	Props      []Property
+	Components []*Component
+}
+
+// Property is a parsed content line. Names and parameter names are upper
+// case; a parameter with several values keeps the first.
+type Property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Prop returns the first property with the given name, or nil
+func (c *Component) Prop(name string) *Property {
+	for i := range c.Props {
+		if c.Props[i].Name == name {
+			return &c.Props[i]
+		}
+	}
+	return nil
+}
+
+// Text returns the unescaped TEXT value of the first property with the
+// given name, or "" when there is none
+func (c *Component) Text(name string) string {
+	if p := c.Prop(name); p != nil {
+		return UnescapeText(p.Value)
+	}
+	return ""
+}
+
+// Parse reads an iCalendar stream holding one top-level component, usually
+// a VCALENDAR. Lines may end in CRLF or LF, and folded lines are unfolded.
+func Parse(r io.Reader) (*Component, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var root *Component
+	var stack []*Component
+	for n, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+1, err)
+		}
+		switch prop.Name {
+		case "BEGIN":
+			if root != nil && len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: more than one top-level component", n+1)
+			}
+			component := &Component{Name: strings.ToUpper(prop.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, component)
+			} else {
+				root = component
+			}
+			stack = append(stack, component)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", n+1, prop.Value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property outside of a component", n+1)
+			}
+			current := stack[len(stack)-1]
+			current.Props = append(current.Props, *prop)
+		}
+	}
+	if root == nil {
+		return nil, errors.New("no component")
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// unfold reads the logical content lines of a stream
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits a content line into its name, parameters and value.
+// Parameter values may be quoted to hold ':', ';' and ','.
+func parseLine(line string) (*Property, error) {
+	prop := &Property{Params: map[string]string{}}
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return nil, errors.New("missing property name")
+	}
+	prop.Name = strings.ToUpper(line[:i])
+
+	for line[i] == ';' {
+		rest := line[i+1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("invalid parameter of %s", prop.Name)
+		}
+		name := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+		consumed := i + 1 + eq + 1
+
+		value, n, err := paramValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("parameter %s: %w", name, err)
+		}
+		consumed += n
+		// Keep the first value of a multi-valued parameter
+		for consumed < len(line) && line[consumed] == ',' {
+			_, n, err := paramValue(line[consumed+1:])
+			if err != nil {
+				return nil, fmt.Errorf("parameter %s: %w", name, err)
+			}
+			consumed += 1 + n
+		}
+		if _, ok := prop.Params[name]; !ok {
+			prop.Params[name] = value
+		}
+		i = consumed
+		if i >= len(line) {
+			return nil, fmt.Errorf("missing value of %s", prop.Name)
+		}
+	}
+
+	if line[i] != ':' {
+		return nil, fmt.Errorf("invalid parameters of %s", prop.Name)
+	}
+	prop.Value = line[i+1:]
+	return prop, nil
+}
+
+// paramValue reads one parameter value, quoted or not, from the start of s
+// and returns it along with the number of bytes it took up
+func paramValue(s string) (string, int, error) {
+	if strings.HasPrefix(s, `"`) {
+		end := strings.IndexByte(s[1:], '"')
+		if end < 0 {
+			return "", 0, errors.New("unterminated quote")
+		}
+		return s[1 : end+1], end + 2, nil
+	}
+	end := strings.IndexAny(s, ",;:")
+	if end < 0 {
+		return "", 0, errors.New("missing property value")
+	}
+	return s[:end], end, nil
+}
+
+// textUnescaper reverses EscapeText
+var textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// UnescapeText unescapes a TEXT value
+func UnescapeText(value string) string {
+	return textUnescaper.Replace(value)
+}
+
+// ParseTime parses the DATE or DATE-TIME value of a property. UTC times end
+// in Z; other times are in the zone named by the TZID parameter, or in UTC
+// when it is missing or unknown. A DATE is midnight UTC of that day.
+func ParseTime(p *Property) (time.Time, error) {
+	value := strings.TrimSpace(p.Value)
+	if p.Params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		return time.Parse("20060102", value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(dateTimeFormat, value)
+	}
+	location := time.UTC
+	if tzid := strings.TrimPrefix(p.Params["TZID"], "/"); tzid != "" {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,89 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("nested components with folded lines", func(t *testing.T) {
+		data := "BEGIN:VCALENDAR\r\n" +
+			"VERSION:2.0\r\n" +
+			"BEGIN:VTODO\r\n" +
+			"UID:abc-123\r\n" +
+			`SUMMARY:Buy milk\, eggs\; bread\\butter\nand ja` + "\r\n" +
+			" m\r\n" +
+			`DUE;TZID="Europe/Berlin";X-A=1,"b:c":20261102T103000` + "\r\n" +
+			"END:VTODO\r\n" +
+			"END:VCALENDAR\r\n"
+
+		cal, err := Parse(strings.NewReader(data))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "VCALENDAR", cal.Name)
+		assert.Equal(t, "2.0", cal.Prop("VERSION").Value)
+		assert.Len(t, cal.Components, 1)
+		todo := cal.Components[0]
+		assert.Equal(t, "VTODO", todo.Name)
+		assert.Equal(t, "Buy milk, eggs; bread\\butter\nand jam", todo.Text("SUMMARY"))
+		due := todo.Prop("DUE")
+		assert.Equal(t, "Europe/Berlin", due.Params["TZID"])
+		assert.Equal(t, "1", due.Params["X-A"])
+		assert.Equal(t, "20261102T103000", due.Value)
+		assert.Nil(t, todo.Prop("DESCRIPTION"))
+	})
+
+	t.Run("round trip through the writer", func(t *testing.T) {
+		var b strings.Builder
+		w := NewWriter(&b)
+		w.Begin("VTODO")
+		w.Text("DESCRIPTION", strings.Repeat("Ünïcødé, ", 20)+"\nend")
+		w.End("VTODO")
+		w.Flush()
+
+		todo, err := Parse(strings.NewReader(b.String()))
+
+		assert.NoError(t, err)
+		assert.Equal(t, strings.Repeat("Ünïcødé, ", 20)+"\nend", todo.Text("DESCRIPTION"))
+	})
+
+	t.Run("invalid data", func(t *testing.T) {
+		for _, data := range []string{
+			"",
+			"SUMMARY:outside\n",
+			"BEGIN:VTODO\nSUMMARY:unterminated\n",
+			"BEGIN:VTODO\nEND:VEVENT\n",
+			"BEGIN:VTODO\nSUMMARY\nEND:VTODO\n",
+			"BEGIN:VTODO\nDUE;TZID=\"Europe/Berlin:20261102\nEND:VTODO\n",
+			"BEGIN:VTODO\nEND:VTODO\nBEGIN:VTODO\nEND:VTODO\n",
+		} {
+			_, err := Parse(strings.NewReader(data))
+			assert.Error(t, err, data)
+		}
+	})
+}
+
+func TestParseTime(t *testing.T) {
+	cases := []struct {
+		prop Property
+		want time.Time
+	}{
+		{Property{Value: "20261102T093000Z"}, time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC)},
+		{Property{Params: map[string]string{"TZID": "Europe/Berlin"}, Value: "20261102T103000"}, time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC)},
+		{Property{Params: map[string]string{"TZID": "Not/AZone"}, Value: "20261102T103000"}, time.Date(2026, 11, 2, 10, 30, 0, 0, time.UTC)},
+		{Property{Value: "20261102T103000"}, time.Date(2026, 11, 2, 10, 30, 0, 0, time.UTC)},
+		{Property{Params: map[string]string{"VALUE": "DATE"}, Value: "20261102"}, time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		got, err := ParseTime(&tc.prop)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, got)
+	}
+
+	_, err := ParseTime(&Property{Value: "tomorrow"})
+	assert.Error(t, err)
+}
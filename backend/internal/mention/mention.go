@@ -0,0 +1,128 @@
+// Package mention finds @mentions in the markdown body of a comment.
+package mention
+
+import (
+	"strings"
+	"unicode"
+)
+
+// MaxLength is the longest handle that counts as a mention
+const MaxLength = 64
+
+// Parse returns the handles mentioned in a markdown text, lowercased and in
+// the order they first appear. A mention is an @ followed by letters,
+// digits, underscores, dots or hyphens, not preceded by a letter or digit so
+// email addresses do not count. Mentions inside code spans, fenced code
+// blocks or after a backslash are ignored.
+func Parse(markdown string) []string {
+	var handles []string
+	seen := make(map[string]bool)
+	fence := ""
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if marker := fenceMarker(trimmed); marker != "" {
+			switch {
+			case fence == "":
+				fence = marker
+			case strings.HasPrefix(marker, fence) && strings.TrimSpace(trimmed[len(marker):]) == "":
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" || strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+			continue // Code block
+		}
+
+		for _, handle := range parseLine(line) {
+			if !seen[handle] {
+				seen[handle] = true
+				handles = append(handles, handle)
+			}
+		}
+	}
+	return handles
+}
+
+// fenceMarker returns the run of backticks or tildes opening or closing a
+// fenced code block at the start of a line, or "" if there is none
+func fenceMarker(line string) string {
+	for _, c := range []string{"`", "~"} {
+		n := 0
+		for n < len(line) && line[n] == c[0] {
+			n++
+		}
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// parseLine returns the mentions in a line of text outside code spans
+func parseLine(line string) []string {
+	var handles []string
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			i++ // Escaped character
+		case '`':
+			// Skip to the end of the code span, a run of as many backticks
+			n := run(runes, i, '`')
+			closing := closeSpan(runes, i+n, n)
+			if closing < 0 {
+				i += n - 1 // No closing run, the backticks are literal
+			} else {
+				i = closing + n - 1
+			}
+		case '@':
+			if i > 0 && (isHandle(runes[i-1]) || runes[i-1] == '@') {
+				continue
+			}
+			end := i + 1
+			for end < len(runes) && isHandle(runes[end]) {
+				end++
+			}
+			// Punctuation ending a sentence is not part of the handle
+			for end > i+1 && strings.ContainsRune(".-", runes[end-1]) {
+				end--
+			}
+			if end > i+1 && end-i-1 <= MaxLength {
+				handles = append(handles, strings.ToLower(string(runes[i+1:end])))
+			}
+			i = end - 1
+		}
+	}
+	return handles
+}
+
+// run returns the length of the run of c starting at i
+func run(runes []rune, i int, c rune) int {
+	n := 0
+	for i+n < len(runes) && runes[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// closeSpan returns where a run of exactly n backticks starts at or after i,
+// or -1 if there is none
+func closeSpan(runes []rune, i, n int) int {
+	for i < len(runes) {
+		if runes[i] != '`' {
+			i++
+			continue
+		}
+		m := run(runes, i, '`')
+		if m == n {
+			return i
+		}
+		i += m
+	}
+	return -1
+}
+
+// isHandle reports whether r may appear in a handle
+func isHandle(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-'
+}
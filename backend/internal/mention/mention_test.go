@@ -0,0 +1,43 @@
+package mention
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("mentions in order of appearance", func(t *testing.T) {
+		handles := Parse("@Alice can you review this with @bob.smith? Thanks @alice!")
+
+		assert.Equal(t, []string{"alice", "bob.smith"}, handles)
+	})
+
+	t.Run("trailing punctuation is not part of the handle", func(t *testing.T) {
+		assert.Equal(t, []string{"carol"}, Parse("Ask @carol."))
+		assert.Equal(t, []string{"dave"}, Parse("(cc @dave)"))
+	})
+
+	t.Run("email addresses are not mentions", func(t *testing.T) {
+		assert.Empty(t, Parse("Mail alice@example.com or @@bob"))
+	})
+
+	t.Run("code is ignored", func(t *testing.T) {
+		text := "Run `ping @host` first, ``@a ` b``\n" +
+			"```sh\n" +
+			"echo @nobody\n" +
+			"```\n" +
+			"    @indented\n" +
+			"then tell @erin"
+
+		assert.Equal(t, []string{"erin"}, Parse(text))
+	})
+
+	t.Run("escaped and bare @ signs", func(t *testing.T) {
+		assert.Empty(t, Parse(`\@frank and a lone @ sign`))
+	})
+
+	t.Run("unclosed backticks are literal", func(t *testing.T) {
+		assert.Equal(t, []string{"grace"}, Parse("a ` tick then @grace"))
+	})
+}
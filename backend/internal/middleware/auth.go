@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"todoListChallenge/internal/auth"
+	"todoListChallenge/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userIDKey is the gin context key holding the authenticated user ID
+const userIDKey = "userID"
+
+// RequireAuth rejects requests without a valid bearer access token and stores
+// the authenticated user ID in the context
+func RequireAuth(tokens *auth.TokenManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(token) == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			problem.Respond(c, http.StatusUnauthorized, "missing bearer token", nil)
+			return
+		}
+
+		userID, err := tokens.ParseAccessToken(strings.TrimSpace(token))
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+			problem.Respond(c, http.StatusUnauthorized, err.Error(), nil)
+			return
+		}
+
+		c.Set(userIDKey, userID)
+		c.Next()
+	}
+}
+
+// UserID returns the authenticated user ID set by RequireAuth
+func UserID(c *gin.Context) uint {
+	return c.GetUint(userIDKey)
+}
+
+// QueryToken lets the access token be passed as the access_token query
+// parameter for endpoints opened by EventSource or WebSocket clients, which
+// cannot set request headers. It must run before RequireAuth.
+func QueryToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
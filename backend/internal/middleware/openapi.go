@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"todoListChallenge/internal/openapi"
+	"todoListChallenge/internal/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ginParam matches the :name and *name parameters of a Gin route
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// ValidateRequests rejects requests that do not match the operation of their
+// route in the OpenAPI document: 400 for invalid parameters and malformed
+// bodies, 413 for a JSON body too large to check, 415 for a body of the
+// wrong media type and 422 for a body that does not match its schema. The
+// response lists every invalid field, with the first also as error and
+// field like other errors. Routes the document does not describe are let
+// through.
+func ValidateRequests(doc *openapi.Document) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		operation := routeOperation(doc, c)
+		if operation == nil {
+			c.Next()
+			return
+		}
+
+		params := make(map[string]string, len(c.Params))
+		for _, param := range c.Params {
+			params[param.Key] = param.Value
+		}
+		if err := operation.ValidateRequest(c.Request, params); err != nil {
+			var requestErr *openapi.RequestError
+			if !errors.As(err, &requestErr) {
+				problem.Respond(c, http.StatusBadRequest, err.Error(), nil)
+				return
+			}
+			first := requestErr.Errors[0]
+			problem.Respond(c, requestErr.Status, first.Msg, gin.H{"field": first.Field, "errors": requestErr.Errors})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ValidateResponses checks the responses of routes the OpenAPI document
+// describes against it and calls report with each mismatch. The response is
+// sent as it is; this is meant for tests and development, where report
+// fails the test or logs the mismatch. Only JSON bodies are held for the
+// check, so streams pass through.
+func ValidateResponses(doc *openapi.Document, report func(c *gin.Context, err error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		operation := routeOperation(doc, c)
+		if operation == nil {
+			c.Next()
+			return
+		}
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if err := operation.ValidateResponse(writer.Status(), writer.Header(), writer.body.Bytes()); err != nil {
+			report(c, err)
+		}
+	}
+}
+
+// routeOperation returns the operation of the route of a request, or nil
+func routeOperation(doc *openapi.Document, c *gin.Context) *openapi.Operation {
+	if c.FullPath() == "" {
+		return nil
+	}
+	return doc.Operation(c.Request.Method, ginParam.ReplaceAllString(c.FullPath(), "{$1}"))
+}
+
+// recordingWriter keeps a copy of a JSON response body as it is written
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.record(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// record copies data written with a JSON content type
+func (w *recordingWriter) record(data []byte) {
+	mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type"))
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		w.body.Write(data)
+	}
+}
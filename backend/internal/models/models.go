@@ -2,8 +2,9 @@ package models
 
 import (
 	"time"
-)
 
+	"gorm.io/gorm"
+)
 
 type Priority string
 
@@ -15,27 +16,281 @@ const (
 
 // Todo represents a todo item
 type Todo struct {
-	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	Title       string    `json:"title" gorm:"not null"`
-	Description string    `json:"description"`
-	Completed   bool      `json:"completed" gorm:"default:false"`
-	CategoryID  *uint     `json:"category_id" gorm:"index"`
-	Priority    Priority  `json:"priority" gorm:"type:varchar(10);default:'medium'"`
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      uint       `json:"user_id" gorm:"index"`
+	Title       string     `json:"title" gorm:"not null"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed" gorm:"default:false"`
+	CategoryID  *uint      `json:"category_id" gorm:"index"`
+	ParentID    *uint      `json:"parent_id" gorm:"index"`
+	Priority    Priority   `json:"priority" gorm:"type:varchar(10);default:'medium'"`
 	DueDate     *time.Time `json:"due_date" gorm:"type:timestamp"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	Recurrence  string     `json:"recurrence" gorm:"type:varchar(255)"` // RRULE like FREQ=WEEKLY;BYDAY=MO
+	Version     uint       `json:"version" gorm:"not null;default:1"`   // Bumped on every change, served as the ETag
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
 
+	// DeletedAt is set while the todo is in the trash
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	// TrashedCategoryID is the category the todo belonged to when that
+	// category was moved to the trash, so restoring it can link them again
+	TrashedCategoryID *uint `json:"-"`
 
 	Category *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Subtasks []Todo    `json:"subtasks,omitempty" gorm:"foreignKey:ParentID"`
+	Progress *Progress `json:"progress,omitempty" gorm:"-"`
+	Tags     []Tag     `json:"tags,omitempty" gorm:"many2many:todo_tags"`
+
+	// TagIDs replaces the todo's tags on create and update when set
+	TagIDs []uint `json:"tag_ids,omitempty" gorm:"-"`
+	// Search is set on the results of a full-text search
+	Search *SearchMatch `json:"search,omitempty" gorm:"-"`
+	// CommentCount is set on todo lists
+	CommentCount *int64 `json:"comment_count,omitempty" gorm:"-"`
+}
+
+// Progress summarizes how many direct subtasks of a todo are done
+type Progress struct {
+	Completed int `json:"completed"`
+	Total     int `json:"total"`
+}
+
+// SearchMatch describes how a todo matched a full-text search. Title and
+// Description are HTML-escaped with the matches wrapped in <mark> tags.
+type SearchMatch struct {
+	Rank        float64 `json:"rank"`                  // Higher is more relevant
+	Title       string  `json:"title"`                 // Highlighted title
+	Description string  `json:"description,omitempty"` // Matching fragments of the description
 }
 
 // Todo, Category represents a category for todos
 type Category struct {
+	ID        uint           `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint           `json:"user_id" gorm:"uniqueIndex:idx_categories_user_name,where:deleted_at IS NULL"`
+	Name      string         `json:"name" gorm:"not null;uniqueIndex:idx_categories_user_name,where:deleted_at IS NULL"`
+	Color     string         `json:"color" gorm:"not null;type:varchar(7)"` // Hex color like #3B82F6
+	Version   uint           `json:"version" gorm:"not null;default:1"`     // Bumped on every change, served as the ETag
+	CreatedAt time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index"` // Set while the category is in the trash
+
+	Todos []Todo `json:"todos,omitempty" gorm:"foreignKey:CategoryID"`
+}
+
+// Tag represents a label that can be attached to any number of todos
+type Tag struct {
 	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name      string    `json:"name" gorm:"not null;unique"`
-	Color     string    `json:"color" gorm:"not null;type:varchar(7)"` // Hex color like #3B82F6
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_tags_user_name"`
+	Name      string    `json:"name" gorm:"not null;uniqueIndex:idx_tags_user_name"`
+	Color     string    `json:"color" gorm:"not null;type:varchar(7)"` // Hex color like #6B7280
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
 
+// User represents an account that owns todos, categories and tags
+type User struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Email        string    `json:"email" gorm:"not null;unique"`
+	Name         string    `json:"name"`
+	PasswordHash string    `json:"-" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
 
-	Todos []Todo `json:"todos,omitempty" gorm:"foreignKey:CategoryID"`
-}
\ No newline at end of file
+// RefreshToken is a long-lived token that can be exchanged for a new access token.
+// Only a hash of the token is stored.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;unique"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Webhook is a subscription that receives signed HTTP callbacks for a user's events
+type Webhook struct {
+	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint      `json:"user_id" gorm:"not null;index"`
+	URL        string    `json:"url" gorm:"not null;type:varchar(2048)"`
+	Secret     string    `json:"-" gorm:"not null"`                           // HMAC-SHA256 signing key, only in the create response
+	EventTypes []string  `json:"event_types" gorm:"not null;serializer:json"` // e.g. ["todo.toggled"], or ["*"] for all
+	Active     bool      `json:"active" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// WebhookDelivery status values
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent, or still to be sent, to a webhook
+type WebhookDelivery struct {
+	ID             uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	WebhookID      uint       `json:"webhook_id" gorm:"not null;index"`
+	EventType      string     `json:"event_type" gorm:"not null"`
+	Payload        string     `json:"payload" gorm:"not null;type:text"`
+	Status         string     `json:"status" gorm:"not null;index:idx_webhook_deliveries_due"`
+	Attempts       int        `json:"attempts" gorm:"not null;default:0"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty" gorm:"type:text"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" gorm:"index:idx_webhook_deliveries_due"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Comment is a note in the discussion of a todo
+type Comment struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TodoID    uint       `json:"todo_id" gorm:"not null;index"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`             // Author
+	Body      string     `json:"body" gorm:"type:text;not null"`            // Markdown
+	Mentions  []string   `json:"mentions" gorm:"serializer:json;type:text"` // Handles mentioned as @handle in the body
+	EditedAt  *time.Time `json:"edited_at"`                                 // Last time the body was changed
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// Attachment is a file attached to a todo. The content is kept in storage
+// under its SHA-256, so identical files are only stored once.
+type Attachment struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TodoID      uint      `json:"todo_id" gorm:"not null;index"`
+	UserID      uint      `json:"user_id" gorm:"not null;index"`
+	FileName    string    `json:"file_name" gorm:"not null;type:varchar(255)"`
+	ContentType string    `json:"content_type" gorm:"not null;type:varchar(255)"` // Detected from the content
+	Size        int64     `json:"size" gorm:"not null"`
+	SHA256      string    `json:"sha256" gorm:"column:sha256;not null;type:varchar(64);index"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// AttachmentBlob is a file in storage. It is removed from storage once no
+// attachment refers to it any more.
+type AttachmentBlob struct {
+	SHA256    string    `gorm:"column:sha256;primaryKey;type:varchar(64)"`
+	Size      int64     `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// Reminder status values
+const (
+	ReminderPending   = "pending"   // Fires at FireAt
+	ReminderSent      = "sent"      // Fired; snoozing makes it pending again
+	ReminderDismissed = "dismissed" // Will not fire again
+)
+
+// Notification channels a reminder can be sent over
+const (
+	ChannelInApp   = "in_app"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Reminder notifies a user about a todo, either at a fixed time or a number
+// of minutes before the todo is due
+type Reminder struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TodoID        uint       `json:"todo_id" gorm:"not null;index"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	RemindAt      *time.Time `json:"remind_at"`                                // Fixed time, or nil for an offset reminder
+	OffsetMinutes *int       `json:"offset_minutes"`                           // Minutes before the due date, or nil for a fixed time
+	Channels      []string   `json:"channels" gorm:"not null;serializer:json"` // e.g. ["in_app", "email"]
+	Status        string     `json:"status" gorm:"not null;type:varchar(20);index:idx_reminders_due"`
+	FireAt        *time.Time `json:"fire_at" gorm:"index:idx_reminders_due"` // Next time it fires; nil while an offset reminder's todo has no due date
+	SentAt        *time.Time `json:"sent_at"`                                // Last time it fired
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Notification status values
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
+
+// Notification is a fired reminder on one channel. In-app notifications are
+// sent as soon as they are created; the others are queued until a notifier
+// delivers them.
+type Notification struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	TodoID        uint       `json:"todo_id" gorm:"not null;index"`
+	ReminderID    uint       `json:"reminder_id" gorm:"not null;index"`
+	Channel       string     `json:"channel" gorm:"not null;type:varchar(20)"`
+	Title         string     `json:"title" gorm:"not null;type:varchar(255)"`
+	Message       string     `json:"message" gorm:"not null;type:text"`
+	Status        string     `json:"status" gorm:"not null;type:varchar(20);index:idx_notifications_due"`
+	Attempts      int        `json:"-" gorm:"not null;default:0"`
+	LastError     string     `json:"-" gorm:"type:text"`
+	NextAttemptAt *time.Time `json:"-" gorm:"index:idx_notifications_due"`
+	SentAt        *time.Time `json:"sent_at"`
+	ReadAt        *time.Time `json:"read_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// CalendarFeed is the secret address of a user's todos as an iCalendar
+// feed. Only a hash of its token is stored.
+type CalendarFeed struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `gorm:"not null;unique"`
+	TokenHash string    `gorm:"not null;unique"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// CalDAVObject keeps the resource name and UID a CalDAV client gave a todo
+// it created, so the client finds the todo under them again. Other todos
+// are served as todo-<id>.ics with the UID todo-<id>@todolist. A todo in
+// the trash keeps its name, which another todo may take over. A UID belongs
+// to one todo per user: a new todo taking over the UID of one in the trash
+// leaves it with its default UID.
+type CalDAVObject struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `gorm:"not null;index:idx_caldav_objects_user_name;uniqueIndex:idx_caldav_objects_user_uid"`
+	TodoID    uint      `gorm:"not null;unique"`
+	Name      string    `gorm:"not null;type:varchar(255);index:idx_caldav_objects_user_name"`
+	UID       string    `gorm:"not null;type:varchar(255);uniqueIndex:idx_caldav_objects_user_uid"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// TableName keeps GORM from naming the table cal_dav_objects
+func (CalDAVObject) TableName() string {
+	return "caldav_objects"
+}
+
+// Entity types of audit entries
+const (
+	AuditTodo     = "todo"
+	AuditCategory = "category"
+)
+
+// Actions of audit entries
+const (
+	AuditCreated  = "created"
+	AuditUpdated  = "updated"
+	AuditDeleted  = "deleted"
+	AuditToggled  = "toggled"  // Completion status changed
+	AuditRestored = "restored" // Taken out of the trash
+	AuditReverted = "reverted" // Set back to an earlier revision
+)
+
+// AuditEntry records a change to a todo or category: who made it, when, and
+// the fields it changed
+type AuditEntry struct {
+	ID         uint                   `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     uint                   `json:"user_id" gorm:"not null;index"` // Owner of the changed todo or category
+	ActorID    uint                   `json:"actor_id" gorm:"not null"`      // User who made the change
+	EntityType string                 `json:"entity_type" gorm:"not null;type:varchar(20);index:idx_audit_entries_entity"`
+	EntityID   uint                   `json:"entity_id" gorm:"not null;index:idx_audit_entries_entity"`
+	Action     string                 `json:"action" gorm:"not null;type:varchar(20)"`
+	Version    uint                   `json:"version"`                                           // Version of the entity after the change
+	Changes    map[string]FieldChange `json:"changes" gorm:"not null;serializer:json;type:text"` // Changed fields by JSON name
+	State      map[string]interface{} `json:"state" gorm:"not null;serializer:json;type:text"`   // Audited fields after the change, before it for a delete
+	CreatedAt  time.Time              `json:"created_at" gorm:"autoCreateTime;index:idx_audit_entries_created_at"`
+}
+
+// FieldChange is the value of a field before and after a change
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
@@ -0,0 +1,362 @@
+// Package openapi validates HTTP requests and responses against the
+// operations of an OpenAPI 3.1 document.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// methods are the HTTP methods an OpenAPI path item can describe
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Document is an OpenAPI document, as decoded from JSON or YAML into maps,
+// slices and scalars
+type Document struct {
+	root       map[string]interface{}
+	operations map[string]*Operation // By method and path, like "GET /api/todos/{id}"
+
+	patterns sync.Map // Compiled regexp of each pattern keyword
+}
+
+// Operation is an operation of a Document
+type Operation struct {
+	doc        *Document
+	node       map[string]interface{}
+	parameters []map[string]interface{} // Of the path item and the operation
+}
+
+// FieldError is a value of a request or response that does not match its
+// schema. Field is a parameter name, or the path of a value in the body like
+// operations[0].priority; it is empty for the body as a whole.
+type FieldError struct {
+	Field string `json:"field"`
+	In    string `json:"in"` // path, query, header or body; response for responses
+	Msg   string `json:"error"`
+}
+
+// MaxBodySize is the size in bytes up to which a JSON body is read to be
+// checked, as large as the largest file an import takes
+const MaxBodySize = 10 << 20
+
+// RequestError reports every way a request does not match its operation.
+// Status is 400 for invalid parameters and malformed bodies, 413 for a body
+// larger than MaxBodySize, 415 for a body of a media type the operation does
+// not take, and 422 for a body that does not match its schema.
+type RequestError struct {
+	Status int
+	Errors []FieldError
+}
+
+func (e *RequestError) Error() string {
+	return e.Errors[0].Msg
+}
+
+// New indexes the operations of an OpenAPI document
+func New(root map[string]interface{}) (*Document, error) {
+	paths, ok := root["paths"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("the document has no paths")
+	}
+	doc := &Document{root: root, operations: make(map[string]*Operation)}
+	for path, node := range paths {
+		item := doc.resolve(node)
+		for _, method := range methods {
+			operation, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			doc.operations[strings.ToUpper(method)+" "+path] = &Operation{
+				doc:        doc,
+				node:       operation,
+				parameters: doc.parameters(item["parameters"], operation["parameters"]),
+			}
+		}
+	}
+	return doc, nil
+}
+
+// Operation returns the operation of a method on a path template like
+// /api/todos/{id}, or nil when the document does not describe it
+func (d *Document) Operation(method, path string) *Operation {
+	return d.operations[strings.ToUpper(method)+" "+path]
+}
+
+// parameters returns the parameters of a path item with those of one of its
+// operations, which take the place of path item parameters with the same
+// name and location
+func (d *Document) parameters(item, operation interface{}) []map[string]interface{} {
+	var parameters []map[string]interface{}
+	index := make(map[string]int)
+	for _, list := range []interface{}{item, operation} {
+		nodes, _ := list.([]interface{})
+		for _, node := range nodes {
+			parameter := d.resolve(node)
+			key := fmt.Sprint(parameter["in"], " ", parameter["name"])
+			if i, ok := index[key]; ok {
+				parameters[i] = parameter
+				continue
+			}
+			index[key] = len(parameters)
+			parameters = append(parameters, parameter)
+		}
+	}
+	return parameters
+}
+
+// resolve follows the $ref of a node, if it has one, within the document
+func (d *Document) resolve(node interface{}) map[string]interface{} {
+	object, _ := node.(map[string]interface{})
+	for depth := 0; object != nil && depth < 32; depth++ {
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return object
+		}
+		object, _ = d.lookup(ref).(map[string]interface{})
+	}
+	return object
+}
+
+// lookup returns the node a local reference like #/components/schemas/Todo
+// points to, or nil
+func (d *Document) lookup(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var node interface{} = d.root
+	for _, key := range strings.Split(ref[2:], "/") {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = object[strings.ReplaceAll(strings.ReplaceAll(key, "~1", "/"), "~0", "~")]
+	}
+	return node
+}
+
+// ValidateRequest checks the parameters and body of a request against the
+// operation, given the values of its path parameters. A JSON body is read
+// to be checked, up to MaxBodySize, and put back for the handler; other
+// bodies are only checked for their media type. The error is a
+// *RequestError.
+func (o *Operation) ValidateRequest(r *http.Request, pathParams map[string]string) error {
+	var errs []FieldError
+	query := r.URL.Query()
+	for _, parameter := range o.parameters {
+		name, _ := parameter["name"].(string)
+		in, _ := parameter["in"].(string)
+		schema := o.doc.resolve(parameter["schema"])
+
+		var value interface{}
+		present := false
+		switch in {
+		case "path":
+			var s string
+			s, present = pathParams[name]
+			value = o.doc.coerce(schema, s)
+		case "query":
+			if parameter["style"] == "deepObject" {
+				value, present = deepObject(query, name)
+				break
+			}
+			var values []string
+			values, present = query[name]
+			if present {
+				value = o.doc.coerce(schema, values[0])
+			}
+		case "header":
+			s := r.Header.Get(name)
+			present = s != ""
+			value = o.doc.coerce(schema, s)
+		default:
+			continue
+		}
+
+		if !present {
+			if required, _ := parameter["required"].(bool); required {
+				errs = append(errs, FieldError{Field: name, In: in, Msg: name + " is required"})
+			}
+			continue
+		}
+		errs = append(errs, o.doc.validate(schema, value, name, in)...)
+	}
+	if len(errs) > 0 {
+		return &RequestError{Status: http.StatusBadRequest, Errors: errs}
+	}
+	return o.validateBody(r)
+}
+
+// validateBody checks the body of a request against the request body of the
+// operation
+func (o *Operation) validateBody(r *http.Request) error {
+	requestBody := o.doc.resolve(o.node["requestBody"])
+	if requestBody == nil {
+		return nil
+	}
+	content, _ := requestBody["content"].(map[string]interface{})
+	required, _ := requestBody["required"].(bool)
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		if required {
+			return bodyError(http.StatusBadRequest, "", "request body is required")
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	media, ok := mediaFor(content, mediaType)
+	if !ok {
+		types := make([]string, 0, len(content))
+		for contentType := range content {
+			types = append(types, contentType)
+		}
+		sort.Strings(types)
+		return bodyError(http.StatusUnsupportedMediaType, "", "content type must be "+orList(types))
+	}
+	if !isJSON(mediaType) {
+		return nil
+	}
+
+	if r.ContentLength > MaxBodySize {
+		return bodyError(http.StatusRequestEntityTooLarge, "", "request body is too large")
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, MaxBodySize+1))
+	r.Body.Close()
+	if err != nil {
+		return bodyError(http.StatusBadRequest, "", "could not read request body: "+err.Error())
+	}
+	if len(body) > MaxBodySize {
+		return bodyError(http.StatusRequestEntityTooLarge, "", "request body is too large")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		if required {
+			return bodyError(http.StatusBadRequest, "", "request body is required")
+		}
+		return nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return bodyError(http.StatusBadRequest, "", "invalid JSON: "+err.Error())
+	}
+	if errs := o.doc.validate(o.doc.resolve(media["schema"]), value, "", "body"); len(errs) > 0 {
+		return &RequestError{Status: http.StatusUnprocessableEntity, Errors: errs}
+	}
+	return nil
+}
+
+// ValidateResponse checks a response against the responses of the
+// operation: its status must be documented, and a JSON body must match the
+// schema of its media type
+func (o *Operation) ValidateResponse(status int, header http.Header, body []byte) error {
+	responses, _ := o.node["responses"].(map[string]interface{})
+	node, ok := responses[strconv.Itoa(status)]
+	if !ok {
+		node, ok = responses[strconv.Itoa(status/100)+"XX"]
+	}
+	if !ok {
+		node, ok = responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("status %d is not documented", status)
+	}
+
+	content, _ := o.doc.resolve(node)["content"].(map[string]interface{})
+	if len(content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("status %d is documented without a body, but has one", status)
+		}
+		return nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	media, ok := mediaFor(content, mediaType)
+	if !ok {
+		return fmt.Errorf("content type %q is not documented for status %d", mediaType, status)
+	}
+	if !isJSON(mediaType) {
+		return nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if errs := o.doc.validate(o.doc.resolve(media["schema"]), value, "", "response"); len(errs) > 0 {
+		msgs := make([]string, len(errs))
+		for i, err := range errs {
+			msgs[i] = err.Msg
+		}
+		return fmt.Errorf("status %d: %s", status, strings.Join(msgs, "; "))
+	}
+	return nil
+}
+
+// mediaFor returns the media type object of a content map for a media type,
+// which may be listed as itself or under a range like image/* or */*
+func mediaFor(content map[string]interface{}, mediaType string) (map[string]interface{}, bool) {
+	keys := []string{mediaType}
+	if slash := strings.IndexByte(mediaType, '/'); slash > 0 {
+		keys = append(keys, mediaType[:slash]+"/*")
+	}
+	for _, key := range append(keys, "*/*") {
+		if media, ok := content[key].(map[string]interface{}); ok {
+			return media, true
+		}
+	}
+	return nil, false
+}
+
+// isJSON reports whether a media type, without parameters, is JSON:
+// application/json or a type with a +json suffix
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// decodeJSON decodes a JSON document, keeping numbers as json.Number so
+// integers can be told apart
+func decodeJSON(body []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+// deepObject collects the query parameters name[key]=value of a deepObject
+// parameter into an object
+func deepObject(query map[string][]string, name string) (map[string]interface{}, bool) {
+	object := make(map[string]interface{})
+	for key, values := range query {
+		if strings.HasPrefix(key, name+"[") && strings.HasSuffix(key, "]") {
+			object[key[len(name)+1:len(key)-1]] = values[0]
+		}
+	}
+	return object, len(object) > 0
+}
+
+// bodyError returns a RequestError about the body
+func bodyError(status int, field, msg string) *RequestError {
+	return &RequestError{Status: status, Errors: []FieldError{{Field: field, In: "body", Msg: msg}}}
+}
+
+// orList joins values like "a, b or c"
+func orList(values []string) string {
+	if len(values) < 2 {
+		return strings.Join(values, "")
+	}
+	return strings.Join(values[:len(values)-1], ", ") + " or " + values[len(values)-1]
+}
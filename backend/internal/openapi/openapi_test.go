@@ -0,0 +1,313 @@
+package openapi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+const testSpec = `
+openapi: 3.1.0
+paths:
+  /todos:
+    get:
+      parameters:
+        - name: completed
+          in: query
+          schema:
+            type: boolean
+        - name: priority
+          in: query
+          schema:
+            $ref: "#/components/schemas/Priority"
+        - name: map
+          in: query
+          style: deepObject
+          schema:
+            type: object
+            additionalProperties:
+              type: string
+      responses:
+        "200":
+          description: Todos
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: "#/components/schemas/Todo"
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/TodoInput"
+      responses:
+        "201":
+          description: Created
+          content:
+            application/json:
+              schema:
+                $ref: "#/components/schemas/Todo"
+  /todos/{id}:
+    parameters:
+      - name: id
+        in: path
+        required: true
+        schema:
+          type: integer
+          minimum: 1
+    delete:
+      parameters:
+        - name: If-Match
+          in: header
+          schema:
+            type: string
+            pattern: '^"\d+"$'
+      responses:
+        "204":
+          description: Deleted
+    patch:
+      requestBody:
+        content:
+          application/merge-patch+json:
+            schema:
+              $ref: "#/components/schemas/TodoInput"
+          multipart/form-data:
+            schema:
+              type: object
+      responses:
+        "200":
+          description: Changed
+          content:
+            "*/*":
+              schema:
+                type: string
+components:
+  schemas:
+    Priority:
+      type: string
+      enum: [low, medium, high]
+    Todo:
+      type: object
+      required: [id, title, due_date]
+      properties:
+        id:
+          type: integer
+        title:
+          type: string
+        due_date:
+          type: [string, "null"]
+          format: date-time
+    TodoInput:
+      type: object
+      required: [title]
+      properties:
+        title:
+          type: string
+          minLength: 1
+          maxLength: 10
+        priority:
+          $ref: "#/components/schemas/Priority"
+        tag_ids:
+          type: array
+          maxItems: 3
+          items:
+            type: integer
+        owner:
+          oneOf:
+            - type: integer
+            - type: object
+              required: [email]
+              properties:
+                email:
+                  type: string
+                  format: email
+`
+
+func testDocument(t *testing.T) *Document {
+	var root map[string]interface{}
+	if err := yaml.Unmarshal([]byte(testSpec), &root); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := New(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// requestErrors validates a request and returns the status and the messages
+// of its errors, or 0 and none when it is valid
+func requestErrors(t *testing.T, doc *Document, method, path, target, contentType, body string, params map[string]string) (int, []string) {
+	operation := doc.Operation(method, path)
+	if !assert.NotNil(t, operation, "%s %s", method, path) {
+		return 0, nil
+	}
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	err := operation.ValidateRequest(req, params)
+	if err == nil {
+		return 0, nil
+	}
+	requestErr := err.(*RequestError)
+	var msgs []string
+	for _, fieldErr := range requestErr.Errors {
+		msgs = append(msgs, fieldErr.Msg)
+	}
+	return requestErr.Status, msgs
+}
+
+func TestDocument_Operation(t *testing.T) {
+	doc := testDocument(t)
+
+	assert.NotNil(t, doc.Operation("GET", "/todos"))
+	assert.NotNil(t, doc.Operation("delete", "/todos/{id}"))
+	assert.Nil(t, doc.Operation("PUT", "/todos/{id}"))
+	assert.Nil(t, doc.Operation("GET", "/tags"))
+}
+
+func TestValidateRequest_Parameters(t *testing.T) {
+	doc := testDocument(t)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		target string
+		params map[string]string
+		status int
+		errors []string
+	}{
+		{name: "valid query", method: "GET", path: "/todos", target: "/todos?completed=true&priority=high"},
+		{name: "bool like strconv", method: "GET", path: "/todos", target: "/todos?completed=1"},
+		{name: "bad bool", method: "GET", path: "/todos", target: "/todos?completed=maybe", status: 400, errors: []string{"completed must be a boolean"}},
+		{name: "bad enum by ref", method: "GET", path: "/todos", target: "/todos?priority=urgent", status: 400, errors: []string{"priority must be one of low, medium, high"}},
+		{name: "every error", method: "GET", path: "/todos", target: "/todos?completed=x&priority=y", status: 400, errors: []string{"completed must be a boolean", "priority must be one of low, medium, high"}},
+		{name: "deep object", method: "GET", path: "/todos", target: "/todos?map[title]=Name"},
+		{name: "valid path", method: "DELETE", path: "/todos/{id}", target: "/todos/3", params: map[string]string{"id": "3"}},
+		{name: "path not integer", method: "DELETE", path: "/todos/{id}", target: "/todos/abc", params: map[string]string{"id": "abc"}, status: 400, errors: []string{"id must be an integer"}},
+		{name: "path below minimum", method: "DELETE", path: "/todos/{id}", target: "/todos/0", params: map[string]string{"id": "0"}, status: 400, errors: []string{"id must be at least 1"}},
+		{name: "missing path", method: "DELETE", path: "/todos/{id}", target: "/todos/", status: 400, errors: []string{"id is required"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, errors := requestErrors(t, doc, tt.method, tt.path, tt.target, "", "", tt.params)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.errors, errors)
+		})
+	}
+
+	t.Run("header", func(t *testing.T) {
+		operation := doc.Operation("DELETE", "/todos/{id}")
+		req := httptest.NewRequest("DELETE", "/todos/1", nil)
+		req.Header.Set("If-Match", `"4"`)
+		assert.NoError(t, operation.ValidateRequest(req, map[string]string{"id": "1"}))
+
+		req.Header.Set("If-Match", "4")
+		err := operation.ValidateRequest(req, map[string]string{"id": "1"})
+		if assert.Error(t, err) {
+			assert.Equal(t, "header", err.(*RequestError).Errors[0].In)
+		}
+	})
+}
+
+func TestValidateRequest_Body(t *testing.T) {
+	doc := testDocument(t)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		errors      []string
+	}{
+		{name: "valid", contentType: "application/json", body: `{"title": "Buy milk", "priority": "high", "tag_ids": [1, 2]}`},
+		{name: "content type parameters", contentType: "application/json; charset=utf-8", body: `{"title": "Buy milk"}`},
+		{name: "unknown fields allowed", contentType: "application/json", body: `{"title": "Buy milk", "id": 4}`},
+		{name: "missing", contentType: "application/json", status: 400, errors: []string{"request body is required"}},
+		{name: "blank", contentType: "application/json", body: "  ", status: 400, errors: []string{"request body is required"}},
+		{name: "malformed", contentType: "application/json", body: `{"title":`, status: 400, errors: []string{"invalid JSON: unexpected EOF"}},
+		{name: "trailing data", contentType: "application/json", body: `{} {}`, status: 400, errors: []string{"invalid JSON: unexpected data after the JSON value"}},
+		{name: "media type", contentType: "text/plain", body: `{"title": "Buy milk"}`, status: 415, errors: []string{"content type must be application/json"}},
+		{name: "not an object", contentType: "application/json", body: `[]`, status: 422, errors: []string{"request body must be an object"}},
+		{name: "required", contentType: "application/json", body: `{}`, status: 422, errors: []string{"title is required"}},
+		{name: "empty string", contentType: "application/json", body: `{"title": ""}`, status: 422, errors: []string{"title must not be empty"}},
+		{name: "too long", contentType: "application/json", body: `{"title": "ééééééééééé"}`, status: 422, errors: []string{"title must be at most 10 characters"}},
+		{name: "enum", contentType: "application/json", body: `{"title": "a", "priority": "urgent"}`, status: 422, errors: []string{"priority must be one of low, medium, high"}},
+		{name: "array items", contentType: "application/json", body: `{"title": "a", "tag_ids": [1, 2.5, "3"]}`, status: 422, errors: []string{"tag_ids[1] must be an integer", "tag_ids[2] must be an integer"}},
+		{name: "too many items", contentType: "application/json", body: `{"title": "a", "tag_ids": [1, 2, 3, 4]}`, status: 422, errors: []string{"tag_ids must have at most 3 items"}},
+		{name: "one of", contentType: "application/json", body: `{"title": "a", "owner": {"email": "a@example.com"}}`},
+		{name: "none of", contentType: "application/json", body: `{"title": "a", "owner": {"email": "nope"}}`, status: 422, errors: []string{"owner does not match any of the allowed forms"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, errors := requestErrors(t, doc, "POST", "/todos", "/todos", tt.contentType, tt.body, nil)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.errors, errors)
+		})
+	}
+
+	t.Run("body is put back", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/todos", strings.NewReader(`{"title": "Buy milk"}`))
+		req.Header.Set("Content-Type", "application/json")
+		assert.NoError(t, doc.Operation("POST", "/todos").ValidateRequest(req, nil))
+		body, _ := io.ReadAll(req.Body)
+		assert.Equal(t, `{"title": "Buy milk"}`, string(body))
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		large := `{"title": "` + strings.Repeat("a", MaxBodySize) + `"}`
+		status, errors := requestErrors(t, doc, "POST", "/todos", "/todos", "application/json", large, nil)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+		assert.Equal(t, []string{"request body is too large"}, errors)
+
+		req := httptest.NewRequest("POST", "/todos", strings.NewReader(large))
+		req.Header.Set("Content-Type", "application/json")
+		req.ContentLength = -1 // Sent in chunks, so its length is not known up front
+		err := doc.Operation("POST", "/todos").ValidateRequest(req, nil)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, err.(*RequestError).Status)
+	})
+
+	t.Run("optional body", func(t *testing.T) {
+		status, _ := requestErrors(t, doc, "PATCH", "/todos/{id}", "/todos/1", "", "", map[string]string{"id": "1"})
+		assert.Zero(t, status)
+	})
+
+	t.Run("other media types are not read", func(t *testing.T) {
+		status, _ := requestErrors(t, doc, "PATCH", "/todos/{id}", "/todos/1", "multipart/form-data; boundary=x", "--x--", map[string]string{"id": "1"})
+		assert.Zero(t, status)
+	})
+}
+
+func TestValidateResponse(t *testing.T) {
+	doc := testDocument(t)
+	jsonHeader := http.Header{"Content-Type": {"application/json; charset=utf-8"}}
+
+	list := doc.Operation("GET", "/todos")
+	assert.NoError(t, list.ValidateResponse(200, jsonHeader, []byte(`[{"id": 1, "title": "a", "due_date": null}]`)))
+	assert.NoError(t, list.ValidateResponse(200, jsonHeader, []byte(`[{"id": 1, "title": "a", "due_date": "2026-11-01T09:00:00.5+07:00"}]`)))
+	assert.EqualError(t, list.ValidateResponse(200, jsonHeader, []byte(`[{"id": 1, "due_date": "tomorrow"}]`)),
+		"status 200: [0].title is required; [0].due_date must be a date and time like 2006-01-02T15:04:05Z")
+	assert.EqualError(t, list.ValidateResponse(500, jsonHeader, []byte(`{}`)), "status 500 is not documented")
+	assert.EqualError(t, list.ValidateResponse(200, http.Header{"Content-Type": {"text/plain"}}, []byte("a")), `content type "text/plain" is not documented for status 200`)
+
+	change := doc.Operation("PATCH", "/todos/{id}")
+	assert.NoError(t, change.ValidateResponse(200, http.Header{"Content-Type": {"image/png"}}, []byte("png")))
+
+	remove := doc.Operation("DELETE", "/todos/{id}")
+	assert.NoError(t, remove.ValidateResponse(204, http.Header{}, nil))
+	assert.Error(t, remove.ValidateResponse(204, jsonHeader, []byte("null")))
+}
@@ -0,0 +1,342 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// typeNames describe the JSON Schema types in messages
+var typeNames = map[string]string{
+	"null":    "null",
+	"boolean": "a boolean",
+	"object":  "an object",
+	"array":   "an array",
+	"number":  "a number",
+	"integer": "an integer",
+	"string":  "a string",
+}
+
+// validate checks a decoded JSON value against a schema and returns the
+// errors, if any. It understands the keywords the API's document uses:
+// $ref, type, enum, const, properties, required, additionalProperties,
+// items, the length, size and range limits, pattern, format and the
+// oneOf/anyOf/allOf combinations. Others, like description, are ignored.
+func (d *Document) validate(schema map[string]interface{}, value interface{}, field, in string) []FieldError {
+	if schema == nil {
+		return nil
+	}
+	fail := func(format string, args ...interface{}) []FieldError {
+		return []FieldError{{Field: field, In: in, Msg: describe(field, in) + " " + fmt.Sprintf(format, args...)}}
+	}
+
+	var errs []FieldError
+	if ref, ok := schema["$ref"].(string); ok {
+		errs = append(errs, d.validate(d.resolve(map[string]interface{}{"$ref": ref}), value, field, in)...)
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			matched = matched || hasType(value, t)
+		}
+		if !matched {
+			names := make([]string, len(types))
+			for i, t := range types {
+				names[i] = typeNames[t]
+			}
+			return append(errs, fail("must be %s", orList(names))...)
+		}
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && !contains(enum, value) {
+		names := make([]string, len(enum))
+		for i, option := range enum {
+			names[i] = fmt.Sprint(option)
+		}
+		return append(errs, fail("must be one of %s", strings.Join(names, ", "))...)
+	}
+	if constant, ok := schema["const"]; ok && !equal(constant, value) {
+		return append(errs, fail("must be %v", constant)...)
+	}
+
+	switch value := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(value)
+		if limit, ok := number(schema["minLength"]); ok && float64(length) < limit {
+			if limit == 1 {
+				errs = append(errs, fail("must not be empty")...)
+			} else {
+				errs = append(errs, fail("must be at least %v characters", limit)...)
+			}
+		}
+		if limit, ok := number(schema["maxLength"]); ok && float64(length) > limit {
+			errs = append(errs, fail("must be at most %v characters", limit)...)
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !d.match(pattern, value) {
+			errs = append(errs, fail("must match %s", pattern)...)
+		}
+		if format, ok := schema["format"].(string); ok {
+			if msg := checkFormat(format, value); msg != "" {
+				errs = append(errs, fail("%s", msg)...)
+			}
+		}
+	case json.Number:
+		n, _ := value.Float64()
+		if limit, ok := number(schema["minimum"]); ok && n < limit {
+			errs = append(errs, fail("must be at least %v", limit)...)
+		}
+		if limit, ok := number(schema["maximum"]); ok && n > limit {
+			errs = append(errs, fail("must be at most %v", limit)...)
+		}
+	case map[string]interface{}:
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			name, _ := name.(string)
+			if _, ok := value[name]; !ok {
+				child := join(field, name)
+				errs = append(errs, FieldError{Field: child, In: in, Msg: describe(child, in) + " is required"})
+			}
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, name := range sortedKeys(value) {
+			if property, ok := properties[name]; ok {
+				errs = append(errs, d.validate(d.resolve(property), value[name], join(field, name), in)...)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					child := join(field, name)
+					errs = append(errs, FieldError{Field: child, In: in, Msg: describe(child, in) + " is not allowed"})
+				}
+			case map[string]interface{}:
+				errs = append(errs, d.validate(d.resolve(additional), value[name], join(field, name), in)...)
+			}
+		}
+	case []interface{}:
+		if limit, ok := number(schema["minItems"]); ok && float64(len(value)) < limit {
+			if limit == 1 {
+				errs = append(errs, fail("must not be empty")...)
+			} else {
+				errs = append(errs, fail("must have at least %v items", limit)...)
+			}
+		}
+		if limit, ok := number(schema["maxItems"]); ok && float64(len(value)) > limit {
+			errs = append(errs, fail("must have at most %v items", limit)...)
+		}
+		if items := d.resolve(schema["items"]); items != nil {
+			for i, item := range value {
+				errs = append(errs, d.validate(items, item, fmt.Sprintf("%s[%d]", field, i), in)...)
+			}
+		}
+	}
+
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			errs = append(errs, d.validate(d.resolve(sub), value, field, in)...)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok && d.matches(anyOf, value, field, in) == 0 {
+		errs = append(errs, fail("does not match any of the allowed forms")...)
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		switch d.matches(oneOf, value, field, in) {
+		case 0:
+			errs = append(errs, fail("does not match any of the allowed forms")...)
+		case 1:
+		default:
+			errs = append(errs, fail("matches more than one of the allowed forms")...)
+		}
+	}
+	return errs
+}
+
+// matches returns how many of a list of schemas a value matches
+func (d *Document) matches(schemas []interface{}, value interface{}, field, in string) int {
+	count := 0
+	for _, sub := range schemas {
+		if len(d.validate(d.resolve(sub), value, field, in)) == 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// match reports whether a string matches a pattern; a pattern that does not
+// compile matches everything
+func (d *Document) match(pattern, s string) bool {
+	compiled, ok := d.patterns.Load(pattern)
+	if !ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return true
+		}
+		compiled, _ = d.patterns.LoadOrStore(pattern, re)
+	}
+	return compiled.(*regexp.Regexp).MatchString(s)
+}
+
+// coerce converts the string value of a parameter to the type of its
+// schema. A value that does not convert is left a string, for validate to
+// reject.
+func (d *Document) coerce(schema map[string]interface{}, s string) interface{} {
+	if ref, ok := schema["$ref"]; ok {
+		schema = d.resolve(map[string]interface{}{"$ref": ref})
+	}
+	for _, t := range schemaTypes(schema) {
+		switch t {
+		case "integer", "number":
+			if _, err := strconv.ParseFloat(s, 64); err == nil {
+				return json.Number(s)
+			}
+		case "boolean":
+			// Like strconv.ParseBool, which the handlers read them with
+			if b, err := strconv.ParseBool(s); err == nil {
+				return b
+			}
+		}
+	}
+	return s
+}
+
+// checkFormat returns why a string does not have a format, or "" when it
+// does or the format is not checked
+func checkFormat(format, s string) string {
+	switch format {
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return "must be a date and time like 2006-01-02T15:04:05Z"
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", s); err != nil {
+			return "must be a date like 2006-01-02"
+		}
+	case "email":
+		if address, err := mail.ParseAddress(s); err != nil || address.Address != s {
+			return "must be an email address"
+		}
+	case "uri":
+		if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+			return "must be an absolute URI"
+		}
+	}
+	return ""
+}
+
+// schemaTypes returns the types a schema allows; none means any
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, name := range t {
+			if name, ok := name.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// hasType reports whether a decoded JSON value has a JSON Schema type
+func hasType(value interface{}, t string) bool {
+	switch value := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case json.Number:
+		if t == "integer" {
+			n, err := value.Float64()
+			return err == nil && n == math.Trunc(n)
+		}
+		return t == "number"
+	case map[string]interface{}:
+		return t == "object"
+	case []interface{}:
+		return t == "array"
+	}
+	return false
+}
+
+// contains reports whether an enum has a value
+func contains(enum []interface{}, value interface{}) bool {
+	for _, option := range enum {
+		if equal(option, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// equal compares a value of the document, decoded from YAML or JSON, with a
+// decoded JSON value
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b) && fmt.Sprintf("%T", a) == fmt.Sprintf("%T", b)
+}
+
+// number returns a numeric value as a float64
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// describe names a value in a message
+func describe(field, in string) string {
+	switch {
+	case field != "":
+		return field
+	case in == "body":
+		return "request body"
+	case in == "response":
+		return "response body"
+	}
+	return field
+}
+
+// join returns the path of a property of the value at field
+func join(field, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// sortedKeys returns the keys of an object in order, so errors come in the
+// same order every time
+func sortedKeys(object map[string]interface{}) []string {
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,398 @@
+// Package patch applies RFC 7396 JSON Merge Patch and RFC 6902 JSON Patch
+// documents to JSON values.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Error reports a patch that is malformed or cannot be applied
+type Error struct {
+	Index     int    // Index of the failing JSON Patch operation, -1 for a merge patch
+	Path      string // JSON Pointer the failure is about, if any
+	Msg       string
+	Malformed bool // The patch itself is invalid, as opposed to not applying to the document
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	if e.Index >= 0 {
+		fmt.Fprintf(&b, "operation %d: ", e.Index)
+	}
+	if e.Path != "" {
+		fmt.Fprintf(&b, "%s: ", e.Path)
+	}
+	b.WriteString(e.Msg)
+	return b.String()
+}
+
+// MergePatch applies an RFC 7396 merge patch to doc. Members set to null
+// are removed, objects are merged recursively and anything else replaces
+// the target value.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, &Error{Index: -1, Msg: "invalid JSON: " + err.Error(), Malformed: true}
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+		} else {
+			targetObj[key] = merge(targetObj[key], value)
+		}
+	}
+	return targetObj
+}
+
+// operation is a single RFC 6902 operation
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Apply applies an RFC 6902 JSON Patch to doc. The operations are applied
+// in order and the whole patch fails if any of them does.
+func Apply(doc, patch []byte) ([]byte, error) {
+	target, err := decode(doc)
+	if err != nil {
+		return nil, err
+	}
+	var ops []operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, &Error{Index: -1, Msg: "a JSON Patch must be an array of operations", Malformed: true}
+	}
+
+	for i, op := range ops {
+		if target, err = op.apply(target); err != nil {
+			if patchErr, ok := err.(*Error); ok {
+				patchErr.Index = i
+			}
+			return nil, err
+		}
+	}
+	return json.Marshal(target)
+}
+
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	if op.Path == nil {
+		return nil, &Error{Msg: "path is required", Malformed: true}
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, &Error{Path: *op.Path, Msg: "value is required", Malformed: true}
+		}
+		value, err := decode(op.Value)
+		if err != nil {
+			return nil, &Error{Path: *op.Path, Msg: "invalid value", Malformed: true}
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, value)
+		case "replace":
+			if _, err := get(doc, path); err != nil {
+				return nil, err
+			}
+			return update(doc, path, func(parent interface{}, key string) (interface{}, error) {
+				return setChild(parent, key, value, *op.Path)
+			}, value)
+		default:
+			current, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !Equal(current, value) {
+				return nil, &Error{Path: *op.Path, Msg: "test failed"}
+			}
+			return doc, nil
+		}
+	case "remove":
+		if len(path) == 0 {
+			return nil, &Error{Path: *op.Path, Msg: "cannot remove the whole document", Malformed: true}
+		}
+		return remove(doc, path)
+	case "move", "copy":
+		if op.From == nil {
+			return nil, &Error{Path: *op.Path, Msg: "from is required", Malformed: true}
+		}
+		from, err := parsePointer(*op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == "copy" {
+			return add(doc, path, clone(value))
+		}
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, &Error{Path: *op.Path, Msg: "cannot move a value into itself"}
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	}
+	return nil, &Error{Msg: fmt.Sprintf("unknown operation %q", op.Op), Malformed: true}
+}
+
+// add adds value at path, inserting into arrays and setting object members
+func add(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	return update(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[key] = value
+			return p, nil
+		case []interface{}:
+			i := len(p)
+			if key != "-" {
+				var err error
+				if i, err = arrayIndex(key, len(p)+1, path); err != nil {
+					return nil, err
+				}
+			}
+			p = append(p, nil)
+			copy(p[i+1:], p[i:])
+			p[i] = value
+			return p, nil
+		}
+		return nil, &Error{Path: pointer(path), Msg: "parent is not an object or array"}
+	}, value)
+}
+
+// remove removes the value at path
+func remove(doc interface{}, path []string) (interface{}, error) {
+	return update(doc, path, func(parent interface{}, key string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := p[key]; !ok {
+				return nil, &Error{Path: pointer(path), Msg: "path does not exist"}
+			}
+			delete(p, key)
+			return p, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(p), path)
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:i], p[i+1:]...), nil
+		}
+		return nil, &Error{Path: pointer(path), Msg: "path does not exist"}
+	}, nil)
+}
+
+// update walks to the parent of the last path token and replaces it with the
+// result of fn. An empty path replaces the whole document with root.
+func update(doc interface{}, path []string, fn func(parent interface{}, key string) (interface{}, error), root interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return root, nil
+	}
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	child, err := get(doc, path[:1])
+	if err != nil {
+		return nil, &Error{Path: pointer(path), Msg: "path does not exist"}
+	}
+	child, err = update(child, path[1:], func(parent interface{}, key string) (interface{}, error) {
+		result, err := fn(parent, key)
+		if patchErr, ok := err.(*Error); ok {
+			patchErr.Path = pointer(path)
+		}
+		return result, err
+	}, root)
+	if err != nil {
+		return nil, err
+	}
+	return setChild(doc, path[0], child, pointer(path))
+}
+
+// setChild replaces an existing object member or array element
+func setChild(parent interface{}, key string, value interface{}, path string) (interface{}, error) {
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[key] = value
+		return p, nil
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i >= len(p) {
+			return nil, &Error{Path: path, Msg: "path does not exist"}
+		}
+		p[i] = value
+		return p, nil
+	}
+	return nil, &Error{Path: path, Msg: "path does not exist"}
+}
+
+// get returns the value at path
+func get(doc interface{}, path []string) (interface{}, error) {
+	node := doc
+	for i, key := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[key]
+			if !ok {
+				return nil, &Error{Path: pointer(path), Msg: "path does not exist"}
+			}
+			node = child
+		case []interface{}:
+			index, err := arrayIndex(key, len(n), path[:i+1])
+			if err != nil {
+				return nil, err
+			}
+			node = n[index]
+		default:
+			return nil, &Error{Path: pointer(path), Msg: "path does not exist"}
+		}
+	}
+	return node, nil
+}
+
+// arrayIndex parses an array index that must be below limit
+func arrayIndex(key string, limit int, path []string) (int, error) {
+	if key == "" || (len(key) > 1 && key[0] == '0') || strings.TrimLeft(key, "0123456789") != "" {
+		return 0, &Error{Path: pointer(path), Msg: "invalid array index"}
+	}
+	i, err := strconv.Atoi(key)
+	if err != nil || i >= limit {
+		return 0, &Error{Path: pointer(path), Msg: "array index out of range"}
+	}
+	return i, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped tokens
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, &Error{Path: p, Msg: "a path must be empty or start with /", Malformed: true}
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+// pointer formats tokens as a JSON Pointer
+func pointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+	return b.String()
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// decode parses JSON keeping numbers exact
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON value")
+	}
+	return value, nil
+}
+
+func clone(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			c[key] = clone(child)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, child := range v {
+			c[i] = clone(child)
+		}
+		return c
+	}
+	return value
+}
+
+// Equal reports whether two decoded JSON values are equal as RFC 6902
+// defines it for test: numbers compare by value, objects ignore member order.
+func Equal(a, b interface{}) bool {
+	switch x := a.(type) {
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !Equal(value, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !Equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, okx := new(big.Float).SetString(string(x))
+		fy, oky := new(big.Float).SetString(string(y))
+		return okx && oky && fx.Cmp(fy) == 0
+	}
+	return a == b
+}
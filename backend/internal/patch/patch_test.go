@@ -0,0 +1,95 @@
+package patch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergePatch(t *testing.T) {
+	// Examples from RFC 7396 appendix A
+	cases := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"id":12345678901234567890}`, `{"x":1}`, `{"id":12345678901234567890,"x":1}`},
+	}
+	for _, tc := range cases {
+		got, err := MergePatch([]byte(tc.doc), []byte(tc.patch))
+		assert.NoError(t, err, tc.patch)
+		assert.JSONEq(t, tc.want, string(got), tc.patch)
+	}
+
+	_, err := MergePatch([]byte(`{}`), []byte(`{"a":`))
+	var patchErr *Error
+	assert.ErrorAs(t, err, &patchErr)
+	assert.True(t, patchErr.Malformed)
+}
+
+func TestApply(t *testing.T) {
+	// Examples from RFC 6902 appendix A
+	cases := []struct{ doc, patch, want string }{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`, `{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null}]`, `{"foo":null}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"remove","path":"/~1"}]`, `{"~1":10}`},
+		{`{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`, `{"a":{"b":1},"c":{"b":2}}`},
+		{`{"a":1}`, `[{"op":"replace","path":"","value":[1]}]`, `[1]`},
+	}
+	for _, tc := range cases {
+		got, err := Apply([]byte(tc.doc), []byte(tc.patch))
+		assert.NoError(t, err, tc.patch)
+		assert.JSONEq(t, tc.want, string(got), tc.patch)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	cases := []struct {
+		doc, patch string
+		index      int
+		path       string
+		msg        string
+		malformed  bool
+	}{
+		{`{"baz":"qux"}`, `[{"op":"test","path":"/baz","value":"bar"}]`, 0, "/baz", "test failed", false},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz/bat","value":"qux"}]`, 0, "/baz/bat", "path does not exist", false},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/a","value":1},{"op":"remove","path":"/nope"}]`, 1, "/nope", "path does not exist", false},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/2","value":1}]`, 0, "/foo/2", "array index out of range", false},
+		{`{"foo":["bar"]}`, `[{"op":"replace","path":"/foo/01","value":1}]`, 0, "/foo/01", "invalid array index", false},
+		{`{"a":{"b":1}}`, `[{"op":"move","from":"/a","path":"/a/b/c"}]`, 0, "/a/b/c", "cannot move a value into itself", false},
+		{`{}`, `[{"op":"add","path":"/a"}]`, 0, "/a", "value is required", true},
+		{`{}`, `[{"op":"frobnicate","path":"/a"}]`, 0, "", `unknown operation "frobnicate"`, true},
+		{`{}`, `[{"op":"add","path":"a","value":1}]`, 0, "a", "a path must be empty or start with /", true},
+		{`{}`, `{"op":"add"}`, -1, "", "a JSON Patch must be an array of operations", true},
+	}
+	for _, tc := range cases {
+		_, err := Apply([]byte(tc.doc), []byte(tc.patch))
+		var patchErr *Error
+		if assert.ErrorAs(t, err, &patchErr, tc.patch) {
+			assert.Equal(t, tc.index, patchErr.Index, tc.patch)
+			assert.Equal(t, tc.path, patchErr.Path, tc.patch)
+			assert.Equal(t, tc.msg, patchErr.Msg, tc.patch)
+			assert.Equal(t, tc.malformed, patchErr.Malformed, tc.patch)
+		}
+	}
+}
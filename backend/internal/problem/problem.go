@@ -0,0 +1,31 @@
+// Package problem writes error responses as RFC 7807 problem details.
+package problem
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of a problem details response
+const ContentType = "application/problem+json"
+
+// Respond aborts a request with a problem details response. detail says what
+// went wrong in this occurrence; it is sent as error too, which clients read
+// since before responses were problem details. members are added to the
+// body, like the field at fault.
+func Respond(c *gin.Context, status int, detail string, members gin.H) {
+	body := gin.H{
+		"type":     "about:blank",
+		"title":    http.StatusText(status),
+		"status":   status,
+		"detail":   detail,
+		"instance": c.Request.URL.Path,
+		"error":    detail,
+	}
+	for name, value := range members {
+		body[name] = value
+	}
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(status, body)
+}
@@ -0,0 +1,36 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/todos/:id", func(c *gin.Context) {
+		Respond(c, http.StatusUnprocessableEntity, "title is required", gin.H{"field": "title"})
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/todos/7?x=1", nil))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, map[string]interface{}{
+		"type":     "about:blank",
+		"title":    "Unprocessable Entity",
+		"status":   float64(422),
+		"detail":   "title is required",
+		"instance": "/todos/7",
+		"error":    "title is required",
+		"field":    "title",
+	}, body)
+}
@@ -0,0 +1,376 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules used by
+// recurring todos: FREQ, INTERVAL, BYDAY, UNTIL and COUNT.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is the base unit a rule repeats in
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// maxPeriods bounds how many periods are scanned for a single occurrence so a
+// rule that can never match (e.g. the 5th Monday every 12 months) terminates
+const maxPeriods = 5000
+
+// untilLayouts are the accepted UNTIL formats: UTC date-time, floating date-time and date
+var untilLayouts = []string{"20060102T150405Z", "20060102T150405", "20060102"}
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+// WeekdayNum is a BYDAY entry such as MO, 2TU or -1FR. N is zero when the
+// weekday applies to every matching day in the period.
+type WeekdayNum struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Rule is a parsed recurrence rule
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	ByDay    []WeekdayNum
+	Until    *time.Time
+	Count    int
+}
+
+// Parse parses an RRULE value like "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE".
+// A leading "RRULE:" is accepted.
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("recurrence rule is empty")
+	}
+
+	rule := &Rule{Interval: 1}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		name, val, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		val = strings.ToUpper(strings.TrimSpace(val))
+		if !ok || val == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%s is given more than once", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "FREQ":
+			switch Frequency(val) {
+			case Daily, Weekly, Monthly, Yearly:
+				rule.Freq = Frequency(val)
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("INTERVAL must be a positive integer")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive integer")
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(val)
+			if err != nil {
+				return nil, err
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, code := range strings.Split(val, ",") {
+				day, err := parseWeekdayNum(code)
+				if err != nil {
+					return nil, err
+				}
+				rule.ByDay = append(rule.ByDay, day)
+			}
+		case "WKST":
+			if val != "MO" {
+				return nil, errors.New("only WKST=MO is supported")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", name)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rule.Until != nil && rule.Count != 0 {
+		return nil, errors.New("UNTIL and COUNT cannot be combined")
+	}
+	for _, day := range rule.ByDay {
+		if day.N != 0 && rule.Freq != Monthly && rule.Freq != Yearly {
+			return nil, errors.New("numbered BYDAY is only allowed with MONTHLY or YEARLY")
+		}
+	}
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range untilLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// A date-only UNTIL includes the whole day
+				t = t.Add(24*time.Hour - time.Second)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", value)
+}
+
+func parseWeekdayNum(code string) (WeekdayNum, error) {
+	code = strings.TrimSpace(code)
+	if len(code) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", code)
+	}
+	weekday, ok := weekdayCodes[code[len(code)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", code)
+	}
+	day := WeekdayNum{Weekday: weekday}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n > 53 || n < -53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", code)
+		}
+		day.N = n
+	}
+	return day, nil
+}
+
+// String returns the canonical RRULE value of the rule
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = day.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayouts[0]))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	return strings.Join(parts, ";")
+}
+
+// String returns the BYDAY code, e.g. "MO" or "-1FR"
+func (d WeekdayNum) String() string {
+	code := strings.ToUpper(d.Weekday.String()[:2])
+	if d.N != 0 {
+		return strconv.Itoa(d.N) + code
+	}
+	return code
+}
+
+// Occurrences returns up to n occurrences of the rule starting at dtstart.
+// As in RFC 5545, dtstart is the first occurrence and counts toward COUNT.
+func (r *Rule) Occurrences(dtstart time.Time, n int) []time.Time {
+	var occurrences []time.Time
+	if n < 1 {
+		return occurrences
+	}
+	r.iterate(dtstart, func(t time.Time) bool {
+		occurrences = append(occurrences, t)
+		return len(occurrences) < n
+	})
+	return occurrences
+}
+
+// Next returns the first occurrence of the series starting at dtstart that is
+// strictly after the given time, or false when the series has ended
+func (r *Rule) Next(dtstart, after time.Time) (time.Time, bool) {
+	var next time.Time
+	found := false
+	r.iterate(dtstart, func(t time.Time) bool {
+		if t.After(after) {
+			next, found = t, true
+			return false
+		}
+		return true
+	})
+	return next, found
+}
+
+// Advance returns the occurrence following dtstart together with the rule that
+// describes the rest of the series from there, so a recurring todo can hand its
+// schedule on to the next instance. It returns false when the series has ended.
+func (r *Rule) Advance(dtstart time.Time) (time.Time, *Rule, bool) {
+	if r.Count == 1 {
+		return time.Time{}, nil, false
+	}
+	next, ok := r.Next(dtstart, dtstart)
+	if !ok {
+		return time.Time{}, nil, false
+	}
+	rest := *r
+	if rest.Count > 0 {
+		rest.Count--
+	}
+	return next, &rest, true
+}
+
+// iterate calls yield for each occurrence in order until yield returns false
+// or the series ends
+func (r *Rule) iterate(dtstart time.Time, yield func(time.Time) bool) {
+	if r.Until != nil && dtstart.After(*r.Until) {
+		return
+	}
+	// DTSTART is always the first occurrence, even when the rule would not produce it
+	emitted := 1
+	if !yield(dtstart) || r.Count == 1 {
+		return
+	}
+
+	misses := 0
+	for period := 0; misses < maxPeriods; period++ {
+		candidates := r.candidates(dtstart, period*r.Interval)
+		if len(candidates) == 0 {
+			misses++
+			continue
+		}
+		misses = 0
+		for _, t := range candidates {
+			if !t.After(dtstart) {
+				continue
+			}
+			if r.Until != nil && t.After(*r.Until) {
+				return
+			}
+			emitted++
+			if !yield(t) || (r.Count > 0 && emitted >= r.Count) {
+				return
+			}
+		}
+	}
+}
+
+// candidates returns the sorted occurrences in the period that lies offset
+// frequency units after the period containing dtstart
+func (r *Rule) candidates(dtstart time.Time, offset int) []time.Time {
+	y, m, d := dtstart.Date()
+	hh, mm, ss := dtstart.Clock()
+	loc := dtstart.Location()
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, hh, mm, ss, 0, loc)
+	}
+
+	var days []time.Time
+	switch r.Freq {
+	case Daily:
+		day := at(y, m, d+offset)
+		if len(r.ByDay) == 0 || r.matchesWeekday(day.Weekday()) {
+			days = append(days, day)
+		}
+	case Weekly:
+		// Weeks start on Monday (WKST=MO)
+		weekStart := at(y, m, d-(int(dtstart.Weekday())+6)%7+7*offset)
+		if len(r.ByDay) == 0 {
+			days = append(days, at(y, m, d+7*offset))
+			break
+		}
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if r.matchesWeekday(day.Weekday()) {
+				days = append(days, day)
+			}
+		}
+	case Monthly:
+		first := at(y, m+time.Month(offset), 1)
+		if len(r.ByDay) == 0 {
+			// Months without the start day (e.g. the 31st) are skipped
+			if day := at(first.Year(), first.Month(), d); day.Day() == d {
+				days = append(days, day)
+			}
+			break
+		}
+		days = r.weekdaysIn(first, first.AddDate(0, 1, 0))
+	case Yearly:
+		if len(r.ByDay) == 0 {
+			// Non-leap years are skipped for a February 29th start
+			if day := at(y+offset, m, d); day.Day() == d {
+				days = append(days, day)
+			}
+			break
+		}
+		first := at(y+offset, time.January, 1)
+		days = r.weekdaysIn(first, first.AddDate(1, 0, 0))
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return dedupe(days)
+}
+
+// matchesWeekday reports whether the weekday is listed in BYDAY
+func (r *Rule) matchesWeekday(weekday time.Weekday) bool {
+	for _, day := range r.ByDay {
+		if day.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// weekdaysIn expands BYDAY within [start, end), honoring ordinals like 2TU or -1FR
+func (r *Rule) weekdaysIn(start, end time.Time) []time.Time {
+	var days []time.Time
+	for _, byDay := range r.ByDay {
+		var matches []time.Time
+		for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+			if day.Weekday() == byDay.Weekday {
+				matches = append(matches, day)
+			}
+		}
+		switch {
+		case byDay.N == 0:
+			days = append(days, matches...)
+		case byDay.N > 0 && byDay.N <= len(matches):
+			days = append(days, matches[byDay.N-1])
+		case byDay.N < 0 && -byDay.N <= len(matches):
+			days = append(days, matches[len(matches)+byDay.N])
+		}
+	}
+	return days
+}
+
+func dedupe(days []time.Time) []time.Time {
+	if len(days) < 2 {
+		return days
+	}
+	out := days[:1]
+	for _, day := range days[1:] {
+		if !day.Equal(out[len(out)-1]) {
+			out = append(out, day)
+		}
+	}
+	return out
+}
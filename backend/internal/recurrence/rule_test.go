@@ -0,0 +1,170 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 9, 0, 0, 0, time.UTC)
+}
+
+func TestParse(t *testing.T) {
+	t.Run("full rule", func(t *testing.T) {
+		rule, err := Parse("RRULE:FREQ=weekly;INTERVAL=2;BYDAY=MO,WE;UNTIL=20261231T000000Z")
+
+		assert.NoError(t, err)
+		assert.Equal(t, Weekly, rule.Freq)
+		assert.Equal(t, 2, rule.Interval)
+		assert.Equal(t, []WeekdayNum{{Weekday: time.Monday}, {Weekday: time.Wednesday}}, rule.ByDay)
+		assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20261231T000000Z", rule.String())
+	})
+
+	t.Run("numbered weekday", func(t *testing.T) {
+		rule, err := Parse("FREQ=MONTHLY;BYDAY=-1FR;COUNT=3")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []WeekdayNum{{N: -1, Weekday: time.Friday}}, rule.ByDay)
+		assert.Equal(t, "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3", rule.String())
+	})
+
+	errorCases := map[string]string{
+		"":                                  "empty",
+		"INTERVAL=2":                        "FREQ is required",
+		"FREQ=HOURLY":                       "unsupported FREQ",
+		"FREQ=DAILY;INTERVAL=0":             "INTERVAL must be a positive integer",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101": "cannot be combined",
+		"FREQ=WEEKLY;BYDAY=2MO":             "only allowed with MONTHLY or YEARLY",
+		"FREQ=DAILY;BYDAY=XX":               "invalid BYDAY",
+		"FREQ=DAILY;BYHOUR=9":               "unsupported rule part",
+		"FREQ=DAILY;FREQ=WEEKLY":            "more than once",
+	}
+	for value, message := range errorCases {
+		t.Run("error "+value, func(t *testing.T) {
+			_, err := Parse(value)
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), message)
+		})
+	}
+}
+
+func TestRule_Occurrences(t *testing.T) {
+	cases := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "daily with interval",
+			rule:    "FREQ=DAILY;INTERVAL=3",
+			dtstart: date(2026, 1, 30),
+			want:    []time.Time{date(2026, 1, 30), date(2026, 2, 2), date(2026, 2, 5)},
+		},
+		{
+			name:    "daily limited to weekdays",
+			rule:    "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			dtstart: date(2026, 10, 16), // Friday
+			want:    []time.Time{date(2026, 10, 16), date(2026, 10, 19), date(2026, 10, 20)},
+		},
+		{
+			name:    "every other week on monday and wednesday",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE",
+			dtstart: date(2026, 10, 19), // Monday
+			want:    []time.Time{date(2026, 10, 19), date(2026, 10, 21), date(2026, 11, 2), date(2026, 11, 4)},
+		},
+		{
+			name:    "weekly skips days before dtstart",
+			rule:    "FREQ=WEEKLY;BYDAY=MO,FR",
+			dtstart: date(2026, 10, 21), // Wednesday
+			want:    []time.Time{date(2026, 10, 21), date(2026, 10, 23), date(2026, 10, 26)},
+		},
+		{
+			name:    "monthly on the 31st skips short months",
+			rule:    "FREQ=MONTHLY",
+			dtstart: date(2026, 1, 31),
+			want:    []time.Time{date(2026, 1, 31), date(2026, 3, 31), date(2026, 5, 31)},
+		},
+		{
+			name:    "monthly on the last friday",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR",
+			dtstart: date(2026, 10, 1),
+			want:    []time.Time{date(2026, 10, 1), date(2026, 10, 30), date(2026, 11, 27)},
+		},
+		{
+			name:    "yearly on leap day",
+			rule:    "FREQ=YEARLY",
+			dtstart: date(2024, 2, 29),
+			want:    []time.Time{date(2024, 2, 29), date(2028, 2, 29)},
+		},
+		{
+			name:    "dtstart counts even when out of sync",
+			rule:    "FREQ=WEEKLY;BYDAY=FR;COUNT=3",
+			dtstart: date(2026, 10, 21), // Wednesday
+			want:    []time.Time{date(2026, 10, 21), date(2026, 10, 23), date(2026, 10, 30)},
+		},
+		{
+			name:    "count limits the series",
+			rule:    "FREQ=DAILY;COUNT=2",
+			dtstart: date(2026, 1, 1),
+			want:    []time.Time{date(2026, 1, 1), date(2026, 1, 2)},
+		},
+		{
+			name:    "until is inclusive",
+			rule:    "FREQ=WEEKLY;UNTIL=20260115",
+			dtstart: date(2026, 1, 1),
+			want:    []time.Time{date(2026, 1, 1), date(2026, 1, 8), date(2026, 1, 15)},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := Parse(tc.rule)
+			assert.NoError(t, err)
+
+			got := rule.Occurrences(tc.dtstart, len(tc.want))
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestRule_Advance(t *testing.T) {
+	t.Run("hands on the remaining count", func(t *testing.T) {
+		rule, _ := Parse("FREQ=WEEKLY;COUNT=3")
+
+		next, rest, ok := rule.Advance(date(2026, 1, 1))
+
+		assert.True(t, ok)
+		assert.Equal(t, date(2026, 1, 8), next)
+		assert.Equal(t, 2, rest.Count)
+		assert.Equal(t, 3, rule.Count)
+	})
+
+	t.Run("last occurrence ends the series", func(t *testing.T) {
+		rule, _ := Parse("FREQ=WEEKLY;COUNT=1")
+
+		_, _, ok := rule.Advance(date(2026, 1, 1))
+
+		assert.False(t, ok)
+	})
+
+	t.Run("until ends the series", func(t *testing.T) {
+		rule, _ := Parse("FREQ=WEEKLY;UNTIL=20260105")
+
+		_, _, ok := rule.Advance(date(2026, 1, 1))
+
+		assert.False(t, ok)
+	})
+}
+
+func TestRule_OccurrencesEndsWithSeries(t *testing.T) {
+	rule, _ := Parse("FREQ=DAILY;COUNT=2")
+
+	got := rule.Occurrences(date(2026, 1, 1), 10)
+
+	assert.Equal(t, []time.Time{date(2026, 1, 1), date(2026, 1, 2)}, got)
+}
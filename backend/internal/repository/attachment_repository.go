@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// unreferenced matches attachment blobs no attachment refers to
+const unreferenced = "NOT EXISTS (SELECT 1 FROM attachments WHERE attachments.sha256 = attachment_blobs.sha256)"
+
+// AttachmentRepository handles database operations for Attachment
+type AttachmentRepository struct {
+	db *gorm.DB
+}
+
+// NewAttachmentRepository creates a new AttachmentRepository
+func NewAttachmentRepository(db *gorm.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+// TodoExists reports whether a todo outside the trash belongs to the user
+func (r *AttachmentRepository) TodoExists(userID, todoID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Todo{}).Where("user_id = ? AND id = ?", userID, todoID).Count(&count).Error
+	return count > 0, err
+}
+
+// Create creates a new attachment along with the row of its blob. Writing
+// the blob row locks it, so the attachment cannot be created while
+// DeleteBlob removes the same content.
+func (r *AttachmentRepository) Create(attachment *models.Attachment) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		blob := &models.AttachmentBlob{SHA256: attachment.SHA256, Size: attachment.Size}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "sha256"}},
+			DoUpdates: clause.AssignmentColumns([]string{"size"}),
+		}).Create(blob).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(attachment).Error
+	})
+}
+
+// GetByTodo gets the attachments of a user's todo, oldest first
+func (r *AttachmentRepository) GetByTodo(userID, todoID uint) ([]models.Attachment, error) {
+	var attachments []models.Attachment
+	err := r.db.Where("user_id = ? AND todo_id = ?", userID, todoID).Order("created_at asc, id asc").Find(&attachments).Error
+	return attachments, err
+}
+
+// GetByID gets a user's attachment of a todo by ID
+func (r *AttachmentRepository) GetByID(userID, todoID, id uint) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := r.db.Where("user_id = ? AND todo_id = ?", userID, todoID).First(&attachment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+// Delete deletes a user's attachment of a todo and reports whether it existed
+func (r *AttachmentRepository) Delete(userID, todoID, id uint) (bool, error) {
+	result := r.db.Where("user_id = ? AND todo_id = ?", userID, todoID).Delete(&models.Attachment{}, id)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetUnreferencedBlobs gets the hashes of up to limit blobs no attachment refers to
+func (r *AttachmentRepository) GetUnreferencedBlobs(limit int) ([]string, error) {
+	var hashes []string
+	err := r.db.Model(&models.AttachmentBlob{}).Where(unreferenced).Order("created_at asc").Limit(limit).Pluck("sha256", &hashes).Error
+	return hashes, err
+}
+
+// DeleteBlob deletes the row of a blob no attachment refers to, calling
+// remove to delete the content from storage first. The row stays locked
+// meanwhile, so no attachment can start using the blob. It reports false
+// without calling remove when the blob is gone or in use.
+func (r *AttachmentRepository) DeleteBlob(hash string, remove func() error) (bool, error) {
+	var deleted bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// A no-op update locks the row, unlike a plain read
+		result := tx.Model(&models.AttachmentBlob{}).Where("sha256 = ?", hash).Where(unreferenced).Update("size", gorm.Expr("size"))
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if err := remove(); err != nil {
+			return err
+		}
+		deleted = true
+		return tx.Where("sha256 = ?", hash).Delete(&models.AttachmentBlob{}).Error
+	})
+	return deleted, err
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"time"
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditFilter narrows down a list of audit entries. Zero fields match everything.
+type AuditFilter struct {
+	EntityType string
+	EntityID   uint
+	Action     string
+	ActorID    uint
+	Since      *time.Time // Entries created at or after
+	Until      *time.Time // Entries created before
+}
+
+// AuditRepository handles database operations for AuditEntry
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new AuditRepository
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// GetAll gets a user's audit entries matching filter with pagination, newest first
+func (r *AuditRepository) GetAll(userID uint, filter AuditFilter, page, limit int) ([]models.AuditEntry, int64, error) {
+	query := r.db.Model(&models.AuditEntry{}).Where("user_id = ?", userID)
+	if filter.EntityType != "" {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if filter.EntityID != 0 {
+		query = query.Where("entity_id = ?", filter.EntityID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("created_at < ?", *filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var entries []models.AuditEntry
+	err := query.Order("id DESC").Offset((page - 1) * limit).Limit(limit).Find(&entries).Error
+	return entries, total, err
+}
+
+// AddAuditEntry records a change to one of the user's todos
+func (r *TodoRepository) AddAuditEntry(entry *models.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+// FindAuditEntry gets an audit entry of a user's todo by ID
+func (r *TodoRepository) FindAuditEntry(userID, todoID, entryID uint) (*models.AuditEntry, error) {
+	var entry models.AuditEntry
+	err := r.db.Where("user_id = ? AND entity_type = ? AND entity_id = ?", userID, models.AuditTodo, todoID).First(&entry, entryID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetSnapshots gets a user's todos by ID with their tags, including those in
+// the trash, to compare them before and after a change
+func (r *TodoRepository) GetSnapshots(userID uint, ids []uint) ([]models.Todo, error) {
+	var todos []models.Todo
+	if len(ids) == 0 {
+		return todos, nil
+	}
+	err := r.db.Unscoped().Preload("Tags").Where("user_id = ? AND id IN ?", userID, ids).Find(&todos).Error
+	return todos, err
+}
+
+// AddAuditEntry records a change to one of the user's categories
+func (r *CategoryRepository) AddAuditEntry(entry *models.AuditEntry) error {
+	return r.db.Create(entry).Error
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"errors"
+	"time"
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CalDAVRepository handles the database reads of the CalDAV server and its
+// CalDAVObject records. Todos are changed through the TodoRepository, which
+// Todos binds to the transaction of the repository.
+type CalDAVRepository struct {
+	db *gorm.DB
+}
+
+// NewCalDAVRepository creates a new CalDAVRepository
+func NewCalDAVRepository(db *gorm.DB) *CalDAVRepository {
+	return &CalDAVRepository{db: db}
+}
+
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *CalDAVRepository) Transaction(fn func(tx *CalDAVRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&CalDAVRepository{db: tx})
+	})
+}
+
+// Todos returns a TodoRepository bound to the same transaction
+func (r *CalDAVRepository) Todos() *TodoRepository {
+	return &TodoRepository{db: r.db}
+}
+
+// GetTodos gets a user's todos in a category, or without one for a nil
+// categoryID, with their category and tags. A non-nil completed only gets
+// the todos with that status.
+func (r *CalDAVRepository) GetTodos(userID uint, categoryID *uint, completed *bool) ([]models.Todo, error) {
+	query := r.db.Where("user_id = ?", userID).Preload("Category").Preload("Tags").Order("id")
+	if categoryID != nil {
+		query = query.Where("category_id = ?", *categoryID)
+	} else {
+		query = query.Where("category_id IS NULL")
+	}
+	if completed != nil {
+		query = query.Where("completed = ?", *completed)
+	}
+	var todos []models.Todo
+	err := query.Find(&todos).Error
+	return todos, err
+}
+
+// GetTodo gets a user's todo outside the trash with its category and tags
+func (r *CalDAVRepository) GetTodo(userID, id uint) (*models.Todo, error) {
+	var todo models.Todo
+	err := r.db.Where("user_id = ?", userID).Preload("Category").Preload("Tags").First(&todo, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// GetChangedTodos gets a user's todos that changed or were moved to the
+// trash at or after since, including those in the trash
+func (r *CalDAVRepository) GetChangedTodos(userID uint, since time.Time) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := r.db.Unscoped().Where("user_id = ? AND (updated_at >= ? OR deleted_at >= ?)", userID, since, since).
+		Preload("Category").Preload("Tags").Order("id").Find(&todos).Error
+	return todos, err
+}
+
+// LastChange returns when a user's todos last changed or were moved to the
+// trash, the zero time when the user has none
+func (r *CalDAVRepository) LastChange(userID uint) (time.Time, error) {
+	var last time.Time
+	for _, column := range []string{"updated_at", "deleted_at"} {
+		var todo models.Todo
+		err := r.db.Unscoped().Select(column).Where("user_id = ? AND "+column+" IS NOT NULL", userID).Order(column + " desc").First(&todo).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if todo.UpdatedAt.After(last) {
+			last = todo.UpdatedAt
+		}
+		if todo.DeletedAt.Valid && todo.DeletedAt.Time.After(last) {
+			last = todo.DeletedAt.Time
+		}
+	}
+	return last, nil
+}
+
+// GetObjects gets the CalDAV names of the given todos of a user, keyed by todo ID
+func (r *CalDAVRepository) GetObjects(userID uint, todoIDs []uint) (map[uint]models.CalDAVObject, error) {
+	objects := make(map[uint]models.CalDAVObject)
+	if len(todoIDs) == 0 {
+		return objects, nil
+	}
+	var found []models.CalDAVObject
+	if err := r.db.Where("user_id = ? AND todo_id IN ?", userID, todoIDs).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for _, object := range found {
+		objects[object.TodoID] = object
+	}
+	return objects, nil
+}
+
+// GetObjectByName gets the CalDAV object with a resource name of a user's
+// todo outside the trash in a category, or without one for a nil categoryID
+func (r *CalDAVRepository) GetObjectByName(userID uint, categoryID *uint, name string) (*models.CalDAVObject, error) {
+	query := r.liveObjects().Where("caldav_objects.user_id = ? AND caldav_objects.name = ?", userID, name)
+	if categoryID != nil {
+		query = query.Where("todos.category_id = ?", *categoryID)
+	} else {
+		query = query.Where("todos.category_id IS NULL")
+	}
+	var object models.CalDAVObject
+	if err := query.First(&object).Error; err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+// ReleaseUID deletes the CalDAV object with the UID of a user's todo in the
+// trash, so that a new todo can take the UID over
+func (r *CalDAVRepository) ReleaseUID(userID uint, uid string) error {
+	trashed := r.db.Unscoped().Model(&models.Todo{}).Select("id").Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+	return r.db.Where("user_id = ? AND uid = ? AND todo_id IN (?)", userID, uid, trashed).Delete(&models.CalDAVObject{}).Error
+}
+
+// liveObjects selects the CalDAV objects of todos outside the trash
+func (r *CalDAVRepository) liveObjects() *gorm.DB {
+	return r.db.Model(&models.CalDAVObject{}).Joins("JOIN todos ON todos.id = caldav_objects.todo_id AND todos.deleted_at IS NULL")
+}
+
+// CreateObject creates a CalDAV object
+func (r *CalDAVRepository) CreateObject(object *models.CalDAVObject) error {
+	return r.db.Create(object).Error
+}
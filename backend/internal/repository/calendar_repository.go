@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CalendarRepository handles database operations for CalendarFeed
+type CalendarRepository struct {
+	db *gorm.DB
+}
+
+// NewCalendarRepository creates a new CalendarRepository
+func NewCalendarRepository(db *gorm.DB) *CalendarRepository {
+	return &CalendarRepository{db: db}
+}
+
+// SetFeed replaces the calendar feed of a user, so the old token stops working
+func (r *CalendarRepository) SetFeed(feed *models.CalendarFeed) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", feed.UserID).Delete(&models.CalendarFeed{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(feed).Error
+	})
+}
+
+// DeleteFeed deletes the calendar feed of a user, reporting whether there was one
+func (r *CalendarRepository) DeleteFeed(userID uint) (bool, error) {
+	result := r.db.Where("user_id = ?", userID).Delete(&models.CalendarFeed{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetFeedByHash gets a calendar feed by the hash of its token
+func (r *CalendarRepository) GetFeedByHash(hash string) (*models.CalendarFeed, error) {
+	var feed models.CalendarFeed
+	err := r.db.Where("token_hash = ?", hash).First(&feed).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feed, nil
+}
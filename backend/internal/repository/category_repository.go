@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"time"
 	"todoListChallenge/internal/models"
 
 	"gorm.io/gorm"
@@ -16,34 +17,151 @@ func NewCategoryRepository(db *gorm.DB) *CategoryRepository {
 	return &CategoryRepository{db: db}
 }
 
-// Create creates a new category
+// Create creates a new category at version 1
 func (r *CategoryRepository) Create(category *models.Category) error {
+	category.Version = 1
 	return r.db.Create(category).Error
 }
 
-// GetAll gets all categories
-func (r *CategoryRepository) GetAll() ([]models.Category, error) {
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *CategoryRepository) Transaction(fn func(tx *CategoryRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&CategoryRepository{db: tx})
+	})
+}
+
+// GetAll gets all categories of a user
+func (r *CategoryRepository) GetAll(userID uint) ([]models.Category, error) {
 	var categories []models.Category
-	err := r.db.Find(&categories).Error
+	err := r.db.Where("user_id = ?", userID).Find(&categories).Error
 	return categories, err
 }
 
-// GetByID gets a category by ID
-func (r *CategoryRepository) GetByID(id uint) (*models.Category, error) {
+// GetByID gets a user's category by ID
+func (r *CategoryRepository) GetByID(userID, id uint) (*models.Category, error) {
 	var category models.Category
-	err := r.db.First(&category, id).Error
+	err := r.db.Where("user_id = ?", userID).First(&category, id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &category, nil
 }
 
-// Update updates a category
-func (r *CategoryRepository) Update(category *models.Category) error {
-	return r.db.Save(category).Error
+// Update saves a category that was read at the given version and bumps its
+// version. It reports false, leaving the category unchanged, when the stored
+// category is no longer at that version.
+func (r *CategoryRepository) Update(category *models.Category, version uint) (bool, error) {
+	category.Version = version + 1
+	result := r.db.Model(category).Where("version = ?", version).Select("*").Omit("Todos", "DeletedAt").Updates(category)
+	if result.Error != nil || result.RowsAffected == 0 {
+		category.Version = version
+		return false, result.Error
+	}
+	return true, nil
 }
 
-// Delete deletes a category
-func (r *CategoryRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Category{}, id).Error
-}
\ No newline at end of file
+// Delete moves a user's category to the trash. Its todos, including those
+// in the trash, leave the category but remember it so Restore can link them
+// again. When version is non-zero the category is only deleted if it is
+// still at that version; it reports whether a category was deleted.
+func (r *CategoryRepository) Delete(userID, id, version uint) (bool, error) {
+	var deleted bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("user_id = ?", userID)
+		if version != 0 {
+			query = query.Where("version = ?", version)
+		}
+		result := query.Delete(&models.Category{}, id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+		return tx.Unscoped().Model(&models.Todo{}).Where("user_id = ? AND category_id = ?", userID, id).Updates(map[string]interface{}{
+			"trashed_category_id": gorm.Expr("category_id"),
+			"category_id":         nil,
+			"version":             gorm.Expr("version + 1"),
+		}).Error
+	})
+	return deleted, err
+}
+
+// GetTrash gets a user's categories in the trash, most recently deleted first
+func (r *CategoryRepository) GetTrash(userID uint) ([]models.Category, error) {
+	var categories []models.Category
+	err := r.db.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Order("deleted_at DESC, id ASC").Find(&categories).Error
+	return categories, err
+}
+
+// FindTrashed gets a user's category in the trash by ID
+func (r *CategoryRepository) FindTrashed(userID, id uint) (*models.Category, error) {
+	var category models.Category
+	err := r.db.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).First(&category, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// NameTaken reports whether one of the user's categories outside the trash has the name
+func (r *CategoryRepository) NameTaken(userID uint, name string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Category{}).Where("user_id = ? AND name = ?", userID, name).Count(&count).Error
+	return count > 0, err
+}
+
+// Restore takes a user's category out of the trash and links it again to
+// the todos that belonged to it, unless they were moved to another category
+// in the meantime. It reports how many todos were linked again.
+func (r *CategoryRepository) Restore(userID, id uint) (int64, error) {
+	var relinked int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&models.Category{}).Where("user_id = ? AND id = ? AND deleted_at IS NOT NULL", userID, id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return err
+		}
+
+		todos := tx.Unscoped().Model(&models.Todo{}).Where("user_id = ? AND trashed_category_id = ?", userID, id)
+		result := todos.Session(&gorm.Session{}).Where("category_id IS NULL").Updates(map[string]interface{}{
+			"category_id":         id,
+			"trashed_category_id": nil,
+			"version":             gorm.Expr("version + 1"),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		relinked = result.RowsAffected
+		return todos.Session(&gorm.Session{}).Update("trashed_category_id", nil).Error
+	})
+	return relinked, err
+}
+
+// Purge permanently deletes a user's categories in the trash
+func (r *CategoryRepository) Purge(userID uint, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		trashed := tx.Unscoped().Model(&models.Category{}).Select("id").Where("user_id = ? AND id IN ? AND deleted_at IS NOT NULL", userID, ids)
+		if err := tx.Unscoped().Model(&models.Todo{}).Where("trashed_category_id IN (?)", trashed).Update("trashed_category_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Delete(&models.Category{}, ids).Error
+	})
+}
+
+// PurgeDeletedBefore permanently deletes the categories of every user that
+// were moved to the trash before cutoff and returns how many were deleted
+func (r *CategoryRepository) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Unscoped().Model(&models.Category{}).Select("id").Where("deleted_at < ?", cutoff)
+		if err := tx.Unscoped().Model(&models.Todo{}).Where("trashed_category_id IN (?)", expired).Update("trashed_category_id", nil).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.Category{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// CommentRepository handles database operations for Comment
+type CommentRepository struct {
+	db *gorm.DB
+}
+
+// NewCommentRepository creates a new CommentRepository
+func NewCommentRepository(db *gorm.DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+// TodoExists reports whether a todo outside the trash belongs to the user
+func (r *CommentRepository) TodoExists(userID, todoID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Todo{}).Where("user_id = ? AND id = ?", userID, todoID).Count(&count).Error
+	return count > 0, err
+}
+
+// Create creates a new comment
+func (r *CommentRepository) Create(comment *models.Comment) error {
+	return r.db.Create(comment).Error
+}
+
+// GetByTodo gets the comments on a user's todo, oldest first
+func (r *CommentRepository) GetByTodo(userID, todoID uint) ([]models.Comment, error) {
+	var comments []models.Comment
+	err := r.db.Where("user_id = ? AND todo_id = ?", userID, todoID).Order("created_at asc, id asc").Find(&comments).Error
+	return comments, err
+}
+
+// GetByID gets a user's comment on a todo by ID
+func (r *CommentRepository) GetByID(userID, todoID, id uint) (*models.Comment, error) {
+	var comment models.Comment
+	err := r.db.Where("user_id = ? AND todo_id = ?", userID, todoID).First(&comment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// Update saves the body of a comment
+func (r *CommentRepository) Update(comment *models.Comment) error {
+	return r.db.Model(comment).Select("Body", "Mentions", "EditedAt").Updates(comment).Error
+}
+
+// Delete deletes a user's comment on a todo and reports whether it existed
+func (r *CommentRepository) Delete(userID, todoID, id uint) (bool, error) {
+	result := r.db.Where("user_id = ? AND todo_id = ?", userID, todoID).Delete(&models.Comment{}, id)
+	return result.RowsAffected > 0, result.Error
+}
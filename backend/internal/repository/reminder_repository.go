@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"time"
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// ReminderRepository handles database operations for Reminder and Notification
+type ReminderRepository struct {
+	db *gorm.DB
+}
+
+// NewReminderRepository creates a new ReminderRepository
+func NewReminderRepository(db *gorm.DB) *ReminderRepository {
+	return &ReminderRepository{db: db}
+}
+
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *ReminderRepository) Transaction(fn func(tx *ReminderRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&ReminderRepository{db: tx})
+	})
+}
+
+// FindTodo gets a user's todo outside the trash by ID, without relations
+func (r *ReminderRepository) FindTodo(userID, id uint) (*models.Todo, error) {
+	var todo models.Todo
+	err := r.db.Where("user_id = ?", userID).First(&todo, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// FindTodoOfReminder gets the todo of a reminder, including one in the trash
+func (r *ReminderRepository) FindTodoOfReminder(reminder *models.Reminder) (*models.Todo, error) {
+	var todo models.Todo
+	err := r.db.Unscoped().Where("user_id = ?", reminder.UserID).First(&todo, reminder.TodoID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// FindUser gets a user by ID, for delivering notifications
+func (r *ReminderRepository) FindUser(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Create creates a new reminder
+func (r *ReminderRepository) Create(reminder *models.Reminder) error {
+	return r.db.Create(reminder).Error
+}
+
+// GetByTodo gets the reminders of a user's todo, soonest first
+func (r *ReminderRepository) GetByTodo(userID, todoID uint) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	err := r.db.Where("user_id = ? AND todo_id = ?", userID, todoID).Order("fire_at asc, id asc").Find(&reminders).Error
+	return reminders, err
+}
+
+// GetUpcoming gets a user's pending reminders of todos outside the trash
+// that have a fire time, soonest first
+func (r *ReminderRepository) GetUpcoming(userID uint, limit int) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	err := r.db.Where("user_id = ? AND status = ? AND fire_at IS NOT NULL", userID, models.ReminderPending).
+		Where("todo_id IN (?)", r.db.Model(&models.Todo{}).Select("id").Where("user_id = ?", userID)).
+		Order("fire_at asc, id asc").
+		Limit(limit).
+		Find(&reminders).Error
+	return reminders, err
+}
+
+// GetByID gets a user's reminder by ID
+func (r *ReminderRepository) GetByID(userID, id uint) (*models.Reminder, error) {
+	var reminder models.Reminder
+	err := r.db.Where("user_id = ?", userID).First(&reminder, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reminder, nil
+}
+
+// Update saves a reminder
+func (r *ReminderRepository) Update(reminder *models.Reminder) error {
+	return r.db.Save(reminder).Error
+}
+
+// Delete deletes a user's reminder of a todo together with its
+// notifications and reports whether it existed
+func (r *ReminderRepository) Delete(userID, todoID, id uint) (bool, error) {
+	var deleted bool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND todo_id = ?", userID, todoID).Delete(&models.Reminder{}, id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+		return tx.Where("reminder_id = ?", id).Delete(&models.Notification{}).Error
+	})
+	return deleted, err
+}
+
+// GetDue gets pending reminders whose fire time has come, oldest first
+func (r *ReminderRepository) GetDue(now time.Time, limit int) ([]models.Reminder, error) {
+	var reminders []models.Reminder
+	err := r.db.Where("status = ? AND fire_at <= ?", models.ReminderPending, now).
+		Order("fire_at asc, id asc").
+		Limit(limit).
+		Find(&reminders).Error
+	return reminders, err
+}
+
+// Claim moves a pending reminder whose fire time has come to status, which
+// also locks it for the rest of the transaction. It reports false when the
+// reminder was claimed, snoozed or removed in the meantime, so every firing
+// is claimed exactly once.
+func (r *ReminderRepository) Claim(id uint, now time.Time, status string) (bool, error) {
+	result := r.db.Model(&models.Reminder{}).
+		Where("id = ? AND status = ? AND fire_at <= ?", id, models.ReminderPending, now).
+		Updates(map[string]interface{}{"status": status, "fire_at": nil, "sent_at": now})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateNotifications creates notifications
+func (r *ReminderRepository) CreateNotifications(notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.Create(&notifications).Error
+}
+
+// GetNotifications gets up to limit of a user's in-app notifications,
+// newest first, optionally only the unread ones
+func (r *ReminderRepository) GetNotifications(userID uint, unread bool, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	query := r.db.Where("user_id = ? AND channel = ?", userID, models.ChannelInApp)
+	if unread {
+		query = query.Where("read_at IS NULL")
+	}
+	err := query.Order("id desc").Limit(limit).Find(&notifications).Error
+	return notifications, err
+}
+
+// MarkRead marks a user's in-app notification as read and reports whether it exists
+func (r *ReminderRepository) MarkRead(userID, id uint, now time.Time) (bool, error) {
+	result := r.db.Model(&models.Notification{}).Where("user_id = ? AND id = ? AND channel = ?", userID, id, models.ChannelInApp).
+		Update("read_at", gorm.Expr("COALESCE(read_at, ?)", now))
+	return result.RowsAffected > 0, result.Error
+}
+
+// MarkReminderRead marks the unread in-app notifications of a reminder as read
+func (r *ReminderRepository) MarkReminderRead(reminderID uint, now time.Time) error {
+	return r.db.Model(&models.Notification{}).Where("reminder_id = ? AND channel = ? AND read_at IS NULL", reminderID, models.ChannelInApp).
+		Update("read_at", now).Error
+}
+
+// GetDueNotifications gets pending notifications whose next attempt is due, oldest first
+func (r *ReminderRepository) GetDueNotifications(now time.Time, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.NotificationPending, now).
+		Order("next_attempt_at asc, id asc").
+		Limit(limit).
+		Find(&notifications).Error
+	return notifications, err
+}
+
+// ClaimNotification counts an attempt of a pending notification and keeps
+// it from being attempted again before leaseUntil, so a notification is
+// never sent twice at once. It reports false when the attempt was claimed
+// elsewhere.
+func (r *ReminderRepository) ClaimNotification(notification *models.Notification, leaseUntil time.Time) (bool, error) {
+	result := r.db.Model(&models.Notification{}).
+		Where("id = ? AND status = ? AND attempts = ?", notification.ID, models.NotificationPending, notification.Attempts).
+		Updates(map[string]interface{}{"attempts": notification.Attempts + 1, "next_attempt_at": leaseUntil})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	notification.Attempts++
+	notification.NextAttemptAt = &leaseUntil
+	return true, nil
+}
+
+// UpdateNotification saves the outcome of a delivery attempt
+func (r *ReminderRepository) UpdateNotification(notification *models.Notification) error {
+	return r.db.Save(notification).Error
+}
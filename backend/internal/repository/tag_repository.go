@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// TagRepository handles database operations for Tag
+type TagRepository struct {
+	db *gorm.DB
+}
+
+// NewTagRepository creates a new TagRepository
+func NewTagRepository(db *gorm.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// Create creates a new tag
+func (r *TagRepository) Create(tag *models.Tag) error {
+	return r.db.Create(tag).Error
+}
+
+// GetAll gets all tags of a user ordered by name
+func (r *TagRepository) GetAll(userID uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	err := r.db.Where("user_id = ?", userID).Order("name asc").Find(&tags).Error
+	return tags, err
+}
+
+// GetByID gets a user's tag by ID
+func (r *TagRepository) GetByID(userID, id uint) (*models.Tag, error) {
+	var tag models.Tag
+	err := r.db.Where("user_id = ?", userID).First(&tag, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// Update updates a tag
+func (r *TagRepository) Update(tag *models.Tag) error {
+	return r.db.Save(tag).Error
+}
+
+// Delete deletes a user's tag and detaches it from all todos
+func (r *TagRepository) Delete(userID, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", userID).Delete(&models.Tag{}, id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Exec("DELETE FROM todo_tags WHERE tag_id = ?", id).Error
+	})
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/search"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// priorityRank orders priorities from low to high
+const priorityRank = "CASE todos.priority WHEN 'low' THEN 1 WHEN 'medium' THEN 2 WHEN 'high' THEN 3 ELSE 0 END"
+
+// priorityRanks are the values of priorityRank
+var priorityRanks = map[models.Priority]int{models.PriorityLow: 1, models.PriorityMedium: 2, models.PriorityHigh: 3}
+
+// Cursor is a position in a sorted list of todos: the sort key and ID of the
+// todo it sits next to. Key is a string for title, an int for priority, a
+// float64 for relevance and a time.Time for the timestamp sorts; it is nil
+// for a todo without a due date. A Before cursor pages backwards.
+type Cursor struct {
+	SortBy    string
+	SortOrder string
+	Key       interface{}
+	ID        uint
+	Before    bool
+}
+
+// Page is a page of todos along with the cursors of the pages before and
+// after it. A nil cursor means there is nothing more in that direction.
+type Page struct {
+	Todos []models.Todo
+	Next  *Cursor
+	Prev  *Cursor
+}
+
+// sortKey is the expression a list of todos is ordered by. Every order ends
+// with the todo ID so todos with equal keys keep a stable position.
+type sortKey struct {
+	sortBy    string // Requested sort, recorded in cursors
+	sortOrder string
+	column    string // Todo field the key is read from
+	expr      clause.Expr
+	nullable  bool // NULLs come last in both directions
+	desc      bool
+}
+
+// sortKeyFor returns the order for a sort field. Relevance needs a search on
+// a backend that ranks matches and falls back to newest first otherwise.
+func sortKeyFor(sortBy, sortOrder, backend string, q *search.Query) sortKey {
+	key := sortKey{sortBy: sortBy, sortOrder: sortOrder, column: sortBy, desc: sortOrder != "asc"}
+	switch sortBy {
+	case "title", "updated_at":
+		key.expr = clause.Expr{SQL: "todos." + sortBy}
+	case "due_date":
+		key.expr = clause.Expr{SQL: "todos.due_date"}
+		key.nullable = true
+	case "priority":
+		key.expr = clause.Expr{SQL: priorityRank}
+	case "relevance":
+		key.desc = true
+		if q != nil {
+			if rank, ok := rankExpr(backend, q); ok {
+				key.expr = rank
+				return key
+			}
+		}
+		key.column = "created_at"
+		key.expr = clause.Expr{SQL: "todos.created_at"}
+	default:
+		key.column = "created_at"
+		key.expr = clause.Expr{SQL: "todos.created_at"}
+	}
+	return key
+}
+
+// order sorts query by the key, or in the opposite direction when reverse is
+// set. The whole order is a single expression since an expression order
+// cannot be combined with column orders in GORM.
+func (k sortKey) order(query *gorm.DB, reverse bool) *gorm.DB {
+	dir := " ASC"
+	if k.desc != reverse {
+		dir = " DESC"
+	}
+	sql := k.expr.SQL + dir + ", todos.id" + dir
+	if k.nullable {
+		nulls := " ASC"
+		if reverse {
+			nulls = " DESC"
+		}
+		sql = k.expr.SQL + " IS NULL" + nulls + ", " + sql
+	}
+	return query.Order(clause.OrderBy{Expression: clause.Expr{SQL: sql, Vars: k.expr.Vars}})
+}
+
+// after returns the condition for todos that come after the cursor, or
+// before it for a Before cursor
+func (k sortKey) after(cursor *Cursor) clause.Expr {
+	op := " > ?"
+	if k.desc != cursor.Before {
+		op = " < ?"
+	}
+	expr := k.expr.SQL
+
+	if k.nullable && cursor.Key == nil {
+		// Todos without a key come last and are ordered by ID alone
+		if cursor.Before {
+			return clause.Expr{SQL: "(" + expr + " IS NOT NULL OR todos.id" + op + ")", Vars: []interface{}{cursor.ID}}
+		}
+		return clause.Expr{SQL: "(" + expr + " IS NULL AND todos.id" + op + ")", Vars: []interface{}{cursor.ID}}
+	}
+
+	var vars []interface{}
+	vars = append(vars, k.expr.Vars...)
+	vars = append(vars, cursor.Key)
+	vars = append(vars, k.expr.Vars...)
+	vars = append(vars, cursor.Key, cursor.ID)
+	sql := expr + op + " OR (" + expr + " = ? AND todos.id" + op + ")"
+	if k.nullable && !cursor.Before {
+		sql = expr + " IS NULL OR " + sql
+	}
+	return clause.Expr{SQL: "(" + sql + ")", Vars: vars}
+}
+
+// cursor returns the position of a todo in the order
+func (k sortKey) cursor(todo *models.Todo, before bool) *Cursor {
+	cursor := &Cursor{SortBy: k.sortBy, SortOrder: k.sortOrder, ID: todo.ID, Before: before}
+	switch k.column {
+	case "title":
+		cursor.Key = todo.Title
+	case "updated_at":
+		cursor.Key = todo.UpdatedAt
+	case "due_date":
+		if todo.DueDate != nil {
+			cursor.Key = *todo.DueDate
+		}
+	case "priority":
+		cursor.Key = priorityRanks[todo.Priority]
+	case "relevance":
+		if todo.Search != nil {
+			cursor.Key = todo.Search.Rank
+		}
+	default:
+		cursor.Key = todo.CreatedAt
+	}
+	return cursor
+}
+
+// GetPage gets the page of a user's todos that follows the cursor, or
+// precedes it for a Before cursor. A nil cursor starts at the beginning.
+// Unlike GetAll it does not count the matching todos.
+func (r *TodoRepository) GetPage(userID uint, cursor *Cursor, limit int, q *search.Query, sortBy, sortOrder string, filters map[string]interface{}) (*Page, error) {
+	var backend string
+	if q != nil {
+		backend = r.searchBackend()
+	}
+	key := sortKeyFor(sortBy, sortOrder, backend, q)
+	query := r.listQuery(userID, backend, q, filters)
+
+	before := cursor != nil && cursor.Before
+	if cursor != nil {
+		query = query.Where(key.after(cursor))
+	}
+
+	// One extra todo tells whether there is another page
+	var todos []models.Todo
+	if err := key.order(query, before).Limit(limit + 1).Find(&todos).Error; err != nil {
+		return nil, err
+	}
+	more := len(todos) > limit
+	if more {
+		todos = todos[:limit]
+	}
+	if before {
+		for i, j := 0, len(todos)-1; i < j; i, j = i+1, j-1 {
+			todos[i], todos[j] = todos[j], todos[i]
+		}
+	}
+
+	if err := r.attachListData(userID, todos, backend, q); err != nil {
+		return nil, err
+	}
+
+	page := &Page{Todos: todos}
+	if len(todos) == 0 {
+		// Nothing left in that direction; the way back starts at the cursor
+		if cursor != nil {
+			back := *cursor
+			back.Before = !cursor.Before
+			if before {
+				page.Next = &back
+			} else {
+				page.Prev = &back
+			}
+		}
+		return page, nil
+	}
+
+	first, last := key.cursor(&todos[0], true), key.cursor(&todos[len(todos)-1], false)
+	if before {
+		page.Next = last
+		if more {
+			page.Prev = first
+		}
+	} else {
+		if more {
+			page.Next = last
+		}
+		if cursor != nil {
+			page.Prev = first
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"time"
+	"todoListChallenge/internal/models"
+)
+
+// RescheduleReminders moves the offset reminders of a todo to its new due
+// date. Reminders that already fired for the old due date fire again for
+// the new one; dismissed reminders stay dismissed.
+func (r *TodoRepository) RescheduleReminders(todo *models.Todo) error {
+	var reminders []models.Reminder
+	err := r.db.Where("todo_id = ? AND offset_minutes IS NOT NULL AND status <> ?", todo.ID, models.ReminderDismissed).Find(&reminders).Error
+	if err != nil {
+		return err
+	}
+	for i := range reminders {
+		reminders[i].Status = models.ReminderPending
+		reminders[i].FireAt = OffsetFireAt(todo.DueDate, *reminders[i].OffsetMinutes)
+		if err := r.db.Save(&reminders[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyReminders gives the next occurrence of a recurring todo the offset
+// reminders of the previous one that were not dismissed
+func (r *TodoRepository) CopyReminders(from, to *models.Todo) error {
+	var reminders []models.Reminder
+	err := r.db.Where("todo_id = ? AND offset_minutes IS NOT NULL AND status <> ?", from.ID, models.ReminderDismissed).Order("id asc").Find(&reminders).Error
+	if err != nil || len(reminders) == 0 {
+		return err
+	}
+	copies := make([]models.Reminder, len(reminders))
+	for i, reminder := range reminders {
+		copies[i] = models.Reminder{
+			TodoID:        to.ID,
+			UserID:        to.UserID,
+			OffsetMinutes: reminder.OffsetMinutes,
+			Channels:      reminder.Channels,
+			Status:        models.ReminderPending,
+			FireAt:        OffsetFireAt(to.DueDate, *reminder.OffsetMinutes),
+		}
+	}
+	return r.db.Create(&copies).Error
+}
+
+// OffsetFireAt returns when a reminder minutes before a due date fires, or
+// nil without a due date
+func OffsetFireAt(dueDate *time.Time, minutes int) *time.Time {
+	if dueDate == nil {
+		return nil
+	}
+	at := dueDate.Add(-time.Duration(minutes) * time.Minute)
+	return &at
+}
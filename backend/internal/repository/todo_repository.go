@@ -2,8 +2,10 @@ package repository
 
 import (
 	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/search"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // TodoRepository handles database operations for Todo
@@ -16,35 +18,218 @@ func NewTodoRepository(db *gorm.DB) *TodoRepository {
 	return &TodoRepository{db: db}
 }
 
-// Create creates a new todo
+// Create creates a new todo at version 1
 func (r *TodoRepository) Create(todo *models.Todo) error {
-	return r.db.Create(todo).Error
+	todo.Version = 1
+	return r.db.Omit("Subtasks", "Tags").Create(todo).Error
 }
 
-// GetByID gets a todo by ID with category
-func (r *TodoRepository) GetByID(id uint) (*models.Todo, error) {
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *TodoRepository) Transaction(fn func(tx *TodoRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&TodoRepository{db: tx})
+	})
+}
+
+// GetByID gets a user's todo by ID with category and its full subtask tree
+func (r *TodoRepository) GetByID(userID, id uint) (*models.Todo, error) {
 	var todo models.Todo
-	err := r.db.Preload("Category").First(&todo, id).Error
+	err := r.db.Preload("Category").Preload("Tags").Where("user_id = ?", userID).First(&todo, id).Error
 	if err != nil {
 		return nil, err
 	}
+	if err := r.loadSubtasks(&todo); err != nil {
+		return nil, err
+	}
 	return &todo, nil
 }
 
-// GetAll gets todos with pagination and filters
-func (r *TodoRepository) GetAll(page, limit int, search, sortBy, sortOrder string, filters map[string]interface{}) ([]models.Todo, int64, error) {
+// loadSubtasks loads the subtask tree below todo one level at a time
+func (r *TodoRepository) loadSubtasks(todo *models.Todo) error {
+	level := []*models.Todo{todo}
+	for len(level) > 0 {
+		ids := make([]uint, len(level))
+		byID := make(map[uint]*models.Todo, len(level))
+		for i, t := range level {
+			ids[i] = t.ID
+			byID[t.ID] = t
+		}
+
+		var children []models.Todo
+		if err := r.db.Preload("Category").Preload("Tags").Where("user_id = ? AND parent_id IN ?", todo.UserID, ids).Order("created_at asc, id asc").Find(&children).Error; err != nil {
+			return err
+		}
+
+		for _, child := range children {
+			parent := byID[*child.ParentID]
+			parent.Subtasks = append(parent.Subtasks, child)
+		}
+
+		var next []*models.Todo
+		for _, t := range level {
+			t.Progress = progressOf(t.Subtasks)
+			for i := range t.Subtasks {
+				next = append(next, &t.Subtasks[i])
+			}
+		}
+		level = next
+	}
+	return nil
+}
+
+// progressOf returns the roll-up of direct subtasks, or nil when there are none
+func progressOf(subtasks []models.Todo) *models.Progress {
+	if len(subtasks) == 0 {
+		return nil
+	}
+	progress := &models.Progress{Total: len(subtasks)}
+	for _, s := range subtasks {
+		if s.Completed {
+			progress.Completed++
+		}
+	}
+	return progress
+}
+
+// FindByID gets a user's todo by ID without loading its subtasks
+func (r *TodoRepository) FindByID(userID, id uint) (*models.Todo, error) {
+	var todo models.Todo
+	err := r.db.Where("user_id = ?", userID).First(&todo, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// GetChildren gets the direct subtasks of a todo
+func (r *TodoRepository) GetChildren(userID, parentID uint) ([]models.Todo, error) {
+	var children []models.Todo
+	err := r.db.Preload("Category").Preload("Tags").Where("user_id = ? AND parent_id = ?", userID, parentID).Order("created_at asc, id asc").Find(&children).Error
+	return children, err
+}
+
+// GetDescendantIDs gets the IDs of every todo below the given todo
+func (r *TodoRepository) GetDescendantIDs(userID, id uint) ([]uint, error) {
+	var descendants []uint
+	level := []uint{id}
+	for len(level) > 0 {
+		var ids []uint
+		if err := r.db.Model(&models.Todo{}).Where("user_id = ? AND parent_id IN ?", userID, level).Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		descendants = append(descendants, ids...)
+		level = ids
+	}
+	return descendants, nil
+}
+
+// GetProgress gets subtask roll-ups for the given todos, keyed by todo ID
+func (r *TodoRepository) GetProgress(userID uint, ids []uint) (map[uint]*models.Progress, error) {
+	var rows []struct {
+		ParentID  uint
+		Total     int
+		Completed int
+	}
+	err := r.db.Model(&models.Todo{}).
+		Select("parent_id, COUNT(*) AS total, SUM(CASE WHEN completed THEN 1 ELSE 0 END) AS completed").
+		Where("user_id = ? AND parent_id IN ?", userID, ids).
+		Group("parent_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	progress := make(map[uint]*models.Progress, len(rows))
+	for _, row := range rows {
+		progress[row.ParentID] = &models.Progress{Completed: row.Completed, Total: row.Total}
+	}
+	return progress, nil
+}
+
+// GetAll gets a user's todos with pagination and filters. A nil search
+// matches every todo; sortBy "relevance" orders search results by rank.
+func (r *TodoRepository) GetAll(userID uint, page, limit int, q *search.Query, sortBy, sortOrder string, filters map[string]interface{}) ([]models.Todo, int64, error) {
 	var todos []models.Todo
 	var total int64
 
-	query := r.db.Model(&models.Todo{}).Preload("Category")
+	var backend string
+	if q != nil {
+		backend = r.searchBackend()
+	}
+	query := r.listQuery(userID, backend, q, filters)
+
+	// Count total
+	query.Count(&total)
+
+	// Sorting
+	query = sortKeyFor(sortBy, sortOrder, backend, q).order(query, false)
+
+	// Pagination
+	offset := (page - 1) * limit
+	err := query.Offset(offset).Limit(limit).Find(&todos).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err := r.attachListData(userID, todos, backend, q); err != nil {
+		return nil, 0, err
+	}
+	return todos, total, nil
+}
+
+// GetIDs gets the IDs of all of a user's todos matching a search and
+// filters, in the order they were created
+func (r *TodoRepository) GetIDs(userID uint, q *search.Query, filters map[string]interface{}) ([]uint, error) {
+	var backend string
+	if q != nil {
+		backend = r.searchBackend()
+	}
+	var ids []uint
+	err := r.filterQuery(userID, backend, q, filters).Order("todos.id").Pluck("todos.id", &ids).Error
+	return ids, err
+}
 
-	// Search filter - use LIKE for SQLite compatibility, ILIKE for PostgreSQL
-	if search != "" {
-		if r.db.Dialector.Name() == "postgres" {
-			query = query.Where("title ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%")
-		} else {
-			query = query.Where("title LIKE ? OR description LIKE ?", "%"+search+"%", "%"+search+"%")
+// EachBatch calls fn with every one of a user's todos matching a search and
+// filters, in the order they were created, size todos at a time, so large
+// lists are never loaded at once. It stops at the first error fn returns.
+func (r *TodoRepository) EachBatch(userID uint, q *search.Query, filters map[string]interface{}, size int, fn func(todos []models.Todo) error) error {
+	var backend string
+	if q != nil {
+		backend = r.searchBackend()
+	}
+	var lastID uint
+	for {
+		var todos []models.Todo
+		err := r.listQuery(userID, backend, q, filters).Where("todos.id > ?", lastID).Order("todos.id").Limit(size).Find(&todos).Error
+		if err != nil {
+			return err
+		}
+		if len(todos) == 0 {
+			return nil
+		}
+		if err := fn(todos); err != nil {
+			return err
 		}
+		if len(todos) < size {
+			return nil
+		}
+		lastID = todos[len(todos)-1].ID
+	}
+}
+
+// listQuery builds the query for a user's todos matching a search and
+// filters, loading their category and tags
+func (r *TodoRepository) listQuery(userID uint, backend string, q *search.Query, filters map[string]interface{}) *gorm.DB {
+	return r.filterQuery(userID, backend, q, filters).Preload("Category").Preload("Tags")
+}
+
+// filterQuery selects a user's todos matching a search and filters
+func (r *TodoRepository) filterQuery(userID uint, backend string, q *search.Query, filters map[string]interface{}) *gorm.DB {
+	query := r.db.Model(&models.Todo{}).Where("user_id = ?", userID)
+
+	// Full-text search
+	if q != nil {
+		query = applySearch(query, backend, q)
 	}
 
 	// Filter by completion status
@@ -62,33 +247,192 @@ func (r *TodoRepository) GetAll(page, limit int, search, sortBy, sortOrder strin
 		query = query.Where("priority = ?", priority)
 	}
 
-	// Count total
-	query.Count(&total)
+	// Filter by parent todo
+	if parentID, ok := filters["parent_id"].(uint); ok {
+		query = query.Where("parent_id = ?", parentID)
+	}
 
-	// Sorting
-	if sortBy != "" {
-		order := sortBy + " " + sortOrder
-		query = query.Order(order)
+	// Filter by tags: at least one of, all of, or none of the given tags
+	if tagIDs, ok := filters["tags_any"].([]uint); ok && len(tagIDs) > 0 {
+		query = query.Where("id IN (?)", r.db.Table("todo_tags").Select("todo_id").Where("tag_id IN ?", tagIDs))
+	}
+	if tagIDs, ok := filters["tags_all"].([]uint); ok && len(tagIDs) > 0 {
+		query = query.Where("id IN (?)", r.db.Table("todo_tags").Select("todo_id").Where("tag_id IN ?", tagIDs).
+			Group("todo_id").Having("COUNT(DISTINCT tag_id) = ?", len(tagIDs)))
+	}
+	if tagIDs, ok := filters["tags_none"].([]uint); ok && len(tagIDs) > 0 {
+		query = query.Where("id NOT IN (?)", r.db.Table("todo_tags").Select("todo_id").Where("tag_id IN ?", tagIDs))
 	}
 
-	// Pagination
-	offset := (page - 1) * limit
-	err := query.Offset(offset).Limit(limit).Find(&todos).Error
+	// Only top-level todos
+	if topLevel, ok := filters["top_level"].(bool); ok && topLevel {
+		query = query.Where("parent_id IS NULL")
+	}
+
+	// Compiled filter language expression
+	if expr, ok := filters["q"].(clause.Expr); ok {
+		query = query.Where(expr)
+	}
+
+	return query
+}
+
+// attachListData fills in the subtask roll-ups, comment counts and search
+// matches of a page of todos
+func (r *TodoRepository) attachListData(userID uint, todos []models.Todo, backend string, q *search.Query) error {
+	if err := r.attachProgress(userID, todos); err != nil {
+		return err
+	}
+	if err := r.attachCommentCounts(todos); err != nil {
+		return err
+	}
+	if q != nil {
+		return r.attachSearchMatches(todos, backend, q)
+	}
+	return nil
+}
+
+// attachProgress fills in the subtask roll-up for a page of todos
+func (r *TodoRepository) attachProgress(userID uint, todos []models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(todos))
+	for i, t := range todos {
+		ids[i] = t.ID
+	}
+	progress, err := r.GetProgress(userID, ids)
+	if err != nil {
+		return err
+	}
+	for i := range todos {
+		todos[i].Progress = progress[todos[i].ID]
+	}
+	return nil
+}
+
+// attachCommentCounts fills in the number of comments on a page of todos
+// with a single query
+func (r *TodoRepository) attachCommentCounts(todos []models.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(todos))
+	for i, t := range todos {
+		ids[i] = t.ID
+	}
 
-	return todos, total, err
+	var rows []struct {
+		TodoID uint
+		Count  int64
+	}
+	err := r.db.Model(&models.Comment{}).Select("todo_id, COUNT(*) AS count").Where("todo_id IN ?", ids).Group("todo_id").Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TodoID] = row.Count
+	}
+	for i := range todos {
+		count := counts[todos[i].ID]
+		todos[i].CommentCount = &count
+	}
+	return nil
+}
+
+// Update saves a todo that was read at the given version and bumps its
+// version. It reports false, leaving the todo unchanged, when the stored
+// todo is no longer at that version.
+func (r *TodoRepository) Update(todo *models.Todo, version uint) (bool, error) {
+	todo.Version = version + 1
+	result := r.db.Model(todo).Where("version = ?", version).Select("*").Omit("Subtasks", "Tags", "Category", "DeletedAt", "TrashedCategoryID").Updates(todo)
+	if result.Error != nil || result.RowsAffected == 0 {
+		todo.Version = version
+		return false, result.Error
+	}
+	return true, nil
+}
+
+// Delete moves a user's todos to the trash. They keep their tags so they
+// can be restored, and todos deleted together share their deletion time.
+func (r *TodoRepository) Delete(userID uint, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Where("user_id = ?", userID).Delete(&models.Todo{}, ids).Error
+}
+
+// CategoryExists reports whether a category belongs to the user
+func (r *TodoRepository) CategoryExists(userID, categoryID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.Category{}).Where("user_id = ? AND id = ?", userID, categoryID).Count(&count).Error
+	return count > 0, err
+}
+
+// GetTagsByIDs gets the user's tags with the given IDs
+func (r *TodoRepository) GetTagsByIDs(userID uint, ids []uint) ([]models.Tag, error) {
+	var tags []models.Tag
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	err := r.db.Where("user_id = ? AND id IN ?", userID, ids).Find(&tags).Error
+	return tags, err
 }
 
-// Update updates a todo
-func (r *TodoRepository) Update(todo *models.Todo) error {
-	return r.db.Save(todo).Error
+// ReplaceTags replaces the tags attached to a todo
+func (r *TodoRepository) ReplaceTags(todo *models.Todo, tags []models.Tag) error {
+	if err := r.db.Model(todo).Omit("Tags.*").Association("Tags").Replace(tags); err != nil {
+		return err
+	}
+	todo.Tags = tags
+	return nil
+}
+
+// AddTag attaches a tag to a todo and bumps its version
+func (r *TodoRepository) AddTag(todo *models.Todo, tag *models.Tag) error {
+	if err := r.db.Model(todo).Omit("Tags.*").Association("Tags").Append(tag); err != nil {
+		return err
+	}
+	return r.bumpVersion(todo.UserID, todo.ID)
+}
+
+// RemoveTag detaches a tag from a todo and bumps its version
+func (r *TodoRepository) RemoveTag(todo *models.Todo, tag *models.Tag) error {
+	if err := r.db.Model(todo).Association("Tags").Delete(tag); err != nil {
+		return err
+	}
+	return r.bumpVersion(todo.UserID, todo.ID)
+}
+
+// ClaimVersion locks a todo for the rest of the transaction if it is still at
+// the given version, leaving the version to the change that follows. It
+// reports false when the todo is missing or at another version.
+func (r *TodoRepository) ClaimVersion(userID, id, version uint) (bool, error) {
+	var todo models.Todo
+	result := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").Where("user_id = ? AND id = ? AND version = ?", userID, id, version).Limit(1).Find(&todo)
+	return result.RowsAffected > 0, result.Error
+}
+
+// bumpVersion marks the given todos as changed
+func (r *TodoRepository) bumpVersion(userID uint, ids ...uint) error {
+	return r.db.Model(&models.Todo{}).Where("user_id = ? AND id IN ?", userID, ids).Update("version", gorm.Expr("version + 1")).Error
 }
 
-// Delete deletes a todo
-func (r *TodoRepository) Delete(id uint) error {
-	return r.db.Delete(&models.Todo{}, id).Error
+// Reparent moves the direct subtasks of a todo under a new parent (nil for top level)
+func (r *TodoRepository) Reparent(userID, fromParentID uint, toParentID *uint) error {
+	return r.db.Model(&models.Todo{}).Where("user_id = ? AND parent_id = ?", userID, fromParentID).Updates(map[string]interface{}{"parent_id": toParentID, "version": gorm.Expr("version + 1")}).Error
 }
 
-// ToggleComplete toggles the completion status
-func (r *TodoRepository) ToggleComplete(id uint) error {
-	return r.db.Model(&models.Todo{}).Where("id = ?", id).Update("completed", gorm.Expr("NOT completed")).Error
-}
\ No newline at end of file
+// SetRecurrence sets the recurrence rule of a todo
+func (r *TodoRepository) SetRecurrence(userID, id uint, rule string) error {
+	return r.db.Model(&models.Todo{}).Where("user_id = ? AND id = ?", userID, id).Updates(map[string]interface{}{"recurrence": rule, "version": gorm.Expr("version + 1")}).Error
+}
+
+// SetCompleted sets the completion status of the given todos
+func (r *TodoRepository) SetCompleted(userID uint, ids []uint, completed bool) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.Model(&models.Todo{}).Where("user_id = ? AND id IN ?", userID, ids).Updates(map[string]interface{}{"completed": completed, "version": gorm.Expr("version + 1")}).Error
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/search"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Full-text search implementations, picked by the database in use
+const (
+	searchPostgres = "postgres" // tsvector column with a GIN index, see migration 000008
+	searchFTS5     = "fts5"     // SQLite FTS5 table, see SetupSQLiteSearch
+	searchLike     = "like"     // LIKE fallback for SQLite builds without FTS5
+)
+
+// tsQuery parses a search in the english configuration, matching the search_vector column
+const tsQuery = "to_tsquery('english', ?)"
+
+// Headline options for ts_headline; the marks are turned into <mark> tags by search.Markup
+const (
+	titleHeadline       = "HighlightAll=true, StartSel=" + search.StartMark + ", StopSel=" + search.StopMark
+	descriptionHeadline = "MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=\" … \", StartSel=" +
+		search.StartMark + ", StopSel=" + search.StopMark
+)
+
+// sqliteSearchSetup creates an FTS5 index over todo titles and descriptions
+// and keeps it in sync with triggers
+var sqliteSearchSetup = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS todos_fts USING fts5(
+		title, description,
+		content='todos', content_rowid='id',
+		tokenize='porter unicode61 remove_diacritics 2'
+	)`,
+	`CREATE TRIGGER IF NOT EXISTS todos_fts_insert AFTER INSERT ON todos BEGIN
+		INSERT INTO todos_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS todos_fts_delete AFTER DELETE ON todos BEGIN
+		INSERT INTO todos_fts(todos_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS todos_fts_update AFTER UPDATE OF title, description ON todos BEGIN
+		INSERT INTO todos_fts(todos_fts, rowid, title, description) VALUES ('delete', old.id, old.title, old.description);
+		INSERT INTO todos_fts(rowid, title, description) VALUES (new.id, new.title, new.description);
+	END`,
+	`INSERT INTO todos_fts(todos_fts) VALUES ('rebuild')`,
+}
+
+// SetupSQLiteSearch creates the full-text index on a SQLite database. It
+// does nothing when SQLite was built without FTS5 (build with -tags
+// sqlite_fts5 to enable it); searches then fall back to LIKE.
+func SetupSQLiteSearch(db *gorm.DB) error {
+	var enabled bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return err
+	}
+	if !enabled {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range sqliteSearchSetup {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// searchBackend returns the full-text search implementation of the database
+func (r *TodoRepository) searchBackend() string {
+	if r.db.Dialector.Name() == "postgres" {
+		return searchPostgres
+	}
+	var count int64
+	r.db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'todos_fts'").Scan(&count)
+	if count > 0 {
+		return searchFTS5
+	}
+	return searchLike
+}
+
+// applySearch restricts a todo query to the matches of a search
+func applySearch(query *gorm.DB, backend string, q *search.Query) *gorm.DB {
+	switch backend {
+	case searchPostgres:
+		return query.Where("todos.search_vector @@ "+tsQuery, q.TSQuery())
+	case searchFTS5:
+		return query.Where("todos.id IN (SELECT rowid FROM todos_fts WHERE todos_fts MATCH ?)", q.FTS5())
+	}
+	for _, term := range q.Include {
+		pattern := term.LikePattern()
+		query = query.Where("(todos.title LIKE ? OR todos.description LIKE ?)", pattern, pattern)
+	}
+	for _, term := range q.Exclude {
+		pattern := term.LikePattern()
+		query = query.Where("NOT (todos.title LIKE ? OR todos.description LIKE ?)", pattern, pattern)
+	}
+	return query
+}
+
+// rankExpr returns the relevance of a search match, higher is better. Title
+// matches weigh more than description matches. It returns false when the
+// backend cannot rank matches.
+func rankExpr(backend string, q *search.Query) (clause.Expr, bool) {
+	switch backend {
+	case searchPostgres:
+		return clause.Expr{SQL: "ts_rank(todos.search_vector, " + tsQuery + ")", Vars: []interface{}{q.TSQuery()}}, true
+	case searchFTS5:
+		return clause.Expr{
+			SQL:  "(SELECT -bm25(todos_fts, 10.0, 1.0) FROM todos_fts WHERE todos_fts MATCH ? AND todos_fts.rowid = todos.id)",
+			Vars: []interface{}{q.FTS5()},
+		}, true
+	}
+	return clause.Expr{}, false
+}
+
+// searchMatchRow is a row of attachSearchMatches' queries
+type searchMatchRow struct {
+	ID          uint
+	Rank        float64
+	Title       string
+	Description string
+}
+
+// attachSearchMatches fills in the rank and highlighted title and description
+// snippet of a page of search results
+func (r *TodoRepository) attachSearchMatches(todos []models.Todo, backend string, q *search.Query) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(todos))
+	for i, t := range todos {
+		ids[i] = t.ID
+	}
+
+	var rows []searchMatchRow
+	var err error
+	switch backend {
+	case searchPostgres:
+		err = r.db.Raw(`SELECT id, ts_rank(search_vector, query) AS rank,
+				ts_headline('english', title, query, ?) AS title,
+				ts_headline('english', coalesce(description, ''), query, ?) AS description
+			FROM todos, `+tsQuery+` AS query
+			WHERE id IN ?`, titleHeadline, descriptionHeadline, q.TSQuery(), ids).Scan(&rows).Error
+	case searchFTS5:
+		err = r.db.Raw(`SELECT rowid AS id, -bm25(todos_fts, 10.0, 1.0) AS rank,
+				highlight(todos_fts, 0, ?, ?) AS title,
+				snippet(todos_fts, 1, ?, ?, '…', 24) AS description
+			FROM todos_fts
+			WHERE todos_fts MATCH ? AND rowid IN ?`,
+			search.StartMark, search.StopMark, search.StartMark, search.StopMark, q.FTS5(), ids).Scan(&rows).Error
+	default:
+		for _, t := range todos {
+			rows = append(rows, searchMatchRow{ID: t.ID, Title: t.Title, Description: t.Description})
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	matches := make(map[uint]*models.SearchMatch, len(rows))
+	for _, row := range rows {
+		match := &models.SearchMatch{Rank: row.Rank}
+		if backend == searchLike {
+			match.Title = q.Highlight(row.Title)
+			match.Description = q.Snippet(row.Description)
+		} else {
+			match.Title = search.Markup(row.Title)
+			if search.HasMatch(row.Description) {
+				match.Description = search.Markup(row.Description)
+			}
+		}
+		matches[row.ID] = match
+	}
+	for i := range todos {
+		todos[i].Search = matches[todos[i].ID]
+	}
+	return nil
+}
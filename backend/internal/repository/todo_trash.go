@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"time"
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// trashed selects todos in the trash
+func (r *TodoRepository) trashed() *gorm.DB {
+	return r.db.Unscoped().Model(&models.Todo{}).Where("todos.deleted_at IS NOT NULL")
+}
+
+// GetTrash gets a user's todos in the trash, most recently deleted first
+func (r *TodoRepository) GetTrash(userID uint) ([]models.Todo, error) {
+	var todos []models.Todo
+	err := r.trashed().Preload("Category").Preload("Tags").Where("user_id = ?", userID).Order("deleted_at DESC, id ASC").Find(&todos).Error
+	return todos, err
+}
+
+// FindTrashed gets a user's todo in the trash by ID
+func (r *TodoRepository) FindTrashed(userID, id uint) (*models.Todo, error) {
+	var todo models.Todo
+	err := r.trashed().Where("user_id = ?", userID).First(&todo, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
+
+// GetTrashedDescendantIDs gets the IDs of the todos in the trash below the
+// given todo. With a non-nil since it only follows subtasks deleted at or
+// after that time, which are those deleted along with the todo: a subtask
+// cannot be deleted after its parent.
+func (r *TodoRepository) GetTrashedDescendantIDs(userID, id uint, since *time.Time) ([]uint, error) {
+	var descendants []uint
+	level := []uint{id}
+	for len(level) > 0 {
+		query := r.trashed().Where("user_id = ? AND parent_id IN ?", userID, level)
+		if since != nil {
+			query = query.Where("deleted_at >= ?", *since)
+		}
+		var ids []uint
+		if err := query.Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		descendants = append(descendants, ids...)
+		level = ids
+	}
+	return descendants, nil
+}
+
+// Restore takes a user's todos out of the trash
+func (r *TodoRepository) Restore(userID uint, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.trashed().Where("user_id = ? AND id IN ?", userID, ids).Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+}
+
+// SetParent moves a todo under a new parent (nil for top level)
+func (r *TodoRepository) SetParent(userID, id uint, parentID *uint) error {
+	return r.db.Model(&models.Todo{}).Where("user_id = ? AND id = ?", userID, id).Updates(map[string]interface{}{"parent_id": parentID, "version": gorm.Expr("version + 1")}).Error
+}
+
+// todoDependents returns the rows that belong to a todo and are deleted
+// along with it
+func todoDependents() []interface{} {
+	return []interface{}{&models.Comment{}, &models.Attachment{}, &models.Notification{}, &models.Reminder{}, &models.CalDAVObject{}}
+}
+
+// Purge permanently deletes a user's todos in the trash along with their tag
+// links, comments, attachments, reminders and CalDAV names. The attachment
+// files stay in storage until the attachment cleanup finds them unused.
+func (r *TodoRepository) Purge(userID uint, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	owned := r.trashed().Select("id").Where("user_id = ? AND id IN ?", userID, ids)
+	if err := r.db.Exec("DELETE FROM todo_tags WHERE todo_id IN (?)", owned).Error; err != nil {
+		return err
+	}
+	for _, model := range todoDependents() {
+		if err := r.db.Where("todo_id IN (?)", owned).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return r.db.Unscoped().Where("user_id = ? AND deleted_at IS NOT NULL", userID).Delete(&models.Todo{}, ids).Error
+}
+
+// PurgeDeletedBefore permanently deletes the todos of every user that were
+// moved to the trash before cutoff and returns how many were deleted
+func (r *TodoRepository) PurgeDeletedBefore(cutoff time.Time) (int64, error) {
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		expired := tx.Unscoped().Model(&models.Todo{}).Select("id").Where("deleted_at < ?", cutoff)
+		if err := tx.Exec("DELETE FROM todo_tags WHERE todo_id IN (?)", expired).Error; err != nil {
+			return err
+		}
+		for _, model := range todoDependents() {
+			if err := tx.Where("todo_id IN (?)", expired).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		result := tx.Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.Todo{})
+		purged = result.RowsAffected
+		return result.Error
+	})
+	return purged, err
+}
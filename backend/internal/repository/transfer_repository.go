@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"strings"
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// TransferRepository handles database operations for imports, which create
+// categories, tags and todos in a single transaction
+type TransferRepository struct {
+	db *gorm.DB
+}
+
+// NewTransferRepository creates a new TransferRepository
+func NewTransferRepository(db *gorm.DB) *TransferRepository {
+	return &TransferRepository{db: db}
+}
+
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *TransferRepository) Transaction(fn func(tx *TransferRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&TransferRepository{db: tx})
+	})
+}
+
+// Todos returns a TodoRepository bound to the same transaction
+func (r *TransferRepository) Todos() *TodoRepository {
+	return &TodoRepository{db: r.db}
+}
+
+// Categories returns a CategoryRepository bound to the same transaction
+func (r *TransferRepository) Categories() *CategoryRepository {
+	return &CategoryRepository{db: r.db}
+}
+
+// Tags returns a TagRepository bound to the same transaction
+func (r *TransferRepository) Tags() *TagRepository {
+	return &TagRepository{db: r.db}
+}
+
+// FindDuplicate finds the oldest of a user's todos outside the trash with
+// the same title, ignoring case, in the same category
+func (r *TransferRepository) FindDuplicate(userID uint, title string, categoryID *uint) (*models.Todo, error) {
+	query := r.db.Where("user_id = ? AND LOWER(TRIM(title)) = ?", userID, strings.ToLower(strings.TrimSpace(title)))
+	if categoryID != nil {
+		query = query.Where("category_id = ?", *categoryID)
+	} else {
+		query = query.Where("category_id IS NULL")
+	}
+	var todo models.Todo
+	if err := query.Order("id").First(&todo).Error; err != nil {
+		return nil, err
+	}
+	return &todo, nil
+}
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"time"
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// UserRepository handles database operations for User and its refresh tokens
+type UserRepository struct {
+	db *gorm.DB
+}
+
+// NewUserRepository creates a new UserRepository
+func NewUserRepository(db *gorm.DB) *UserRepository {
+	return &UserRepository{db: db}
+}
+
+// Create creates a new user. The first user takes over the todos,
+// categories and tags created before there were accounts, which have no
+// owner.
+func (r *UserRepository) Create(user *models.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		var first uint
+		if err := tx.Model(&models.User{}).Select("MIN(id)").Scan(&first).Error; err != nil {
+			return err
+		}
+		if user.ID != first {
+			return nil
+		}
+		for _, model := range []interface{}{&models.Todo{}, &models.Category{}, &models.Tag{}} {
+			if err := tx.Unscoped().Model(model).Where("user_id IS NULL").UpdateColumn("user_id", user.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetByID gets a user by ID
+func (r *UserRepository) GetByID(id uint) (*models.User, error) {
+	var user models.User
+	err := r.db.First(&user, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetByEmail gets a user by email address
+func (r *UserRepository) GetByEmail(email string) (*models.User, error) {
+	var user models.User
+	err := r.db.Where("email = ?", email).First(&user).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// CreateRefreshToken stores a new refresh token
+func (r *UserRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetRefreshToken gets a refresh token by its hash
+func (r *UserRepository) GetRefreshToken(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RevokeRefreshToken marks a refresh token as revoked, reporting whether it was still active
+func (r *UserRepository) RevokeRefreshToken(id uint, at time.Time) (bool, error) {
+	result := r.db.Model(&models.RefreshToken{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeAllRefreshTokens revokes every active refresh token of a user
+func (r *UserRepository) RevokeAllRefreshTokens(userID uint, at time.Time) error {
+	return r.db.Model(&models.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", at).Error
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"time"
+	"todoListChallenge/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// WebhookRepository handles database operations for Webhook and WebhookDelivery
+type WebhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new WebhookRepository
+func NewWebhookRepository(db *gorm.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+// Create creates a new webhook
+func (r *WebhookRepository) Create(webhook *models.Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+// GetAll gets all webhooks of a user
+func (r *WebhookRepository) GetAll(userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.Where("user_id = ?", userID).Order("id asc").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// GetByID gets a user's webhook by ID
+func (r *WebhookRepository) GetByID(userID, id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.db.Where("user_id = ?", userID).First(&webhook, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// GetActive gets the active webhooks of a user
+func (r *WebhookRepository) GetActive(userID uint) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	err := r.db.Where("user_id = ? AND active = ?", userID, true).Find(&webhooks).Error
+	return webhooks, err
+}
+
+// Update updates a webhook
+func (r *WebhookRepository) Update(webhook *models.Webhook) error {
+	return r.db.Save(webhook).Error
+}
+
+// Delete deletes a user's webhook together with its delivery log
+func (r *WebhookRepository) Delete(userID, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ?", userID).Delete(&models.Webhook{}, id)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error
+	})
+}
+
+// CreateDeliveries queues deliveries
+func (r *WebhookRepository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Create(&deliveries).Error
+}
+
+// GetDeliveries gets the most recent deliveries of a webhook, newest first
+func (r *WebhookRepository) GetDeliveries(webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("webhook_id = ?", webhookID).Order("id desc").Limit(limit).Find(&deliveries).Error
+	return deliveries, err
+}
+
+// GetDueDeliveries gets pending deliveries whose next attempt is due, oldest first
+func (r *WebhookRepository) GetDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at asc, id asc").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+// FindWebhook gets a webhook by ID regardless of its owner, for delivery
+func (r *WebhookRepository) FindWebhook(id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.db.First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// UpdateDelivery saves the outcome of a delivery attempt
+func (r *WebhookRepository) UpdateDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
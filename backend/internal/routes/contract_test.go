@@ -0,0 +1,305 @@
+package routes
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"todoListChallenge/internal/apidocs"
+	"todoListChallenge/internal/auth"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/handlers"
+	"todoListChallenge/internal/middleware"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/openapi"
+	"todoListChallenge/internal/repository"
+	"todoListChallenge/internal/services"
+	"todoListChallenge/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupApp sets up the routes with real handlers over an in-memory database,
+// checking requests against the OpenAPI document and failing the test for
+// every response that does not match it
+func setupApp(t *testing.T) *gin.Engine {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true, Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Todo{}, &models.Category{}, &models.Tag{}, &models.AuditEntry{}, &models.Comment{}, &models.Attachment{}, &models.AttachmentBlob{}, &models.Reminder{}, &models.Notification{}, &models.CalDAVObject{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.CalendarFeed{})
+	repository.SetupSQLiteSearch(db)
+
+	spec, err := apidocs.Spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := openapi.New(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	specJSON, err := apidocs.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := auth.NewTokenManager([]byte("test-secret"), time.Hour)
+	bus := events.NewBus(events.DefaultHistorySize)
+	todoService := services.NewTodoService(repository.NewTodoRepository(db), bus)
+	categoryService := services.NewCategoryService(repository.NewCategoryRepository(db), bus)
+	authService := services.NewAuthService(repository.NewUserRepository(db), tokens, time.Hour)
+	transferService := services.NewTransferService(repository.NewTransferRepository(db), todoService, categoryService)
+	attachmentService := services.NewAttachmentService(repository.NewAttachmentRepository(db), store, services.AttachmentLimits{MaxSize: 1 << 20, Types: services.DefaultAttachmentTypes})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ValidateResponses(doc, func(c *gin.Context, err error) {
+		t.Errorf("%s %s: %v", c.Request.Method, c.Request.URL, err)
+	}))
+	SetupRoutes(router,
+		handlers.NewTodoHandler(todoService, transferService),
+		handlers.NewCategoryHandler(categoryService),
+		handlers.NewTagHandler(services.NewTagService(repository.NewTagRepository(db))),
+		handlers.NewAuthHandler(authService),
+		handlers.NewEventHandler(bus),
+		handlers.NewWebhookHandler(services.NewWebhookService(repository.NewWebhookRepository(db), http.DefaultClient)),
+		handlers.NewTrashHandler(todoService, categoryService),
+		handlers.NewAuditHandler(services.NewAuditService(repository.NewAuditRepository(db)), todoService),
+		handlers.NewCommentHandler(services.NewCommentService(repository.NewCommentRepository(db), bus)),
+		handlers.NewAttachmentHandler(attachmentService),
+		handlers.NewReminderHandler(services.NewReminderService(repository.NewReminderRepository(db), bus, nil)),
+		handlers.NewCalendarHandler(services.NewCalendarService(repository.NewCalendarRepository(db), todoService)),
+		handlers.NewCalDAVHandler(services.NewCalDAVService(repository.NewCalDAVRepository(db), todoService, categoryService), authService),
+		handlers.NewTransferHandler(transferService),
+		handlers.NewDocsHandler(specJSON),
+		middleware.RequireAuth(tokens), func(c *gin.Context) {}, middleware.ValidateRequests(doc))
+	return router
+}
+
+// call sends a request to the app and returns the status and decoded body
+func call(router *gin.Engine, token, method, target, contentType, body string) (int, map[string]interface{}) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req := httptest.NewRequest(method, target, reader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var out map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
+
+func TestRoutes_Contract(t *testing.T) {
+	router := setupApp(t)
+
+	status, out := call(router, "", "POST", "/api/auth/register", "application/json", `{"email": "ada@example.com", "name": "Ada", "password": "password1"}`)
+	if !assert.Equal(t, http.StatusCreated, status) {
+		return
+	}
+	token, _ := out["access_token"].(string)
+	status, _ = call(router, "", "POST", "/api/auth/refresh", "application/json", `{"refresh_token": "`+out["refresh_token"].(string)+`"}`)
+	assert.Equal(t, http.StatusOK, status)
+
+	// Every response of a typical session matches the document
+	steps := []struct {
+		method      string
+		target      string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"GET", "/api/auth/me", "", "", http.StatusOK},
+		{"POST", "/api/categories", "application/json", `{"name": "Work", "color": "#3B82F6"}`, http.StatusCreated},
+		{"PATCH", "/api/categories/1", "application/merge-patch+json", `{"name": "Office"}`, http.StatusOK},
+		{"POST", "/api/tags", "application/json", `{"name": "errand", "color": "#FF0000"}`, http.StatusCreated},
+		{"POST", "/api/todos", "application/json", `{"title": "Buy milk", "priority": "high", "category_id": 1, "due_date": "2030-01-02T09:00:00Z", "recurrence": "FREQ=WEEKLY", "tag_ids": [1]}`, http.StatusCreated},
+		{"GET", "/api/todos", "", "", http.StatusOK},
+		{"GET", "/api/todos?pagination=cursor&priority=high", "", "", http.StatusOK},
+		{"GET", "/api/todos/1", "", "", http.StatusOK},
+		{"PATCH", "/api/todos/1", "application/json-patch+json", `[{"op": "replace", "path": "/title", "value": "Buy oat milk"}]`, http.StatusOK},
+		{"PATCH", "/api/todos/1", "application/json-patch+json", `[{"op": "remove", "path": "/priority"}]`, http.StatusOK},
+		{"PATCH", "/api/todos/1/complete", "", "", http.StatusOK},
+		{"GET", "/api/todos/1/history", "", "", http.StatusOK},
+		{"POST", "/api/todos/1/subtasks", "application/json", `{"title": "Find a shop"}`, http.StatusCreated},
+		{"POST", "/api/todos/1/comments", "application/json", `{"body": "Ask @bob"}`, http.StatusCreated},
+		{"POST", "/api/todos/1/reminders", "application/json", `{"offset_minutes": 30}`, http.StatusCreated},
+		{"POST", "/api/todos/bulk", "application/json", `{"operations": [{"op": "set_priority", "id": 1, "priority": "low"}]}`, http.StatusOK},
+		{"POST", "/api/todos/bulk", "application/json", `{"operations": [{"op": "delete", "id": 99}]}`, http.StatusUnprocessableEntity},
+		{"POST", "/api/webhooks", "application/json", `{"url": "https://example.com/hook", "event_types": ["todo.created"]}`, http.StatusCreated},
+		{"GET", "/api/audit", "", "", http.StatusOK},
+		{"GET", "/api/export", "", "", http.StatusOK},
+		{"POST", "/api/import", "application/json", `{"todos": [{"title": "Renew passport", "priority": "low"}]}`, http.StatusOK},
+		{"POST", "/api/import/preview?format=todotxt", "text/plain", "(A) Call the bank due:2030-01-05", http.StatusOK},
+		{"DELETE", "/api/todos/1", "", "", http.StatusNoContent},
+		{"GET", "/api/trash", "", "", http.StatusOK},
+		{"GET", "/api/todos/1", "", "", http.StatusNotFound},
+		{"GET", "/api/todos", "", "", http.StatusOK},
+	}
+	for _, step := range steps {
+		status, _ := call(router, token, step.method, step.target, step.contentType, step.body)
+		assert.Equal(t, step.status, status, "%s %s", step.method, step.target)
+	}
+	status, _ = call(router, "", "GET", "/api/todos", "", "")
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestRoutes_InvalidRequests(t *testing.T) {
+	router := setupApp(t)
+
+	_, out := call(router, "", "POST", "/api/auth/register", "application/json", `{"email": "ada@example.com", "name": "Ada", "password": "password1"}`)
+	token, _ := out["access_token"].(string)
+
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		status      int
+		field       string
+	}{
+		{"unknown priority", "POST", "/api/todos", "application/json", `{"title": "Buy milk", "priority": "urgent"}`, http.StatusUnprocessableEntity, "priority"},
+		{"empty title", "POST", "/api/todos", "application/json", `{"title": ""}`, http.StatusUnprocessableEntity, "title"},
+		{"malformed body", "POST", "/api/todos", "application/json", `{"title":`, http.StatusBadRequest, ""},
+		{"wrong media type", "POST", "/api/todos", "text/plain", `{"title": "Buy milk"}`, http.StatusUnsupportedMediaType, ""},
+		{"patch priority", "PATCH", "/api/todos/1", "application/merge-patch+json", `{"priority": "urgent"}`, http.StatusUnprocessableEntity, "priority"},
+		{"bulk priority", "POST", "/api/todos/bulk", "application/json", `{"operations": [{"op": "set_priority", "id": 1, "priority": "urgent"}]}`, http.StatusUnprocessableEntity, "operations[0].priority"},
+		{"path id", "GET", "/api/todos/abc", "", "", http.StatusBadRequest, "id"},
+		{"query priority", "GET", "/api/todos?priority=urgent", "", "", http.StatusBadRequest, "priority"},
+		{"query limit", "GET", "/api/todos?limit=ten", "", "", http.StatusBadRequest, "limit"},
+		{"category color", "POST", "/api/categories", "application/json", `{"name": "Work", "color": "blue"}`, http.StatusUnprocessableEntity, "color"},
+		{"webhook event types", "POST", "/api/webhooks", "application/json", `{"url": "https://example.com/hook", "event_types": []}`, http.StatusUnprocessableEntity, "event_types"},
+		{"register email", "POST", "/api/auth/register", "application/json", `{"email": "ada", "name": "Ada", "password": "password1"}`, http.StatusUnprocessableEntity, "email"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, out := call(router, token, tt.method, tt.target, tt.contentType, tt.body)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, tt.field, out["field"])
+			assert.NotEmpty(t, out["errors"])
+		})
+	}
+}
+
+func TestRoutes_ReplaceTodoWithoutPriority(t *testing.T) {
+	router := setupApp(t)
+
+	_, out := call(router, "", "POST", "/api/auth/register", "application/json", `{"email": "ada@example.com", "name": "Ada", "password": "password1"}`)
+	token, _ := out["access_token"].(string)
+	call(router, token, "POST", "/api/todos", "application/json", `{"title": "Buy milk", "priority": "high"}`)
+
+	status, out := call(router, token, "PUT", "/api/todos/1", "application/json", `{"title": "Buy oat milk"}`)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "Buy oat milk", out["title"])
+	assert.Equal(t, "medium", out["priority"])
+}
+
+func TestRoutes_WebhookSecret(t *testing.T) {
+	router := setupApp(t)
+
+	_, out := call(router, "", "POST", "/api/auth/register", "application/json", `{"email": "ada@example.com", "name": "Ada", "password": "password1"}`)
+	token, _ := out["access_token"].(string)
+
+	status, out := call(router, token, "POST", "/api/webhooks", "application/json", `{"url": "https://example.com/hook", "event_types": ["*"]}`)
+	assert.Equal(t, http.StatusCreated, status)
+	assert.NotEmpty(t, out["secret"])
+
+	// Only the create response has the secret
+	_, out = call(router, token, "GET", "/api/webhooks/1", "", "")
+	assert.Equal(t, "https://example.com/hook", out["url"])
+	assert.NotContains(t, out, "secret")
+	_, out = call(router, token, "PUT", "/api/webhooks/1", "application/json", `{"url": "https://example.com/other", "event_types": ["*"]}`)
+	assert.NotContains(t, out, "secret")
+}
+
+func TestRoutes_IfMatch(t *testing.T) {
+	router := setupApp(t)
+
+	_, out := call(router, "", "POST", "/api/auth/register", "application/json", `{"email": "ada@example.com", "name": "Ada", "password": "password1"}`)
+	token, _ := out["access_token"].(string)
+	call(router, token, "POST", "/api/todos", "application/json", `{"title": "Buy milk"}`)
+
+	toggle := func(id, tag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/api/todos/"+id+"/complete", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("If-Match", tag)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// A change under If-Match moves the todo to the next version only
+	rec := toggle("1", `"1"`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `"2"`, rec.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusPreconditionFailed, toggle("1", `"1"`).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, toggle("99", `"1"`).Code)
+}
+
+func TestRoutes_Problems(t *testing.T) {
+	router := setupApp(t)
+
+	_, out := call(router, "", "POST", "/api/auth/register", "application/json", `{"email": "ada@example.com", "name": "Ada", "password": "password1"}`)
+	token, _ := out["access_token"].(string)
+	call(router, token, "POST", "/api/categories", "application/json", `{"name": "Work", "color": "#3B82F6"}`)
+	call(router, token, "POST", "/api/tags", "application/json", `{"name": "errand", "color": "#FF0000"}`)
+	call(router, token, "POST", "/api/todos", "application/json", `{"title": "Buy milk"}`)
+
+	// Errors of the services come back as problem details with the status
+	// for their kind; the app checks the content type against the document
+	tests := []struct {
+		name        string
+		method      string
+		target      string
+		contentType string
+		body        string
+		status      int
+		detail      string
+		field       string
+	}{
+		{"blank title", "POST", "/api/todos", "application/json", `{"title": "   "}`, http.StatusUnprocessableEntity, "title is required", "title"},
+		{"unknown category", "POST", "/api/todos", "application/json", `{"title": "Buy milk", "category_id": 99}`, http.StatusUnprocessableEntity, "category not found", "category_id"},
+		{"unknown todo", "GET", "/api/todos/99", "", "", http.StatusNotFound, "todo not found", ""},
+		{"unknown tag", "PUT", "/api/todos/1/tags/99", "", "", http.StatusNotFound, "tag not found", ""},
+		{"category name taken", "POST", "/api/categories", "application/json", `{"name": "Work", "color": "#3B82F6"}`, http.StatusConflict, "another category already has this name", "name"},
+		{"tag name taken", "POST", "/api/tags", "application/json", `{"name": "errand", "color": "#00FF00"}`, http.StatusConflict, "another tag already has this name", "name"},
+		{"not recurring", "GET", "/api/todos/1/occurrences", "", "", http.StatusConflict, "todo is not recurring", ""},
+		{"email taken", "POST", "/api/auth/register", "application/json", `{"email": "ada@example.com", "name": "Ada", "password": "password1"}`, http.StatusConflict, "email is already registered", "email"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, out := call(router, token, tt.method, tt.target, tt.contentType, tt.body)
+			assert.Equal(t, tt.status, status)
+			assert.Equal(t, float64(tt.status), out["status"])
+			assert.Equal(t, http.StatusText(tt.status), out["title"])
+			assert.Equal(t, tt.detail, out["detail"])
+			assert.Equal(t, tt.detail, out["error"])
+			if tt.field != "" {
+				assert.Equal(t, tt.field, out["field"])
+			} else {
+				assert.NotContains(t, out, "field")
+			}
+		})
+	}
+}
@@ -2,35 +2,166 @@ package routes
 
 import (
 	"todoListChallenge/internal/handlers"
+	"todoListChallenge/internal/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes sets up all routes for the application
-func SetupRoutes(router *gin.Engine, todoHandler *handlers.TodoHandler, categoryHandler *handlers.CategoryHandler) {
+// SetupRoutes sets up all routes for the application. validate checks
+// requests against the OpenAPI document; it runs after authentication, so
+// requests without a valid token are turned away first.
+func SetupRoutes(router *gin.Engine, todoHandler *handlers.TodoHandler, categoryHandler *handlers.CategoryHandler, tagHandler *handlers.TagHandler, authHandler *handlers.AuthHandler, eventHandler *handlers.EventHandler, webhookHandler *handlers.WebhookHandler, trashHandler *handlers.TrashHandler, auditHandler *handlers.AuditHandler, commentHandler *handlers.CommentHandler, attachmentHandler *handlers.AttachmentHandler, reminderHandler *handlers.ReminderHandler, calendarHandler *handlers.CalendarHandler, caldavHandler *handlers.CalDAVHandler, transferHandler *handlers.TransferHandler, docsHandler *handlers.DocsHandler, requireAuth, requireBasicAuth, validate gin.HandlerFunc) {
 	// API group
 	api := router.Group("/api")
+	{
+		// Auth routes
+		auth := api.Group("/auth", validate)
+		{
+			auth.POST("/register", authHandler.Register) // POST /api/auth/register - Create account
+			auth.POST("/login", authHandler.Login)       // POST /api/auth/login - Log in with email and password
+			auth.POST("/refresh", authHandler.Refresh)   // POST /api/auth/refresh - Exchange refresh token for new tokens
+			auth.POST("/logout", authHandler.Logout)     // POST /api/auth/logout - Revoke refresh token
+			auth.GET("/me", requireAuth, authHandler.Me) // GET /api/auth/me - Get current user
+		}
+
+		// Calendar feed, authenticated by the secret token in its address
+		api.GET("/calendar/:token", validate, calendarHandler.GetFeed) // GET /api/calendar/:token - Subscribe to todos as iCalendar
+
+		// API documentation
+		api.GET("/openapi.json", docsHandler.GetSpec) // GET /api/openapi.json - OpenAPI document of the API
+		api.GET("/docs", docsHandler.GetDocs)         // GET /api/docs - Browse the API in Swagger UI
+	}
+
+	// Routes below require a bearer access token
+	protected := api.Group("", requireAuth, validate)
 	{
 		// Todo routes
-		todos := api.Group("/todos")
+		todos := protected.Group("/todos")
 		{
-			todos.GET("", todoHandler.GetTodos)           // GET /api/todos - List todos with pagination and filters
-			todos.POST("", todoHandler.CreateTodo)        // POST /api/todos - Create new todo
-			todos.GET("/:id", todoHandler.GetTodo)        // GET /api/todos/:id - Get specific todo
-			todos.PUT("/:id", todoHandler.UpdateTodo)     // PUT /api/todos/:id - Update todo
-			todos.DELETE("/:id", todoHandler.DeleteTodo)  // DELETE /api/todos/:id - Delete todo
-			todos.PATCH("/:id/complete", todoHandler.ToggleComplete) // PATCH /api/todos/:id/complete - Toggle completion status
+			todos.GET("", todoHandler.GetTodos)                                                       // GET /api/todos - List todos with pagination and filters
+			todos.POST("", todoHandler.CreateTodo)                                                    // POST /api/todos - Create new todo
+			todos.POST("/bulk", todoHandler.BulkTodos)                                                // POST /api/todos/bulk - Run many operations in one transaction
+			todos.GET("/:id", todoHandler.GetTodo)                                                    // GET /api/todos/:id - Get specific todo
+			todos.PUT("/:id", todoHandler.UpdateTodo)                                                 // PUT /api/todos/:id - Update todo
+			todos.PATCH("/:id", todoHandler.PatchTodo)                                                // PATCH /api/todos/:id - Partially update todo
+			todos.DELETE("/:id", todoHandler.DeleteTodo)                                              // DELETE /api/todos/:id - Delete todo
+			todos.PATCH("/:id/complete", todoHandler.ToggleComplete)                                  // PATCH /api/todos/:id/complete - Toggle completion status
+			todos.GET("/:id/history", auditHandler.GetTodoHistory)                                    // GET /api/todos/:id/history - Change history
+			todos.POST("/:id/revert", todoHandler.RevertTodo)                                         // POST /api/todos/:id/revert - Revert to an earlier revision
+			todos.GET("/:id/subtasks", todoHandler.GetSubtasks)                                       // GET /api/todos/:id/subtasks - Get subtask tree
+			todos.POST("/:id/subtasks", todoHandler.CreateSubtask)                                    // POST /api/todos/:id/subtasks - Create subtask
+			todos.PUT("/:id/tags/:tagId", todoHandler.AddTag)                                         // PUT /api/todos/:id/tags/:tagId - Attach tag
+			todos.DELETE("/:id/tags/:tagId", todoHandler.RemoveTag)                                   // DELETE /api/todos/:id/tags/:tagId - Detach tag
+			todos.GET("/:id/occurrences", todoHandler.GetOccurrences)                                 // GET /api/todos/:id/occurrences - Preview upcoming due dates
+			todos.GET("/:id/comments", commentHandler.GetComments)                                    // GET /api/todos/:id/comments - List comments, oldest first
+			todos.POST("/:id/comments", commentHandler.CreateComment)                                 // POST /api/todos/:id/comments - Add comment
+			todos.GET("/:id/comments/:commentId", commentHandler.GetComment)                          // GET /api/todos/:id/comments/:commentId - Get specific comment
+			todos.PUT("/:id/comments/:commentId", commentHandler.UpdateComment)                       // PUT /api/todos/:id/comments/:commentId - Edit comment
+			todos.DELETE("/:id/comments/:commentId", commentHandler.DeleteComment)                    // DELETE /api/todos/:id/comments/:commentId - Delete comment
+			todos.GET("/:id/attachments", attachmentHandler.GetAttachments)                           // GET /api/todos/:id/attachments - List attachments
+			todos.POST("/:id/attachments", attachmentHandler.CreateAttachment)                        // POST /api/todos/:id/attachments - Upload file as multipart form field "file"
+			todos.GET("/:id/attachments/:attachmentId", attachmentHandler.GetAttachment)              // GET /api/todos/:id/attachments/:attachmentId - Get attachment metadata
+			todos.GET("/:id/attachments/:attachmentId/content", attachmentHandler.DownloadAttachment) // GET /api/todos/:id/attachments/:attachmentId/content - Download file
+			todos.DELETE("/:id/attachments/:attachmentId", attachmentHandler.DeleteAttachment)        // DELETE /api/todos/:id/attachments/:attachmentId - Delete attachment
+			todos.GET("/:id/reminders", reminderHandler.GetReminders)                                 // GET /api/todos/:id/reminders - List reminders
+			todos.POST("/:id/reminders", reminderHandler.CreateReminder)                              // POST /api/todos/:id/reminders - Add reminder at a time or before the due date
+			todos.DELETE("/:id/reminders/:reminderId", reminderHandler.DeleteReminder)                // DELETE /api/todos/:id/reminders/:reminderId - Delete reminder
 		}
 
+		// Calendar routes
+		protected.GET("/todos.ics", calendarHandler.GetCalendar)             // GET /api/todos.ics - Export todos as iCalendar with the list filters
+		protected.POST("/calendar/token", calendarHandler.CreateFeedToken)   // POST /api/calendar/token - Create or rotate the feed token
+		protected.DELETE("/calendar/token", calendarHandler.DeleteFeedToken) // DELETE /api/calendar/token - Revoke the feed token
+
+		// Export and import
+		protected.GET("/export", transferHandler.Export)           // GET /api/export - Download todos as JSON or CSV with the list filters
+		protected.POST("/import", transferHandler.Import)          // POST /api/import - Import todos from a file of this app, Todoist, todo.txt or Trello
+		protected.POST("/import/preview", transferHandler.Preview) // POST /api/import/preview - Show what an import would create without saving it
+
+		// Recurrence routes
+		protected.POST("/recurrence/preview", todoHandler.PreviewRecurrence) // POST /api/recurrence/preview - Preview occurrences of a rule
+
 		// Category routes
-		categories := api.Group("/categories")
+		categories := protected.Group("/categories")
 		{
-			categories.GET("", categoryHandler.GetCategories)        // GET /api/categories - List all categories
-			categories.POST("", categoryHandler.CreateCategory)      // POST /api/categories - Create new category
-			categories.GET("/:id", categoryHandler.GetCategory)      // GET /api/categories/:id - Get specific category
-			categories.PUT("/:id", categoryHandler.UpdateCategory)   // PUT /api/categories/:id - Update category
+			categories.GET("", categoryHandler.GetCategories)         // GET /api/categories - List all categories
+			categories.POST("", categoryHandler.CreateCategory)       // POST /api/categories - Create new category
+			categories.GET("/:id", categoryHandler.GetCategory)       // GET /api/categories/:id - Get specific category
+			categories.PUT("/:id", categoryHandler.UpdateCategory)    // PUT /api/categories/:id - Update category
+			categories.PATCH("/:id", categoryHandler.PatchCategory)   // PATCH /api/categories/:id - Partially update category
 			categories.DELETE("/:id", categoryHandler.DeleteCategory) // DELETE /api/categories/:id - Delete category
 		}
+
+		// Tag routes
+		tags := protected.Group("/tags")
+		{
+			tags.GET("", tagHandler.GetTags)          // GET /api/tags - List all tags
+			tags.POST("", tagHandler.CreateTag)       // POST /api/tags - Create new tag
+			tags.GET("/:id", tagHandler.GetTag)       // GET /api/tags/:id - Get specific tag
+			tags.PUT("/:id", tagHandler.UpdateTag)    // PUT /api/tags/:id - Update tag
+			tags.DELETE("/:id", tagHandler.DeleteTag) // DELETE /api/tags/:id - Delete tag
+		}
+
+		// Trash routes
+		trash := protected.Group("/trash")
+		{
+			trash.GET("", trashHandler.GetTrash)                                // GET /api/trash - List deleted todos and categories
+			trash.DELETE("", trashHandler.EmptyTrash)                           // DELETE /api/trash - Permanently delete everything in the trash
+			trash.POST("/todos/:id/restore", trashHandler.RestoreTodo)          // POST /api/trash/todos/:id/restore - Restore todo with its subtasks
+			trash.DELETE("/todos/:id", trashHandler.PurgeTodo)                  // DELETE /api/trash/todos/:id - Permanently delete todo
+			trash.POST("/categories/:id/restore", trashHandler.RestoreCategory) // POST /api/trash/categories/:id/restore - Restore category and its todos
+			trash.DELETE("/categories/:id", trashHandler.PurgeCategory)         // DELETE /api/trash/categories/:id - Permanently delete category
+		}
+
+		// Reminder routes
+		reminders := protected.Group("/reminders")
+		{
+			reminders.GET("", reminderHandler.GetUpcomingReminders)         // GET /api/reminders - List reminders yet to fire
+			reminders.POST("/:id/snooze", reminderHandler.SnoozeReminder)   // POST /api/reminders/:id/snooze - Fire again later
+			reminders.POST("/:id/dismiss", reminderHandler.DismissReminder) // POST /api/reminders/:id/dismiss - Stop reminder
+		}
+
+		// Notification routes
+		notifications := protected.Group("/notifications")
+		{
+			notifications.GET("", reminderHandler.GetNotifications)           // GET /api/notifications - List in-app notifications
+			notifications.POST("/:id/read", reminderHandler.ReadNotification) // POST /api/notifications/:id/read - Mark notification read
+		}
+
+		// Audit log
+		protected.GET("/audit", auditHandler.GetAuditLog) // GET /api/audit - List changes to todos and categories
+
+		// Webhook routes
+		webhooks := protected.Group("/webhooks")
+		{
+			webhooks.GET("", webhookHandler.GetWebhooks)                  // GET /api/webhooks - List webhooks
+			webhooks.POST("", webhookHandler.CreateWebhook)               // POST /api/webhooks - Create webhook
+			webhooks.GET("/:id", webhookHandler.GetWebhook)               // GET /api/webhooks/:id - Get specific webhook
+			webhooks.PUT("/:id", webhookHandler.UpdateWebhook)            // PUT /api/webhooks/:id - Update webhook
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)         // DELETE /api/webhooks/:id - Delete webhook
+			webhooks.GET("/:id/deliveries", webhookHandler.GetDeliveries) // GET /api/webhooks/:id/deliveries - Delivery log
+		}
+	}
+
+	// Change feed routes; browsers cannot set headers here, so the token may also be a query parameter
+	events := api.Group("/events", middleware.QueryToken(), requireAuth, validate)
+	{
+		events.GET("", eventHandler.Stream)       // GET /api/events - Server-Sent Events change feed
+		events.GET("/ws", eventHandler.WebSocket) // GET /api/events/ws - WebSocket change feed
+	}
+
+	// CalDAV server for calendar apps, which sign in with the account email and password
+	router.GET("/.well-known/caldav", caldavHandler.WellKnown)                // GET /.well-known/caldav - Redirect to the CalDAV principal
+	router.Handle("PROPFIND", "/.well-known/caldav", caldavHandler.WellKnown) // PROPFIND /.well-known/caldav - Redirect to the CalDAV principal
+	router.OPTIONS("/dav/*path", caldavHandler.Options)                       // OPTIONS /dav/* - Advertise CalDAV support
+	dav := router.Group("/dav", requireBasicAuth)
+	{
+		dav.Handle("PROPFIND", "/*path", caldavHandler.Propfind) // PROPFIND /dav/* - Describe principal, calendars and todos
+		dav.Handle("REPORT", "/*path", caldavHandler.Report)     // REPORT /dav/calendars/:calendar/ - calendar-query, calendar-multiget, sync-collection
+		dav.GET("/*path", caldavHandler.Get)                     // GET /dav/calendars/:calendar/:todo - Get todo as VTODO
+		dav.HEAD("/*path", caldavHandler.Get)                    // HEAD /dav/calendars/:calendar/:todo - Check todo ETag
+		dav.PUT("/*path", caldavHandler.Put)                     // PUT /dav/calendars/:calendar/:todo - Create or replace todo from VTODO
+		dav.DELETE("/*path", caldavHandler.Delete)               // DELETE /dav/calendars/:calendar/:todo - Move todo to trash
 	}
 
 	// Health check endpoint
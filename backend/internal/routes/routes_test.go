@@ -0,0 +1,83 @@
+package routes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"todoListChallenge/internal/apidocs"
+	"todoListChallenge/internal/handlers"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// ginParam matches the :name and *name parameters of a Gin path
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// openAPIMethods are the HTTP methods an OpenAPI path item can describe
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// undocumented reports whether a route is left out of the OpenAPI document on
+// purpose: the CalDAV server speaks WebDAV methods like PROPFIND, which
+// OpenAPI cannot describe
+func undocumented(path string) bool {
+	return strings.HasPrefix(path, "/dav/") || strings.HasPrefix(path, "/.well-known/")
+}
+
+// setupRouter sets up the routes with empty handlers, which only work for
+// docs
+func setupRouter(docs *handlers.DocsHandler) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	noop := func(c *gin.Context) {}
+	SetupRoutes(router, &handlers.TodoHandler{}, &handlers.CategoryHandler{}, &handlers.TagHandler{}, &handlers.AuthHandler{}, &handlers.EventHandler{}, &handlers.WebhookHandler{}, &handlers.TrashHandler{}, &handlers.AuditHandler{}, &handlers.CommentHandler{}, &handlers.AttachmentHandler{}, &handlers.ReminderHandler{}, &handlers.CalendarHandler{}, &handlers.CalDAVHandler{}, &handlers.TransferHandler{}, docs, noop, noop, noop)
+	return router
+}
+
+func TestRoutes_Documented(t *testing.T) {
+	spec, err := apidocs.Spec()
+	if !assert.NoError(t, err) {
+		return
+	}
+	paths, _ := spec["paths"].(map[string]interface{})
+
+	routes := make(map[string]bool)
+	for _, route := range setupRouter(&handlers.DocsHandler{}).Routes() {
+		if undocumented(route.Path) {
+			continue
+		}
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		routes[method+" "+path] = true
+
+		item, _ := paths[path].(map[string]interface{})
+		assert.Contains(t, item, method, "%s %s is missing from the OpenAPI document", route.Method, path)
+	}
+
+	// And the other way around, so removed routes do not linger in the docs
+	for path, item := range paths {
+		for _, method := range openAPIMethods {
+			if _, ok := item.(map[string]interface{})[method]; ok {
+				assert.True(t, routes[method+" "+path], "%s %s is documented but not routed", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestRoutes_Docs(t *testing.T) {
+	spec, err := apidocs.JSON()
+	if !assert.NoError(t, err) {
+		return
+	}
+	router := setupRouter(handlers.NewDocsHandler(spec))
+
+	for path, contentType := range map[string]string{"/api/openapi.json": "application/json", "/api/docs": "text/html"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code, path)
+		assert.Contains(t, rec.Header().Get("Content-Type"), contentType, path)
+	}
+}
@@ -0,0 +1,135 @@
+package search
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// StartMark and StopMark delimit matches in highlighted text coming from the
+// database. Control characters cannot clash with user content the way HTML
+// tags can, and Markup turns them into <mark> tags after escaping.
+const (
+	StartMark = "\x01"
+	StopMark  = "\x02"
+)
+
+// snippetLength is the length in characters of a description snippet
+const snippetLength = 160
+
+// Markup HTML-escapes text highlighted with StartMark and StopMark and wraps
+// the matches in <mark> tags
+func Markup(text string) string {
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, StartMark, "<mark>")
+	return strings.ReplaceAll(text, StopMark, "</mark>")
+}
+
+// HasMatch reports whether highlighted text contains a match
+func HasMatch(text string) bool {
+	return strings.Contains(text, StartMark)
+}
+
+// Highlight marks every occurrence of the included words in text, for
+// databases that cannot highlight matches themselves
+func (q *Query) Highlight(text string) string {
+	runes := []rune(text)
+	return q.mark(runes, q.matches(runes))
+}
+
+// Snippet returns the part of text around the first match, highlighted like
+// Highlight. It returns an empty string when nothing matches.
+func (q *Query) Snippet(text string) string {
+	runes := []rune(text)
+	matches := q.matches(runes)
+	if len(matches) == 0 {
+		return ""
+	}
+	if len(runes) <= snippetLength {
+		return q.mark(runes, matches)
+	}
+
+	start := matches[0][0] - snippetLength/4
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetLength
+	if end > len(runes) {
+		end, start = len(runes), len(runes)-snippetLength
+	}
+
+	var window [][2]int
+	for _, m := range matches {
+		if m[0] >= start && m[1] <= end {
+			window = append(window, [2]int{m[0] - start, m[1] - start})
+		}
+	}
+	snippet := q.mark(runes[start:end], window)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// matches returns the sorted, non-overlapping rune ranges of included words
+// in text. Words match at word starts, case-insensitively.
+func (q *Query) matches(runes []rune) [][2]int {
+	lower := []rune(strings.ToLower(string(runes)))
+	var ranges [][2]int
+	for _, term := range q.Include {
+		for _, word := range term.Words {
+			w := []rune(word)
+			for i := 0; i+len(w) <= len(lower); i++ {
+				if i > 0 && isWordRune(lower[i-1]) {
+					continue
+				}
+				if string(lower[i:i+len(w)]) != word {
+					continue
+				}
+				end := i + len(w)
+				for end < len(lower) && isWordRune(lower[end]) {
+					end++ // Mark the whole word, as stemming and prefixes match word forms
+				}
+				ranges = append(ranges, [2]int{i, end})
+			}
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	var merged [][2]int
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r[0] <= merged[n-1][1] {
+			if r[1] > merged[n-1][1] {
+				merged[n-1][1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// mark wraps the given ranges of text in StartMark and StopMark and returns
+// the result as escaped HTML
+func (q *Query) mark(runes []rune, ranges [][2]int) string {
+	clean := strings.NewReplacer(StartMark, "", StopMark, "")
+	var b strings.Builder
+	last := 0
+	for _, r := range ranges {
+		b.WriteString(clean.Replace(string(runes[last:r[0]])))
+		b.WriteString(StartMark)
+		b.WriteString(clean.Replace(string(runes[r[0]:r[1]])))
+		b.WriteString(StopMark)
+		last = r[1]
+	}
+	b.WriteString(clean.Replace(string(runes[last:])))
+	return Markup(b.String())
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,182 @@
+// Package search parses the full-text search syntax of the todo list and
+// translates it into the query languages of the supported databases.
+//
+// A search is a list of terms that must all match:
+//
+//	deploy            a word; matches word forms like "deploys" where the database stems
+//	deploy*           a word prefix
+//	"deploy to prod"  a phrase
+//	-staging          a term that must not match; works with phrases and prefixes too
+package search
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// maxTerms bounds the size of a search
+const maxTerms = 32
+
+// Term is a word or phrase of a search
+type Term struct {
+	// Words are the lowercased words of the term; more than one makes a phrase
+	Words []string
+	// Prefix matches the last word as a prefix
+	Prefix bool
+}
+
+// Query is a parsed search
+type Query struct {
+	// Include lists the terms that must match
+	Include []Term
+	// Exclude lists the terms that must not match
+	Exclude []Term
+}
+
+// SyntaxError reports an invalid search and the position (in characters) of
+// the offending term
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid search at position %d: %s", e.Pos, e.Msg)
+}
+
+// Parse parses a search. It returns nil when the search has no terms.
+func Parse(input string) (*Query, error) {
+	runes := []rune(input)
+	q := &Query{}
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		negated := runes[i] == '-'
+		if negated {
+			i++
+			if i == len(runes) || unicode.IsSpace(runes[i]) {
+				return nil, &SyntaxError{Pos: start, Msg: "expected a term after '-'"}
+			}
+		}
+
+		var text string
+		quoted := runes[i] == '"'
+		if quoted {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &SyntaxError{Pos: i, Msg: "unterminated phrase"}
+			}
+			text = string(runes[i+1 : end])
+			i = end + 1
+		} else {
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '"' {
+				end++
+			}
+			text = string(runes[i:end])
+			i = end
+		}
+
+		prefix := false
+		for i < len(runes) && runes[i] == '*' {
+			prefix = true
+			i++
+		}
+		if !quoted && strings.HasSuffix(text, "*") {
+			prefix = true
+		}
+
+		words := splitWords(text)
+		if len(words) == 0 {
+			if quoted || negated {
+				return nil, &SyntaxError{Pos: start, Msg: "term has no words"}
+			}
+			continue // Stray punctuation
+		}
+
+		term := Term{Words: words, Prefix: prefix}
+		if negated {
+			q.Exclude = append(q.Exclude, term)
+		} else {
+			q.Include = append(q.Include, term)
+		}
+		if len(q.Include)+len(q.Exclude) > maxTerms {
+			return nil, &SyntaxError{Pos: start, Msg: fmt.Sprintf("a search can have at most %d terms", maxTerms)}
+		}
+	}
+
+	if len(q.Include) == 0 {
+		if len(q.Exclude) > 0 {
+			return nil, &SyntaxError{Pos: 0, Msg: "a search needs at least one term that is not negated"}
+		}
+		return nil, nil
+	}
+	return q, nil
+}
+
+// splitWords lowercases text and splits it into its letter and digit runs
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// TSQuery returns the search as a PostgreSQL to_tsquery expression
+func (q *Query) TSQuery() string {
+	parts := make([]string, 0, len(q.Include)+len(q.Exclude))
+	for _, term := range q.Include {
+		parts = append(parts, term.tsquery())
+	}
+	for _, term := range q.Exclude {
+		parts = append(parts, "!"+term.tsquery())
+	}
+	return strings.Join(parts, " & ")
+}
+
+func (t Term) tsquery() string {
+	words := make([]string, len(t.Words))
+	copy(words, t.Words)
+	if t.Prefix {
+		words[len(words)-1] += ":*"
+	}
+	if len(words) == 1 {
+		return words[0]
+	}
+	return "(" + strings.Join(words, " <-> ") + ")"
+}
+
+// FTS5 returns the search as a SQLite FTS5 MATCH expression
+func (q *Query) FTS5() string {
+	include := make([]string, len(q.Include))
+	for i, term := range q.Include {
+		include[i] = term.fts5()
+	}
+	expr := "(" + strings.Join(include, " AND ") + ")"
+	for _, term := range q.Exclude {
+		expr += " NOT " + term.fts5()
+	}
+	return expr
+}
+
+func (t Term) fts5() string {
+	// Words only hold letters and digits, so they need no escaping
+	phrase := `"` + strings.Join(t.Words, " ") + `"`
+	if t.Prefix {
+		phrase += "*"
+	}
+	return phrase
+}
+
+// LikePattern returns a LIKE pattern approximating the term, for databases
+// without full-text search
+func (t Term) LikePattern() string {
+	return "%" + strings.Join(t.Words, "%") + "%"
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("terms, phrases, prefixes and negation", func(t *testing.T) {
+		q, err := Parse(`Deploy "prod server" back* -staging -"dry run" -old*`)
+		assert.NoError(t, err)
+		assert.Equal(t, []Term{
+			{Words: []string{"deploy"}},
+			{Words: []string{"prod", "server"}},
+			{Words: []string{"back"}, Prefix: true},
+		}, q.Include)
+		assert.Equal(t, []Term{
+			{Words: []string{"staging"}},
+			{Words: []string{"dry", "run"}},
+			{Words: []string{"old"}, Prefix: true},
+		}, q.Exclude)
+	})
+
+	t.Run("punctuation splits words into a phrase", func(t *testing.T) {
+		q, err := Parse("e-mail & v2.1")
+		assert.NoError(t, err)
+		assert.Equal(t, []Term{
+			{Words: []string{"e", "mail"}},
+			{Words: []string{"v2", "1"}},
+		}, q.Include)
+	})
+
+	t.Run("quoted prefix phrase", func(t *testing.T) {
+		q, err := Parse(`"release not"*`)
+		assert.NoError(t, err)
+		assert.Equal(t, []Term{{Words: []string{"release", "not"}, Prefix: true}}, q.Include)
+	})
+
+	t.Run("empty search", func(t *testing.T) {
+		q, err := Parse("   ")
+		assert.NoError(t, err)
+		assert.Nil(t, q)
+	})
+
+	t.Run("errors point at the offending term", func(t *testing.T) {
+		cases := []struct {
+			input string
+			pos   int
+			msg   string
+		}{
+			{`deploy "prod`, 7, "unterminated phrase"},
+			{`deploy - prod`, 7, "expected a term after '-'"},
+			{`deploy -""`, 7, "term has no words"},
+			{`-staging`, 0, "at least one term that is not negated"},
+		}
+		for _, tc := range cases {
+			_, err := Parse(tc.input)
+			var syntaxErr *SyntaxError
+			if assert.ErrorAs(t, err, &syntaxErr, tc.input) {
+				assert.Equal(t, tc.pos, syntaxErr.Pos, tc.input)
+				assert.Contains(t, syntaxErr.Msg, tc.msg, tc.input)
+			}
+		}
+	})
+}
+
+func TestQueryTranslation(t *testing.T) {
+	q, _ := Parse(`deploy "prod server" back* -staging -"dry run"`)
+
+	assert.Equal(t, "deploy & (prod <-> server) & back:* & !staging & !(dry <-> run)", q.TSQuery())
+	assert.Equal(t, `("deploy" AND "prod server" AND "back"*) NOT "staging" NOT "dry run"`, q.FTS5())
+	assert.Equal(t, "%prod%server%", q.Include[1].LikePattern())
+}
+
+func TestHighlight(t *testing.T) {
+	q, _ := Parse("deploy serv*")
+
+	t.Run("marks whole words and escapes HTML", func(t *testing.T) {
+		assert.Equal(t,
+			"<mark>Deploying</mark> the &lt;api&gt; <mark>server</mark>, not redeploy",
+			q.Highlight("Deploying the <api> server, not redeploy"))
+	})
+
+	t.Run("markup from the database", func(t *testing.T) {
+		assert.Equal(t, "<mark>a</mark> &amp; b", Markup(StartMark+"a"+StopMark+" & b"))
+		assert.True(t, HasMatch(StartMark+"a"+StopMark))
+		assert.False(t, HasMatch("a"))
+	})
+
+	t.Run("snippet is cut around the first match", func(t *testing.T) {
+		long := ""
+		for i := 0; i < 40; i++ {
+			long += "word "
+		}
+		snippet := q.Snippet(long + "then deploy it " + long)
+		assert.Contains(t, snippet, "<mark>deploy</mark>")
+		assert.True(t, len([]rune(snippet)) < 200)
+		assert.Equal(t, "…", string([]rune(snippet)[0]))
+
+		assert.Equal(t, "", q.Snippet("nothing to see"))
+		assert.Equal(t, "short <mark>deploy</mark>", q.Snippet("short deploy"))
+	})
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+	"todoListChallenge/internal/storage"
+
+	"gorm.io/gorm"
+)
+
+// DefaultMaxAttachmentSize is the largest attachment accepted by default, 10 MiB
+const DefaultMaxAttachmentSize = 10 << 20
+
+// DefaultAttachmentTypes are the content types accepted by default:
+// screenshots, logs and common documents
+var DefaultAttachmentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp",
+	"text/plain", "text/csv", "application/json", "application/pdf",
+	"application/zip", "application/x-gzip",
+}
+
+// attachmentCleanupInterval is how often RunAttachmentCleanup looks for unused files
+const attachmentCleanupInterval = time.Hour
+
+// attachmentCleanupBatch is how many unused files a cleanup removes at most
+const attachmentCleanupBatch = 100
+
+// ErrAttachmentTooLarge is returned for a file above the size limit
+var ErrAttachmentTooLarge = errors.New("file is too large")
+
+// ErrUnsupportedAttachment is returned for a file whose content type is not allowed
+var ErrUnsupportedAttachment = errors.New("file type is not allowed")
+
+// AttachmentLimits restricts what may be attached to todos
+type AttachmentLimits struct {
+	MaxSize int64    // Largest file in bytes
+	Types   []string // Allowed content types, as detected from the content
+}
+
+// AttachmentService handles business logic for Attachment
+type AttachmentService struct {
+	repo   *repository.AttachmentRepository
+	store  storage.Storage
+	limits AttachmentLimits
+}
+
+// NewAttachmentService creates a new AttachmentService that keeps files in
+// store. Zero limits fall back to DefaultMaxAttachmentSize and
+// DefaultAttachmentTypes.
+func NewAttachmentService(repo *repository.AttachmentRepository, store storage.Storage, limits AttachmentLimits) *AttachmentService {
+	if limits.MaxSize <= 0 {
+		limits.MaxSize = DefaultMaxAttachmentSize
+	}
+	if len(limits.Types) == 0 {
+		limits.Types = DefaultAttachmentTypes
+	}
+	return &AttachmentService{repo: repo, store: store, limits: limits}
+}
+
+// MaxSize returns the largest file accepted
+func (s *AttachmentService) MaxSize() int64 {
+	return s.limits.MaxSize
+}
+
+// CreateAttachment attaches the file read from r to a user's todo. The
+// content type is detected from the content rather than trusted from the
+// client. It fails with ErrAttachmentTooLarge or ErrUnsupportedAttachment
+// when the file breaks the limits. A file whose content is already stored
+// is not stored again.
+func (s *AttachmentService) CreateAttachment(ctx context.Context, userID, todoID uint, fileName string, r io.Reader) (*models.Attachment, error) {
+	fileName = cleanFileName(fileName)
+	if err := s.findTodo(userID, todoID); err != nil {
+		return nil, err
+	}
+
+	// Spool the upload to disk to hash it before it goes to storage
+	tmp, err := os.CreateTemp("", "attachment-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(r, s.limits.MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if size > s.limits.MaxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	contentType := detectContentType(head[:n])
+	if !s.allowed(contentType) {
+		return nil, ErrUnsupportedAttachment
+	}
+
+	attachment := &models.Attachment{
+		TodoID:      todoID,
+		UserID:      userID,
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+	}
+	if err := s.repo.Create(attachment); err != nil {
+		return nil, dbError(err, "todo")
+	}
+
+	if err := s.storeFile(ctx, tmp, attachment); err != nil {
+		if _, deleteErr := s.repo.Delete(userID, todoID, attachment.ID); deleteErr != nil {
+			log.Printf("Failed to remove attachment %d after storage error: %v", attachment.ID, deleteErr)
+		}
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// storeFile puts the content of an attachment into storage unless it is
+// there already
+func (s *AttachmentService) storeFile(ctx context.Context, file *os.File, attachment *models.Attachment) error {
+	exists, err := s.store.Exists(ctx, attachment.SHA256)
+	if err != nil || exists {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return s.store.Put(ctx, attachment.SHA256, file, attachment.Size, attachment.ContentType)
+}
+
+// GetAttachments gets the attachments of a user's todo, oldest first
+func (s *AttachmentService) GetAttachments(userID, todoID uint) ([]models.Attachment, error) {
+	if err := s.findTodo(userID, todoID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByTodo(userID, todoID)
+}
+
+// GetAttachmentByID gets a user's attachment of a todo by ID
+func (s *AttachmentService) GetAttachmentByID(userID, todoID, id uint) (*models.Attachment, error) {
+	attachment, err := s.repo.GetByID(userID, todoID, id)
+	if err != nil {
+		return nil, dbError(err, "attachment")
+	}
+	return attachment, nil
+}
+
+// OpenAttachment opens the content of an attachment; the caller closes it
+func (s *AttachmentService) OpenAttachment(ctx context.Context, attachment *models.Attachment) (io.ReadCloser, error) {
+	return s.store.Get(ctx, attachment.SHA256)
+}
+
+// DeleteAttachment deletes a user's attachment of a todo, removing its
+// content from storage unless other attachments share it
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, userID, todoID, id uint) error {
+	attachment, err := s.repo.GetByID(userID, todoID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // Deleting a missing attachment is a no-op
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := s.repo.Delete(userID, todoID, id); err != nil {
+		return err
+	}
+	_, err = s.removeBlob(ctx, attachment.SHA256)
+	return err
+}
+
+// CleanUp removes files from storage that no attachment uses any more, for
+// example because their todos were permanently deleted, and returns how
+// many it removed
+func (s *AttachmentService) CleanUp(ctx context.Context) (int, error) {
+	hashes, err := s.repo.GetUnreferencedBlobs(attachmentCleanupBatch)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, hash := range hashes {
+		ok, err := s.removeBlob(ctx, hash)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// removeBlob removes a file from storage if no attachment uses it
+func (s *AttachmentService) removeBlob(ctx context.Context, hash string) (bool, error) {
+	return s.repo.DeleteBlob(hash, func() error {
+		return s.store.Delete(ctx, hash)
+	})
+}
+
+// RunAttachmentCleanup removes unused files from storage every hour until
+// ctx is done
+func (s *AttachmentService) RunAttachmentCleanup(ctx context.Context) {
+	ticker := time.NewTicker(attachmentCleanupInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.CleanUp(ctx); err != nil {
+			log.Printf("Failed to clean up attachment storage: %v", err)
+		} else if n > 0 {
+			log.Printf("Removed %d unused files from attachment storage", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// findTodo checks that a todo outside the trash belongs to the user,
+// returning a *NotFoundError otherwise
+func (s *AttachmentService) findTodo(userID, todoID uint) error {
+	exists, err := s.repo.TodoExists(userID, todoID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &NotFoundError{Resource: "todo", Err: gorm.ErrRecordNotFound}
+	}
+	return nil
+}
+
+// allowed reports whether a content type may be attached
+func (s *AttachmentService) allowed(contentType string) bool {
+	for _, t := range s.limits.Types {
+		if strings.EqualFold(t, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// detectContentType returns the media type of a file from its first bytes,
+// without parameters like the charset
+func detectContentType(head []byte) string {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil {
+		return "application/octet-stream"
+	}
+	return mediaType
+}
+
+// cleanFileName reduces a client file name to its base name without
+// control characters, at most 255 bytes long
+func cleanFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		name = "attachment"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return strings.ToValidUTF8(name, "")
+}
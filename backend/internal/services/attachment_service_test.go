@@ -0,0 +1,151 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+	"todoListChallenge/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pngHeader is the start of a PNG file, enough for content type detection
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func setupAttachmentService(t *testing.T, limits AttachmentLimits) (*AttachmentService, *TodoService, storage.Storage) {
+	db := setupTestDB()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	todos := NewTodoService(repository.NewTodoRepository(db), nil)
+	return NewAttachmentService(repository.NewAttachmentRepository(db), store, limits), todos, store
+}
+
+func TestAttachmentService_CreateAttachment(t *testing.T) {
+	ctx := context.Background()
+	service, todos, store := setupAttachmentService(t, AttachmentLimits{MaxSize: 1024})
+
+	todo := &models.Todo{Title: "Fix login bug"}
+	todos.CreateTodo(testUserID, todo)
+
+	t.Run("success", func(t *testing.T) {
+		attachment, err := service.CreateAttachment(ctx, testUserID, todo.ID, `C:\Users\me\screenshot.png`, bytes.NewReader(pngHeader))
+
+		assert.NoError(t, err)
+		assert.NotZero(t, attachment.ID)
+		assert.Equal(t, "screenshot.png", attachment.FileName)
+		assert.Equal(t, "image/png", attachment.ContentType)
+		assert.Equal(t, int64(len(pngHeader)), attachment.Size)
+		assert.Len(t, attachment.SHA256, 64)
+
+		content, err := service.OpenAttachment(ctx, attachment)
+		assert.NoError(t, err)
+		defer content.Close()
+		data, _ := io.ReadAll(content)
+		assert.Equal(t, pngHeader, data)
+	})
+
+	t.Run("content type is detected, not trusted", func(t *testing.T) {
+		attachment, err := service.CreateAttachment(ctx, testUserID, todo.ID, "server.log", strings.NewReader("panic: nil map\n"))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "text/plain", attachment.ContentType)
+	})
+
+	t.Run("same content is stored once", func(t *testing.T) {
+		first, _ := service.CreateAttachment(ctx, testUserID, todo.ID, "a.png", bytes.NewReader(pngHeader))
+		second, err := service.CreateAttachment(ctx, testUserID, todo.ID, "b.png", bytes.NewReader(pngHeader))
+
+		assert.NoError(t, err)
+
+		assert.Equal(t, first.SHA256, second.SHA256)
+		exists, _ := store.Exists(ctx, first.SHA256)
+		assert.True(t, exists)
+	})
+
+	t.Run("file too large", func(t *testing.T) {
+		_, err := service.CreateAttachment(ctx, testUserID, todo.ID, "big.txt", strings.NewReader(strings.Repeat("a", 1025)))
+
+		assert.ErrorIs(t, err, ErrAttachmentTooLarge)
+	})
+
+	t.Run("type not allowed", func(t *testing.T) {
+		_, err := service.CreateAttachment(ctx, testUserID, todo.ID, "run.exe", bytes.NewReader([]byte("MZ\x90\x00\x03\x00\x00\x00")))
+
+		assert.ErrorIs(t, err, ErrUnsupportedAttachment)
+	})
+
+	t.Run("todo of another user", func(t *testing.T) {
+		_, err := service.CreateAttachment(ctx, 2, todo.ID, "a.png", bytes.NewReader(pngHeader))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestAttachmentService_DeleteAttachment(t *testing.T) {
+	ctx := context.Background()
+	service, todos, store := setupAttachmentService(t, AttachmentLimits{})
+
+	todo := &models.Todo{Title: "Fix login bug"}
+	todos.CreateTodo(testUserID, todo)
+	first, _ := service.CreateAttachment(ctx, testUserID, todo.ID, "a.png", bytes.NewReader(pngHeader))
+	second, _ := service.CreateAttachment(ctx, testUserID, todo.ID, "b.png", bytes.NewReader(pngHeader))
+
+	t.Run("shared content is kept", func(t *testing.T) {
+		err := service.DeleteAttachment(ctx, testUserID, todo.ID, first.ID)
+
+		assert.NoError(t, err)
+		exists, _ := store.Exists(ctx, second.SHA256)
+		assert.True(t, exists)
+		attachments, _ := service.GetAttachments(testUserID, todo.ID)
+		assert.Len(t, attachments, 1)
+	})
+
+	t.Run("last attachment removes content", func(t *testing.T) {
+		err := service.DeleteAttachment(ctx, testUserID, todo.ID, second.ID)
+
+		assert.NoError(t, err)
+		exists, _ := store.Exists(ctx, second.SHA256)
+		assert.False(t, exists)
+	})
+
+	t.Run("missing attachment is a no-op", func(t *testing.T) {
+		assert.NoError(t, service.DeleteAttachment(ctx, testUserID, todo.ID, 999))
+	})
+}
+
+func TestAttachmentService_CleanUp(t *testing.T) {
+	ctx := context.Background()
+	service, todos, store := setupAttachmentService(t, AttachmentLimits{})
+
+	todo := &models.Todo{Title: "Fix login bug"}
+	todos.CreateTodo(testUserID, todo)
+	attachment, _ := service.CreateAttachment(ctx, testUserID, todo.ID, "a.png", bytes.NewReader(pngHeader))
+
+	t.Run("todo in trash keeps its files", func(t *testing.T) {
+		assert.NoError(t, todos.DeleteTodo(testUserID, todo.ID, DeleteCascade, 0))
+
+		removed, err := service.CleanUp(ctx)
+
+		assert.NoError(t, err)
+		assert.Zero(t, removed)
+		exists, _ := store.Exists(ctx, attachment.SHA256)
+		assert.True(t, exists)
+	})
+
+	t.Run("purged todo releases its files", func(t *testing.T) {
+		assert.NoError(t, todos.PurgeTodo(testUserID, todo.ID))
+
+		removed, err := service.CleanUp(ctx)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, removed)
+		exists, _ := store.Exists(ctx, attachment.SHA256)
+		assert.False(t, exists)
+	})
+}
@@ -0,0 +1,278 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/patch"
+	"todoListChallenge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// AuditService handles business logic for the audit log
+type AuditService struct {
+	repo *repository.AuditRepository
+}
+
+// NewAuditService creates a new AuditService
+func NewAuditService(repo *repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// GetAuditLog gets a user's audit entries matching filter with pagination,
+// newest first
+func (s *AuditService) GetAuditLog(userID uint, filter repository.AuditFilter, page, limit int) ([]models.AuditEntry, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	limit = validLimit(limit)
+	return s.repo.GetAll(userID, filter, page, limit)
+}
+
+// GetTodoHistory gets the audit entries of one of a user's todos with
+// pagination, newest first
+func (s *AuditService) GetTodoHistory(userID, todoID uint, page, limit int) ([]models.AuditEntry, int64, error) {
+	return s.GetAuditLog(userID, repository.AuditFilter{EntityType: models.AuditTodo, EntityID: todoID}, page, limit)
+}
+
+// todoState is the part of a todo the audit log keeps track of
+type todoState struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Completed   bool            `json:"completed"`
+	CategoryID  *uint           `json:"category_id"`
+	ParentID    *uint           `json:"parent_id"`
+	Priority    models.Priority `json:"priority"`
+	DueDate     *time.Time      `json:"due_date"`
+	Recurrence  string          `json:"recurrence"`
+	TagIDs      []uint          `json:"tag_ids"`
+}
+
+// categoryState is the part of a category the audit log keeps track of
+type categoryState struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// todoAudit records the changes a transaction makes to a user's todos by
+// comparing them before and after
+type todoAudit struct {
+	tx     *repository.TodoRepository
+	userID uint
+	ids    []uint
+	before map[uint]*models.Todo
+}
+
+// newTodoAudit starts recording changes to todos made through tx
+func newTodoAudit(tx *repository.TodoRepository, userID uint) *todoAudit {
+	return &todoAudit{tx: tx, userID: userID, before: make(map[uint]*models.Todo)}
+}
+
+// watch takes a snapshot of todos that are about to change
+func (a *todoAudit) watch(ids ...uint) error {
+	var fresh []uint
+	for _, id := range ids {
+		if _, ok := a.before[id]; !ok {
+			a.before[id] = nil
+			fresh = append(fresh, id)
+		}
+	}
+	todos, err := a.tx.GetSnapshots(a.userID, fresh)
+	if err != nil {
+		return err
+	}
+	for i := range todos {
+		a.before[todos[i].ID] = &todos[i]
+	}
+	a.ids = append(a.ids, fresh...)
+	return nil
+}
+
+// watchAncestors takes a snapshot of the todos above parentID, which
+// syncAncestors may complete or reopen
+func (a *todoAudit) watchAncestors(parentID *uint) error {
+	for parentID != nil {
+		if err := a.watch(*parentID); err != nil {
+			return err
+		}
+		parent := a.before[*parentID]
+		if parent == nil {
+			return nil
+		}
+		parentID = parent.ParentID
+	}
+	return nil
+}
+
+// record adds an audit entry for every watched todo that changed and for the
+// created todos. Todos that were created, moved to the trash or restored get
+// the matching action, other changed todos get action.
+func (a *todoAudit) record(action string, created ...uint) error {
+	ids := append(append([]uint{}, a.ids...), created...)
+	todos, err := a.tx.GetSnapshots(a.userID, ids)
+	if err != nil {
+		return err
+	}
+	after := make(map[uint]*models.Todo, len(todos))
+	for i := range todos {
+		after[todos[i].ID] = &todos[i]
+	}
+
+	for _, id := range ids {
+		before, now := a.before[id], after[id]
+		if now == nil {
+			continue // Purged, nothing left to audit
+		}
+		entry := &models.AuditEntry{Version: now.Version}
+		var from, to map[string]interface{}
+		switch {
+		case before == nil:
+			entry.Action = models.AuditCreated
+			to = stateOf(todoStateOf(now))
+			entry.State = to
+		case now.DeletedAt.Valid && !before.DeletedAt.Valid:
+			entry.Action = models.AuditDeleted
+			from = stateOf(todoStateOf(before))
+			entry.State = from
+		case before.DeletedAt.Valid && !now.DeletedAt.Valid:
+			entry.Action = models.AuditRestored
+			from, to = stateOf(todoStateOf(before)), stateOf(todoStateOf(now))
+			entry.State = to
+		default:
+			entry.Action = action
+			from, to = stateOf(todoStateOf(before)), stateOf(todoStateOf(now))
+			entry.State = to
+		}
+		entry.Changes = diffStates(from, to)
+		if len(entry.Changes) == 0 && entry.Action != models.AuditRestored {
+			continue
+		}
+
+		entry.UserID, entry.ActorID = a.userID, a.userID
+		entry.EntityType, entry.EntityID = models.AuditTodo, id
+		if err := a.tx.AddAuditEntry(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordCategory adds an audit entry for a change to a user's category.
+// before is nil for a created category and after is nil for a deleted one.
+func recordCategory(tx *repository.CategoryRepository, userID uint, action string, before, after *models.Category) error {
+	entry := &models.AuditEntry{UserID: userID, ActorID: userID, EntityType: models.AuditCategory, Action: action}
+	var from, to map[string]interface{}
+	if before != nil {
+		from = stateOf(categoryState{Name: before.Name, Color: before.Color})
+		entry.EntityID, entry.Version, entry.State = before.ID, before.Version, from
+	}
+	if after != nil {
+		to = stateOf(categoryState{Name: after.Name, Color: after.Color})
+		entry.EntityID, entry.Version, entry.State = after.ID, after.Version, to
+	}
+	entry.Changes = diffStates(from, to)
+	if len(entry.Changes) == 0 && action == models.AuditUpdated {
+		return nil
+	}
+	return tx.AddAuditEntry(entry)
+}
+
+// todoStateOf returns the audited state of a todo loaded with its tags
+func todoStateOf(todo *models.Todo) todoState {
+	tagIDs := make([]uint, len(todo.Tags))
+	for i, tag := range todo.Tags {
+		tagIDs[i] = tag.ID
+	}
+	sort.Slice(tagIDs, func(i, j int) bool { return tagIDs[i] < tagIDs[j] })
+	return todoState{
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   todo.Completed,
+		CategoryID:  todo.CategoryID,
+		ParentID:    todo.ParentID,
+		Priority:    todo.Priority,
+		DueDate:     todo.DueDate,
+		Recurrence:  todo.Recurrence,
+		TagIDs:      tagIDs,
+	}
+}
+
+// stateOf converts a state to its JSON members, the form it is stored in
+func stateOf(state interface{}) map[string]interface{} {
+	doc, err := json.Marshal(state)
+	if err != nil {
+		panic(err) // States only hold plain values
+	}
+	var members map[string]interface{}
+	if err := json.Unmarshal(doc, &members); err != nil {
+		panic(err)
+	}
+	return members
+}
+
+// diffStates returns the fields that differ between two states. A nil state
+// stands for a todo or category that does not exist, so every field changes.
+func diffStates(before, after map[string]interface{}) map[string]models.FieldChange {
+	changes := make(map[string]models.FieldChange)
+	for key, value := range after {
+		if old, ok := before[key]; !ok || !patch.Equal(old, value) {
+			changes[key] = models.FieldChange{From: before[key], To: value}
+		}
+	}
+	for key, old := range before {
+		if _, ok := after[key]; !ok {
+			changes[key] = models.FieldChange{From: old}
+		}
+	}
+	return changes
+}
+
+// RevertTodo sets a user's todo back to its state after the change recorded
+// by an audit entry. The revert is recorded as a change of its own, so it
+// can be reverted too. It fails with a *ValidationError when the entry does
+// not belong to the todo or when its category or tags no longer exist. A
+// non-zero version must match the todo's current version.
+func (s *TodoService) RevertTodo(userID, id, entryID, version uint) (*models.Todo, error) {
+	if _, err := s.repo.FindByID(userID, id); err != nil {
+		return nil, dbError(err, "todo")
+	}
+	entry, err := s.repo.FindAuditEntry(userID, id, entryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &ValidationError{Field: "entry_id", Msg: "audit entry not found for this todo"}
+	} else if err != nil {
+		return nil, dbError(err, "audit entry")
+	}
+
+	doc, err := json.Marshal(entry.State)
+	if err != nil {
+		return nil, err
+	}
+	var state todoState
+	if err := json.Unmarshal(doc, &state); err != nil {
+		return nil, err
+	}
+
+	todo := &models.Todo{
+		ID:          id,
+		Title:       state.Title,
+		Description: state.Description,
+		Completed:   state.Completed,
+		CategoryID:  state.CategoryID,
+		ParentID:    state.ParentID,
+		Priority:    state.Priority,
+		DueDate:     state.DueDate,
+		Recurrence:  state.Recurrence,
+		TagIDs:      state.TagIDs,
+		Version:     version,
+	}
+	if todo.TagIDs == nil {
+		todo.TagIDs = []uint{}
+	}
+	if err := s.updateTodo(userID, todo, models.AuditReverted); err != nil {
+		return nil, err
+	}
+	return s.GetTodoByID(userID, id)
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+	"todoListChallenge/internal/auth"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEmailTaken is returned when registering an email that already has an account
+	ErrEmailTaken = &ConflictError{Field: "email", Msg: "email is already registered"}
+	// ErrInvalidCredentials is returned when the email or password does not match
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+)
+
+// TokenPair is returned after a successful login, registration or refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// AuthService handles registration, login and token refresh
+type AuthService struct {
+	repo       *repository.UserRepository
+	tokens     *auth.TokenManager
+	refreshTTL time.Duration
+}
+
+// NewAuthService creates a new AuthService
+func NewAuthService(repo *repository.UserRepository, tokens *auth.TokenManager, refreshTTL time.Duration) *AuthService {
+	return &AuthService{repo: repo, tokens: tokens, refreshTTL: refreshTTL}
+}
+
+// Register creates a new account and logs it in
+func (s *AuthService) Register(email, name, password string) (*models.User, *TokenPair, error) {
+	email = normalizeEmail(email)
+	if err := validateCredentials(email, password); err != nil {
+		return nil, nil, err
+	}
+
+	_, err := s.repo.GetByEmail(email)
+	if err == nil {
+		return nil, nil, ErrEmailTaken
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, nil, err
+	}
+	user := &models.User{Email: email, Name: strings.TrimSpace(name), PasswordHash: string(hash)}
+	if err := s.repo.Create(user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, nil, ErrEmailTaken // Registered at the same time
+		}
+		return nil, nil, err
+	}
+
+	pair, err := s.issueTokens(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, pair, nil
+}
+
+// Login checks the credentials and issues a new token pair
+func (s *AuthService) Login(email, password string) (*models.User, *TokenPair, error) {
+	user, err := s.CheckPassword(email, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	pair, err := s.issueTokens(user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, pair, nil
+}
+
+// CheckPassword returns the user when the email and password match
+func (s *AuthService) CheckPassword(email, password string) (*models.User, error) {
+	user, err := s.repo.GetByEmail(normalizeEmail(email))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Refresh tokens are
+// single-use: presenting one that was already used revokes every refresh
+// token of the account, since it means the token has leaked.
+func (s *AuthService) Refresh(refreshToken string) (*TokenPair, error) {
+	stored, err := s.repo.GetRefreshToken(auth.HashRefreshToken(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != nil {
+		if err := s.repo.RevokeAllRefreshTokens(stored.UserID, now); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+	if now.After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	revoked, err := s.repo.RevokeRefreshToken(stored.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, ErrInvalidRefreshToken // Used concurrently by another request
+	}
+	return s.issueTokens(stored.UserID)
+}
+
+// Logout revokes a refresh token
+func (s *AuthService) Logout(refreshToken string) error {
+	stored, err := s.repo.GetRefreshToken(auth.HashRefreshToken(refreshToken))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.repo.RevokeRefreshToken(stored.ID, time.Now())
+	return err
+}
+
+// GetUser gets a user by ID
+func (s *AuthService) GetUser(id uint) (*models.User, error) {
+	user, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, dbError(err, "user")
+	}
+	return user, nil
+}
+
+// issueTokens creates an access token and a stored refresh token for a user
+func (s *AuthService) issueTokens(userID uint) (*TokenPair, error) {
+	accessToken, err := s.tokens.IssueAccessToken(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	stored := &models.RefreshToken{UserID: userID, TokenHash: hash, ExpiresAt: time.Now().Add(s.refreshTTL)}
+	if err := s.repo.CreateRefreshToken(stored); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.tokens.AccessTTL().Seconds()),
+	}, nil
+}
+
+// normalizeEmail trims and lowercases an email address
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validateCredentials validates the email and password of a new account
+func validateCredentials(email, password string) error {
+	if email == "" {
+		return &ValidationError{Field: "email", Msg: "email is required"}
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return &ValidationError{Field: "email", Msg: "email must be a valid email address"}
+	}
+	if len(password) < 8 {
+		return &ValidationError{Field: "password", Msg: "password must be at least 8 characters"}
+	}
+	if len(password) > 72 {
+		return &ValidationError{Field: "password", Msg: "password must be at most 72 characters"}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"testing"
+	"time"
+	"todoListChallenge/internal/auth"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAuthService() (*AuthService, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+	db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Todo{}, &models.Category{}, &models.Tag{})
+	tokens := auth.NewTokenManager([]byte("test-secret"), 15*time.Minute)
+	return NewAuthService(repository.NewUserRepository(db), tokens, time.Hour), db
+}
+
+func TestAuthService_Register(t *testing.T) {
+	service, _ := setupAuthService()
+
+	t.Run("success", func(t *testing.T) {
+		user, tokens, err := service.Register(" Ada@Example.com ", "Ada", "correct horse")
+
+		assert.NoError(t, err)
+		assert.NotZero(t, user.ID)
+		assert.Equal(t, "ada@example.com", user.Email)
+		assert.NotEqual(t, "correct horse", user.PasswordHash)
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.NotEmpty(t, tokens.RefreshToken)
+		assert.Equal(t, 900, tokens.ExpiresIn)
+	})
+
+	t.Run("duplicate email", func(t *testing.T) {
+		_, _, err := service.Register("ada@example.com", "Ada", "another password")
+
+		assert.ErrorIs(t, err, ErrEmailTaken)
+	})
+
+	t.Run("validation error - invalid email", func(t *testing.T) {
+		_, _, err := service.Register("not-an-email", "", "correct horse")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "valid email address")
+	})
+
+	t.Run("validation error - short password", func(t *testing.T) {
+		_, _, err := service.Register("bob@example.com", "", "short")
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "at least 8 characters")
+	})
+}
+
+func TestAuthService_RegisterTakesOverOwnerlessRows(t *testing.T) {
+	service, db := setupAuthService()
+	// Created before there were accounts
+	db.Exec("INSERT INTO todos (title, priority) VALUES ('Buy milk', 'medium')")
+	db.Exec("INSERT INTO categories (name, color) VALUES ('Work', '#3B82F6')")
+	db.Exec("INSERT INTO tags (name, color) VALUES ('errand', '#FF0000')")
+
+	first, _, err := service.Register("ada@example.com", "Ada", "correct horse")
+	assert.NoError(t, err)
+	second, _, err := service.Register("bob@example.com", "Bob", "correct horse")
+	assert.NoError(t, err)
+
+	for _, table := range []string{"todos", "categories", "tags"} {
+		var owners []uint
+		db.Table(table).Pluck("user_id", &owners)
+		assert.Equal(t, []uint{first.ID}, owners, table)
+	}
+	assert.NotEqual(t, first.ID, second.ID)
+}
+
+func TestAuthService_Login(t *testing.T) {
+	service, _ := setupAuthService()
+	registered, _, _ := service.Register("ada@example.com", "Ada", "correct horse")
+
+	t.Run("success", func(t *testing.T) {
+		user, tokens, err := service.Login("ADA@example.com", "correct horse")
+
+		assert.NoError(t, err)
+		assert.Equal(t, registered.ID, user.ID)
+		userID, err := service.tokens.ParseAccessToken(tokens.AccessToken)
+		assert.NoError(t, err)
+		assert.Equal(t, registered.ID, userID)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, _, err := service.Login("ada@example.com", "wrong password")
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		_, _, err := service.Login("nobody@example.com", "correct horse")
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestAuthService_Refresh(t *testing.T) {
+	service, _ := setupAuthService()
+	_, first, _ := service.Register("ada@example.com", "Ada", "correct horse")
+
+	t.Run("rotates the refresh token", func(t *testing.T) {
+		second, err := service.Refresh(first.RefreshToken)
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+
+		// Reusing the old token fails and revokes the rotated one too
+		_, err = service.Refresh(first.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		_, err = service.Refresh(second.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("logout revokes the token", func(t *testing.T) {
+		_, tokens, _ := service.Login("ada@example.com", "correct horse")
+
+		assert.NoError(t, service.Logout(tokens.RefreshToken))
+
+		_, err := service.Refresh(tokens.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		_, err := service.Refresh("not-a-token")
+
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"todoListChallenge/internal/ical"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// InboxCollection is the name of the CalDAV collection of todos without a category
+const InboxCollection = "inbox"
+
+// syncTokenPrefix starts every sync token, which must be a URI
+const syncTokenPrefix = "http://todolist/ns/sync/"
+
+// ErrInvalidSyncToken is returned for a sync token that was not issued by SyncToken
+var ErrInvalidSyncToken = errors.New("invalid sync token")
+
+// ErrUnsupportedComponent is returned for calendar data without a VTODO or
+// with events or journal entries, which cannot be stored as todos
+var ErrUnsupportedComponent = errors.New("only VTODO components are supported")
+
+// ErrUIDConflict is returned when calendar data has the UID of another todo,
+// or changes the UID of the todo it replaces
+var ErrUIDConflict = &ConflictError{Msg: "the UID is in use by another todo"}
+
+// CalDAVCollection is a calendar of todos. Every category is one, and the
+// inbox holds the todos without a category.
+type CalDAVCollection struct {
+	Name        string // Path segment: the category ID, or InboxCollection
+	DisplayName string
+	Color       string
+	CategoryID  *uint
+}
+
+// CalDAVObject is a todo as a calendar object resource
+type CalDAVObject struct {
+	Name      string // Resource name within its collection
+	UID       string
+	ParentUID string // UID of the parent todo, empty for top-level todos
+	Todo      *models.Todo
+}
+
+// CalDAVService maps CalDAV collections and calendar object resources onto
+// categories and todos. Todos are changed through the TodoService, so the
+// same validation, audit log and events apply as for the REST API.
+type CalDAVService struct {
+	repo       *repository.CalDAVRepository
+	todos      *TodoService
+	categories *CategoryService
+}
+
+// NewCalDAVService creates a new CalDAVService
+func NewCalDAVService(repo *repository.CalDAVRepository, todos *TodoService, categories *CategoryService) *CalDAVService {
+	return &CalDAVService{repo: repo, todos: todos, categories: categories}
+}
+
+// Collections gets the collections of a user: the inbox, then one per category
+func (s *CalDAVService) Collections(userID uint) ([]CalDAVCollection, error) {
+	categories, err := s.categories.GetCategories(userID)
+	if err != nil {
+		return nil, err
+	}
+	collections := []CalDAVCollection{inbox()}
+	for i := range categories {
+		collections = append(collections, categoryCollection(&categories[i]))
+	}
+	return collections, nil
+}
+
+// Collection gets a user's collection by name, returning
+// gorm.ErrRecordNotFound when there is no such collection
+func (s *CalDAVService) Collection(userID uint, name string) (*CalDAVCollection, error) {
+	if name == InboxCollection {
+		collection := inbox()
+		return &collection, nil
+	}
+	id, err := strconv.ParseUint(name, 10, 32)
+	if err != nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	category, err := s.categories.GetCategoryByID(userID, uint(id))
+	if err != nil {
+		return nil, err
+	}
+	collection := categoryCollection(category)
+	return &collection, nil
+}
+
+// Objects gets the todos of a collection, optionally only those with the
+// given completion status
+func (s *CalDAVService) Objects(userID uint, collection *CalDAVCollection, completed *bool) ([]CalDAVObject, error) {
+	todos, err := s.repo.GetTodos(userID, collection.CategoryID, completed)
+	if err != nil {
+		return nil, err
+	}
+	return s.objects(userID, todos)
+}
+
+// Object gets the todo of a collection with the given resource name,
+// returning gorm.ErrRecordNotFound when the collection has no such todo
+func (s *CalDAVService) Object(userID uint, collection *CalDAVCollection, name string) (*CalDAVObject, error) {
+	var id uint
+	object, err := s.repo.GetObjectByName(userID, collection.CategoryID, name)
+	switch {
+	case err == nil:
+		id = object.TodoID
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if id = defaultTodoID(name); id == 0 {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	todo, err := s.repo.GetTodo(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !sameID(todo.CategoryID, collection.CategoryID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	objects, err := s.objects(userID, []models.Todo{*todo})
+	if err != nil {
+		return nil, err
+	}
+	if objects[0].Name != name {
+		return nil, gorm.ErrRecordNotFound // Only reachable by the name its client gave it
+	}
+	return &objects[0], nil
+}
+
+// FindObject gets the todo of a collection with the given resource name
+// like Object, but returns nil without an error when there is none
+func (s *CalDAVService) FindObject(userID uint, collection *CalDAVCollection, name string) (*CalDAVObject, error) {
+	object, err := s.Object(userID, collection, name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return object, err
+}
+
+// SyncToken returns a token that stands for the current state of a user's
+// todos. It changes whenever any of them changes.
+func (s *CalDAVService) SyncToken(userID uint) (string, error) {
+	last, err := s.repo.LastChange(userID)
+	if err != nil {
+		return "", err
+	}
+	var micros int64
+	if !last.IsZero() {
+		micros = last.UnixMicro()
+	}
+	return syncTokenPrefix + strconv.FormatInt(micros, 10), nil
+}
+
+// Changes gets what changed in a collection since the state a sync token
+// stands for: the todos added or changed and the names of the todos that
+// were removed, by being deleted or moved to another collection. An empty
+// token gets every todo. It also returns the token of the new state. Todos
+// that changed in the instant the old token was issued may be reported
+// again; todos purged from the trash are not reported.
+func (s *CalDAVService) Changes(userID uint, collection *CalDAVCollection, token string) ([]CalDAVObject, []string, string, error) {
+	next, err := s.SyncToken(userID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if token == "" {
+		objects, err := s.Objects(userID, collection, nil)
+		return objects, nil, next, err
+	}
+	micros, err := strconv.ParseInt(strings.TrimPrefix(token, syncTokenPrefix), 10, 64)
+	if err != nil || !strings.HasPrefix(token, syncTokenPrefix) {
+		return nil, nil, "", ErrInvalidSyncToken
+	}
+
+	todos, err := s.repo.GetChangedTodos(userID, time.UnixMicro(micros))
+	if err != nil {
+		return nil, nil, "", err
+	}
+	all, err := s.objects(userID, todos)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	var changed []CalDAVObject
+	var removed []string
+	for _, object := range all {
+		if object.Todo.DeletedAt.Valid || !sameID(object.Todo.CategoryID, collection.CategoryID) {
+			removed = append(removed, object.Name)
+		} else {
+			changed = append(changed, object)
+		}
+	}
+	return changed, removed, next, nil
+}
+
+// PutObject stores calendar data holding a VTODO as the todo with the given
+// resource name in a collection: a new todo when existing is nil, else the
+// existing todo, replacing its title, description, priority, due date,
+// recurrence and completion. Its tags and parent stay as they are.
+// Completing a recurring todo creates its next occurrence, like
+// TodoService.ToggleComplete. Invalid calendar data or todo fields are a
+// *ValidationError, and a UID of another todo is ErrUIDConflict. A new todo
+// is created in one transaction with its object.
+func (s *CalDAVService) PutObject(userID uint, collection *CalDAVCollection, name string, existing *CalDAVObject, data io.Reader) (*CalDAVObject, error) {
+	vtodo, err := parseVTODO(data)
+	if err != nil {
+		return nil, err
+	}
+	todo, completed, err := todoFromVTODO(vtodo)
+	if err != nil {
+		return nil, err
+	}
+	uid := vtodo.Text("UID")
+
+	if existing == nil {
+		todo.CategoryID = collection.CategoryID
+		todo.Completed = completed
+		var pending []pendingEvent
+		err := s.repo.Transaction(func(tx *repository.CalDAVRepository) error {
+			if err := tx.ReleaseUID(userID, uid); err != nil {
+				return err
+			}
+			todos := &TodoService{repo: tx.Todos(), pending: &pending}
+			if err := todos.CreateTodo(userID, todo); err != nil {
+				return err
+			}
+			return tx.CreateObject(&models.CalDAVObject{UserID: userID, TodoID: todo.ID, Name: name, UID: uid})
+		})
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return nil, ErrUIDConflict
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.todos.publishPending(pending)
+	} else {
+		if uid != existing.UID {
+			return nil, ErrUIDConflict
+		}
+		current := existing.Todo
+		todo.ID = current.ID
+		todo.CategoryID = current.CategoryID
+		todo.ParentID = current.ParentID
+		todo.Completed = current.Completed
+		todo.Version = current.Version
+		if err := s.todos.UpdateTodo(userID, todo); err != nil {
+			return nil, err
+		}
+		if completed != current.Completed {
+			if err := s.todos.ToggleComplete(userID, todo.ID, 0); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s.Object(userID, collection, name)
+}
+
+// DeleteObject moves the todo of a resource to the trash. Its subtasks move
+// up to its parent rather than disappearing along with it. A non-zero
+// version must match the todo's current version.
+func (s *CalDAVService) DeleteObject(userID uint, object *CalDAVObject, version uint) error {
+	return s.todos.DeleteTodo(userID, object.Todo.ID, DeleteReparent, version)
+}
+
+// WriteObject writes a resource as a VCALENDAR holding its VTODO
+func WriteObject(w io.Writer, object *CalDAVObject) error {
+	cal := ical.NewWriter(w)
+	cal.Begin("VCALENDAR")
+	cal.Line("VERSION", "2.0")
+	cal.Line("PRODID", calendarProductID)
+	writeTodo(cal, object.Todo, object.UID, object.ParentUID, object.Todo.UpdatedAt)
+	cal.End("VCALENDAR")
+	return cal.Flush()
+}
+
+// objects returns the resources of todos, with the names and UIDs their
+// clients gave them
+func (s *CalDAVService) objects(userID uint, todos []models.Todo) ([]CalDAVObject, error) {
+	ids := make([]uint, 0, len(todos))
+	for _, todo := range todos {
+		ids = append(ids, todo.ID)
+		if todo.ParentID != nil {
+			ids = append(ids, *todo.ParentID)
+		}
+	}
+	stored, err := s.repo.GetObjects(userID, ids)
+	if err != nil {
+		return nil, err
+	}
+	uid := func(id uint) string {
+		if object, ok := stored[id]; ok {
+			return object.UID
+		}
+		return todoUID(id)
+	}
+
+	objects := make([]CalDAVObject, len(todos))
+	for i := range todos {
+		object := CalDAVObject{Name: fmt.Sprintf("todo-%d.ics", todos[i].ID), UID: uid(todos[i].ID), Todo: &todos[i]}
+		if stored, ok := stored[todos[i].ID]; ok {
+			object.Name = stored.Name
+		}
+		if todos[i].ParentID != nil {
+			object.ParentUID = uid(*todos[i].ParentID)
+		}
+		objects[i] = object
+	}
+	return objects, nil
+}
+
+// parseVTODO reads calendar data and returns the VTODO it holds. Time zone
+// definitions are skipped, as are overrides of single occurrences, which
+// todos have no place for.
+func parseVTODO(data io.Reader) (*ical.Component, error) {
+	cal, err := ical.Parse(data)
+	if err != nil {
+		return nil, &ValidationError{Field: "calendar_data", Msg: "invalid iCalendar data: " + err.Error()}
+	}
+	if cal.Name != "VCALENDAR" {
+		return nil, &ValidationError{Field: "calendar_data", Msg: "calendar data must be a VCALENDAR"}
+	}
+	var vtodo *ical.Component
+	for _, component := range cal.Components {
+		switch component.Name {
+		case "VTIMEZONE":
+		case "VTODO":
+			if component.Prop("RECURRENCE-ID") == nil {
+				if vtodo != nil {
+					return nil, &ValidationError{Field: "calendar_data", Msg: "calendar data must hold a single todo"}
+				}
+				vtodo = component
+			}
+		default:
+			return nil, ErrUnsupportedComponent
+		}
+	}
+	if vtodo == nil {
+		return nil, ErrUnsupportedComponent
+	}
+	if strings.TrimSpace(vtodo.Text("UID")) == "" {
+		return nil, &ValidationError{Field: "uid", Msg: "UID is required"}
+	}
+	return vtodo, nil
+}
+
+// todoFromVTODO returns the todo a VTODO describes and whether it is completed
+func todoFromVTODO(vtodo *ical.Component) (*models.Todo, bool, error) {
+	todo := &models.Todo{
+		Title:       vtodo.Text("SUMMARY"),
+		Description: vtodo.Text("DESCRIPTION"),
+		Priority:    todoPriority(vtodo.Prop("PRIORITY")),
+	}
+	if due := vtodo.Prop("DUE"); due != nil {
+		t, err := ical.ParseTime(due)
+		if err != nil {
+			return nil, false, &ValidationError{Field: "due_date", Msg: "invalid DUE: " + due.Value}
+		}
+		todo.DueDate = &t
+	}
+	if rule := vtodo.Prop("RRULE"); rule != nil {
+		todo.Recurrence = rule.Value
+	}
+
+	completed := vtodo.Prop("COMPLETED") != nil
+	if status := vtodo.Prop("STATUS"); status != nil {
+		completed = strings.EqualFold(status.Value, "COMPLETED")
+	}
+	return todo, completed, nil
+}
+
+// todoPriority maps an iCalendar priority to a todo priority: 1 to 4 is
+// high, 6 to 9 low, and anything else medium
+func todoPriority(p *ical.Property) models.Priority {
+	if p == nil {
+		return models.PriorityMedium
+	}
+	switch n, _ := strconv.Atoi(strings.TrimSpace(p.Value)); {
+	case n >= 1 && n <= 4:
+		return models.PriorityHigh
+	case n >= 6 && n <= 9:
+		return models.PriorityLow
+	}
+	return models.PriorityMedium
+}
+
+// inbox returns the collection of todos without a category
+func inbox() CalDAVCollection {
+	return CalDAVCollection{Name: InboxCollection, DisplayName: "Inbox"}
+}
+
+// categoryCollection returns the collection of a category
+func categoryCollection(category *models.Category) CalDAVCollection {
+	id := category.ID
+	return CalDAVCollection{Name: strconv.FormatUint(uint64(id), 10), DisplayName: category.Name, Color: category.Color, CategoryID: &id}
+}
+
+// defaultTodoID returns the todo ID in a resource name like todo-7.ics, or 0
+func defaultTodoID(name string) uint {
+	digits, ok := strings.CutPrefix(strings.TrimSuffix(name, ".ics"), "todo-")
+	if !ok || !strings.HasSuffix(name, ".ics") {
+		return 0
+	}
+	id, err := strconv.ParseUint(digits, 10, 32)
+	if err != nil || strconv.FormatUint(id, 10) != digits {
+		return 0
+	}
+	return uint(id)
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupCalDAVService() (*CalDAVService, *TodoService, *gorm.DB) {
+	db := setupTestDB()
+	todos := NewTodoService(repository.NewTodoRepository(db), nil)
+	categories := NewCategoryService(repository.NewCategoryRepository(db), nil)
+	return NewCalDAVService(repository.NewCalDAVRepository(db), todos, categories), todos, db
+}
+
+// vtodo returns calendar data holding a single VTODO with the given properties
+func vtodo(props ...string) string {
+	return "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//Test//EN\r\nBEGIN:VTODO\r\n" +
+		strings.Join(props, "\r\n") + "\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+}
+
+func TestCalDAVService_Collections(t *testing.T) {
+	service, _, db := setupCalDAVService()
+	category := &models.Category{UserID: testUserID, Name: "Work", Color: "#ff0000"}
+	db.Create(category)
+	db.Create(&models.Category{UserID: 2, Name: "Not mine"})
+
+	t.Run("inbox and categories", func(t *testing.T) {
+		collections, err := service.Collections(testUserID)
+
+		assert.NoError(t, err)
+		assert.Len(t, collections, 2)
+		assert.Equal(t, InboxCollection, collections[0].Name)
+		assert.Nil(t, collections[0].CategoryID)
+		assert.Equal(t, "Work", collections[1].DisplayName)
+		assert.Equal(t, "#ff0000", collections[1].Color)
+	})
+
+	t.Run("by name", func(t *testing.T) {
+		collection, err := service.Collection(testUserID, "1")
+		assert.NoError(t, err)
+		assert.Equal(t, category.ID, *collection.CategoryID)
+
+		_, err = service.Collection(testUserID, "2")
+		assert.Error(t, err)
+		_, err = service.Collection(testUserID, "work")
+		assert.Error(t, err)
+	})
+}
+
+func TestCalDAVService_PutObject(t *testing.T) {
+	service, todos, db := setupCalDAVService()
+	category := &models.Category{UserID: testUserID, Name: "Work"}
+	db.Create(category)
+	work, _ := service.Collection(testUserID, "1")
+	inbox, _ := service.Collection(testUserID, InboxCollection)
+
+	var created *CalDAVObject
+	t.Run("create", func(t *testing.T) {
+		data := vtodo("UID:abc-123", "SUMMARY:Submit report", "DESCRIPTION:Line one\\nline two", "PRIORITY:1", "DUE;TZID=Europe/Berlin:20261102T093000")
+
+		object, err := service.PutObject(testUserID, work, "abc-123.ics", nil, strings.NewReader(data))
+
+		assert.NoError(t, err)
+		created = object
+		assert.Equal(t, "abc-123.ics", object.Name)
+		assert.Equal(t, "abc-123", object.UID)
+		assert.Equal(t, "Submit report", object.Todo.Title)
+		assert.Equal(t, "Line one\nline two", object.Todo.Description)
+		assert.Equal(t, models.PriorityHigh, object.Todo.Priority)
+		assert.Equal(t, "2026-11-02T08:30:00Z", object.Todo.DueDate.UTC().Format("2006-01-02T15:04:05Z"))
+		assert.Equal(t, category.ID, *object.Todo.CategoryID)
+	})
+
+	t.Run("found by its name only", func(t *testing.T) {
+		object, err := service.Object(testUserID, work, "abc-123.ics")
+		assert.NoError(t, err)
+		assert.Equal(t, created.Todo.ID, object.Todo.ID)
+
+		_, err = service.Object(testUserID, work, "todo-1.ics")
+		assert.Error(t, err)
+		_, err = service.Object(testUserID, inbox, "abc-123.ics")
+		assert.Error(t, err)
+	})
+
+	t.Run("todos from the REST API have default names", func(t *testing.T) {
+		todo := &models.Todo{Title: "Water plants"}
+		todos.CreateTodo(testUserID, todo)
+
+		object, err := service.Object(testUserID, inbox, "todo-2.ics")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "todo-2@todolist", object.UID)
+	})
+
+	t.Run("update keeps tags and completes", func(t *testing.T) {
+		tag := &models.Tag{UserID: testUserID, Name: "q4"}
+		db.Create(tag)
+		todo, _ := todos.GetTodoByID(testUserID, created.Todo.ID)
+		todo.TagIDs = []uint{tag.ID}
+		todos.UpdateTodo(testUserID, todo)
+		existing, _ := service.Object(testUserID, work, "abc-123.ics")
+
+		data := vtodo("UID:abc-123", "SUMMARY:Submit final report", "PRIORITY:9", "STATUS:COMPLETED")
+		object, err := service.PutObject(testUserID, work, "abc-123.ics", existing, strings.NewReader(data))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Submit final report", object.Todo.Title)
+		assert.Equal(t, models.PriorityLow, object.Todo.Priority)
+		assert.Nil(t, object.Todo.DueDate)
+		assert.True(t, object.Todo.Completed)
+		assert.Greater(t, object.Todo.Version, existing.Todo.Version)
+		found, _ := todos.GetTodoByID(testUserID, created.Todo.ID)
+		assert.Len(t, found.Tags, 1)
+	})
+
+	t.Run("uid conflicts", func(t *testing.T) {
+		var before int64
+		db.Model(&models.Todo{}).Count(&before)
+		_, err := service.PutObject(testUserID, inbox, "other.ics", nil, strings.NewReader(vtodo("UID:abc-123", "SUMMARY:Copy")))
+		assert.ErrorIs(t, err, ErrUIDConflict)
+		var after int64
+		db.Model(&models.Todo{}).Count(&after)
+		assert.Equal(t, before, after) // The todo is rolled back with its object
+
+		existing, _ := service.Object(testUserID, work, "abc-123.ics")
+		_, err = service.PutObject(testUserID, work, "abc-123.ics", existing, strings.NewReader(vtodo("UID:xyz", "SUMMARY:Renamed")))
+		assert.ErrorIs(t, err, ErrUIDConflict)
+	})
+
+	t.Run("invalid calendar data", func(t *testing.T) {
+		cases := []struct {
+			data  string
+			field string
+		}{
+			{"not a calendar", "calendar_data"},
+			{vtodo("SUMMARY:No UID"), "uid"},
+			{vtodo("UID:no-title"), "title"},
+			{vtodo("UID:bad-due", "SUMMARY:Bad due", "DUE:tomorrow"), "due_date"},
+		}
+		for _, tc := range cases {
+			_, err := service.PutObject(testUserID, inbox, "new.ics", nil, strings.NewReader(tc.data))
+
+			var validationErr *ValidationError
+			assert.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tc.field, validationErr.Field)
+		}
+	})
+
+	t.Run("events are not supported", func(t *testing.T) {
+		data := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:event\r\nSUMMARY:Meeting\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+		_, err := service.PutObject(testUserID, inbox, "event.ics", nil, strings.NewReader(data))
+
+		assert.ErrorIs(t, err, ErrUnsupportedComponent)
+	})
+}
+
+func TestCalDAVService_Changes(t *testing.T) {
+	service, todos, db := setupCalDAVService()
+	category := &models.Category{UserID: testUserID, Name: "Work"}
+	db.Create(category)
+	work, _ := service.Collection(testUserID, "1")
+	inbox, _ := service.Collection(testUserID, InboxCollection)
+
+	service.PutObject(testUserID, inbox, "kept.ics", nil, strings.NewReader(vtodo("UID:kept", "SUMMARY:Kept")))
+	moved, _ := service.PutObject(testUserID, inbox, "moved.ics", nil, strings.NewReader(vtodo("UID:moved", "SUMMARY:Moved")))
+	deleted, _ := service.PutObject(testUserID, inbox, "deleted.ics", nil, strings.NewReader(vtodo("UID:deleted", "SUMMARY:Deleted")))
+
+	t.Run("initial sync", func(t *testing.T) {
+		changed, removed, token, err := service.Changes(testUserID, inbox, "")
+
+		assert.NoError(t, err)
+		assert.Len(t, changed, 3)
+		assert.Empty(t, removed)
+		assert.True(t, strings.HasPrefix(token, syncTokenPrefix))
+	})
+
+	t.Run("moves and deletions are removals", func(t *testing.T) {
+		// Back-date the todos so that the changes below are after the token
+		db.Exec("UPDATE todos SET updated_at = datetime(updated_at, '-1 minute')")
+		token, _ := service.SyncToken(testUserID)
+
+		todo, _ := todos.GetTodoByID(testUserID, moved.Todo.ID)
+		todo.CategoryID = &category.ID
+		todos.UpdateTodo(testUserID, todo)
+		assert.NoError(t, service.DeleteObject(testUserID, deleted, 0))
+
+		changed, removed, next, err := service.Changes(testUserID, inbox, token)
+
+		assert.NoError(t, err)
+		assert.Empty(t, changed)
+		assert.ElementsMatch(t, []string{"moved.ics", "deleted.ics"}, removed)
+		assert.NotEqual(t, token, next)
+
+		changed, _, _, _ = service.Changes(testUserID, work, token)
+		assert.Len(t, changed, 1)
+		assert.Equal(t, "moved.ics", changed[0].Name)
+	})
+
+	t.Run("a deleted name can be taken again", func(t *testing.T) {
+		object, err := service.PutObject(testUserID, inbox, "deleted.ics", nil, strings.NewReader(vtodo("UID:deleted", "SUMMARY:Deleted again")))
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, deleted.Todo.ID, object.Todo.ID)
+		found, _ := service.Object(testUserID, inbox, "deleted.ics")
+		assert.Equal(t, "Deleted again", found.Todo.Title)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, _, _, err := service.Changes(testUserID, inbox, "http://example.com/sync/1")
+
+		assert.ErrorIs(t, err, ErrInvalidSyncToken)
+	})
+}
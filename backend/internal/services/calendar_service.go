@@ -0,0 +1,188 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"todoListChallenge/internal/auth"
+	"todoListChallenge/internal/ical"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+)
+
+// calendarProductID identifies this application in the calendars it writes
+const calendarProductID = "-//todoListChallenge//Todo List//EN"
+
+// calendarRefreshInterval is how often subscribed clients are asked to reload the feed
+const calendarRefreshInterval = "PT1H"
+
+// CalendarService writes a user's todos as an iCalendar feed and manages the
+// secret tokens the feed can be subscribed to with
+type CalendarService struct {
+	repo  *repository.CalendarRepository
+	todos *TodoService
+	now   func() time.Time
+}
+
+// NewCalendarService creates a new CalendarService that lists todos through todos
+func NewCalendarService(repo *repository.CalendarRepository, todos *TodoService) *CalendarService {
+	return &CalendarService{repo: repo, todos: todos, now: time.Now}
+}
+
+// CreateFeedToken gives a user a new feed token and returns it. Any token
+// the user had before stops working. Only its hash is kept, so the token
+// cannot be shown again later.
+func (s *CalendarService) CreateFeedToken(userID uint) (string, error) {
+	token, hash, err := auth.NewFeedToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.repo.SetFeed(&models.CalendarFeed{UserID: userID, TokenHash: hash}); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// DeleteFeedToken stops a user's feed token from working
+func (s *CalendarService) DeleteFeedToken(userID uint) error {
+	_, err := s.repo.DeleteFeed(userID)
+	return err
+}
+
+// FeedUser returns the user a feed token belongs to, or a *NotFoundError
+// for an unknown token
+func (s *CalendarService) FeedUser(token string) (uint, error) {
+	feed, err := s.repo.GetFeedByHash(auth.HashFeedToken(token))
+	if err != nil {
+		return 0, dbError(err, "calendar feed")
+	}
+	return feed.UserID, nil
+}
+
+// WriteCalendar writes a user's todos matching a search and filters to w as
+// an iCalendar stream. Every todo is a VTODO; a todo with a due date is
+// also a VEVENT at that time, since many calendar apps show no VTODOs.
+func (s *CalendarService) WriteCalendar(w io.Writer, userID uint, searchText string, filters map[string]interface{}) error {
+	cal := ical.NewWriter(w)
+	stamp := s.now()
+
+	cal.Begin("VCALENDAR")
+	cal.Line("VERSION", "2.0")
+	cal.Line("PRODID", calendarProductID)
+	cal.Line("CALSCALE", "GREGORIAN")
+	cal.Line("METHOD", "PUBLISH")
+	cal.Text("X-WR-CALNAME", "Todos")
+	cal.Line("REFRESH-INTERVAL;VALUE=DURATION", calendarRefreshInterval)
+	cal.Line("X-PUBLISHED-TTL", calendarRefreshInterval)
+
+	err := s.todos.EachTodo(userID, searchText, filters, func(todos []models.Todo) error {
+		for i := range todos {
+			var parentUID string
+			if todos[i].ParentID != nil {
+				parentUID = todoUID(*todos[i].ParentID)
+			}
+			writeTodo(cal, &todos[i], todoUID(todos[i].ID), parentUID, stamp)
+			if todos[i].DueDate != nil {
+				writeDueEvent(cal, &todos[i], stamp)
+			}
+		}
+		// Stop early when the client has gone away
+		return cal.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	cal.End("VCALENDAR")
+	return cal.Flush()
+}
+
+// writeTodo writes a todo as a VTODO with the given UID, related to the
+// VTODO of its parent by parentUID unless that is empty
+func writeTodo(cal *ical.Writer, todo *models.Todo, uid, parentUID string, stamp time.Time) {
+	cal.Begin("VTODO")
+	cal.Text("UID", uid)
+	cal.Time("DTSTAMP", stamp)
+	cal.Time("CREATED", todo.CreatedAt)
+	cal.Time("LAST-MODIFIED", todo.UpdatedAt)
+	cal.Line("SEQUENCE", strconv.Itoa(int(todo.Version)-1))
+	cal.Text("SUMMARY", todo.Title)
+	if todo.Description != "" {
+		cal.Text("DESCRIPTION", todo.Description)
+	}
+	if todo.DueDate != nil {
+		cal.Time("DUE", *todo.DueDate)
+	}
+	cal.Line("PRIORITY", strconv.Itoa(calendarPriority(todo.Priority)))
+	if todo.Completed {
+		cal.Line("STATUS", "COMPLETED")
+		cal.Line("PERCENT-COMPLETE", "100")
+	} else {
+		cal.Line("STATUS", "NEEDS-ACTION")
+	}
+	writeCategories(cal, todo)
+	if todo.Recurrence != "" {
+		cal.Line("RRULE", todo.Recurrence)
+	}
+	if parentUID != "" {
+		cal.Text("RELATED-TO", parentUID)
+	}
+	cal.End("VTODO")
+}
+
+// writeDueEvent writes the due date of a todo as a VEVENT that does not
+// block time
+func writeDueEvent(cal *ical.Writer, todo *models.Todo, stamp time.Time) {
+	cal.Begin("VEVENT")
+	cal.Line("UID", fmt.Sprintf("todo-%d-due@todolist", todo.ID))
+	cal.Time("DTSTAMP", stamp)
+	cal.Time("CREATED", todo.CreatedAt)
+	cal.Time("LAST-MODIFIED", todo.UpdatedAt)
+	cal.Line("SEQUENCE", strconv.Itoa(int(todo.Version)-1))
+	cal.Time("DTSTART", *todo.DueDate)
+	cal.Text("SUMMARY", todo.Title)
+	if todo.Description != "" {
+		cal.Text("DESCRIPTION", todo.Description)
+	}
+	cal.Line("PRIORITY", strconv.Itoa(calendarPriority(todo.Priority)))
+	cal.Line("TRANSP", "TRANSPARENT")
+	writeCategories(cal, todo)
+	if todo.Recurrence != "" {
+		cal.Line("RRULE", todo.Recurrence)
+	}
+	cal.End("VEVENT")
+}
+
+// writeCategories writes the category of a todo followed by its tags as CATEGORIES
+func writeCategories(cal *ical.Writer, todo *models.Todo) {
+	var categories []string
+	if todo.Category != nil {
+		categories = append(categories, todo.Category.Name)
+	}
+	for _, tag := range todo.Tags {
+		categories = append(categories, tag.Name)
+	}
+	if len(categories) > 0 {
+		cal.List("CATEGORIES", categories)
+	}
+}
+
+// todoUID returns the iCalendar UID of a todo
+func todoUID(id uint) string {
+	return fmt.Sprintf("todo-%d@todolist", id)
+}
+
+// calendarPriority maps a todo priority to the iCalendar scale, where 1 is
+// the highest, 9 the lowest and 0 undefined
+func calendarPriority(priority models.Priority) int {
+	switch priority {
+	case models.PriorityHigh:
+		return 1
+	case models.PriorityMedium:
+		return 5
+	case models.PriorityLow:
+		return 9
+	}
+	return 0
+}
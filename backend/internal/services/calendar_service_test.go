@@ -0,0 +1,109 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupCalendarService() (*CalendarService, *TodoService, *gorm.DB) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.CalendarFeed{})
+	todos := NewTodoService(repository.NewTodoRepository(db), nil)
+	service := NewCalendarService(repository.NewCalendarRepository(db), todos)
+	service.now = func() time.Time { return time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC) }
+	return service, todos, db
+}
+
+func TestCalendarService_WriteCalendar(t *testing.T) {
+	service, todos, db := setupCalendarService()
+
+	category := &models.Category{UserID: testUserID, Name: "Work, urgent"}
+	db.Create(category)
+	tag := &models.Tag{UserID: testUserID, Name: "q4"}
+	db.Create(tag)
+	due := time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC)
+	dated := &models.Todo{Title: "Submit report", Description: "Line one\nline two", Priority: models.PriorityHigh, DueDate: &due, CategoryID: &category.ID, TagIDs: []uint{tag.ID}, Recurrence: "FREQ=WEEKLY"}
+	todos.CreateTodo(testUserID, dated)
+	undated := &models.Todo{Title: "Someday", Priority: models.PriorityLow}
+	todos.CreateTodo(testUserID, undated)
+	todos.ToggleComplete(testUserID, undated.ID, 0)
+	todos.CreateTodo(2, &models.Todo{Title: "Not mine"})
+
+	t.Run("todos and due dates", func(t *testing.T) {
+		var out strings.Builder
+		err := service.WriteCalendar(&out, testUserID, "", map[string]interface{}{})
+
+		assert.NoError(t, err)
+		cal := out.String()
+		assert.True(t, strings.HasPrefix(cal, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+		assert.True(t, strings.HasSuffix(cal, "END:VCALENDAR\r\n"))
+		assert.Equal(t, 2, strings.Count(cal, "BEGIN:VTODO\r\n"))
+		assert.Equal(t, 1, strings.Count(cal, "BEGIN:VEVENT\r\n"))
+		assert.NotContains(t, cal, "Not mine")
+
+		assert.Contains(t, cal, "UID:todo-1@todolist\r\n")
+		assert.Contains(t, cal, "DTSTAMP:20261018T090000Z\r\n")
+		assert.Contains(t, cal, `DESCRIPTION:Line one\nline two`+"\r\n")
+		assert.Contains(t, cal, "DUE:20261102T093000Z\r\n")
+		assert.Contains(t, cal, "PRIORITY:1\r\n")
+		assert.Contains(t, cal, `CATEGORIES:Work\, urgent,q4`+"\r\n")
+		assert.Contains(t, cal, "RRULE:FREQ=WEEKLY\r\n")
+		assert.Contains(t, cal, "STATUS:NEEDS-ACTION\r\n")
+		assert.Contains(t, cal, "UID:todo-1-due@todolist\r\nDTSTAMP")
+		assert.Contains(t, cal, "DTSTART:20261102T093000Z\r\n")
+
+		assert.Contains(t, cal, "PRIORITY:9\r\n")
+		assert.Contains(t, cal, "STATUS:COMPLETED\r\n")
+	})
+
+	t.Run("honors list filters", func(t *testing.T) {
+		var out strings.Builder
+		err := service.WriteCalendar(&out, testUserID, "", map[string]interface{}{"completed": true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(out.String(), "BEGIN:VTODO\r\n"))
+		assert.Contains(t, out.String(), "SUMMARY:Someday\r\n")
+		assert.NotContains(t, out.String(), "BEGIN:VEVENT")
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		var out strings.Builder
+		err := service.WriteCalendar(&out, testUserID, "", map[string]interface{}{"q": "priority:"})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestCalendarService_FeedToken(t *testing.T) {
+	service, _, _ := setupCalendarService()
+
+	token, err := service.CreateFeedToken(testUserID)
+	assert.NoError(t, err)
+
+	userID, err := service.FeedUser(token)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(testUserID), userID)
+
+	t.Run("rotating revokes the old token", func(t *testing.T) {
+		rotated, err := service.CreateFeedToken(testUserID)
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, token, rotated)
+		_, err = service.FeedUser(token)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+		token = rotated
+	})
+
+	t.Run("deleted token stops working", func(t *testing.T) {
+		assert.NoError(t, service.DeleteFeedToken(testUserID))
+
+		_, err := service.FeedUser(token)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+}
@@ -4,65 +4,152 @@ import (
 	"errors"
 	"regexp"
 	"strings"
+	"todoListChallenge/internal/events"
 	"todoListChallenge/internal/models"
 	"todoListChallenge/internal/repository"
+
+	"gorm.io/gorm"
 )
 
+// ErrNameTaken is returned when creating, renaming or restoring a category
+// with the name of another category
+var ErrNameTaken = &ConflictError{Field: "name", Msg: "another category already has this name"}
+
 // CategoryService handles business logic for Category
 type CategoryService struct {
 	repo *repository.CategoryRepository
+	bus  *events.Bus
 }
 
-// NewCategoryService creates a new CategoryService
-func NewCategoryService(repo *repository.CategoryRepository) *CategoryService {
-	return &CategoryService{repo: repo}
+// NewCategoryService creates a new CategoryService that publishes changes to
+// bus. bus may be nil when no change feed is needed.
+func NewCategoryService(repo *repository.CategoryRepository, bus *events.Bus) *CategoryService {
+	return &CategoryService{repo: repo, bus: bus}
 }
 
-// CreateCategory creates a new category with validation
-func (s *CategoryService) CreateCategory(category *models.Category) error {
+// CreateCategory creates a new category for a user with validation
+func (s *CategoryService) CreateCategory(userID uint, category *models.Category) error {
 	if err := s.validateCategory(category); err != nil {
 		return err
 	}
-	return s.repo.Create(category)
+	category.UserID = userID
+	err := s.repo.Transaction(func(tx *repository.CategoryRepository) error {
+		if err := tx.Create(category); err != nil {
+			return err
+		}
+		return recordCategory(tx, userID, models.AuditCreated, nil, category)
+	})
+	if err != nil {
+		return categoryError(err)
+	}
+	s.bus.Publish(userID, events.CategoryCreated, category)
+	return nil
 }
 
-// GetCategories gets all categories
-func (s *CategoryService) GetCategories() ([]models.Category, error) {
-	return s.repo.GetAll()
+// GetCategories gets all categories of a user
+func (s *CategoryService) GetCategories(userID uint) ([]models.Category, error) {
+	return s.repo.GetAll(userID)
 }
 
-// GetCategoryByID gets a category by ID
-func (s *CategoryService) GetCategoryByID(id uint) (*models.Category, error) {
-	return s.repo.GetByID(id)
+// GetCategoryByID gets a user's category by ID
+func (s *CategoryService) GetCategoryByID(userID, id uint) (*models.Category, error) {
+	category, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, dbError(err, "category")
+	}
+	return category, nil
 }
 
-// UpdateCategory updates a category with validation
-func (s *CategoryService) UpdateCategory(category *models.Category) error {
+// UpdateCategory updates a user's category with validation. When
+// category.Version is set, the update only applies to that version of the
+// category and fails with ErrVersionMismatch otherwise.
+func (s *CategoryService) UpdateCategory(userID uint, category *models.Category) error {
 	if err := s.validateCategory(category); err != nil {
 		return err
 	}
-	return s.repo.Update(category)
+	existing, err := s.repo.GetByID(userID, category.ID)
+	if err != nil {
+		return dbError(err, "category")
+	}
+	category.UserID = userID
+	category.CreatedAt = existing.CreatedAt
+	version := category.Version
+	if version == 0 {
+		version = existing.Version
+	}
+	err = s.repo.Transaction(func(tx *repository.CategoryRepository) error {
+		updated, err := tx.Update(category, version)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrVersionMismatch
+		}
+		return recordCategory(tx, userID, models.AuditUpdated, existing, category)
+	})
+	if err != nil {
+		return categoryError(err)
+	}
+	s.bus.Publish(userID, events.CategoryUpdated, category)
+	return nil
 }
 
-// DeleteCategory deletes a category
-func (s *CategoryService) DeleteCategory(id uint) error {
-	return s.repo.Delete(id)
+// DeleteCategory deletes a user's category. A non-zero version must match
+// the category's current version.
+func (s *CategoryService) DeleteCategory(userID, id, version uint) error {
+	var deleted bool
+	err := s.repo.Transaction(func(tx *repository.CategoryRepository) error {
+		existing, err := tx.GetByID(userID, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if deleted, err = tx.Delete(userID, id, version); err != nil || !deleted {
+			return err
+		}
+		return recordCategory(tx, userID, models.AuditDeleted, existing, nil)
+	})
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		if version == 0 {
+			return nil // Deleting a missing category is a no-op
+		}
+		if _, err := s.repo.GetByID(userID, id); err != nil {
+			return dbError(err, "category")
+		}
+		return ErrVersionMismatch
+	}
+	s.bus.Publish(userID, events.CategoryDeleted, events.Deleted{ID: id})
+	return nil
+}
+
+// categoryError turns an error of the database about a category into an
+// error of the services; the only unique key of a category is its name
+func categoryError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrNameTaken
+	}
+	return dbError(err, "category")
 }
 
 // validateCategory validates category fields
 func (s *CategoryService) validateCategory(category *models.Category) error {
 	if strings.TrimSpace(category.Name) == "" {
-		return errors.New("name is required")
+		return &ValidationError{Field: "name", Msg: "name is required"}
 	}
 	if len(category.Name) > 255 {
-		return errors.New("name must be less than 255 characters")
+		return &ValidationError{Field: "name", Msg: "name must be less than 255 characters"}
 	}
 
 	// Validate hex color format
 	colorRegex := regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
 	if !colorRegex.MatchString(category.Color) {
-		return errors.New("color must be a valid hex color (e.g., #3B82F6)")
+		return &ValidationError{Field: "color", Msg: "color must be a valid hex color (e.g., #3B82F6)"}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
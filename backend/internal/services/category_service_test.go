@@ -3,6 +3,7 @@ package services
 import (
 	"testing"
 	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/patch"
 	"todoListChallenge/internal/repository"
 
 	"github.com/stretchr/testify/assert"
@@ -11,29 +12,35 @@ import (
 )
 
 func setupCategoryTestDB() *gorm.DB {
-	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&models.Category{})
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+	db.AutoMigrate(&models.Category{}, &models.Todo{}, &models.AuditEntry{})
 	return db
 }
 
 func TestCategoryService_CreateCategory(t *testing.T) {
 	db := setupCategoryTestDB()
 	repo := repository.NewCategoryRepository(db)
-	service := NewCategoryService(repo)
+	service := NewCategoryService(repo, nil)
 
 	t.Run("success", func(t *testing.T) {
 		category := &models.Category{Name: "Work", Color: "#3B82F6"}
 
-		err := service.CreateCategory(category)
+		err := service.CreateCategory(testUserID, category)
 
 		assert.NoError(t, err)
 		assert.NotZero(t, category.ID)
 	})
 
+	t.Run("duplicate name", func(t *testing.T) {
+		err := service.CreateCategory(testUserID, &models.Category{Name: "Work", Color: "#10B981"})
+
+		assert.ErrorIs(t, err, ErrNameTaken)
+	})
+
 	t.Run("validation error - empty name", func(t *testing.T) {
 		category := &models.Category{Name: "", Color: "#3B82F6"}
 
-		err := service.CreateCategory(category)
+		err := service.CreateCategory(testUserID, category)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "name is required")
@@ -42,7 +49,7 @@ func TestCategoryService_CreateCategory(t *testing.T) {
 	t.Run("validation error - name too long", func(t *testing.T) {
 		category := &models.Category{Name: string(make([]byte, 256)), Color: "#3B82F6"}
 
-		err := service.CreateCategory(category)
+		err := service.CreateCategory(testUserID, category)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "name must be less than 255 characters")
@@ -51,7 +58,7 @@ func TestCategoryService_CreateCategory(t *testing.T) {
 	t.Run("validation error - invalid color format", func(t *testing.T) {
 		category := &models.Category{Name: "Work", Color: "invalid"}
 
-		err := service.CreateCategory(category)
+		err := service.CreateCategory(testUserID, category)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "color must be a valid hex color")
@@ -60,7 +67,7 @@ func TestCategoryService_CreateCategory(t *testing.T) {
 	t.Run("validation error - color without hash", func(t *testing.T) {
 		category := &models.Category{Name: "Work", Color: "3B82F6"}
 
-		err := service.CreateCategory(category)
+		err := service.CreateCategory(testUserID, category)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "color must be a valid hex color")
@@ -69,7 +76,7 @@ func TestCategoryService_CreateCategory(t *testing.T) {
 	t.Run("validation error - color too short", func(t *testing.T) {
 		category := &models.Category{Name: "Work", Color: "#3B82"}
 
-		err := service.CreateCategory(category)
+		err := service.CreateCategory(testUserID, category)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "color must be a valid hex color")
@@ -79,14 +86,14 @@ func TestCategoryService_CreateCategory(t *testing.T) {
 func TestCategoryService_GetCategories(t *testing.T) {
 	db := setupCategoryTestDB()
 	repo := repository.NewCategoryRepository(db)
-	service := NewCategoryService(repo)
+	service := NewCategoryService(repo, nil)
 
 	// Create some categories
-	service.CreateCategory(&models.Category{Name: "Work", Color: "#3B82F6"})
-	service.CreateCategory(&models.Category{Name: "Personal", Color: "#10B981"})
+	service.CreateCategory(testUserID, &models.Category{Name: "Work", Color: "#3B82F6"})
+	service.CreateCategory(testUserID, &models.Category{Name: "Personal", Color: "#10B981"})
 
 	t.Run("success", func(t *testing.T) {
-		categories, err := service.GetCategories()
+		categories, err := service.GetCategories(testUserID)
 
 		assert.NoError(t, err)
 		assert.Len(t, categories, 2)
@@ -96,14 +103,14 @@ func TestCategoryService_GetCategories(t *testing.T) {
 func TestCategoryService_GetCategoryByID(t *testing.T) {
 	db := setupCategoryTestDB()
 	repo := repository.NewCategoryRepository(db)
-	service := NewCategoryService(repo)
+	service := NewCategoryService(repo, nil)
 
 	// Create a category first
 	category := &models.Category{Name: "Work", Color: "#3B82F6"}
-	service.CreateCategory(category)
+	service.CreateCategory(testUserID, category)
 
 	t.Run("success", func(t *testing.T) {
-		found, err := service.GetCategoryByID(category.ID)
+		found, err := service.GetCategoryByID(testUserID, category.ID)
 
 		assert.NoError(t, err)
 		assert.Equal(t, category.ID, found.ID)
@@ -111,9 +118,11 @@ func TestCategoryService_GetCategoryByID(t *testing.T) {
 	})
 
 	t.Run("not found", func(t *testing.T) {
-		found, err := service.GetCategoryByID(999)
+		found, err := service.GetCategoryByID(testUserID, 999)
 
-		assert.Error(t, err)
+		var notFound *NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
 		assert.Nil(t, found)
 	})
 }
@@ -121,28 +130,28 @@ func TestCategoryService_GetCategoryByID(t *testing.T) {
 func TestCategoryService_UpdateCategory(t *testing.T) {
 	db := setupCategoryTestDB()
 	repo := repository.NewCategoryRepository(db)
-	service := NewCategoryService(repo)
+	service := NewCategoryService(repo, nil)
 
 	// Create a category first
 	category := &models.Category{Name: "Work", Color: "#3B82F6"}
-	service.CreateCategory(category)
+	service.CreateCategory(testUserID, category)
 
 	t.Run("success", func(t *testing.T) {
 		category.Name = "Work Updated"
 		category.Color = "#EF4444"
-		err := service.UpdateCategory(category)
+		err := service.UpdateCategory(testUserID, category)
 
 		assert.NoError(t, err)
 
 		// Verify update
-		updated, _ := service.GetCategoryByID(category.ID)
+		updated, _ := service.GetCategoryByID(testUserID, category.ID)
 		assert.Equal(t, "Work Updated", updated.Name)
 		assert.Equal(t, "#EF4444", updated.Color)
 	})
 
 	t.Run("validation error", func(t *testing.T) {
 		category.Name = ""
-		err := service.UpdateCategory(category)
+		err := service.UpdateCategory(testUserID, category)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "name is required")
@@ -151,30 +160,228 @@ func TestCategoryService_UpdateCategory(t *testing.T) {
 	t.Run("validation error - invalid color", func(t *testing.T) {
 		category.Name = "Work"
 		category.Color = "invalid"
-		err := service.UpdateCategory(category)
+		err := service.UpdateCategory(testUserID, category)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "color must be a valid hex color")
 	})
 }
 
+func TestCategoryService_Versions(t *testing.T) {
+	db := setupCategoryTestDB()
+	service := NewCategoryService(repository.NewCategoryRepository(db), nil)
+
+	category := &models.Category{Name: "Work", Color: "#3B82F6"}
+	service.CreateCategory(testUserID, category)
+	assert.Equal(t, uint(1), category.Version)
+
+	t.Run("updates bump the version", func(t *testing.T) {
+		err := service.UpdateCategory(testUserID, &models.Category{ID: category.ID, Name: "Office", Color: "#3B82F6", Version: 1})
+
+		assert.NoError(t, err)
+		found, _ := service.GetCategoryByID(testUserID, category.ID)
+		assert.Equal(t, uint(2), found.Version)
+	})
+
+	t.Run("stale versions are rejected", func(t *testing.T) {
+		err := service.UpdateCategory(testUserID, &models.Category{ID: category.ID, Name: "Lost", Color: "#3B82F6", Version: 1})
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+
+		assert.ErrorIs(t, service.DeleteCategory(testUserID, category.ID, 1), ErrVersionMismatch)
+		found, _ := service.GetCategoryByID(testUserID, category.ID)
+		assert.Equal(t, "Office", found.Name)
+	})
+
+	t.Run("delete with the current version", func(t *testing.T) {
+		assert.NoError(t, service.DeleteCategory(testUserID, category.ID, 2))
+
+		_, err := service.GetCategoryByID(testUserID, category.ID)
+		assert.Error(t, err)
+	})
+}
+
+func TestCategoryService_PatchCategory(t *testing.T) {
+	db := setupCategoryTestDB()
+	service := NewCategoryService(repository.NewCategoryRepository(db), nil)
+
+	category := &models.Category{Name: "Work", Color: "#3B82F6"}
+	service.CreateCategory(testUserID, category)
+
+	t.Run("merge patch keeps the other fields", func(t *testing.T) {
+		patched, err := service.PatchCategory(testUserID, category.ID, 1, func(doc []byte) ([]byte, error) {
+			return patch.MergePatch(doc, []byte(`{"name": "Office"}`))
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Office", patched.Name)
+		assert.Equal(t, "#3B82F6", patched.Color)
+		assert.Equal(t, uint(2), patched.Version)
+	})
+
+	t.Run("JSON Patch", func(t *testing.T) {
+		patched, err := service.PatchCategory(testUserID, category.ID, 0, func(doc []byte) ([]byte, error) {
+			return patch.Apply(doc, []byte(`[{"op": "replace", "path": "/color", "value": "#EF4444"}]`))
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "#EF4444", patched.Color)
+	})
+
+	t.Run("invalid result", func(t *testing.T) {
+		_, err := service.PatchCategory(testUserID, category.ID, 0, func(doc []byte) ([]byte, error) {
+			return patch.MergePatch(doc, []byte(`{"color": "red"}`))
+		})
+
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "color", validationErr.Field)
+	})
+}
+
 func TestCategoryService_DeleteCategory(t *testing.T) {
 	db := setupCategoryTestDB()
 	repo := repository.NewCategoryRepository(db)
-	service := NewCategoryService(repo)
+	service := NewCategoryService(repo, nil)
 
 	// Create a category first
 	category := &models.Category{Name: "Work", Color: "#3B82F6"}
-	service.CreateCategory(category)
+	service.CreateCategory(testUserID, category)
 
 	t.Run("success", func(t *testing.T) {
-		err := service.DeleteCategory(category.ID)
+		err := service.DeleteCategory(testUserID, category.ID, 0)
 
 		assert.NoError(t, err)
 
 		// Verify deletion
-		found, err := service.GetCategoryByID(category.ID)
+		found, err := service.GetCategoryByID(testUserID, category.ID)
+		assert.Error(t, err)
+		assert.Nil(t, found)
+	})
+}
+
+func TestCategoryService_Trash(t *testing.T) {
+	db := setupCategoryTestDB()
+	service := NewCategoryService(repository.NewCategoryRepository(db), nil)
+	todos := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	category := &models.Category{Name: "Work", Color: "#3B82F6"}
+	service.CreateCategory(testUserID, category)
+	kept := &models.Todo{Title: "Write report", CategoryID: &category.ID}
+	todos.CreateTodo(testUserID, kept)
+	moved := &models.Todo{Title: "Book flights", CategoryID: &category.ID}
+	todos.CreateTodo(testUserID, moved)
+
+	t.Run("deleting a category unlinks its todos", func(t *testing.T) {
+		assert.NoError(t, service.DeleteCategory(testUserID, category.ID, 0))
+
+		_, err := service.GetCategoryByID(testUserID, category.ID)
+		assert.Error(t, err)
+		trash, _ := service.GetTrash(testUserID)
+		assert.Len(t, trash, 1)
+
+		found, _ := todos.GetTodoByID(testUserID, kept.ID)
+		assert.Nil(t, found.CategoryID)
+	})
+
+	t.Run("the name is free while the category is in the trash", func(t *testing.T) {
+		other := &models.Category{Name: "Work", Color: "#EF4444"}
+		assert.NoError(t, service.CreateCategory(testUserID, other))
+
+		_, err := service.RestoreCategory(testUserID, category.ID)
+		assert.ErrorIs(t, err, ErrNameTaken)
+
+		assert.NoError(t, service.DeleteCategory(testUserID, other.ID, 0))
+		assert.NoError(t, service.PurgeCategory(testUserID, other.ID))
+	})
+
+	t.Run("restore links the todos again", func(t *testing.T) {
+		travel := &models.Category{Name: "Travel", Color: "#10B981"}
+		service.CreateCategory(testUserID, travel)
+		update := &models.Todo{ID: moved.ID, Title: "Book flights", CategoryID: &travel.ID}
+		assert.NoError(t, todos.UpdateTodo(testUserID, update))
+
+		restored, err := service.RestoreCategory(testUserID, category.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Work", restored.Name)
+
+		found, _ := todos.GetTodoByID(testUserID, kept.ID)
+		assert.Equal(t, &category.ID, found.CategoryID)
+		found, _ = todos.GetTodoByID(testUserID, moved.ID)
+		assert.Equal(t, &travel.ID, found.CategoryID) // Moved elsewhere in the meantime
+	})
+
+	t.Run("empty trash", func(t *testing.T) {
+		assert.NoError(t, service.DeleteCategory(testUserID, category.ID, 0))
+		assert.NoError(t, service.EmptyTrash(testUserID))
+
+		trash, _ := service.GetTrash(testUserID)
+		assert.Empty(t, trash)
+		_, err := service.RestoreCategory(testUserID, category.ID)
+		assert.Error(t, err)
+	})
+}
+
+func TestCategoryService_Audit(t *testing.T) {
+	db := setupCategoryTestDB()
+	service := NewCategoryService(repository.NewCategoryRepository(db), nil)
+	audit := NewAuditService(repository.NewAuditRepository(db))
+
+	category := &models.Category{Name: "Work", Color: "#3B82F6"}
+	service.CreateCategory(testUserID, category)
+	update := &models.Category{ID: category.ID, Name: "Office", Color: "#3B82F6"}
+	service.UpdateCategory(testUserID, update)
+	service.DeleteCategory(testUserID, category.ID, 0)
+	service.RestoreCategory(testUserID, category.ID)
+
+	filter := repository.AuditFilter{EntityType: models.AuditCategory, EntityID: category.ID}
+	entries, total, err := audit.GetAuditLog(testUserID, filter, 1, 10)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+	assert.Equal(t, models.AuditRestored, entries[0].Action)
+	assert.Equal(t, models.AuditDeleted, entries[1].Action)
+	assert.Equal(t, models.AuditUpdated, entries[2].Action)
+	assert.Equal(t, models.FieldChange{From: "Work", To: "Office"}, entries[2].Changes["name"])
+	assert.Len(t, entries[2].Changes, 1)
+	assert.Equal(t, models.AuditCreated, entries[3].Action)
+}
+
+func TestCategoryService_UserScoping(t *testing.T) {
+	db := setupCategoryTestDB()
+	repo := repository.NewCategoryRepository(db)
+	service := NewCategoryService(repo, nil)
+
+	const otherUserID uint = 2
+	category := &models.Category{Name: "Work", Color: "#3B82F6"}
+	service.CreateCategory(testUserID, category)
+
+	t.Run("same name allowed for another user", func(t *testing.T) {
+		err := service.CreateCategory(otherUserID, &models.Category{Name: "Work", Color: "#10B981"})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("other users cannot read", func(t *testing.T) {
+		found, err := service.GetCategoryByID(otherUserID, category.ID)
+
 		assert.Error(t, err)
 		assert.Nil(t, found)
+		categories, _ := service.GetCategories(otherUserID)
+		assert.Len(t, categories, 1)
+	})
+
+	t.Run("other users cannot update", func(t *testing.T) {
+		err := service.UpdateCategory(otherUserID, &models.Category{ID: category.ID, Name: "Stolen", Color: "#000000"})
+
+		assert.Error(t, err)
+		found, _ := service.GetCategoryByID(testUserID, category.ID)
+		assert.Equal(t, "Work", found.Name)
+	})
+
+	t.Run("other users cannot delete", func(t *testing.T) {
+		service.DeleteCategory(otherUserID, category.ID, 0)
+
+		_, err := service.GetCategoryByID(testUserID, category.ID)
+		assert.NoError(t, err)
 	})
 }
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/mention"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// maxCommentLength is the longest comment body in bytes
+const maxCommentLength = 10000
+
+// CommentService handles business logic for Comment
+type CommentService struct {
+	repo *repository.CommentRepository
+	bus  *events.Bus
+}
+
+// NewCommentService creates a new CommentService that publishes changes to
+// bus. bus may be nil when no change feed is needed.
+func NewCommentService(repo *repository.CommentRepository, bus *events.Bus) *CommentService {
+	return &CommentService{repo: repo, bus: bus}
+}
+
+// CreateComment adds a comment to a user's todo with validation
+func (s *CommentService) CreateComment(userID, todoID uint, comment *models.Comment) error {
+	if err := s.validateComment(comment); err != nil {
+		return err
+	}
+	if err := s.findTodo(userID, todoID); err != nil {
+		return err
+	}
+	comment.ID = 0
+	comment.TodoID = todoID
+	comment.UserID = userID
+	comment.Mentions = mentions(comment.Body)
+	comment.EditedAt = nil
+	if err := s.repo.Create(comment); err != nil {
+		return dbError(err, "comment")
+	}
+	s.bus.Publish(userID, events.CommentCreated, comment)
+	return nil
+}
+
+// GetComments gets the comments on a user's todo, oldest first
+func (s *CommentService) GetComments(userID, todoID uint) ([]models.Comment, error) {
+	if err := s.findTodo(userID, todoID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByTodo(userID, todoID)
+}
+
+// GetCommentByID gets a user's comment on a todo by ID
+func (s *CommentService) GetCommentByID(userID, todoID, id uint) (*models.Comment, error) {
+	comment, err := s.repo.GetByID(userID, todoID, id)
+	if err != nil {
+		return nil, dbError(err, "comment")
+	}
+	return comment, nil
+}
+
+// UpdateComment changes the body of a user's comment and marks it as edited
+func (s *CommentService) UpdateComment(userID, todoID, id uint, body string) (*models.Comment, error) {
+	comment, err := s.GetCommentByID(userID, todoID, id)
+	if err != nil {
+		return nil, err
+	}
+	if comment.Body == body {
+		return comment, nil
+	}
+	comment.Body = body
+	if err := s.validateComment(comment); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	comment.Mentions = mentions(comment.Body)
+	comment.EditedAt = &now
+	if err := s.repo.Update(comment); err != nil {
+		return nil, dbError(err, "comment")
+	}
+	s.bus.Publish(userID, events.CommentUpdated, comment)
+	return comment, nil
+}
+
+// DeleteComment deletes a user's comment on a todo
+func (s *CommentService) DeleteComment(userID, todoID, id uint) error {
+	comment, err := s.repo.GetByID(userID, todoID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // Deleting a missing comment is a no-op
+	}
+	if err != nil {
+		return err
+	}
+	deleted, err := s.repo.Delete(userID, todoID, id)
+	if err != nil || !deleted {
+		return err
+	}
+	s.bus.Publish(userID, events.CommentDeleted, comment)
+	return nil
+}
+
+// findTodo checks that a todo outside the trash belongs to the user,
+// returning a *NotFoundError otherwise
+func (s *CommentService) findTodo(userID, todoID uint) error {
+	exists, err := s.repo.TodoExists(userID, todoID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return &NotFoundError{Resource: "todo", Err: gorm.ErrRecordNotFound}
+	}
+	return nil
+}
+
+// validateComment validates comment fields
+func (s *CommentService) validateComment(comment *models.Comment) error {
+	if strings.TrimSpace(comment.Body) == "" {
+		return &ValidationError{Field: "body", Msg: "body is required"}
+	}
+	if len(comment.Body) > maxCommentLength {
+		return &ValidationError{Field: "body", Msg: "body must be at most 10000 characters"}
+	}
+	return nil
+}
+
+// mentions returns the handles mentioned in a comment body, never nil
+func mentions(body string) []string {
+	handles := mention.Parse(body)
+	if handles == nil {
+		return []string{}
+	}
+	return handles
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentService_CreateComment(t *testing.T) {
+	db := setupTestDB()
+	todos := NewTodoService(repository.NewTodoRepository(db), nil)
+	service := NewCommentService(repository.NewCommentRepository(db), nil)
+
+	todo := &models.Todo{Title: "Plan offsite"}
+	todos.CreateTodo(testUserID, todo)
+
+	t.Run("success with mentions", func(t *testing.T) {
+		comment := &models.Comment{Body: "**Venue** booked, @Sam please confirm with `@catering`"}
+
+		err := service.CreateComment(testUserID, todo.ID, comment)
+
+		assert.NoError(t, err)
+		assert.NotZero(t, comment.ID)
+		assert.Equal(t, todo.ID, comment.TodoID)
+		assert.Equal(t, []string{"sam"}, comment.Mentions)
+		assert.Nil(t, comment.EditedAt)
+	})
+
+	t.Run("validation error - empty body", func(t *testing.T) {
+		err := service.CreateComment(testUserID, todo.ID, &models.Comment{Body: "  "})
+
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "body", validationErr.Field)
+	})
+
+	t.Run("validation error - body too long", func(t *testing.T) {
+		err := service.CreateComment(testUserID, todo.ID, &models.Comment{Body: strings.Repeat("a", maxCommentLength+1)})
+
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+	})
+
+	t.Run("todo of another user", func(t *testing.T) {
+		err := service.CreateComment(2, todo.ID, &models.Comment{Body: "Hi"})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestCommentService_EditAndDelete(t *testing.T) {
+	db := setupTestDB()
+	todos := NewTodoService(repository.NewTodoRepository(db), nil)
+	service := NewCommentService(repository.NewCommentRepository(db), nil)
+
+	todo := &models.Todo{Title: "Plan offsite"}
+	todos.CreateTodo(testUserID, todo)
+	comment := &models.Comment{Body: "Draft agenda"}
+	service.CreateComment(testUserID, todo.ID, comment)
+
+	t.Run("edit sets the edited time and mentions", func(t *testing.T) {
+		updated, err := service.UpdateComment(testUserID, todo.ID, comment.ID, "Draft agenda, @kim to review")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, updated.EditedAt)
+		assert.Equal(t, []string{"kim"}, updated.Mentions)
+
+		found, _ := service.GetCommentByID(testUserID, todo.ID, comment.ID)
+		assert.Equal(t, "Draft agenda, @kim to review", found.Body)
+		assert.NotNil(t, found.EditedAt)
+	})
+
+	t.Run("edit of another user's comment", func(t *testing.T) {
+		_, err := service.UpdateComment(2, todo.ID, comment.ID, "Hijacked")
+
+		assert.Error(t, err)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, service.DeleteComment(testUserID, todo.ID, comment.ID))
+
+		comments, err := service.GetComments(testUserID, todo.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, comments)
+		assert.NoError(t, service.DeleteComment(testUserID, todo.ID, comment.ID))
+	})
+}
+
+func TestCommentService_CommentCounts(t *testing.T) {
+	db := setupTestDB()
+	todos := NewTodoService(repository.NewTodoRepository(db), nil)
+	service := NewCommentService(repository.NewCommentRepository(db), nil)
+
+	discussed := &models.Todo{Title: "Discussed"}
+	todos.CreateTodo(testUserID, discussed)
+	quiet := &models.Todo{Title: "Quiet"}
+	todos.CreateTodo(testUserID, quiet)
+	service.CreateComment(testUserID, discussed.ID, &models.Comment{Body: "First"})
+	service.CreateComment(testUserID, discussed.ID, &models.Comment{Body: "Second"})
+
+	list, _, err := todos.GetTodos(testUserID, 1, 10, "", "title", "asc", map[string]interface{}{})
+
+	assert.NoError(t, err)
+	assert.Len(t, list, 2)
+	assert.Equal(t, int64(2), *list[0].CommentCount)
+	assert.Equal(t, int64(0), *list[1].CommentCount)
+
+	t.Run("purging a todo deletes its comments", func(t *testing.T) {
+		todos.DeleteTodo(testUserID, discussed.ID, DeleteCascade, 0)
+		assert.NoError(t, todos.PurgeTodo(testUserID, discussed.ID))
+
+		var count int64
+		db.Model(&models.Comment{}).Count(&count)
+		assert.Zero(t, count)
+	})
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"errors"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// The errors of the services tell apart why a request cannot be done, so the
+// handlers can answer each the same way wherever it comes from. Errors of
+// other types are unexpected failures, like a lost database connection.
+
+// ValidationError reports an invalid value for a field
+type ValidationError struct {
+	Field string // JSON name of the field
+	Msg   string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Msg
+}
+
+// NotFoundError reports that a resource does not exist, or belongs to
+// another user
+type NotFoundError struct {
+	Resource string // Like "todo" or "comment"
+	Err      error  // The error of the database, if it found out
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Resource + " not found"
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// ConflictError reports a change that conflicts with other data, like a
+// category name another category already has
+type ConflictError struct {
+	Field string // JSON name of the field, if one is at fault
+	Msg   string
+	Err   error // The error of the database, if it found out
+}
+
+func (e *ConflictError) Error() string {
+	return e.Msg
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// ReferenceError reports a value that refers to a record that does not
+// exist, caught by a foreign key of the database. It mostly happens when the
+// record is deleted while the change that refers to it is made.
+type ReferenceError struct {
+	Msg string
+	Err error
+}
+
+func (e *ReferenceError) Error() string {
+	return e.Msg
+}
+
+func (e *ReferenceError) Unwrap() error {
+	return e.Err
+}
+
+// dbError turns an error of the database about a resource into an error of
+// the services: a missing record into a *NotFoundError, a unique violation
+// into a *ConflictError, a foreign key violation into a *ReferenceError and
+// a check constraint violation into a *ValidationError.
+// The database must be opened with TranslateError for the violations to be
+// recognised. Other errors, and nil, are returned as they are, as are
+// errors dbError already turned.
+func dbError(err error, resource string) error {
+	var notFound *NotFoundError
+	var conflict *ConflictError
+	var reference *ReferenceError
+	if errors.As(err, &notFound) || errors.As(err, &conflict) || errors.As(err, &reference) {
+		return err
+	}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return &NotFoundError{Resource: resource, Err: err}
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return &ConflictError{Msg: resource + " already exists", Err: err}
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return &ReferenceError{Msg: resource + " refers to a record that does not exist", Err: err}
+	case errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return &ValidationError{Msg: resource + " has an invalid value"}
+	}
+	return err
+}
+
+// itemError returns the message, and the field at fault if there is one, to
+// report for an item of a request that fails on its own, like an operation
+// of a bulk request. Unexpected errors are logged and reported without
+// their details.
+func itemError(err error, resource string) (string, string) {
+	err = dbError(err, resource)
+	var validationErr *ValidationError
+	var notFound *NotFoundError
+	var conflict *ConflictError
+	var reference *ReferenceError
+	switch {
+	case errors.As(err, &validationErr):
+		return validationErr.Msg, validationErr.Field
+	case errors.As(err, &conflict):
+		return conflict.Msg, conflict.Field
+	case errors.As(err, &notFound), errors.As(err, &reference), errors.Is(err, ErrVersionMismatch):
+		return err.Error(), ""
+	}
+	log.Printf("Failed to change %s: %v", resource, err)
+	return "internal error", ""
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDBError(t *testing.T) {
+	t.Run("missing record", func(t *testing.T) {
+		err := dbError(gorm.ErrRecordNotFound, "todo")
+
+		var notFound *NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		assert.Equal(t, "todo not found", err.Error())
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	})
+
+	t.Run("unique violation", func(t *testing.T) {
+		err := dbError(gorm.ErrDuplicatedKey, "tag")
+
+		var conflict *ConflictError
+		assert.ErrorAs(t, err, &conflict)
+		assert.Equal(t, "tag already exists", err.Error())
+	})
+
+	t.Run("foreign key violation", func(t *testing.T) {
+		err := dbError(gorm.ErrForeignKeyViolated, "todo")
+
+		var reference *ReferenceError
+		assert.ErrorAs(t, err, &reference)
+		assert.ErrorIs(t, err, gorm.ErrForeignKeyViolated)
+	})
+
+	t.Run("check constraint violation", func(t *testing.T) {
+		err := dbError(gorm.ErrCheckConstraintViolated, "todo")
+
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "todo has an invalid value", err.Error())
+	})
+
+	t.Run("errors of the services are kept", func(t *testing.T) {
+		notFound := &NotFoundError{Resource: "tag", Err: gorm.ErrRecordNotFound}
+
+		assert.Same(t, notFound, dbError(notFound, "todo"))
+		assert.Same(t, ErrNameTaken, dbError(ErrNameTaken, "category"))
+	})
+
+	t.Run("other errors and nil", func(t *testing.T) {
+		lost := errors.New("connection lost")
+
+		assert.Same(t, lost, dbError(lost, "todo"))
+		assert.NoError(t, dbError(nil, "todo"))
+	})
+}
+
+func TestItemError(t *testing.T) {
+	msg, field := itemError(&ValidationError{Field: "title", Msg: "title is required"}, "todo")
+	assert.Equal(t, "title is required", msg)
+	assert.Equal(t, "title", field)
+
+	msg, field = itemError(gorm.ErrRecordNotFound, "todo")
+	assert.Equal(t, "todo not found", msg)
+	assert.Empty(t, field)
+
+	msg, _ = itemError(gorm.ErrDuplicatedKey, "todo")
+	assert.Equal(t, "todo already exists", msg)
+
+	msg, _ = itemError(gorm.ErrForeignKeyViolated, "todo")
+	assert.Equal(t, "todo refers to a record that does not exist", msg)
+
+	msg, field = itemError(errors.New("disk I/O error at /var/lib/todo.db"), "todo")
+	assert.Equal(t, "internal error", msg)
+	assert.Empty(t, field)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/models"
+)
+
+// smtpTimeout bounds a whole conversation with the mail server
+const smtpTimeout = 30 * time.Second
+
+// Notifier delivers notifications over one channel
+type Notifier interface {
+	Notify(ctx context.Context, user *models.User, notification *models.Notification) error
+}
+
+// SMTPConfig configures the mail server that email notifications are sent through
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string // Optional; authentication needs TLS unless the server is on localhost
+	Password string
+	From     string // Sender address, e.g. "Todo List <noreply@example.com>"
+}
+
+// SMTPNotifier sends notifications as plain text emails
+type SMTPNotifier struct {
+	config SMTPConfig
+	now    func() time.Time
+}
+
+// NewSMTPNotifier creates an SMTPNotifier. It fails without a host or with an
+// invalid sender address.
+func NewSMTPNotifier(config SMTPConfig) (*SMTPNotifier, error) {
+	if config.Host == "" {
+		return nil, errors.New("SMTP host is required")
+	}
+	if config.Port == 0 {
+		config.Port = 25
+	}
+	if _, err := mail.ParseAddress(config.From); err != nil {
+		return nil, fmt.Errorf("invalid SMTP sender %q: %w", config.From, err)
+	}
+	return &SMTPNotifier{config: config, now: time.Now}, nil
+}
+
+// Notify emails a notification to the user. The mail is encrypted with
+// STARTTLS whenever the server offers it.
+func (n *SMTPNotifier) Notify(ctx context.Context, user *models.User, notification *models.Notification) error {
+	from, err := mail.ParseAddress(n.config.From)
+	if err != nil {
+		return err
+	}
+	addr := net.JoinHostPort(n.config.Host, strconv.Itoa(n.config.Port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(smtpTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.config.Host}); err != nil {
+			return err
+		}
+	}
+	if n.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(user.Email); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(n.message(from, user, notification)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message builds the email for a notification
+func (n *SMTPNotifier) message(from *mail.Address, user *models.User, notification *models.Notification) []byte {
+	to := mail.Address{Name: user.Name, Address: user.Email}
+	domain := from.Address[strings.LastIndex(from.Address, "@")+1:]
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "Reminder: "+notification.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <notification-%d@%s>\r\n", notification.ID, domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(notification.Title + "\r\n\r\n" + notification.Message + "\r\n")
+	return b.Bytes()
+}
+
+// WebhookNotifier sends notifications as reminder.due events to the user's
+// webhooks that subscribe to them
+type WebhookNotifier struct {
+	webhooks *WebhookService
+}
+
+// NewWebhookNotifier creates a WebhookNotifier that queues deliveries with webhooks
+func NewWebhookNotifier(webhooks *WebhookService) *WebhookNotifier {
+	return &WebhookNotifier{webhooks: webhooks}
+}
+
+// Notify queues a delivery of the notification to the user's webhooks,
+// which retry it on their own
+func (n *WebhookNotifier) Notify(ctx context.Context, user *models.User, notification *models.Notification) error {
+	return n.webhooks.Send(user.ID, events.ReminderDue, notification)
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpSink is a local mail server that accepts one message and keeps it
+type smtpSink struct {
+	addr     *net.TCPAddr
+	from, to string
+	data     chan string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	sink := &smtpSink{addr: listener.Addr().(*net.TCPAddr), data: make(chan string, 1)}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+		reply("220 localhost ESMTP sink")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.TrimSpace(line)
+			switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); verb {
+			case "EHLO", "HELO":
+				reply("250 localhost")
+			case "MAIL":
+				sink.from = command
+				reply("250 OK")
+			case "RCPT":
+				sink.to = command
+				reply("250 OK")
+			case "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				sink.data <- data.String()
+				reply("250 OK")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return sink
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	t.Run("sends plain text email", func(t *testing.T) {
+		sink := newSMTPSink(t)
+		notifier, err := NewSMTPNotifier(SMTPConfig{Host: "127.0.0.1", Port: sink.addr.Port, From: "Todo List <noreply@todo.test>"})
+		assert.NoError(t, err)
+
+		user := &models.User{ID: 1, Email: "ada@example.com", Name: "Ada"}
+		notification := &models.Notification{ID: 7, Title: "Überweisung\r\nBcc: evil@example.com", Message: "Due Mon, 2 Nov 2026 09:00 UTC."}
+		err = notifier.Notify(context.Background(), user, notification)
+
+		assert.NoError(t, err)
+		data := <-sink.data
+		assert.Equal(t, "MAIL FROM:<noreply@todo.test>", strings.SplitN(sink.from, " BODY", 2)[0])
+		assert.Equal(t, "RCPT TO:<ada@example.com>", sink.to)
+
+		msg, err := mail.ReadMessage(strings.NewReader(data))
+		assert.NoError(t, err)
+		assert.Equal(t, `"Ada" <ada@example.com>`, msg.Header.Get("To"))
+		assert.Empty(t, msg.Header.Get("Bcc"))
+		subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		assert.Equal(t, "Reminder: Überweisung\r\nBcc: evil@example.com", subject)
+		assert.Equal(t, "<notification-7@todo.test>", msg.Header.Get("Message-ID"))
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		_, err := NewSMTPNotifier(SMTPConfig{Host: "localhost", From: "not an address"})
+		assert.Error(t, err)
+
+		_, err = NewSMTPNotifier(SMTPConfig{From: "noreply@todo.test"})
+		assert.Error(t, err)
+	})
+
+	t.Run("unreachable server", func(t *testing.T) {
+		listener, _ := net.Listen("tcp", "127.0.0.1:0")
+		port := listener.Addr().(*net.TCPAddr).Port
+		listener.Close()
+		notifier, _ := NewSMTPNotifier(SMTPConfig{Host: "127.0.0.1", Port: port, From: "noreply@todo.test"})
+
+		err := notifier.Notify(context.Background(), &models.User{Email: "ada@example.com"}, &models.Notification{Title: "x"})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	webhooks, db := setupWebhookService()
+	webhook := &models.Webhook{URL: "https://example.com/hook", EventTypes: []string{events.ReminderDue}, Active: true}
+	webhooks.CreateWebhook(testUserID, webhook)
+
+	notifier := NewWebhookNotifier(webhooks)
+	err := notifier.Notify(context.Background(), &models.User{ID: testUserID}, &models.Notification{ID: 3, Title: "Submit report"})
+
+	assert.NoError(t, err)
+	var deliveries []models.WebhookDelivery
+	db.Find(&deliveries)
+	assert.Len(t, deliveries, 1)
+	assert.Equal(t, events.ReminderDue, deliveries[0].EventType)
+	assert.Contains(t, deliveries[0].Payload, `"title":"Submit report"`)
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/patch"
+)
+
+// Patch transforms the JSON document of a todo or category, for example by
+// applying a merge patch with patch.MergePatch or a JSON Patch with patch.Apply
+type Patch func(doc []byte) ([]byte, error)
+
+// todoPatchFields are the todo fields a patch may change; the rest of the
+// document is read-only
+var todoPatchFields = map[string]bool{
+	"title": true, "description": true, "completed": true, "category_id": true, "parent_id": true,
+	"priority": true, "due_date": true, "recurrence": true, "tag_ids": true,
+}
+
+// categoryPatchFields are the category fields a patch may change
+var categoryPatchFields = map[string]bool{"name": true, "color": true}
+
+// PatchTodo applies a patch to the JSON document of a user's todo and saves
+// the result. The document is the todo as GetTodoByID returns it plus its
+// tag_ids. A field the patch removes, as a merge patch does for null, is
+// cleared, while fields the patch leaves alone keep their value. Changing a
+// read-only field or setting an invalid value is a *ValidationError. A
+// non-zero version must match the todo's current version.
+func (s *TodoService) PatchTodo(userID, id, version uint, apply Patch) (*models.Todo, error) {
+	current, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, dbError(err, "todo")
+	}
+	if version != 0 && current.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	doc, err := todoDocument(current)
+	if err != nil {
+		return nil, err
+	}
+	before, after, err := applyPatch(doc, apply, todoPatchFields)
+	if err != nil {
+		return nil, err
+	}
+
+	todo := &models.Todo{ID: id}
+	if err := decodeFields(after, todo); err != nil {
+		return nil, err
+	}
+	switch tagIDs, ok := after["tag_ids"]; {
+	case !ok:
+		todo.TagIDs = []uint{}
+	case jsonEqual(tagIDs, before["tag_ids"]):
+		todo.TagIDs = nil // Leave the tags alone
+	}
+
+	// Saving at the version the patch was applied to catches concurrent changes
+	todo.Version = current.Version
+	if err := s.UpdateTodo(userID, todo); err != nil {
+		return nil, err
+	}
+	return s.GetTodoByID(userID, id)
+}
+
+// todoDocument returns the JSON document patches to a todo apply to
+func todoDocument(todo *models.Todo) ([]byte, error) {
+	todo.TagIDs = make([]uint, len(todo.Tags))
+	for i, tag := range todo.Tags {
+		todo.TagIDs[i] = tag.ID
+	}
+	doc, err := json.Marshal(todo)
+	if err != nil || len(todo.TagIDs) > 0 {
+		return doc, err
+	}
+	// tag_ids is omitted when empty, but patches should always find it
+	return append(doc[:len(doc)-1], []byte(`,"tag_ids":[]}`)...), nil
+}
+
+// PatchCategory applies a patch to the JSON document of a user's category
+// and saves the result, like PatchTodo
+func (s *CategoryService) PatchCategory(userID, id, version uint, apply Patch) (*models.Category, error) {
+	current, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, dbError(err, "category")
+	}
+	if version != 0 && current.Version != version {
+		return nil, ErrVersionMismatch
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	_, after, err := applyPatch(doc, apply, categoryPatchFields)
+	if err != nil {
+		return nil, err
+	}
+
+	category := &models.Category{ID: id}
+	if err := decodeFields(after, category); err != nil {
+		return nil, err
+	}
+	category.Version = current.Version
+	if err := s.UpdateCategory(userID, category); err != nil {
+		return nil, err
+	}
+	return category, nil
+}
+
+// applyPatch applies a patch to doc and returns the members of the document
+// before the patch and the writable members after it. Read-only members must
+// be left as they are.
+func applyPatch(doc []byte, apply Patch, writable map[string]bool) (map[string]json.RawMessage, map[string]json.RawMessage, error) {
+	patched, err := apply(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var before, after map[string]json.RawMessage
+	if err := json.Unmarshal(doc, &before); err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(patched, &after); err != nil || after == nil {
+		return nil, nil, &ValidationError{Msg: "the patched document must be an object"}
+	}
+
+	for key, value := range after {
+		if writable[key] {
+			continue
+		}
+		old, ok := before[key]
+		if !ok {
+			return nil, nil, &ValidationError{Field: key, Msg: fmt.Sprintf("unknown field %q", key)}
+		}
+		if !jsonEqual(old, value) {
+			return nil, nil, &ValidationError{Field: key, Msg: key + " is read-only"}
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok && !writable[key] {
+			return nil, nil, &ValidationError{Field: key, Msg: key + " is read-only"}
+		}
+	}
+
+	fields := make(map[string]json.RawMessage)
+	for key, value := range after {
+		if writable[key] {
+			fields[key] = value
+		}
+	}
+	return before, fields, nil
+}
+
+// decodeFields sets the fields of v from JSON members one at a time, so an
+// invalid value is reported for its field
+func decodeFields(fields map[string]json.RawMessage, v interface{}) error {
+	for key, value := range fields {
+		member, err := json.Marshal(map[string]json.RawMessage{key: value})
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(member, v); err != nil {
+			return &ValidationError{Field: key, Msg: fmt.Sprintf("invalid value for %s", key)}
+		}
+	}
+	return nil
+}
+
+// jsonEqual reports whether two JSON values are equal, ignoring formatting
+func jsonEqual(a, b json.RawMessage) bool {
+	var x, y interface{}
+	decA := json.NewDecoder(bytes.NewReader(a))
+	decA.UseNumber()
+	decB := json.NewDecoder(bytes.NewReader(b))
+	decB.UseNumber()
+	if decA.Decode(&x) != nil || decB.Decode(&y) != nil {
+		return false
+	}
+	return patch.Equal(x, y)
+}
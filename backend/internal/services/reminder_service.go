@@ -0,0 +1,353 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// reminderPollInterval is how often due reminders and notifications are looked for
+	reminderPollInterval = 15 * time.Second
+	// reminderBatchSize is how many due reminders or notifications are loaded at once
+	reminderBatchSize = 100
+	// maxReminderOffset is the furthest before a due date a reminder can fire, in minutes
+	maxReminderOffset = 366 * 24 * 60
+	// notificationMaxAttempts is how often a notification is tried before it is marked failed
+	notificationMaxAttempts = 5
+	// notificationBaseDelay is the wait after the first failed attempt; it doubles after each further one
+	notificationBaseDelay = time.Minute
+	// notificationLease is how long an attempt may take before the notification is tried again
+	notificationLease = 5 * time.Minute
+)
+
+// DefaultSnooze is how long a reminder is snoozed when no time is given
+const DefaultSnooze = 10 * time.Minute
+
+// ReminderService manages reminders and sends notifications when they fire
+type ReminderService struct {
+	repo      *repository.ReminderRepository
+	bus       *events.Bus
+	notifiers map[string]Notifier
+	now       func() time.Time
+}
+
+// NewReminderService creates a new ReminderService that delivers
+// notifications with notifiers, keyed by channel. In-app notifications need
+// no notifier; they are published to bus, which may be nil.
+func NewReminderService(repo *repository.ReminderRepository, bus *events.Bus, notifiers map[string]Notifier) *ReminderService {
+	return &ReminderService{repo: repo, bus: bus, notifiers: notifiers, now: time.Now}
+}
+
+// CreateReminder adds a reminder to a user's todo with validation. Offset
+// reminders wait for the todo to get a due date and follow it when it moves.
+func (s *ReminderService) CreateReminder(userID, todoID uint, reminder *models.Reminder) error {
+	if err := s.validateReminder(reminder); err != nil {
+		return err
+	}
+	todo, err := s.repo.FindTodo(userID, todoID)
+	if err != nil {
+		return dbError(err, "todo")
+	}
+	reminder.ID = 0
+	reminder.TodoID = todoID
+	reminder.UserID = userID
+	reminder.Status = models.ReminderPending
+	reminder.SentAt = nil
+	if reminder.RemindAt != nil {
+		reminder.FireAt = reminder.RemindAt
+	} else {
+		reminder.FireAt = repository.OffsetFireAt(todo.DueDate, *reminder.OffsetMinutes)
+	}
+	return dbError(s.repo.Create(reminder), "todo")
+}
+
+// GetReminders gets the reminders of a user's todo, soonest first
+func (s *ReminderService) GetReminders(userID, todoID uint) ([]models.Reminder, error) {
+	if _, err := s.repo.FindTodo(userID, todoID); err != nil {
+		return nil, dbError(err, "todo")
+	}
+	return s.repo.GetByTodo(userID, todoID)
+}
+
+// GetUpcomingReminders gets the reminders of a user that have yet to fire, soonest first
+func (s *ReminderService) GetUpcomingReminders(userID uint, limit int) ([]models.Reminder, error) {
+	return s.repo.GetUpcoming(userID, validLimit(limit))
+}
+
+// DeleteReminder deletes a user's reminder of a todo
+func (s *ReminderService) DeleteReminder(userID, todoID, id uint) error {
+	_, err := s.repo.Delete(userID, todoID, id)
+	return err
+}
+
+// SnoozeReminder makes a user's reminder fire again at until, even when it
+// was dismissed, and marks its in-app notifications as read
+func (s *ReminderService) SnoozeReminder(userID, id uint, until time.Time) (*models.Reminder, error) {
+	now := s.now()
+	if !until.After(now) {
+		return nil, &ValidationError{Field: "until", Msg: "until must be in the future"}
+	}
+	return s.settle(userID, id, now, func(reminder *models.Reminder) {
+		reminder.Status = models.ReminderPending
+		reminder.FireAt = &until
+	})
+}
+
+// DismissReminder stops a user's reminder from firing again and marks its
+// in-app notifications as read
+func (s *ReminderService) DismissReminder(userID, id uint) (*models.Reminder, error) {
+	return s.settle(userID, id, s.now(), func(reminder *models.Reminder) {
+		reminder.Status = models.ReminderDismissed
+		reminder.FireAt = nil
+	})
+}
+
+// settle applies a user's answer to a reminder
+func (s *ReminderService) settle(userID, id uint, now time.Time, apply func(reminder *models.Reminder)) (*models.Reminder, error) {
+	var reminder *models.Reminder
+	err := s.repo.Transaction(func(tx *repository.ReminderRepository) error {
+		var err error
+		if reminder, err = tx.GetByID(userID, id); err != nil {
+			return err
+		}
+		apply(reminder)
+		if err := tx.Update(reminder); err != nil {
+			return err
+		}
+		return tx.MarkReminderRead(reminder.ID, now)
+	})
+	if err != nil {
+		return nil, dbError(err, "reminder")
+	}
+	return reminder, nil
+}
+
+// GetNotifications gets up to limit of a user's in-app notifications,
+// newest first, optionally only the unread ones
+func (s *ReminderService) GetNotifications(userID uint, unread bool, limit int) ([]models.Notification, error) {
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.GetNotifications(userID, unread, limit)
+}
+
+// ReadNotification marks a user's in-app notification as read, returning a
+// *NotFoundError when there is no such notification
+func (s *ReminderService) ReadNotification(userID, id uint) error {
+	found, err := s.repo.MarkRead(userID, id, s.now())
+	if err != nil {
+		return err
+	}
+	if !found {
+		return &NotFoundError{Resource: "notification", Err: gorm.ErrRecordNotFound}
+	}
+	return nil
+}
+
+// Run fires due reminders and delivers their notifications until ctx is
+// done. The schedule lives in the database, so reminders that came due
+// while the server was down fire once it is back.
+func (s *ReminderService) Run(ctx context.Context) {
+	ticker := time.NewTicker(reminderPollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.FireDue(); err != nil {
+			log.Printf("Failed to fire reminders: %v", err)
+		}
+		if _, err := s.DeliverDue(ctx); err != nil {
+			log.Printf("Failed to deliver notifications: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// FireDue fires every pending reminder whose time has come and returns how
+// many fired
+func (s *ReminderService) FireDue() (int, error) {
+	fired := 0
+	for {
+		due, err := s.repo.GetDue(s.now(), reminderBatchSize)
+		if err != nil {
+			return fired, err
+		}
+		for i := range due {
+			ok, err := s.fire(&due[i])
+			if err != nil {
+				return fired, err
+			}
+			if ok {
+				fired++
+			}
+		}
+		if len(due) < reminderBatchSize {
+			return fired, nil
+		}
+	}
+}
+
+// fire claims a due reminder and creates a notification for each of its
+// channels in the same transaction, so it fires exactly once. A reminder of
+// a todo that is completed or in the trash is dismissed instead. It reports
+// whether the reminder fired.
+func (s *ReminderService) fire(reminder *models.Reminder) (bool, error) {
+	now := s.now()
+	var notifications []models.Notification
+	err := s.repo.Transaction(func(tx *repository.ReminderRepository) error {
+		todo, err := tx.FindTodoOfReminder(reminder)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		status := models.ReminderSent
+		if todo == nil || todo.Completed || todo.DeletedAt.Valid {
+			status = models.ReminderDismissed // Nothing left to be reminded of
+		}
+		claimed, err := tx.Claim(reminder.ID, now, status)
+		if err != nil || !claimed || status != models.ReminderSent {
+			return err
+		}
+		notifications = notificationsFor(reminder, todo, now)
+		return tx.CreateNotifications(notifications)
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, notification := range notifications {
+		if notification.Channel == models.ChannelInApp {
+			s.bus.Publish(notification.UserID, events.NotificationCreated, notification)
+		}
+	}
+	return len(notifications) > 0, nil
+}
+
+// notificationsFor returns the notifications of a reminder that fired at
+// now, one per channel. In-app notifications count as sent right away.
+func notificationsFor(reminder *models.Reminder, todo *models.Todo, now time.Time) []models.Notification {
+	message := "You asked to be reminded of this todo."
+	if todo.DueDate != nil {
+		message = "Due " + todo.DueDate.UTC().Format("Mon, 2 Jan 2006 15:04 MST") + "."
+	}
+	notifications := make([]models.Notification, 0, len(reminder.Channels))
+	for _, channel := range reminder.Channels {
+		notification := models.Notification{
+			UserID:     reminder.UserID,
+			TodoID:     reminder.TodoID,
+			ReminderID: reminder.ID,
+			Channel:    channel,
+			Title:      todo.Title,
+			Message:    message,
+			Status:     models.NotificationPending,
+		}
+		if channel == models.ChannelInApp {
+			notification.Status = models.NotificationSent
+			notification.SentAt = &now
+		} else {
+			notification.NextAttemptAt = &now
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications
+}
+
+// DeliverDue attempts every pending notification whose next attempt is due
+// and returns how many were attempted
+func (s *ReminderService) DeliverDue(ctx context.Context) (int, error) {
+	attempted := 0
+	for {
+		due, err := s.repo.GetDueNotifications(s.now(), reminderBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+		for i := range due {
+			if err := s.deliver(ctx, &due[i]); err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+		if len(due) < reminderBatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// deliver sends a notification once and records the outcome, scheduling a
+// retry with exponential backoff after a failure. A notification is claimed
+// before it is sent, so it is only sent again when the server stops during
+// the attempt.
+func (s *ReminderService) deliver(ctx context.Context, notification *models.Notification) error {
+	claimed, err := s.repo.ClaimNotification(notification, s.now().Add(notificationLease))
+	if err != nil || !claimed {
+		return err
+	}
+
+	notifier := s.notifiers[notification.Channel]
+	if notifier == nil {
+		err = fmt.Errorf("channel %q is not configured", notification.Channel)
+		notification.Attempts = notificationMaxAttempts
+	} else {
+		var user *models.User
+		if user, err = s.repo.FindUser(notification.UserID); err == nil {
+			err = notifier.Notify(ctx, user, notification)
+		}
+	}
+
+	now := s.now()
+	switch {
+	case err == nil:
+		notification.Status = models.NotificationSent
+		notification.SentAt = &now
+		notification.LastError = ""
+		notification.NextAttemptAt = nil
+	case notification.Attempts >= notificationMaxAttempts:
+		notification.Status = models.NotificationFailed
+		notification.LastError = err.Error()
+		notification.NextAttemptAt = nil
+	default:
+		next := now.Add(notificationBaseDelay << (notification.Attempts - 1))
+		notification.LastError = err.Error()
+		notification.NextAttemptAt = &next
+	}
+	return s.repo.UpdateNotification(notification)
+}
+
+// validateReminder validates reminder fields and defaults the channels to in-app only
+func (s *ReminderService) validateReminder(reminder *models.Reminder) error {
+	switch {
+	case reminder.RemindAt == nil && reminder.OffsetMinutes == nil:
+		return &ValidationError{Field: "remind_at", Msg: "either remind_at or offset_minutes is required"}
+	case reminder.RemindAt != nil && reminder.OffsetMinutes != nil:
+		return &ValidationError{Field: "remind_at", Msg: "remind_at and offset_minutes cannot both be set"}
+	case reminder.RemindAt != nil && !reminder.RemindAt.After(s.now()):
+		return &ValidationError{Field: "remind_at", Msg: "remind_at must be in the future"}
+	case reminder.OffsetMinutes != nil && (*reminder.OffsetMinutes < 0 || *reminder.OffsetMinutes > maxReminderOffset):
+		return &ValidationError{Field: "offset_minutes", Msg: fmt.Sprintf("offset_minutes must be between 0 and %d", maxReminderOffset)}
+	}
+
+	if len(reminder.Channels) == 0 {
+		reminder.Channels = []string{models.ChannelInApp}
+	}
+	seen := make(map[string]bool, len(reminder.Channels))
+	channels := reminder.Channels[:0]
+	for _, channel := range reminder.Channels {
+		if channel != models.ChannelInApp && s.notifiers[channel] == nil {
+			return &ValidationError{Field: "channels", Msg: fmt.Sprintf("channel %q is not available", channel)}
+		}
+		if !seen[channel] {
+			seen[channel] = true
+			channels = append(channels, channel)
+		}
+	}
+	reminder.Channels = channels
+	return nil
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// fakeNotifier records notifications and fails while err is set
+type fakeNotifier struct {
+	sent []models.Notification
+	err  error
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, user *models.User, notification *models.Notification) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.sent = append(n.sent, *notification)
+	return nil
+}
+
+func setupReminderService(notifiers map[string]Notifier) (*ReminderService, *TodoService, *gorm.DB, *time.Time) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.User{})
+	db.Create(&models.User{ID: testUserID, Email: "ada@example.com", Name: "Ada", PasswordHash: "x"})
+
+	clock := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	service := NewReminderService(repository.NewReminderRepository(db), nil, notifiers)
+	service.now = func() time.Time { return clock }
+	return service, NewTodoService(repository.NewTodoRepository(db), nil), db, &clock
+}
+
+func TestReminderService_CreateReminder(t *testing.T) {
+	service, todos, _, clock := setupReminderService(nil)
+
+	due := clock.Add(48 * time.Hour)
+	todo := &models.Todo{Title: "Renew passport", DueDate: &due}
+	todos.CreateTodo(testUserID, todo)
+
+	t.Run("fixed time", func(t *testing.T) {
+		at := clock.Add(time.Hour)
+		reminder := &models.Reminder{RemindAt: &at}
+
+		err := service.CreateReminder(testUserID, todo.ID, reminder)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReminderPending, reminder.Status)
+		assert.Equal(t, at, *reminder.FireAt)
+		assert.Equal(t, []string{models.ChannelInApp}, reminder.Channels)
+	})
+
+	t.Run("offset before due date", func(t *testing.T) {
+		offset := 90
+		reminder := &models.Reminder{OffsetMinutes: &offset}
+
+		err := service.CreateReminder(testUserID, todo.ID, reminder)
+
+		assert.NoError(t, err)
+		assert.Equal(t, due.Add(-90*time.Minute), *reminder.FireAt)
+	})
+
+	t.Run("offset without due date waits", func(t *testing.T) {
+		undated := &models.Todo{Title: "Someday"}
+		todos.CreateTodo(testUserID, undated)
+		offset := 0
+
+		reminder := &models.Reminder{OffsetMinutes: &offset}
+		err := service.CreateReminder(testUserID, undated.ID, reminder)
+
+		assert.NoError(t, err)
+		assert.Nil(t, reminder.FireAt)
+	})
+
+	t.Run("validation errors", func(t *testing.T) {
+		past := clock.Add(-time.Minute)
+		future := clock.Add(time.Minute)
+		negative := -5
+		zero := 0
+		cases := []struct {
+			reminder models.Reminder
+			field    string
+		}{
+			{models.Reminder{}, "remind_at"},
+			{models.Reminder{RemindAt: &future, OffsetMinutes: &zero}, "remind_at"},
+			{models.Reminder{RemindAt: &past}, "remind_at"},
+			{models.Reminder{OffsetMinutes: &negative}, "offset_minutes"},
+			{models.Reminder{RemindAt: &future, Channels: []string{models.ChannelEmail}}, "channels"},
+		}
+		for _, tc := range cases {
+			err := service.CreateReminder(testUserID, todo.ID, &tc.reminder)
+
+			var validationErr *ValidationError
+			assert.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tc.field, validationErr.Field)
+		}
+	})
+
+	t.Run("todo of another user", func(t *testing.T) {
+		at := clock.Add(time.Hour)
+		err := service.CreateReminder(2, todo.ID, &models.Reminder{RemindAt: &at})
+
+		assert.Error(t, err)
+	})
+}
+
+func TestReminderService_FollowsDueDate(t *testing.T) {
+	service, todos, _, clock := setupReminderService(nil)
+
+	due := clock.Add(-30 * time.Minute)
+	todo := &models.Todo{Title: "Water plants", DueDate: &due, Recurrence: "FREQ=DAILY"}
+	todos.CreateTodo(testUserID, todo)
+	offset := 15
+	reminder := &models.Reminder{OffsetMinutes: &offset}
+	service.CreateReminder(testUserID, todo.ID, reminder)
+
+	t.Run("moving the due date reschedules a fired reminder", func(t *testing.T) {
+		fired, _ := service.FireDue()
+		assert.Equal(t, 1, fired)
+
+		found, _ := todos.GetTodoByID(testUserID, todo.ID)
+		later := clock.Add(2 * time.Hour)
+		found.DueDate = &later
+		assert.NoError(t, todos.UpdateTodo(testUserID, found))
+
+		reminders, _ := service.GetReminders(testUserID, todo.ID)
+		assert.Equal(t, models.ReminderPending, reminders[0].Status)
+		assert.Equal(t, later.Add(-15*time.Minute), reminders[0].FireAt.UTC())
+	})
+
+	t.Run("next occurrence gets the reminder", func(t *testing.T) {
+		assert.NoError(t, todos.ToggleComplete(testUserID, todo.ID, 0))
+
+		upcoming, _ := service.GetUpcomingReminders(testUserID, 10)
+		assert.Len(t, upcoming, 2)
+		assert.NotEqual(t, upcoming[0].TodoID, upcoming[1].TodoID)
+	})
+}
+
+func TestReminderService_FireDue(t *testing.T) {
+	service, todos, db, clock := setupReminderService(nil)
+
+	todo := &models.Todo{Title: "Call the dentist"}
+	todos.CreateTodo(testUserID, todo)
+	at := clock.Add(time.Minute)
+	reminder := &models.Reminder{RemindAt: &at}
+	service.CreateReminder(testUserID, todo.ID, reminder)
+
+	t.Run("not yet due", func(t *testing.T) {
+		fired, err := service.FireDue()
+
+		assert.NoError(t, err)
+		assert.Zero(t, fired)
+	})
+
+	t.Run("fires exactly once", func(t *testing.T) {
+		*clock = clock.Add(2 * time.Minute)
+
+		fired, err := service.FireDue()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, fired)
+
+		// A second scheduler, or the same one after a restart, finds nothing left
+		restarted := NewReminderService(repository.NewReminderRepository(db), nil, nil)
+		restarted.now = service.now
+		fired, _ = restarted.FireDue()
+		assert.Zero(t, fired)
+
+		notifications, _ := service.GetNotifications(testUserID, true, 0)
+		assert.Len(t, notifications, 1)
+		assert.Equal(t, "Call the dentist", notifications[0].Title)
+		assert.Equal(t, models.NotificationSent, notifications[0].Status)
+	})
+
+	t.Run("snooze fires again later", func(t *testing.T) {
+		snoozed, err := service.SnoozeReminder(testUserID, reminder.ID, clock.Add(10*time.Minute))
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReminderPending, snoozed.Status)
+		unread, _ := service.GetNotifications(testUserID, true, 0)
+		assert.Empty(t, unread)
+
+		fired, _ := service.FireDue()
+		assert.Zero(t, fired)
+		*clock = clock.Add(10 * time.Minute)
+		fired, _ = service.FireDue()
+		assert.Equal(t, 1, fired)
+	})
+
+	t.Run("snooze into the past", func(t *testing.T) {
+		_, err := service.SnoozeReminder(testUserID, reminder.ID, clock.Add(-time.Minute))
+
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+	})
+
+	t.Run("dismissed reminder stays quiet", func(t *testing.T) {
+		service.SnoozeReminder(testUserID, reminder.ID, clock.Add(time.Minute))
+		dismissed, err := service.DismissReminder(testUserID, reminder.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.ReminderDismissed, dismissed.Status)
+		*clock = clock.Add(time.Hour)
+		fired, _ := service.FireDue()
+		assert.Zero(t, fired)
+	})
+
+	t.Run("completed todo is not reminded of", func(t *testing.T) {
+		done := &models.Todo{Title: "Already done"}
+		todos.CreateTodo(testUserID, done)
+		at := clock.Add(time.Minute)
+		late := &models.Reminder{RemindAt: &at}
+		service.CreateReminder(testUserID, done.ID, late)
+		todos.ToggleComplete(testUserID, done.ID, 0)
+		*clock = clock.Add(time.Hour)
+
+		fired, _ := service.FireDue()
+
+		assert.Zero(t, fired)
+		reminders, _ := service.GetReminders(testUserID, done.ID)
+		assert.Equal(t, models.ReminderDismissed, reminders[0].Status)
+	})
+
+	t.Run("read notification", func(t *testing.T) {
+		notifications, _ := service.GetNotifications(testUserID, false, 0)
+
+		assert.NoError(t, service.ReadNotification(testUserID, notifications[0].ID))
+		assert.Error(t, service.ReadNotification(2, notifications[0].ID))
+	})
+}
+
+func TestReminderService_DeliverDue(t *testing.T) {
+	notifier := &fakeNotifier{err: errors.New("mail server unavailable")}
+	service, todos, _, clock := setupReminderService(map[string]Notifier{models.ChannelEmail: notifier})
+
+	todo := &models.Todo{Title: "Submit report"}
+	todos.CreateTodo(testUserID, todo)
+	at := clock.Add(time.Minute)
+	service.CreateReminder(testUserID, todo.ID, &models.Reminder{RemindAt: &at, Channels: []string{models.ChannelEmail, models.ChannelInApp}})
+	*clock = clock.Add(time.Minute)
+	service.FireDue()
+
+	t.Run("failed attempt is retried later", func(t *testing.T) {
+		attempted, err := service.DeliverDue(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempted)
+		assert.Empty(t, notifier.sent)
+
+		attempted, _ = service.DeliverDue(context.Background())
+		assert.Zero(t, attempted)
+	})
+
+	t.Run("retry succeeds", func(t *testing.T) {
+		notifier.err = nil
+		*clock = clock.Add(notificationBaseDelay)
+
+		attempted, err := service.DeliverDue(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempted)
+		assert.Len(t, notifier.sent, 1)
+		assert.Equal(t, "Submit report", notifier.sent[0].Title)
+		assert.Equal(t, 2, notifier.sent[0].Attempts)
+	})
+
+	t.Run("email notifications are not listed in-app", func(t *testing.T) {
+		notifications, _ := service.GetNotifications(testUserID, false, 0)
+
+		assert.Len(t, notifications, 1)
+		assert.Equal(t, models.ChannelInApp, notifications[0].Channel)
+	})
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// defaultTagColor is used when a tag is created without a color
+const defaultTagColor = "#6B7280"
+
+// TagService handles business logic for Tag
+type TagService struct {
+	repo *repository.TagRepository
+}
+
+// NewTagService creates a new TagService
+func NewTagService(repo *repository.TagRepository) *TagService {
+	return &TagService{repo: repo}
+}
+
+// CreateTag creates a new tag for a user with validation
+func (s *TagService) CreateTag(userID uint, tag *models.Tag) error {
+	if tag.Color == "" {
+		tag.Color = defaultTagColor
+	}
+	if err := s.validateTag(tag); err != nil {
+		return err
+	}
+	tag.UserID = userID
+	return tagError(s.repo.Create(tag))
+}
+
+// GetTags gets all tags of a user
+func (s *TagService) GetTags(userID uint) ([]models.Tag, error) {
+	return s.repo.GetAll(userID)
+}
+
+// GetTagByID gets a user's tag by ID
+func (s *TagService) GetTagByID(userID, id uint) (*models.Tag, error) {
+	tag, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, dbError(err, "tag")
+	}
+	return tag, nil
+}
+
+// UpdateTag updates a user's tag with validation
+func (s *TagService) UpdateTag(userID uint, tag *models.Tag) error {
+	if tag.Color == "" {
+		tag.Color = defaultTagColor
+	}
+	if err := s.validateTag(tag); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetByID(userID, tag.ID)
+	if err != nil {
+		return dbError(err, "tag")
+	}
+	tag.UserID = userID
+	tag.CreatedAt = existing.CreatedAt
+	return tagError(s.repo.Update(tag))
+}
+
+// DeleteTag deletes a user's tag
+func (s *TagService) DeleteTag(userID, id uint) error {
+	return s.repo.Delete(userID, id)
+}
+
+// tagError turns an error of the database about a tag into an error of the
+// services; the only unique key of a tag is its name
+func tagError(err error) error {
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return &ConflictError{Field: "name", Msg: "another tag already has this name", Err: err}
+	}
+	return dbError(err, "tag")
+}
+
+// validateTag validates tag fields
+func (s *TagService) validateTag(tag *models.Tag) error {
+	tag.Name = strings.TrimSpace(tag.Name)
+	if tag.Name == "" {
+		return &ValidationError{Field: "name", Msg: "name is required"}
+	}
+	if len(tag.Name) > 50 {
+		return &ValidationError{Field: "name", Msg: "name must be less than 50 characters"}
+	}
+
+	colorRegex := regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+	if !colorRegex.MatchString(tag.Color) {
+		return &ValidationError{Field: "color", Msg: "color must be a valid hex color (e.g., #6B7280)"}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"testing"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagService_CreateTag(t *testing.T) {
+	db := setupTestDB()
+	repo := repository.NewTagRepository(db)
+	service := NewTagService(repo)
+
+	t.Run("success with default color", func(t *testing.T) {
+		tag := &models.Tag{Name: "backend"}
+
+		err := service.CreateTag(testUserID, tag)
+
+		assert.NoError(t, err)
+		assert.NotZero(t, tag.ID)
+		assert.Equal(t, defaultTagColor, tag.Color)
+	})
+
+	t.Run("validation error - empty name", func(t *testing.T) {
+		err := service.CreateTag(testUserID, &models.Tag{Name: "  "})
+
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "name", validationErr.Field)
+		assert.Contains(t, err.Error(), "name is required")
+	})
+
+	t.Run("validation error - invalid color", func(t *testing.T) {
+		err := service.CreateTag(testUserID, &models.Tag{Name: "urgent", Color: "red"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "color must be a valid hex color")
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		err := service.CreateTag(testUserID, &models.Tag{Name: "backend"})
+
+		var conflict *ConflictError
+		assert.ErrorAs(t, err, &conflict)
+		assert.Equal(t, "name", conflict.Field)
+	})
+}
+
+func TestTagService_UpdateAndDeleteTag(t *testing.T) {
+	db := setupTestDB()
+	tagService := NewTagService(repository.NewTagRepository(db))
+	todoService := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	tag := &models.Tag{Name: "backend", Color: "#3B82F6"}
+	tagService.CreateTag(testUserID, tag)
+	todo := &models.Todo{Title: "Fix login", TagIDs: []uint{tag.ID}}
+	todoService.CreateTodo(testUserID, todo)
+
+	t.Run("update", func(t *testing.T) {
+		tag.Name = "api"
+		err := tagService.UpdateTag(testUserID, tag)
+
+		assert.NoError(t, err)
+		found, _ := tagService.GetTagByID(testUserID, tag.ID)
+		assert.Equal(t, "api", found.Name)
+	})
+
+	t.Run("delete detaches from todos", func(t *testing.T) {
+		err := tagService.DeleteTag(testUserID, tag.ID)
+
+		assert.NoError(t, err)
+		tags, _ := tagService.GetTags(testUserID)
+		assert.Len(t, tags, 0)
+		found, _ := todoService.GetTodoByID(testUserID, todo.ID)
+		assert.Len(t, found.Tags, 0)
+	})
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// MaxBulkOperations is the most operations a single bulk request may run
+const MaxBulkOperations = 500
+
+// BulkMode decides what happens to a bulk request when an operation fails
+type BulkMode string
+
+const (
+	// BulkAtomic applies all operations or none of them
+	BulkAtomic BulkMode = "atomic"
+	// BulkBestEffort applies every operation that succeeds and skips the rest
+	BulkBestEffort BulkMode = "best_effort"
+)
+
+// BulkOp is the kind of a bulk operation
+type BulkOp string
+
+const (
+	BulkCreate      BulkOp = "create"       // Create Todo
+	BulkUpdate      BulkOp = "update"       // Replace the todo with Todo, like PUT
+	BulkDelete      BulkOp = "delete"       // Delete the todo, handling subtasks according to Subtasks
+	BulkComplete    BulkOp = "complete"     // Set the completion status to Completed, true when left out
+	BulkMove        BulkOp = "move"         // Move the todo to CategoryID, out of any category for null
+	BulkSetPriority BulkOp = "set_priority" // Set the priority to Priority
+)
+
+// BulkOperation is a single change in a bulk request. Every operation but
+// create needs the ID of the todo it changes. A non-zero version must match
+// the todo's current version.
+type BulkOperation struct {
+	Op         BulkOp          `json:"op"`
+	ID         uint            `json:"id,omitempty"`
+	Version    uint            `json:"version,omitempty"`
+	Todo       *models.Todo    `json:"todo,omitempty"`
+	Completed  *bool           `json:"completed,omitempty"`
+	CategoryID *uint           `json:"category_id,omitempty"`
+	Priority   models.Priority `json:"priority,omitempty"`
+	Subtasks   DeleteMode      `json:"subtasks,omitempty"`
+}
+
+// BulkStatus is the outcome of a single bulk operation
+type BulkStatus string
+
+const (
+	BulkOK         BulkStatus = "ok"          // Applied
+	BulkFailed     BulkStatus = "failed"      // Failed, see the error
+	BulkRolledBack BulkStatus = "rolled_back" // Succeeded, but undone because another operation failed
+	BulkSkipped    BulkStatus = "skipped"     // Not attempted because an earlier operation failed
+)
+
+// BulkItemResult is the outcome of the operation at Index in a bulk request
+type BulkItemResult struct {
+	Index  int        `json:"index"`
+	Op     BulkOp     `json:"op"`
+	ID     uint       `json:"id,omitempty"` // The created todo for create
+	Status BulkStatus `json:"status"`
+	Error  string     `json:"error,omitempty"`
+	Field  string     `json:"field,omitempty"` // Invalid field of a failed operation
+}
+
+// BulkResult is the outcome of a bulk request. Committed reports whether any
+// changes were saved.
+type BulkResult struct {
+	Committed bool             `json:"committed"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
+
+// pendingEvent is a todo event held back until the bulk transaction that
+// caused it commits. Events carry the todo as it is stored at that point.
+type pendingEvent struct {
+	userID    uint
+	eventType string
+	id        uint
+	deleted   *events.Deleted
+}
+
+// errBulkAborted rolls back an atomic bulk request
+var errBulkAborted = errors.New("bulk request aborted")
+
+// Bulk runs a list of operations on a user's todos in a single database
+// transaction. In atomic mode the first failing operation rolls back the
+// whole request; in best-effort mode each operation is rolled back on its
+// own and the others are kept. Change events are only published once the
+// transaction commits. A request that cannot run at all, like one with too
+// many operations, returns a *ValidationError.
+func (s *TodoService) Bulk(userID uint, ops []BulkOperation, mode BulkMode) (*BulkResult, error) {
+	if mode == "" {
+		mode = BulkAtomic
+	}
+	if mode != BulkAtomic && mode != BulkBestEffort {
+		return nil, &ValidationError{Field: "mode", Msg: "mode must be atomic or best_effort"}
+	}
+	if len(ops) == 0 {
+		return nil, &ValidationError{Field: "operations", Msg: "operations are required"}
+	}
+	if len(ops) > MaxBulkOperations {
+		return nil, &ValidationError{Field: "operations", Msg: fmt.Sprintf("at most %d operations are allowed", MaxBulkOperations)}
+	}
+
+	result := &BulkResult{Results: make([]BulkItemResult, len(ops))}
+	for i, op := range ops {
+		result.Results[i] = BulkItemResult{Index: i, Op: op.Op, ID: op.ID, Status: BulkSkipped}
+	}
+
+	var pending []pendingEvent
+	err := s.repo.Transaction(func(tx *repository.TodoRepository) error {
+		for i, op := range ops {
+			item := &result.Results[i]
+			var err error
+			if mode == BulkBestEffort {
+				// A savepoint undoes a failed operation without the others
+				mark := len(pending)
+				err = tx.Transaction(func(tx *repository.TodoRepository) error {
+					return (&TodoService{repo: tx, pending: &pending}).applyBulk(userID, op, item)
+				})
+				if err != nil {
+					pending = pending[:mark]
+				}
+			} else {
+				err = (&TodoService{repo: tx, pending: &pending}).applyBulk(userID, op, item)
+			}
+			if op.Op == BulkDelete && errors.Is(err, gorm.ErrRecordNotFound) && deletedEarlier(pending, op.ID) {
+				err = nil // Already deleted along with its parent
+			}
+
+			if err != nil {
+				item.fail(err)
+				result.Failed++
+				if mode == BulkAtomic {
+					return errBulkAborted
+				}
+				continue
+			}
+			item.Status = BulkOK
+			result.Succeeded++
+		}
+		return nil
+	})
+
+	if errors.Is(err, errBulkAborted) {
+		for i := range result.Results {
+			if result.Results[i].Status == BulkOK {
+				result.Results[i].Status = BulkRolledBack
+			}
+		}
+		result.Succeeded = 0
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result.Committed = true
+	s.publishPending(pending)
+	return result, nil
+}
+
+// publishPending publishes the events held back during a transaction that
+// has committed
+func (s *TodoService) publishPending(pending []pendingEvent) {
+	for _, event := range pending {
+		if event.deleted != nil {
+			s.bus.Publish(event.userID, event.eventType, *event.deleted)
+		} else {
+			s.publish(event.userID, event.eventType, event.id)
+		}
+	}
+}
+
+// BulkSelect runs an operation on every todo of a user matching a search
+// and a filter in the language of the filter package, like Bulk. An invalid
+// search or filter returns a *search.SyntaxError or *filter.Error.
+func (s *TodoService) BulkSelect(userID uint, searchText, q string, op BulkOperation, mode BulkMode) (*BulkResult, error) {
+	if op.Op == BulkCreate {
+		return nil, &ValidationError{Field: "op", Msg: "create cannot be applied to a selection"}
+	}
+	if searchText == "" && q == "" {
+		return nil, &ValidationError{Field: "filter", Msg: "a filter or search is required to select todos"}
+	}
+
+	filters := map[string]interface{}{"q": q}
+	query, _, _, err := prepareList(userID, searchText, "", "", filters)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.repo.GetIDs(userID, query, filters)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return &BulkResult{Committed: true, Results: []BulkItemResult{}}, nil
+	}
+	if len(ids) > MaxBulkOperations {
+		return nil, &ValidationError{Field: "filter", Msg: fmt.Sprintf("the filter selects %d todos, at most %d are allowed", len(ids), MaxBulkOperations)}
+	}
+
+	ops := make([]BulkOperation, len(ids))
+	for i, id := range ids {
+		ops[i] = op
+		ops[i].ID = id
+		ops[i].Version = 0 // Versions only make sense for a single todo
+	}
+	return s.Bulk(userID, ops, mode)
+}
+
+// applyBulk applies a single bulk operation
+func (s *TodoService) applyBulk(userID uint, op BulkOperation, item *BulkItemResult) error {
+	if op.Op == BulkCreate {
+		if op.Todo == nil {
+			return &ValidationError{Field: "todo", Msg: "todo is required"}
+		}
+		todo := *op.Todo
+		todo.ID = 0
+		if err := s.CreateTodo(userID, &todo); err != nil {
+			return err
+		}
+		item.ID = todo.ID
+		return nil
+	}
+
+	if op.ID == 0 {
+		return &ValidationError{Field: "id", Msg: "id is required"}
+	}
+	todo, err := s.repo.FindByID(userID, op.ID)
+	if err != nil {
+		return err
+	}
+	if op.Version != 0 && todo.Version != op.Version {
+		return ErrVersionMismatch
+	}
+
+	switch op.Op {
+	case BulkUpdate:
+		if op.Todo == nil {
+			return &ValidationError{Field: "todo", Msg: "todo is required"}
+		}
+		update := *op.Todo
+		update.ID = op.ID
+		update.Version = todo.Version
+		return s.UpdateTodo(userID, &update)
+	case BulkDelete:
+		return s.DeleteTodo(userID, op.ID, op.Subtasks, todo.Version)
+	case BulkComplete:
+		completed := op.Completed == nil || *op.Completed
+		if todo.Completed == completed {
+			return nil
+		}
+		return s.ToggleComplete(userID, op.ID, todo.Version)
+	case BulkMove:
+		todo.CategoryID = op.CategoryID
+		return s.UpdateTodo(userID, todo)
+	case BulkSetPriority:
+		if op.Priority == "" {
+			return &ValidationError{Field: "priority", Msg: "priority is required"}
+		}
+		todo.Priority = op.Priority
+		return s.UpdateTodo(userID, todo)
+	}
+	return &ValidationError{Field: "op", Msg: fmt.Sprintf("unknown operation %q", op.Op)}
+}
+
+// deletedEarlier reports whether a todo was deleted by an earlier operation
+// of a bulk request
+func deletedEarlier(pending []pendingEvent, id uint) bool {
+	for _, event := range pending {
+		if event.deleted == nil {
+			continue
+		}
+		for _, deleted := range event.deleted.IDs {
+			if deleted == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fail records the error of a failed operation
+func (r *BulkItemResult) fail(err error) {
+	r.Status = BulkFailed
+	r.Error, r.Field = itemError(err, "todo")
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+	"todoListChallenge/internal/repository"
+)
+
+// ErrInvalidCursor is returned for a malformed cursor or one issued for a different sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorPayload is the JSON inside an opaque cursor
+type cursorPayload struct {
+	SortBy    string      `json:"s"`
+	SortOrder string      `json:"o"`
+	Key       interface{} `json:"k"`
+	ID        uint        `json:"i"`
+	Before    bool        `json:"b,omitempty"`
+}
+
+// encodeCursor returns the opaque form of a cursor, or "" for nil
+func encodeCursor(cursor *repository.Cursor) string {
+	if cursor == nil {
+		return ""
+	}
+	payload := cursorPayload{SortBy: cursor.SortBy, SortOrder: cursor.SortOrder, Key: cursor.Key, ID: cursor.ID, Before: cursor.Before}
+	if t, ok := cursor.Key.(time.Time); ok {
+		payload.Key = t.Format(time.RFC3339Nano)
+	}
+	data, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses an opaque cursor, restoring the type of its sort key
+func decodeCursor(value string) (*repository.Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	cursor := &repository.Cursor{SortBy: payload.SortBy, SortOrder: payload.SortOrder, ID: payload.ID, Before: payload.Before}
+	switch key := payload.Key.(type) {
+	case string:
+		switch payload.SortBy {
+		case "title":
+			cursor.Key = key
+		case "created_at", "updated_at", "due_date", "relevance":
+			// Relevance falls back to creation time where matches cannot be ranked
+			t, err := time.Parse(time.RFC3339Nano, key)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			cursor.Key = t
+		default:
+			return nil, ErrInvalidCursor
+		}
+	case float64:
+		switch payload.SortBy {
+		case "priority":
+			if key != float64(int(key)) {
+				return nil, ErrInvalidCursor
+			}
+			cursor.Key = int(key)
+		case "relevance":
+			cursor.Key = key
+		default:
+			return nil, ErrInvalidCursor
+		}
+	case nil:
+		// Only todos without a due date have no key
+		if payload.SortBy != "due_date" {
+			return nil, ErrInvalidCursor
+		}
+	default:
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
@@ -2,47 +2,184 @@ package services
 
 import (
 	"errors"
+	"fmt"
 	"strings"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/filter"
 	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/recurrence"
 	"todoListChallenge/internal/repository"
+	"todoListChallenge/internal/search"
+
+	"gorm.io/gorm"
+)
+
+// DeleteMode decides what happens to the subtasks of a deleted todo
+type DeleteMode string
+
+const (
+	// DeleteCascade deletes the whole subtask tree along with the todo
+	DeleteCascade DeleteMode = "cascade"
+	// DeleteReparent moves the direct subtasks up to the deleted todo's parent
+	DeleteReparent DeleteMode = "reparent"
 )
 
+// todoBatchSize is how many todos EachTodo loads at once
+const todoBatchSize = 500
+
+// ErrVersionMismatch is returned when a todo or category is changed based on
+// a version other than its current one
+var ErrVersionMismatch = errors.New("version does not match, the resource has been changed")
+
 // TodoService handles business logic for Todo
 type TodoService struct {
 	repo *repository.TodoRepository
+	bus  *events.Bus
+
+	// pending holds events back until a bulk transaction commits
+	pending *[]pendingEvent
 }
 
-// NewTodoService creates a new TodoService
-func NewTodoService(repo *repository.TodoRepository) *TodoService {
-	return &TodoService{repo: repo}
+// NewTodoService creates a new TodoService that publishes changes to bus.
+// bus may be nil when no change feed is needed.
+func NewTodoService(repo *repository.TodoRepository, bus *events.Bus) *TodoService {
+	return &TodoService{repo: repo, bus: bus}
 }
 
-// CreateTodo creates a new todo with validation
-func (s *TodoService) CreateTodo(todo *models.Todo) error {
+// CreateTodo creates a new todo for a user with validation
+func (s *TodoService) CreateTodo(userID uint, todo *models.Todo) error {
+	todo.UserID = userID
 	if err := s.validateTodo(todo); err != nil {
 		return err
 	}
-	return s.repo.Create(todo)
+	if err := s.validateRelations(todo); err != nil {
+		return err
+	}
+	err := s.repo.Transaction(func(tx *repository.TodoRepository) error {
+		audit := newTodoAudit(tx, userID)
+		if err := audit.watchAncestors(todo.ParentID); err != nil {
+			return err
+		}
+		if err := tx.Create(todo); err != nil {
+			return err
+		}
+		if err := applyTags(tx, todo); err != nil {
+			return err
+		}
+		// An open subtask reopens a completed parent
+		if err := syncAncestors(tx, userID, todo.ParentID); err != nil {
+			return err
+		}
+		return audit.record(models.AuditUpdated, todo.ID)
+	})
+	if err != nil {
+		return dbError(err, "todo")
+	}
+	s.publish(userID, events.TodoCreated, todo.ID)
+	return nil
+}
+
+// CreateSubtask creates a new todo below the given parent
+func (s *TodoService) CreateSubtask(userID, parentID uint, todo *models.Todo) error {
+	todo.ParentID = &parentID
+	return s.CreateTodo(userID, todo)
+}
+
+// GetTodoByID gets a user's todo by ID
+func (s *TodoService) GetTodoByID(userID, id uint) (*models.Todo, error) {
+	todo, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, dbError(err, "todo")
+	}
+	return todo, nil
 }
 
-// GetTodoByID gets a todo by ID
-func (s *TodoService) GetTodoByID(id uint) (*models.Todo, error) {
-	return s.repo.GetByID(id)
+// GetSubtasks gets the subtask tree below a todo
+func (s *TodoService) GetSubtasks(userID, parentID uint) ([]models.Todo, error) {
+	parent, err := s.repo.GetByID(userID, parentID)
+	if err != nil {
+		return nil, dbError(err, "todo")
+	}
+	if parent.Subtasks == nil {
+		return []models.Todo{}, nil
+	}
+	return parent.Subtasks, nil
 }
 
-// GetTodos gets todos with pagination and filters
-func (s *TodoService) GetTodos(page, limit int, search, sortBy, sortOrder string, filters map[string]interface{}) ([]models.Todo, int64, error) {
+// GetTodos gets a user's todos with pagination and filters. search uses the
+// syntax of the search package; an invalid search returns a *search.SyntaxError.
+// The "q" filter holds a filter in the language of the filter package; an
+// invalid one returns a *filter.Error.
+func (s *TodoService) GetTodos(userID uint, page, limit int, searchText, sortBy, sortOrder string, filters map[string]interface{}) ([]models.Todo, int64, error) {
 	// Validate pagination
 	if page < 1 {
 		page = 1
 	}
+	limit = validLimit(limit)
+
+	query, sortBy, sortOrder, err := prepareList(userID, searchText, sortBy, sortOrder, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.repo.GetAll(userID, page, limit, query, sortBy, sortOrder, filters)
+}
+
+// GetTodoPage gets a page of a user's todos by cursor rather than page
+// number, so todos added or removed while paging are neither repeated nor
+// skipped. An empty cursor starts at the beginning. It returns the cursors
+// of the next and previous pages, empty when there is nothing more in that
+// direction, and ErrInvalidCursor for a cursor it did not issue or one
+// issued for a different sort.
+func (s *TodoService) GetTodoPage(userID uint, cursor string, limit int, searchText, sortBy, sortOrder string, filters map[string]interface{}) ([]models.Todo, string, string, error) {
+	limit = validLimit(limit)
+
+	query, sortBy, sortOrder, err := prepareList(userID, searchText, sortBy, sortOrder, filters)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var position *repository.Cursor
+	if cursor != "" {
+		position, err = decodeCursor(cursor)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if position.SortBy != sortBy || position.SortOrder != sortOrder {
+			return nil, "", "", ErrInvalidCursor
+		}
+	}
+
+	page, err := s.repo.GetPage(userID, position, limit, query, sortBy, sortOrder, filters)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return page.Todos, encodeCursor(page.Next), encodeCursor(page.Prev), nil
+}
+
+// EachTodo calls fn with every one of a user's todos matching a search and
+// filters, oldest first, a batch at a time
+func (s *TodoService) EachTodo(userID uint, searchText string, filters map[string]interface{}, fn func(todos []models.Todo) error) error {
+	query, _, _, err := prepareList(userID, searchText, "created_at", "asc", filters)
+	if err != nil {
+		return err
+	}
+	return s.repo.EachBatch(userID, query, filters, todoBatchSize, fn)
+}
+
+// validLimit returns the page size to use for a requested limit
+func validLimit(limit int) int {
 	if limit < 1 || limit > 100 {
-		limit = 10
+		return 10
 	}
+	return limit
+}
 
+// prepareList validates the sort and filters of a todo list and parses its search
+func prepareList(userID uint, searchText, sortBy, sortOrder string, filters map[string]interface{}) (*search.Query, string, string, error) {
 	// Validate sort
-	validSortFields := map[string]bool{"title": true, "created_at": true, "updated_at": true, "due_date": true, "priority": true}
-	if sortBy != "" && !validSortFields[sortBy] {
+	validSortFields := map[string]bool{"title": true, "created_at": true, "updated_at": true, "due_date": true, "priority": true, "relevance": true}
+	if !validSortFields[sortBy] {
 		sortBy = "created_at"
 	}
 	if sortOrder != "asc" && sortOrder != "desc" {
@@ -56,37 +193,541 @@ func (s *TodoService) GetTodos(page, limit int, search, sortBy, sortOrder string
 		}
 	}
 
-	return s.repo.GetAll(page, limit, search, sortBy, sortOrder, filters)
+	// Compile the filter language into a clause
+	if text, ok := filters["q"].(string); ok {
+		node, err := filter.Parse(text)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if node == nil {
+			delete(filters, "q")
+		} else {
+			expr, err := filter.Compile(node, filter.Env{UserID: userID, Now: time.Now()})
+			if err != nil {
+				return nil, "", "", err
+			}
+			filters["q"] = expr
+		}
+	}
+
+	query, err := search.Parse(searchText)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if sortBy == "relevance" {
+		if query == nil {
+			sortBy = "created_at" // Relevance needs a search
+		} else {
+			sortOrder = "desc" // Best matches always come first
+		}
+	}
+	return query, sortBy, sortOrder, nil
 }
 
-// UpdateTodo updates a todo with validation
-func (s *TodoService) UpdateTodo(todo *models.Todo) error {
+// UpdateTodo updates a user's todo with validation. When todo.Version is
+// set, the update only applies to that version of the todo and fails with
+// ErrVersionMismatch otherwise. On success todo.Version is the new version.
+func (s *TodoService) UpdateTodo(userID uint, todo *models.Todo) error {
+	return s.updateTodo(userID, todo, models.AuditUpdated)
+}
+
+// updateTodo updates a user's todo like UpdateTodo and records the change
+// in the audit log as action
+func (s *TodoService) updateTodo(userID uint, todo *models.Todo, action string) error {
+	todo.UserID = userID
 	if err := s.validateTodo(todo); err != nil {
 		return err
 	}
-	return s.repo.Update(todo)
+	if err := s.validateRelations(todo); err != nil {
+		return err
+	}
+	existing, err := s.repo.FindByID(userID, todo.ID)
+	if err != nil {
+		return dbError(err, "todo")
+	}
+	todo.CreatedAt = existing.CreatedAt
+	version := todo.Version
+	if version == 0 {
+		version = existing.Version
+	}
+	err = s.repo.Transaction(func(tx *repository.TodoRepository) error {
+		audit := newTodoAudit(tx, userID)
+		if err := audit.watch(todo.ID); err != nil {
+			return err
+		}
+		moved := !sameID(existing.ParentID, todo.ParentID)
+		if moved || existing.Completed != todo.Completed {
+			if err := audit.watchAncestors(existing.ParentID); err != nil {
+				return err
+			}
+			if err := audit.watchAncestors(todo.ParentID); err != nil {
+				return err
+			}
+		}
+		updated, err := tx.Update(todo, version)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return ErrVersionMismatch
+		}
+		if err := applyTags(tx, todo); err != nil {
+			return err
+		}
+		// The old and new parents follow the state of their subtasks
+		if moved {
+			if err := syncAncestors(tx, userID, existing.ParentID); err != nil {
+				return err
+			}
+		}
+		if moved || existing.Completed != todo.Completed {
+			if err := syncAncestors(tx, userID, todo.ParentID); err != nil {
+				return err
+			}
+		}
+		if !sameTime(existing.DueDate, todo.DueDate) {
+			if err := tx.RescheduleReminders(todo); err != nil {
+				return err
+			}
+		}
+		return audit.record(action)
+	})
+	if err != nil {
+		return dbError(err, "todo")
+	}
+	s.publish(userID, events.TodoUpdated, todo.ID)
+	return nil
+}
+
+// AddTag attaches a tag to a todo
+func (s *TodoService) AddTag(userID, todoID, tagID uint) error {
+	todo, tag, err := s.findTodoAndTag(userID, todoID, tagID)
+	if err != nil {
+		return err
+	}
+	err = s.repo.Transaction(func(tx *repository.TodoRepository) error {
+		audit := newTodoAudit(tx, userID)
+		if err := audit.watch(todoID); err != nil {
+			return err
+		}
+		if err := tx.AddTag(todo, tag); err != nil {
+			return err
+		}
+		return audit.record(models.AuditUpdated)
+	})
+	if err != nil {
+		return dbError(err, "todo")
+	}
+	s.publish(userID, events.TodoUpdated, todoID)
+	return nil
+}
+
+// RemoveTag detaches a tag from a todo
+func (s *TodoService) RemoveTag(userID, todoID, tagID uint) error {
+	todo, tag, err := s.findTodoAndTag(userID, todoID, tagID)
+	if err != nil {
+		return err
+	}
+	err = s.repo.Transaction(func(tx *repository.TodoRepository) error {
+		audit := newTodoAudit(tx, userID)
+		if err := audit.watch(todoID); err != nil {
+			return err
+		}
+		if err := tx.RemoveTag(todo, tag); err != nil {
+			return err
+		}
+		return audit.record(models.AuditUpdated)
+	})
+	if err != nil {
+		return dbError(err, "todo")
+	}
+	s.publish(userID, events.TodoUpdated, todoID)
+	return nil
 }
 
-// DeleteTodo deletes a todo
-func (s *TodoService) DeleteTodo(id uint) error {
-	return s.repo.Delete(id)
+// findTodoAndTag loads the todo and tag referenced by a tag attach/detach
+func (s *TodoService) findTodoAndTag(userID, todoID, tagID uint) (*models.Todo, *models.Tag, error) {
+	todo, err := s.repo.FindByID(userID, todoID)
+	if err != nil {
+		return nil, nil, dbError(err, "todo")
+	}
+	tags, err := s.repo.GetTagsByIDs(userID, []uint{tagID})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tags) == 0 {
+		return nil, nil, &NotFoundError{Resource: "tag"}
+	}
+	return todo, &tags[0], nil
 }
 
-// ToggleComplete toggles completion status
-func (s *TodoService) ToggleComplete(id uint) error {
-	return s.repo.ToggleComplete(id)
+// applyTags replaces the todo's tags with TagIDs when the client sent them
+func applyTags(tx *repository.TodoRepository, todo *models.Todo) error {
+	if todo.TagIDs == nil {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(todo.TagIDs))
+	seen := make(map[uint]bool, len(todo.TagIDs))
+	for _, id := range todo.TagIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	tags, err := tx.GetTagsByIDs(todo.UserID, ids)
+	if err != nil {
+		return err
+	}
+	if len(tags) != len(ids) {
+		return &ValidationError{Field: "tag_ids", Msg: "tag not found"}
+	}
+	return tx.ReplaceTags(todo, tags)
+}
+
+// DeleteTodo deletes a user's todo, handling its subtasks according to mode.
+// A non-zero version must match the todo's current version.
+func (s *TodoService) DeleteTodo(userID, id uint, mode DeleteMode, version uint) error {
+	if mode == "" {
+		mode = DeleteCascade
+	}
+	if mode != DeleteCascade && mode != DeleteReparent {
+		return &ValidationError{Field: "subtasks", Msg: "subtasks must be cascade or reparent"}
+	}
+
+	var ids []uint
+	err := s.repo.Transaction(func(tx *repository.TodoRepository) error {
+		if err := claimVersion(tx, userID, id, version); err != nil {
+			return err
+		}
+		todo, err := tx.FindByID(userID, id)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // Deleting a missing todo is a no-op
+		}
+		if err != nil {
+			return err
+		}
+
+		audit := newTodoAudit(tx, userID)
+		ids = []uint{id}
+		if mode == DeleteReparent {
+			children, err := tx.GetChildren(userID, id)
+			if err != nil {
+				return err
+			}
+			for _, child := range children {
+				if err := audit.watch(child.ID); err != nil {
+					return err
+				}
+			}
+			if err := tx.Reparent(userID, id, todo.ParentID); err != nil {
+				return err
+			}
+		} else {
+			descendants, err := tx.GetDescendantIDs(userID, id)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, descendants...)
+		}
+		if err := audit.watch(ids...); err != nil {
+			return err
+		}
+		if err := audit.watchAncestors(todo.ParentID); err != nil {
+			return err
+		}
+
+		if err := tx.Delete(userID, ids...); err != nil {
+			return err
+		}
+		if err := syncAncestors(tx, userID, todo.ParentID); err != nil {
+			return err
+		}
+		return audit.record(models.AuditUpdated)
+	})
+	if err != nil {
+		return dbError(err, "todo")
+	}
+	if len(ids) > 0 {
+		s.publishDeleted(userID, events.Deleted{ID: id, IDs: ids})
+	}
+	return nil
+}
+
+// ToggleComplete toggles completion status.
+//
+// Completing a todo also completes every subtask below it, while reopening a
+// todo leaves its subtasks untouched. Afterwards each ancestor is marked
+// completed exactly when all of its direct subtasks are completed.
+//
+// Completing a recurring todo creates its next occurrence and moves the
+// recurrence rule onto it, so reopening the old instance does not spawn again.
+//
+// A non-zero version must match the todo's current version.
+func (s *TodoService) ToggleComplete(userID, id, version uint) error {
+	var spawned *models.Todo
+	err := s.repo.Transaction(func(tx *repository.TodoRepository) error {
+		if err := claimVersion(tx, userID, id, version); err != nil {
+			return err
+		}
+		todo, err := tx.FindByID(userID, id)
+		if err != nil {
+			return err
+		}
+
+		completed := !todo.Completed
+		ids := []uint{id}
+		if completed {
+			descendants, err := tx.GetDescendantIDs(userID, id)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, descendants...)
+		}
+		audit := newTodoAudit(tx, userID)
+		if err := audit.watch(ids...); err != nil {
+			return err
+		}
+		if err := audit.watchAncestors(todo.ParentID); err != nil {
+			return err
+		}
+
+		if err := tx.SetCompleted(userID, ids, completed); err != nil {
+			return err
+		}
+		if completed && todo.Recurrence != "" {
+			if spawned, err = spawnNextOccurrence(tx, todo); err != nil {
+				return err
+			}
+		}
+		if err := syncAncestors(tx, userID, todo.ParentID); err != nil {
+			return err
+		}
+		if spawned != nil {
+			return audit.record(models.AuditToggled, spawned.ID)
+		}
+		return audit.record(models.AuditToggled)
+	})
+	if err != nil {
+		return dbError(err, "todo")
+	}
+	s.publish(userID, events.TodoToggled, id)
+	if spawned != nil {
+		s.publish(userID, events.TodoCreated, spawned.ID)
+	}
+	return nil
+}
+
+// claimVersion locks a todo for the rest of the transaction when a version
+// is given, failing with ErrVersionMismatch unless the todo is at that
+// version. A missing todo is left to the caller to report.
+func claimVersion(tx *repository.TodoRepository, userID, id, version uint) error {
+	if version == 0 {
+		return nil
+	}
+	claimed, err := tx.ClaimVersion(userID, id, version)
+	if err != nil || claimed {
+		return err
+	}
+	if _, err := tx.FindByID(userID, id); err != nil {
+		return err
+	}
+	return ErrVersionMismatch
+}
+
+// spawnNextOccurrence creates the next instance of a recurring todo, due on
+// the following occurrence of its rule, and clears the rule on the old one.
+// It returns nil when the series has ended.
+func spawnNextOccurrence(tx *repository.TodoRepository, todo *models.Todo) (*models.Todo, error) {
+	rule, err := recurrence.Parse(todo.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence: %w", err)
+	}
+	if todo.DueDate == nil {
+		return nil, errors.New("recurring todos need a due date")
+	}
+
+	if err := tx.SetRecurrence(todo.UserID, todo.ID, ""); err != nil {
+		return nil, err
+	}
+	next, rest, ok := rule.Advance(*todo.DueDate)
+	if !ok {
+		return nil, nil // The series has ended
+	}
+
+	current, err := tx.GetByID(todo.UserID, todo.ID)
+	if err != nil {
+		return nil, err
+	}
+	occurrence := &models.Todo{
+		UserID:      current.UserID,
+		Title:       current.Title,
+		Description: current.Description,
+		CategoryID:  current.CategoryID,
+		ParentID:    current.ParentID,
+		Priority:    current.Priority,
+		DueDate:     &next,
+		Recurrence:  rest.String(),
+	}
+	if err := tx.Create(occurrence); err != nil {
+		return nil, err
+	}
+	if err := tx.CopyReminders(current, occurrence); err != nil {
+		return nil, err
+	}
+	return occurrence, tx.ReplaceTags(occurrence, current.Tags)
+}
+
+// sameTime reports whether two optional times are both unset or equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// sameID reports whether two optional IDs are both unset or equal
+func sameID(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// publish sends a todo event carrying the todo as it is now stored
+func (s *TodoService) publish(userID uint, eventType string, id uint) {
+	if s.pending != nil {
+		*s.pending = append(*s.pending, pendingEvent{userID: userID, eventType: eventType, id: id})
+		return
+	}
+	if s.bus == nil {
+		return
+	}
+	todo, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return // The todo is gone again; its delete event follows
+	}
+	s.bus.Publish(userID, eventType, todo)
+}
+
+// publishDeleted sends the event for deleted todos
+func (s *TodoService) publishDeleted(userID uint, deleted events.Deleted) {
+	if s.pending != nil {
+		*s.pending = append(*s.pending, pendingEvent{userID: userID, eventType: events.TodoDeleted, deleted: &deleted})
+		return
+	}
+	s.bus.Publish(userID, events.TodoDeleted, deleted)
+}
+
+// GetOccurrences previews the next n due dates of a recurring todo
+func (s *TodoService) GetOccurrences(userID, id uint, n int) ([]time.Time, error) {
+	todo, err := s.repo.FindByID(userID, id)
+	if err != nil {
+		return nil, dbError(err, "todo")
+	}
+	if todo.Recurrence == "" || todo.DueDate == nil {
+		return nil, &ConflictError{Msg: "todo is not recurring"}
+	}
+	return s.PreviewRecurrence(todo.Recurrence, *todo.DueDate, n)
+}
+
+// PreviewRecurrence returns the first n occurrences of a rule starting at start
+func (s *TodoService) PreviewRecurrence(rule string, start time.Time, n int) ([]time.Time, error) {
+	parsed, err := recurrence.Parse(rule)
+	if err != nil {
+		return nil, &ValidationError{Field: "rule", Msg: "invalid recurrence: " + err.Error()}
+	}
+	if n < 1 || n > 100 {
+		n = 10
+	}
+	return parsed.Occurrences(start, n), nil
+}
+
+// syncAncestors walks up from parentID, completing or reopening each ancestor
+// so that it matches the state of its direct subtasks
+func syncAncestors(tx *repository.TodoRepository, userID uint, parentID *uint) error {
+	for parentID != nil {
+		parent, err := tx.FindByID(userID, *parentID)
+		if err != nil {
+			return err
+		}
+
+		progress, err := tx.GetProgress(userID, []uint{parent.ID})
+		if err != nil {
+			return err
+		}
+		p, ok := progress[parent.ID]
+		if !ok {
+			return nil // No subtasks left, keep the parent as it is
+		}
+
+		done := p.Completed == p.Total
+		if parent.Completed == done {
+			return nil
+		}
+		if err := tx.SetCompleted(userID, []uint{parent.ID}, done); err != nil {
+			return err
+		}
+		parentID = parent.ParentID
+	}
+	return nil
 }
 
 // validateTodo validates todo fields
 func (s *TodoService) validateTodo(todo *models.Todo) error {
 	if strings.TrimSpace(todo.Title) == "" {
-		return errors.New("title is required")
+		return &ValidationError{Field: "title", Msg: "title is required"}
 	}
 	if len(todo.Title) > 255 {
-		return errors.New("title must be less than 255 characters")
+		return &ValidationError{Field: "title", Msg: "title must be less than 255 characters"}
+	}
+	if todo.Priority == "" {
+		// Updates write every column, so the column default would not apply
+		todo.Priority = models.PriorityMedium
+	}
+	if todo.Priority != models.PriorityHigh && todo.Priority != models.PriorityMedium && todo.Priority != models.PriorityLow {
+		return &ValidationError{Field: "priority", Msg: "invalid priority value"}
+	}
+	if todo.Recurrence != "" {
+		rule, err := recurrence.Parse(todo.Recurrence)
+		if err != nil {
+			return &ValidationError{Field: "recurrence", Msg: "invalid recurrence: " + err.Error()}
+		}
+		if todo.DueDate == nil {
+			return &ValidationError{Field: "due_date", Msg: "recurring todos need a due date"}
+		}
+		todo.Recurrence = rule.String()
+	}
+	return nil
+}
+
+// validateRelations checks that the category and parent belong to the todo's
+// owner and that the parent would not create a cycle
+func (s *TodoService) validateRelations(todo *models.Todo) error {
+	if todo.CategoryID != nil {
+		exists, err := s.repo.CategoryExists(todo.UserID, *todo.CategoryID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return &ValidationError{Field: "category_id", Msg: "category not found"}
+		}
 	}
-	if todo.Priority != "" && todo.Priority != models.PriorityHigh && todo.Priority != models.PriorityMedium && todo.Priority != models.PriorityLow {
-		return errors.New("invalid priority value")
+
+	parentID := todo.ParentID
+	for parentID != nil {
+		if todo.ID != 0 && *parentID == todo.ID {
+			return &ValidationError{Field: "parent_id", Msg: "a todo cannot be a subtask of itself or its subtasks"}
+		}
+		parent, err := s.repo.FindByID(todo.UserID, *parentID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &ValidationError{Field: "parent_id", Msg: "parent todo not found"}
+		}
+		if err != nil {
+			return err
+		}
+		parentID = parent.ParentID
 	}
 	return nil
-}
\ No newline at end of file
+}
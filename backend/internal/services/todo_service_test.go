@@ -1,30 +1,41 @@
 package services
 
 import (
+	"fmt"
+	"strings"
 	"testing"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/filter"
 	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/patch"
 	"todoListChallenge/internal/repository"
+	"todoListChallenge/internal/search"
 
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// testUserID owns everything created in service tests
+const testUserID uint = 1
+
 func setupTestDB() *gorm.DB {
-	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&models.Todo{}, &models.Category{})
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+	db.AutoMigrate(&models.Todo{}, &models.Category{}, &models.Tag{}, &models.AuditEntry{}, &models.Comment{}, &models.Attachment{}, &models.AttachmentBlob{}, &models.Reminder{}, &models.Notification{}, &models.CalDAVObject{})
+	repository.SetupSQLiteSearch(db)
 	return db
 }
 
 func TestTodoService_CreateTodo(t *testing.T) {
 	db := setupTestDB()
 	repo := repository.NewTodoRepository(db)
-	service := NewTodoService(repo)
+	service := NewTodoService(repo, nil)
 
 	t.Run("success", func(t *testing.T) {
 		todo := &models.Todo{Title: "Test Todo", Completed: false}
 
-		err := service.CreateTodo(todo)
+		err := service.CreateTodo(testUserID, todo)
 
 		assert.NoError(t, err)
 		assert.NotZero(t, todo.ID)
@@ -33,7 +44,7 @@ func TestTodoService_CreateTodo(t *testing.T) {
 	t.Run("validation error - empty title", func(t *testing.T) {
 		todo := &models.Todo{Title: "", Completed: false}
 
-		err := service.CreateTodo(todo)
+		err := service.CreateTodo(testUserID, todo)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "title is required")
@@ -42,7 +53,7 @@ func TestTodoService_CreateTodo(t *testing.T) {
 	t.Run("validation error - title too long", func(t *testing.T) {
 		todo := &models.Todo{Title: string(make([]byte, 256)), Completed: false}
 
-		err := service.CreateTodo(todo)
+		err := service.CreateTodo(testUserID, todo)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "title must be less than 255 characters")
@@ -51,7 +62,7 @@ func TestTodoService_CreateTodo(t *testing.T) {
 	t.Run("validation error - invalid priority", func(t *testing.T) {
 		todo := &models.Todo{Title: "Test", Priority: "invalid"}
 
-		err := service.CreateTodo(todo)
+		err := service.CreateTodo(testUserID, todo)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "invalid priority value")
@@ -61,14 +72,14 @@ func TestTodoService_CreateTodo(t *testing.T) {
 func TestTodoService_GetTodoByID(t *testing.T) {
 	db := setupTestDB()
 	repo := repository.NewTodoRepository(db)
-	service := NewTodoService(repo)
+	service := NewTodoService(repo, nil)
 
 	// Create a todo first
 	todo := &models.Todo{Title: "Test Todo"}
-	service.CreateTodo(todo)
+	service.CreateTodo(testUserID, todo)
 
 	t.Run("success", func(t *testing.T) {
-		found, err := service.GetTodoByID(todo.ID)
+		found, err := service.GetTodoByID(testUserID, todo.ID)
 
 		assert.NoError(t, err)
 		assert.Equal(t, todo.ID, found.ID)
@@ -76,9 +87,11 @@ func TestTodoService_GetTodoByID(t *testing.T) {
 	})
 
 	t.Run("not found", func(t *testing.T) {
-		found, err := service.GetTodoByID(999)
+		found, err := service.GetTodoByID(testUserID, 999)
 
-		assert.Error(t, err)
+		var notFound *NotFoundError
+		assert.ErrorAs(t, err, &notFound)
+		assert.Equal(t, "todo not found", err.Error())
 		assert.Nil(t, found)
 	})
 }
@@ -86,14 +99,14 @@ func TestTodoService_GetTodoByID(t *testing.T) {
 func TestTodoService_GetTodos(t *testing.T) {
 	db := setupTestDB()
 	repo := repository.NewTodoRepository(db)
-	service := NewTodoService(repo)
+	service := NewTodoService(repo, nil)
 
 	// Create some todos
-	service.CreateTodo(&models.Todo{Title: "First Todo"})
-	service.CreateTodo(&models.Todo{Title: "Second Todo"})
+	service.CreateTodo(testUserID, &models.Todo{Title: "First Todo"})
+	service.CreateTodo(testUserID, &models.Todo{Title: "Second Todo"})
 
 	t.Run("success with defaults", func(t *testing.T) {
-		todos, total, err := service.GetTodos(1, 10, "", "created_at", "desc", make(map[string]interface{}))
+		todos, total, err := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", make(map[string]interface{}))
 
 		assert.NoError(t, err)
 		assert.Equal(t, int64(2), total)
@@ -101,7 +114,7 @@ func TestTodoService_GetTodos(t *testing.T) {
 	})
 
 	t.Run("with search", func(t *testing.T) {
-		todos, total, err := service.GetTodos(1, 10, "First", "created_at", "desc", make(map[string]interface{}))
+		todos, total, err := service.GetTodos(testUserID, 1, 10, "First", "created_at", "desc", make(map[string]interface{}))
 
 		assert.NoError(t, err)
 		assert.Equal(t, int64(1), total)
@@ -110,29 +123,106 @@ func TestTodoService_GetTodos(t *testing.T) {
 	})
 }
 
+func TestTodoService_Search(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	deployDocs := &models.Todo{Title: "Write docs", Description: "Explain how to deploy <it> safely"}
+	deployProd := &models.Todo{Title: "Deploy API server to production"}
+	deployStaging := &models.Todo{Title: "Deploy API server to staging"}
+	groceries := &models.Todo{Title: "Buy groceries", Description: "Milk and eggs"}
+	for _, todo := range []*models.Todo{deployDocs, deployProd, deployStaging, groceries} {
+		assert.NoError(t, service.CreateTodo(testUserID, todo))
+	}
+
+	titles := func(searchText string) []string {
+		todos, _, err := service.GetTodos(testUserID, 1, 10, searchText, "title", "asc", map[string]interface{}{})
+		assert.NoError(t, err, searchText)
+		result := []string{}
+		for _, todo := range todos {
+			result = append(result, todo.Title)
+		}
+		return result
+	}
+
+	t.Run("every word must match, in any order", func(t *testing.T) {
+		assert.Equal(t, []string{"Deploy API server to production", "Deploy API server to staging"}, titles("server deploy api"))
+		assert.Equal(t, []string{"Buy groceries"}, titles("eggs milk"))
+	})
+
+	t.Run("phrase, prefix and negation", func(t *testing.T) {
+		assert.Equal(t, []string{"Deploy API server to production", "Deploy API server to staging"}, titles(`"api server"`))
+		assert.Equal(t, []string{"Deploy API server to production"}, titles("prod*"))
+		assert.Equal(t, []string{"Deploy API server to production", "Write docs"}, titles("deploy -staging"))
+	})
+
+	t.Run("updates and deletes are searchable immediately", func(t *testing.T) {
+		groceries.Title = "Buy vegetables"
+		assert.NoError(t, service.UpdateTodo(testUserID, groceries))
+		assert.Empty(t, titles("groceries"))
+		assert.Equal(t, []string{"Buy vegetables"}, titles("vegetables"))
+
+		assert.NoError(t, service.DeleteTodo(testUserID, groceries.ID, DeleteCascade, 0))
+		assert.Empty(t, titles("vegetables"))
+	})
+
+	t.Run("results carry highlighted matches", func(t *testing.T) {
+		todos, _, err := service.GetTodos(testUserID, 1, 10, "deploy", "title", "asc", map[string]interface{}{})
+		assert.NoError(t, err)
+		docs := todos[len(todos)-1]
+		assert.Equal(t, "Write docs", docs.Search.Title)
+		assert.Contains(t, docs.Search.Description, "<mark>deploy</mark>")
+		assert.Contains(t, docs.Search.Description, "&lt;it&gt;")
+		assert.Equal(t, "<mark>Deploy</mark> API server to production", todos[0].Search.Title)
+
+		todos, _, _ = service.GetTodos(testUserID, 1, 10, "", "title", "asc", map[string]interface{}{})
+		assert.Nil(t, todos[0].Search)
+	})
+
+	t.Run("invalid search", func(t *testing.T) {
+		_, _, err := service.GetTodos(testUserID, 1, 10, `"deploy`, "title", "asc", map[string]interface{}{})
+		var syntaxErr *search.SyntaxError
+		assert.ErrorAs(t, err, &syntaxErr)
+	})
+
+	t.Run("relevance ranks title matches first", func(t *testing.T) {
+		var fts int64
+		db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE name = 'todos_fts'").Scan(&fts)
+		if fts == 0 {
+			t.Skip("SQLite was built without FTS5; run with -tags sqlite_fts5")
+		}
+
+		todos, _, err := service.GetTodos(testUserID, 1, 10, "deploy", "relevance", "desc", map[string]interface{}{})
+		assert.NoError(t, err)
+		assert.Len(t, todos, 3)
+		assert.Equal(t, "Write docs", todos[2].Title)
+		assert.Greater(t, todos[0].Search.Rank, todos[2].Search.Rank)
+	})
+}
+
 func TestTodoService_UpdateTodo(t *testing.T) {
 	db := setupTestDB()
 	repo := repository.NewTodoRepository(db)
-	service := NewTodoService(repo)
+	service := NewTodoService(repo, nil)
 
 	// Create a todo first
 	todo := &models.Todo{Title: "Original Todo"}
-	service.CreateTodo(todo)
+	service.CreateTodo(testUserID, todo)
 
 	t.Run("success", func(t *testing.T) {
 		todo.Title = "Updated Todo"
-		err := service.UpdateTodo(todo)
+		err := service.UpdateTodo(testUserID, todo)
 
 		assert.NoError(t, err)
 
 		// Verify update
-		updated, _ := service.GetTodoByID(todo.ID)
+		updated, _ := service.GetTodoByID(testUserID, todo.ID)
 		assert.Equal(t, "Updated Todo", updated.Title)
 	})
 
 	t.Run("validation error", func(t *testing.T) {
 		todo.Title = ""
-		err := service.UpdateTodo(todo)
+		err := service.UpdateTodo(testUserID, todo)
 
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "title is required")
@@ -142,19 +232,19 @@ func TestTodoService_UpdateTodo(t *testing.T) {
 func TestTodoService_DeleteTodo(t *testing.T) {
 	db := setupTestDB()
 	repo := repository.NewTodoRepository(db)
-	service := NewTodoService(repo)
+	service := NewTodoService(repo, nil)
 
 	// Create a todo first
 	todo := &models.Todo{Title: "Test Todo"}
-	service.CreateTodo(todo)
+	service.CreateTodo(testUserID, todo)
 
 	t.Run("success", func(t *testing.T) {
-		err := service.DeleteTodo(todo.ID)
+		err := service.DeleteTodo(testUserID, todo.ID, DeleteCascade, 0)
 
 		assert.NoError(t, err)
 
 		// Verify deletion
-		found, err := service.GetTodoByID(todo.ID)
+		found, err := service.GetTodoByID(testUserID, todo.ID)
 		assert.Error(t, err)
 		assert.Nil(t, found)
 	})
@@ -163,19 +253,19 @@ func TestTodoService_DeleteTodo(t *testing.T) {
 func TestTodoService_ToggleComplete(t *testing.T) {
 	db := setupTestDB()
 	repo := repository.NewTodoRepository(db)
-	service := NewTodoService(repo)
+	service := NewTodoService(repo, nil)
 
 	// Create a todo first
 	todo := &models.Todo{Title: "Test Todo", Completed: false}
-	service.CreateTodo(todo)
+	service.CreateTodo(testUserID, todo)
 
 	t.Run("success", func(t *testing.T) {
-		err := service.ToggleComplete(todo.ID)
+		err := service.ToggleComplete(testUserID, todo.ID, 0)
 
 		assert.NoError(t, err)
 
 		// Verify toggle
-		updated, _ := service.GetTodoByID(todo.ID)
+		updated, _ := service.GetTodoByID(testUserID, todo.ID)
 		assert.True(t, updated.Completed)
 	})
 }
@@ -183,20 +273,20 @@ func TestTodoService_ToggleComplete(t *testing.T) {
 func TestTodoService_GetTodosWithFilters(t *testing.T) {
 	db := setupTestDB()
 	repo := repository.NewTodoRepository(db)
-	service := NewTodoService(repo)
+	service := NewTodoService(repo, nil)
 
 	// Create category
-	category := &models.Category{Name: "Work", Color: "#3B82F6"}
+	category := &models.Category{UserID: testUserID, Name: "Work", Color: "#3B82F6"}
 	db.Create(category)
 
 	// Create todos with different attributes
-	service.CreateTodo(&models.Todo{Title: "Todo 1", Completed: true, CategoryID: &category.ID, Priority: models.PriorityHigh})
-	service.CreateTodo(&models.Todo{Title: "Todo 2", Completed: false, CategoryID: &category.ID, Priority: models.PriorityMedium})
-	service.CreateTodo(&models.Todo{Title: "Todo 3", Completed: false, Priority: models.PriorityLow})
+	service.CreateTodo(testUserID, &models.Todo{Title: "Todo 1", Completed: true, CategoryID: &category.ID, Priority: models.PriorityHigh})
+	service.CreateTodo(testUserID, &models.Todo{Title: "Todo 2", Completed: false, CategoryID: &category.ID, Priority: models.PriorityMedium})
+	service.CreateTodo(testUserID, &models.Todo{Title: "Todo 3", Completed: false, Priority: models.PriorityLow})
 
 	t.Run("filter by completed status", func(t *testing.T) {
 		filters := map[string]interface{}{"completed": false}
-		todos, total, err := service.GetTodos(1, 10, "", "created_at", "desc", filters)
+		todos, total, err := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", filters)
 
 		assert.NoError(t, err)
 		assert.Equal(t, int64(2), total)
@@ -206,7 +296,7 @@ func TestTodoService_GetTodosWithFilters(t *testing.T) {
 
 	t.Run("filter by category", func(t *testing.T) {
 		filters := map[string]interface{}{"category_id": category.ID}
-		todos, total, err := service.GetTodos(1, 10, "", "created_at", "desc", filters)
+		todos, total, err := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", filters)
 
 		assert.NoError(t, err)
 		assert.Equal(t, int64(2), total)
@@ -215,7 +305,7 @@ func TestTodoService_GetTodosWithFilters(t *testing.T) {
 
 	t.Run("filter by priority", func(t *testing.T) {
 		filters := map[string]interface{}{"priority": string(models.PriorityHigh)}
-		todos, total, err := service.GetTodos(1, 10, "", "created_at", "desc", filters)
+		todos, total, err := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", filters)
 
 		assert.NoError(t, err)
 		assert.Equal(t, int64(1), total)
@@ -229,7 +319,7 @@ func TestTodoService_GetTodosWithFilters(t *testing.T) {
 			"category_id": category.ID,
 			"priority":    string(models.PriorityMedium),
 		}
-		todos, total, err := service.GetTodos(1, 10, "", "created_at", "desc", filters)
+		todos, total, err := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", filters)
 
 		assert.NoError(t, err)
 		assert.Equal(t, int64(1), total)
@@ -239,9 +329,1076 @@ func TestTodoService_GetTodosWithFilters(t *testing.T) {
 
 	t.Run("invalid priority filter should be ignored", func(t *testing.T) {
 		filters := map[string]interface{}{"priority": "invalid"}
-		_, total, err := service.GetTodos(1, 10, "", "created_at", "desc", filters)
+		_, total, err := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", filters)
 
 		assert.NoError(t, err)
 		assert.Equal(t, int64(3), total) // Should return all todos
 	})
 }
+
+func TestTodoService_Subtasks(t *testing.T) {
+	db := setupTestDB()
+	repo := repository.NewTodoRepository(db)
+	service := NewTodoService(repo, nil)
+
+	parent := &models.Todo{Title: "Release"}
+	service.CreateTodo(testUserID, parent)
+	child := &models.Todo{Title: "Write changelog"}
+	service.CreateSubtask(testUserID, parent.ID, child)
+	grandchild := &models.Todo{Title: "Collect PR links"}
+	service.CreateSubtask(testUserID, child.ID, grandchild)
+	sibling := &models.Todo{Title: "Tag version"}
+	service.CreateSubtask(testUserID, parent.ID, sibling)
+
+	t.Run("loads subtask tree with progress", func(t *testing.T) {
+		found, err := service.GetTodoByID(testUserID, parent.ID)
+
+		assert.NoError(t, err)
+		assert.Len(t, found.Subtasks, 2)
+		assert.Equal(t, &models.Progress{Completed: 0, Total: 2}, found.Progress)
+		assert.Len(t, found.Subtasks[0].Subtasks, 1)
+		assert.Equal(t, "Collect PR links", found.Subtasks[0].Subtasks[0].Title)
+	})
+
+	t.Run("list includes progress", func(t *testing.T) {
+		todos, total, err := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", map[string]interface{}{"top_level": true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, &models.Progress{Completed: 0, Total: 2}, todos[0].Progress)
+	})
+
+	t.Run("parent must exist", func(t *testing.T) {
+		err := service.CreateSubtask(testUserID, 999, &models.Todo{Title: "Orphan"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "parent todo not found")
+	})
+
+	t.Run("cannot move a todo below its own subtask", func(t *testing.T) {
+		moved := *child
+		moved.ParentID = &grandchild.ID
+		err := service.UpdateTodo(testUserID, &moved)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot be a subtask of itself")
+	})
+
+	t.Run("completing the last subtask completes the parent", func(t *testing.T) {
+		assert.NoError(t, service.ToggleComplete(testUserID, child.ID, 0))
+		found, _ := service.GetTodoByID(testUserID, parent.ID)
+		assert.False(t, found.Completed)
+		assert.True(t, found.Subtasks[0].Subtasks[0].Completed) // grandchild completed with child
+
+		assert.NoError(t, service.ToggleComplete(testUserID, sibling.ID, 0))
+		found, _ = service.GetTodoByID(testUserID, parent.ID)
+		assert.True(t, found.Completed)
+	})
+
+	t.Run("reopening a subtask reopens its ancestors", func(t *testing.T) {
+		assert.NoError(t, service.ToggleComplete(testUserID, grandchild.ID, 0))
+
+		found, _ := service.GetTodoByID(testUserID, parent.ID)
+		assert.False(t, found.Completed)
+		assert.False(t, found.Subtasks[0].Completed)
+		assert.True(t, found.Subtasks[1].Completed)
+	})
+}
+
+func TestTodoService_SubtaskRollUp(t *testing.T) {
+	db := setupTestDB()
+	repo := repository.NewTodoRepository(db)
+	service := NewTodoService(repo, nil)
+
+	completed := func(id uint) bool {
+		found, err := service.GetTodoByID(testUserID, id)
+		assert.NoError(t, err)
+		return found.Completed
+	}
+
+	first := &models.Todo{Title: "Pack"}
+	service.CreateTodo(testUserID, first)
+	second := &models.Todo{Title: "Move"}
+	service.CreateTodo(testUserID, second)
+	box := &models.Todo{Title: "Books"}
+	service.CreateSubtask(testUserID, first.ID, box)
+	service.ToggleComplete(testUserID, box.ID, 0)
+	assert.True(t, completed(first.ID))
+
+	t.Run("a new open subtask reopens its parent", func(t *testing.T) {
+		lamp := &models.Todo{Title: "Lamp"}
+		assert.NoError(t, service.CreateSubtask(testUserID, first.ID, lamp))
+
+		assert.False(t, completed(first.ID))
+
+		lamp.Completed = true
+		assert.NoError(t, service.UpdateTodo(testUserID, lamp))
+		assert.True(t, completed(first.ID))
+	})
+
+	t.Run("completing a subtask by update completes its parent", func(t *testing.T) {
+		van := &models.Todo{Title: "Van"}
+		service.CreateSubtask(testUserID, second.ID, van)
+		assert.False(t, completed(second.ID))
+
+		found, _ := service.GetTodoByID(testUserID, van.ID)
+		found.Completed = true
+		assert.NoError(t, service.UpdateTodo(testUserID, found))
+
+		assert.True(t, completed(second.ID))
+	})
+
+	t.Run("moving a subtask updates the old and new parent", func(t *testing.T) {
+		plants := &models.Todo{Title: "Plants"}
+		service.CreateSubtask(testUserID, second.ID, plants)
+		assert.False(t, completed(second.ID))
+
+		plants.ParentID = &first.ID
+		assert.NoError(t, service.UpdateTodo(testUserID, plants))
+
+		assert.True(t, completed(second.ID))
+		assert.False(t, completed(first.ID))
+	})
+}
+
+func TestTodoService_DeleteTodoWithSubtasks(t *testing.T) {
+	db := setupTestDB()
+	repo := repository.NewTodoRepository(db)
+	service := NewTodoService(repo, nil)
+
+	t.Run("cascade deletes the whole tree", func(t *testing.T) {
+		parent := &models.Todo{Title: "Parent"}
+		service.CreateTodo(testUserID, parent)
+		child := &models.Todo{Title: "Child"}
+		service.CreateSubtask(testUserID, parent.ID, child)
+		grandchild := &models.Todo{Title: "Grandchild"}
+		service.CreateSubtask(testUserID, child.ID, grandchild)
+
+		err := service.DeleteTodo(testUserID, parent.ID, DeleteCascade, 0)
+
+		assert.NoError(t, err)
+		_, err = service.GetTodoByID(testUserID, grandchild.ID)
+		assert.Error(t, err)
+	})
+
+	t.Run("reparent moves subtasks up one level", func(t *testing.T) {
+		parent := &models.Todo{Title: "Parent"}
+		service.CreateTodo(testUserID, parent)
+		child := &models.Todo{Title: "Child"}
+		service.CreateSubtask(testUserID, parent.ID, child)
+		grandchild := &models.Todo{Title: "Grandchild"}
+		service.CreateSubtask(testUserID, child.ID, grandchild)
+
+		err := service.DeleteTodo(testUserID, child.ID, DeleteReparent, 0)
+
+		assert.NoError(t, err)
+		found, _ := service.GetTodoByID(testUserID, parent.ID)
+		assert.Len(t, found.Subtasks, 1)
+		assert.Equal(t, grandchild.ID, found.Subtasks[0].ID)
+	})
+}
+
+func TestTodoService_Trash(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	tag := &models.Tag{UserID: testUserID, Name: "chores", Color: "#10B981"}
+	db.Create(tag)
+	parent := &models.Todo{Title: "Clean house", TagIDs: []uint{tag.ID}}
+	service.CreateTodo(testUserID, parent)
+	kitchen := &models.Todo{Title: "Kitchen"}
+	service.CreateSubtask(testUserID, parent.ID, kitchen)
+	garage := &models.Todo{Title: "Garage"}
+	service.CreateSubtask(testUserID, parent.ID, garage)
+
+	t.Run("deleted todos move to the trash", func(t *testing.T) {
+		assert.NoError(t, service.DeleteTodo(testUserID, garage.ID, DeleteCascade, 0))
+		assert.NoError(t, service.DeleteTodo(testUserID, parent.ID, DeleteCascade, 0))
+
+		_, err := service.GetTodoByID(testUserID, parent.ID)
+		assert.Error(t, err)
+		todos, total, _ := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", map[string]interface{}{})
+		assert.Empty(t, todos)
+		assert.Equal(t, int64(0), total)
+
+		trash, err := service.GetTrash(testUserID)
+		assert.NoError(t, err)
+		assert.Len(t, trash, 3)
+		for _, todo := range trash {
+			assert.True(t, todo.DeletedAt.Valid)
+		}
+	})
+
+	t.Run("restore brings back the subtasks deleted with the todo", func(t *testing.T) {
+		restored, err := service.RestoreTodo(testUserID, parent.ID)
+
+		assert.NoError(t, err)
+		assert.False(t, restored.DeletedAt.Valid)
+		assert.Len(t, restored.Tags, 1)
+		assert.Len(t, restored.Subtasks, 1)
+		assert.Equal(t, "Kitchen", restored.Subtasks[0].Title)
+
+		trash, _ := service.GetTrash(testUserID)
+		assert.Len(t, trash, 1)
+		assert.Equal(t, garage.ID, trash[0].ID)
+	})
+
+	t.Run("a subtask whose parent is in the trash is restored at the top level", func(t *testing.T) {
+		assert.NoError(t, service.DeleteTodo(testUserID, parent.ID, DeleteCascade, 0))
+
+		restored, err := service.RestoreTodo(testUserID, kitchen.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, restored.ParentID)
+	})
+
+	t.Run("purging a todo purges its subtasks in the trash", func(t *testing.T) {
+		assert.NoError(t, service.PurgeTodo(testUserID, parent.ID))
+
+		_, err := service.RestoreTodo(testUserID, garage.ID)
+		assert.Error(t, err)
+		trash, _ := service.GetTrash(testUserID)
+		assert.Empty(t, trash)
+	})
+
+	t.Run("only todos in the trash can be restored or purged", func(t *testing.T) {
+		_, err := service.RestoreTodo(testUserID, kitchen.ID)
+		assert.Error(t, err)
+		assert.Error(t, service.PurgeTodo(testUserID, kitchen.ID))
+	})
+
+	t.Run("expired trash is purged", func(t *testing.T) {
+		assert.NoError(t, service.DeleteTodo(testUserID, kitchen.ID, DeleteCascade, 0))
+
+		purged, err := service.PurgeExpired(time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), purged)
+
+		purged, err = service.PurgeExpired(time.Now().Add(time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), purged)
+		trash, _ := service.GetTrash(testUserID)
+		assert.Empty(t, trash)
+	})
+}
+
+func TestTodoService_FilterLanguage(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	work := &models.Category{UserID: testUserID, Name: "Work", Color: "#3B82F6"}
+	db.Create(work)
+	ops := &models.Tag{UserID: testUserID, Name: "ops", Color: "#EF4444"}
+	db.Create(ops)
+
+	due := func(day int) *time.Time {
+		t := time.Date(2026, 10, day, 12, 0, 0, 0, time.UTC)
+		return &t
+	}
+	todos := []*models.Todo{
+		{Title: "Deploy release", Priority: models.PriorityHigh, DueDate: due(20), CategoryID: &work.ID, TagIDs: []uint{ops.ID}},
+		{Title: "Write deploy notes", Priority: models.PriorityMedium, DueDate: due(25), CategoryID: &work.ID},
+		{Title: "Buy groceries", Priority: models.PriorityLow},
+		{Title: "Renew passport", Priority: models.PriorityHigh, DueDate: due(28)},
+	}
+	for _, todo := range todos {
+		assert.NoError(t, service.CreateTodo(testUserID, todo))
+	}
+	service.ToggleComplete(testUserID, todos[1].ID, 0)
+
+	titles := func(q string) []string {
+		found, _, err := service.GetTodos(testUserID, 1, 10, "", "title", "asc", map[string]interface{}{"q": q})
+		assert.NoError(t, err, q)
+		result := []string{}
+		for _, todo := range found {
+			result = append(result, todo.Title)
+		}
+		return result
+	}
+
+	t.Run("conditions combine with AND by default", func(t *testing.T) {
+		assert.Equal(t, []string{"Deploy release"}, titles(`priority:high due<2026-10-26 -completed category:"work" title~deploy`))
+		assert.Equal(t, []string{"Deploy release", "Renew passport"}, titles("priority>=high"))
+	})
+
+	t.Run("OR, NOT and parentheses", func(t *testing.T) {
+		assert.Equal(t, []string{"Buy groceries", "Write deploy notes"}, titles("completed OR category:none -due"))
+		assert.Equal(t, []string{"Renew passport"}, titles("NOT (category:work OR priority<medium)"))
+	})
+
+	t.Run("dates, categories and tags", func(t *testing.T) {
+		assert.Equal(t, []string{"Deploy release"}, titles("due:2026-10-20"))
+		assert.Equal(t, []string{"Renew passport", "Write deploy notes"}, titles("due>2026-10-20"))
+		assert.Equal(t, []string{"Buy groceries"}, titles("due:none"))
+		assert.Equal(t, []string{"Deploy release"}, titles("tag:ops"))
+		assert.Equal(t, []string{"Buy groceries", "Renew passport", "Write deploy notes"}, titles("-tag"))
+	})
+
+	t.Run("invalid filters report the offending token", func(t *testing.T) {
+		_, _, err := service.GetTodos(testUserID, 1, 10, "", "title", "asc", map[string]interface{}{"q": "completed owner:me"})
+
+		var filterErr *filter.Error
+		assert.ErrorAs(t, err, &filterErr)
+		assert.Equal(t, 10, filterErr.Pos)
+		assert.Equal(t, "owner:me", filterErr.Token)
+	})
+}
+
+func TestTodoService_CursorPagination(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	priorities := []models.Priority{models.PriorityLow, models.PriorityMedium, models.PriorityHigh}
+	for i := 0; i < 23; i++ {
+		todo := &models.Todo{
+			Title:       fmt.Sprintf("Deploy task %d", i%7), // Repeated titles tie on the sort key
+			Description: strings.Repeat("deploy ", i%4),
+			Priority:    priorities[i%3],
+		}
+		if i%3 != 0 {
+			due := time.Date(2026, 11, 1+i%5, 9, 0, 0, 0, time.UTC)
+			todo.DueDate = &due
+		}
+		assert.NoError(t, service.CreateTodo(testUserID, todo))
+	}
+
+	ids := func(todos []models.Todo) []uint {
+		result := []uint{}
+		for _, todo := range todos {
+			result = append(result, todo.ID)
+		}
+		return result
+	}
+
+	// walk pages forward to the end and back to the start again
+	walk := func(t *testing.T, searchText, sortBy, sortOrder string) {
+		all, _, err := service.GetTodos(testUserID, 1, 100, searchText, sortBy, sortOrder, map[string]interface{}{})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, all)
+
+		var forward []uint
+		var pages [][]uint
+		cursor := ""
+		for {
+			todos, next, prev, err := service.GetTodoPage(testUserID, cursor, 5, searchText, sortBy, sortOrder, map[string]interface{}{})
+			assert.NoError(t, err)
+			assert.Equal(t, cursor == "", prev == "")
+			forward = append(forward, ids(todos)...)
+			pages = append(pages, ids(todos))
+			if next == "" {
+				cursor = prev
+				break
+			}
+			cursor = next
+		}
+		assert.Equal(t, ids(all), forward)
+
+		for i := len(pages) - 2; i >= 0; i-- {
+			todos, _, prev, err := service.GetTodoPage(testUserID, cursor, 5, searchText, sortBy, sortOrder, map[string]interface{}{})
+			assert.NoError(t, err)
+			assert.Equal(t, pages[i], ids(todos))
+			cursor = prev
+		}
+		assert.Empty(t, cursor)
+	}
+
+	for _, sortBy := range []string{"title", "created_at", "updated_at", "due_date", "priority"} {
+		for _, sortOrder := range []string{"asc", "desc"} {
+			t.Run("stable order by "+sortBy+" "+sortOrder, func(t *testing.T) {
+				walk(t, "", sortBy, sortOrder)
+			})
+		}
+	}
+
+	t.Run("stable order by relevance", func(t *testing.T) {
+		walk(t, "deploy", "relevance", "desc")
+	})
+
+	t.Run("todos added while paging are not repeated", func(t *testing.T) {
+		first, next, _, err := service.GetTodoPage(testUserID, "", 5, "", "created_at", "asc", map[string]interface{}{})
+		assert.NoError(t, err)
+		// A new todo sorts before the next page with the offset paging of
+		// GetTodos, but a cursor stays put
+		assert.NoError(t, service.CreateTodo(testUserID, &models.Todo{Title: "Late arrival"}))
+		assert.NoError(t, service.DeleteTodo(testUserID, first[0].ID, DeleteCascade, 0))
+
+		second, _, _, err := service.GetTodoPage(testUserID, next, 5, "", "created_at", "asc", map[string]interface{}{})
+		assert.NoError(t, err)
+		assert.Equal(t, first[4].ID+1, second[0].ID)
+	})
+
+	t.Run("invalid cursors", func(t *testing.T) {
+		_, next, _, _ := service.GetTodoPage(testUserID, "", 5, "", "title", "asc", map[string]interface{}{})
+
+		for _, cursor := range []string{"not a cursor", "e30", next + "x"} {
+			_, _, _, err := service.GetTodoPage(testUserID, cursor, 5, "", "title", "asc", map[string]interface{}{})
+			assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+		}
+
+		_, _, _, err := service.GetTodoPage(testUserID, next, 5, "", "title", "desc", map[string]interface{}{})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+	})
+}
+
+func TestTodoService_Versions(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	tag := &models.Tag{UserID: testUserID, Name: "home", Color: "#10B981"}
+	db.Create(tag)
+	todo := &models.Todo{Title: "Paint fence"}
+	service.CreateTodo(testUserID, todo)
+	assert.Equal(t, uint(1), todo.Version)
+
+	version := func() uint {
+		found, _ := service.GetTodoByID(testUserID, todo.ID)
+		return found.Version
+	}
+
+	t.Run("every change bumps the version", func(t *testing.T) {
+		update := &models.Todo{ID: todo.ID, Title: "Paint the fence", Version: 1}
+		assert.NoError(t, service.UpdateTodo(testUserID, update))
+		assert.Equal(t, uint(2), update.Version)
+		assert.Equal(t, uint(2), version())
+
+		assert.NoError(t, service.AddTag(testUserID, todo.ID, tag.ID))
+		assert.Equal(t, uint(3), version())
+
+		assert.NoError(t, service.ToggleComplete(testUserID, todo.ID, 3))
+		assert.Equal(t, uint(4), version())
+	})
+
+	t.Run("stale versions are rejected", func(t *testing.T) {
+		current := version()
+
+		err := service.UpdateTodo(testUserID, &models.Todo{ID: todo.ID, Title: "Lost update", Version: 1})
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+		assert.ErrorIs(t, service.ToggleComplete(testUserID, todo.ID, 1), ErrVersionMismatch)
+		assert.ErrorIs(t, service.DeleteTodo(testUserID, todo.ID, DeleteCascade, 1), ErrVersionMismatch)
+
+		found, err := service.GetTodoByID(testUserID, todo.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Paint the fence", found.Title)
+		assert.True(t, found.Completed)
+		assert.Equal(t, current, found.Version)
+	})
+
+	t.Run("delete with the current version", func(t *testing.T) {
+		assert.NoError(t, service.DeleteTodo(testUserID, todo.ID, DeleteCascade, version()))
+
+		_, err := service.GetTodoByID(testUserID, todo.ID)
+		assert.Error(t, err)
+	})
+}
+
+func TestTodoService_Patch(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	category := &models.Category{UserID: testUserID, Name: "Home", Color: "#10B981"}
+	db.Create(category)
+	tag := &models.Tag{UserID: testUserID, Name: "garden", Color: "#10B981"}
+	db.Create(tag)
+	due := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	todo := &models.Todo{Title: "Mow lawn", Description: "Front and back", Priority: models.PriorityHigh, CategoryID: &category.ID, DueDate: &due, TagIDs: []uint{tag.ID}}
+	service.CreateTodo(testUserID, todo)
+
+	mergePatch := func(p string) Patch {
+		return func(doc []byte) ([]byte, error) { return patch.MergePatch(doc, []byte(p)) }
+	}
+	jsonPatch := func(p string) Patch {
+		return func(doc []byte) ([]byte, error) { return patch.Apply(doc, []byte(p)) }
+	}
+
+	t.Run("merge patch changes only the given fields", func(t *testing.T) {
+		patched, err := service.PatchTodo(testUserID, todo.ID, 0, mergePatch(`{"title": "Mow the lawn"}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Mow the lawn", patched.Title)
+		assert.Equal(t, "Front and back", patched.Description)
+		assert.Equal(t, models.PriorityHigh, patched.Priority)
+		assert.Equal(t, &category.ID, patched.CategoryID)
+		assert.NotNil(t, patched.DueDate)
+		assert.Len(t, patched.Tags, 1)
+		assert.Equal(t, uint(2), patched.Version)
+	})
+
+	t.Run("merge patch null clears a field", func(t *testing.T) {
+		patched, err := service.PatchTodo(testUserID, todo.ID, 0, mergePatch(`{"due_date": null, "category_id": null}`))
+
+		assert.NoError(t, err)
+		assert.Nil(t, patched.DueDate)
+		assert.Nil(t, patched.CategoryID)
+		assert.Equal(t, "Mow the lawn", patched.Title)
+		assert.Len(t, patched.Tags, 1)
+	})
+
+	t.Run("a removed priority falls back to medium", func(t *testing.T) {
+		patched, err := service.PatchTodo(testUserID, todo.ID, 0, jsonPatch(`[{"op": "remove", "path": "/priority"}]`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.PriorityMedium, patched.Priority)
+
+		patched, err = service.PatchTodo(testUserID, todo.ID, 0, mergePatch(`{"priority": null}`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.PriorityMedium, patched.Priority)
+	})
+
+	t.Run("JSON Patch with a test operation", func(t *testing.T) {
+		patched, err := service.PatchTodo(testUserID, todo.ID, 0, jsonPatch(`[
+			{"op": "test", "path": "/title", "value": "Mow the lawn"},
+			{"op": "replace", "path": "/priority", "value": "low"},
+			{"op": "remove", "path": "/tag_ids/0"}
+		]`))
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.PriorityLow, patched.Priority)
+		assert.Empty(t, patched.Tags)
+
+		_, err = service.PatchTodo(testUserID, todo.ID, 0, jsonPatch(`[
+			{"op": "test", "path": "/title", "value": "Something else"},
+			{"op": "replace", "path": "/title", "value": "Never applied"}
+		]`))
+		var patchErr *patch.Error
+		assert.ErrorAs(t, err, &patchErr)
+		assert.False(t, patchErr.Malformed)
+	})
+
+	t.Run("read-only and unknown fields are rejected", func(t *testing.T) {
+		var validationErr *ValidationError
+		_, err := service.PatchTodo(testUserID, todo.ID, 0, mergePatch(`{"user_id": 2}`))
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "user_id", validationErr.Field)
+
+		_, err = service.PatchTodo(testUserID, todo.ID, 0, mergePatch(`{"colour": "red"}`))
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "colour", validationErr.Field)
+	})
+
+	t.Run("invalid values are reported by field", func(t *testing.T) {
+		var validationErr *ValidationError
+		_, err := service.PatchTodo(testUserID, todo.ID, 0, mergePatch(`{"title": null}`))
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "title", validationErr.Field)
+
+		_, err = service.PatchTodo(testUserID, todo.ID, 0, mergePatch(`{"completed": "yes"}`))
+		assert.ErrorAs(t, err, &validationErr)
+		assert.Equal(t, "completed", validationErr.Field)
+
+		found, _ := service.GetTodoByID(testUserID, todo.ID)
+		assert.Equal(t, "Mow the lawn", found.Title)
+	})
+
+	t.Run("stale versions are rejected", func(t *testing.T) {
+		_, err := service.PatchTodo(testUserID, todo.ID, 1, mergePatch(`{"title": "Lost update"}`))
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+	})
+}
+
+func TestTodoService_Bulk(t *testing.T) {
+	db := setupTestDB()
+	bus := events.NewBus(events.DefaultHistorySize)
+	service := NewTodoService(repository.NewTodoRepository(db), bus)
+	sub, _ := bus.Subscribe(testUserID, 0)
+	defer sub.Close()
+
+	category := &models.Category{UserID: testUserID, Name: "Sprint", Color: "#3B82F6"}
+	db.Create(category)
+	first := &models.Todo{Title: "Write report"}
+	second := &models.Todo{Title: "Review report"}
+	service.CreateTodo(testUserID, first)
+	service.CreateTodo(testUserID, second)
+	for len(sub.Events) > 0 {
+		<-sub.Events
+	}
+
+	t.Run("atomic requests apply every operation", func(t *testing.T) {
+		result, err := service.Bulk(testUserID, []BulkOperation{
+			{Op: BulkCreate, Todo: &models.Todo{Title: "Plan next sprint"}},
+			{Op: BulkComplete, ID: first.ID},
+			{Op: BulkMove, ID: second.ID, CategoryID: &category.ID},
+			{Op: BulkSetPriority, ID: second.ID, Priority: models.PriorityHigh},
+		}, BulkAtomic)
+
+		assert.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.Equal(t, 4, result.Succeeded)
+		assert.NotZero(t, result.Results[0].ID)
+
+		found, _ := service.GetTodoByID(testUserID, first.ID)
+		assert.True(t, found.Completed)
+		found, _ = service.GetTodoByID(testUserID, second.ID)
+		assert.Equal(t, &category.ID, found.CategoryID)
+		assert.Equal(t, models.PriorityHigh, found.Priority)
+		assert.Len(t, sub.Events, 4)
+		for len(sub.Events) > 0 {
+			<-sub.Events
+		}
+	})
+
+	t.Run("a failing operation rolls back an atomic request", func(t *testing.T) {
+		result, err := service.Bulk(testUserID, []BulkOperation{
+			{Op: BulkDelete, ID: first.ID},
+			{Op: BulkSetPriority, ID: second.ID, Priority: "urgent"},
+			{Op: BulkComplete, ID: second.ID},
+		}, BulkAtomic)
+
+		assert.NoError(t, err)
+		assert.False(t, result.Committed)
+		assert.Equal(t, 0, result.Succeeded)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, BulkRolledBack, result.Results[0].Status)
+		assert.Equal(t, BulkFailed, result.Results[1].Status)
+		assert.Equal(t, "priority", result.Results[1].Field)
+		assert.Equal(t, BulkSkipped, result.Results[2].Status)
+
+		_, err = service.GetTodoByID(testUserID, first.ID)
+		assert.NoError(t, err)
+		assert.Empty(t, sub.Events)
+	})
+
+	t.Run("best effort keeps the operations that succeed", func(t *testing.T) {
+		result, err := service.Bulk(testUserID, []BulkOperation{
+			{Op: BulkComplete, ID: second.ID},
+			{Op: BulkDelete, ID: 9999},
+			{Op: BulkComplete, ID: first.ID, Completed: new(bool)},
+			{Op: BulkUpdate, ID: first.ID, Version: 1, Todo: &models.Todo{Title: "Stale"}},
+		}, BulkBestEffort)
+
+		assert.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.Equal(t, 2, result.Succeeded)
+		assert.Equal(t, "todo not found", result.Results[1].Error)
+		assert.Equal(t, BulkFailed, result.Results[3].Status)
+
+		found, _ := service.GetTodoByID(testUserID, second.ID)
+		assert.True(t, found.Completed)
+		found, _ = service.GetTodoByID(testUserID, first.ID)
+		assert.False(t, found.Completed)
+		assert.Equal(t, "Write report", found.Title)
+	})
+
+	t.Run("database errors are not reported", func(t *testing.T) {
+		db.Exec("CREATE TRIGGER fail_todos BEFORE INSERT ON todos BEGIN SELECT RAISE(ABORT, 'disk I/O error'); END")
+		defer db.Exec("DROP TRIGGER fail_todos")
+
+		result, err := service.Bulk(testUserID, []BulkOperation{
+			{Op: BulkCreate, Todo: &models.Todo{Title: "Archive report"}},
+			{Op: BulkDelete, ID: second.ID, Subtasks: "orphan"},
+		}, BulkBestEffort)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "internal error", result.Results[0].Error)
+		assert.Equal(t, "subtasks must be cascade or reparent", result.Results[1].Error)
+		assert.Equal(t, "subtasks", result.Results[1].Field)
+	})
+
+	t.Run("operations on a filter selection", func(t *testing.T) {
+		parent := &models.Todo{Title: "Old epic"}
+		service.CreateTodo(testUserID, parent)
+		service.CreateSubtask(testUserID, parent.ID, &models.Todo{Title: "Old story"})
+
+		result, err := service.BulkSelect(testUserID, "old", "", BulkOperation{Op: BulkDelete}, BulkAtomic)
+
+		assert.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.Equal(t, 2, result.Succeeded)
+		_, err = service.GetTodoByID(testUserID, parent.ID)
+		assert.Error(t, err)
+
+		result, err = service.BulkSelect(testUserID, "", "-completed", BulkOperation{Op: BulkComplete}, BulkAtomic)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.Succeeded)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		var validationErr *ValidationError
+		_, err := service.Bulk(testUserID, nil, BulkAtomic)
+		assert.ErrorAs(t, err, &validationErr)
+		_, err = service.Bulk(testUserID, []BulkOperation{{Op: BulkDelete, ID: first.ID}}, "sometimes")
+		assert.ErrorAs(t, err, &validationErr)
+		_, err = service.BulkSelect(testUserID, "", "", BulkOperation{Op: BulkDelete}, BulkAtomic)
+		assert.ErrorAs(t, err, &validationErr)
+		_, err = service.BulkSelect(testUserID, "", "priority:", BulkOperation{Op: BulkDelete}, BulkAtomic)
+		var filterErr *filter.Error
+		assert.ErrorAs(t, err, &filterErr)
+	})
+}
+
+func TestTodoService_Audit(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+	audit := NewAuditService(repository.NewAuditRepository(db))
+
+	history := func(id uint) []models.AuditEntry {
+		entries, _, err := audit.GetTodoHistory(testUserID, id, 1, 100)
+		assert.NoError(t, err)
+		return entries
+	}
+
+	tag := &models.Tag{UserID: testUserID, Name: "errands", Color: "#10B981"}
+	db.Create(tag)
+	due := time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC)
+	todo := &models.Todo{Title: "Renew passport", Priority: models.PriorityLow, DueDate: &due}
+	service.CreateTodo(testUserID, todo)
+
+	t.Run("create records every field", func(t *testing.T) {
+		entries := history(todo.ID)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, models.AuditCreated, entries[0].Action)
+		assert.Equal(t, testUserID, entries[0].ActorID)
+		assert.Equal(t, "Renew passport", entries[0].Changes["title"].To)
+		assert.Nil(t, entries[0].Changes["title"].From)
+	})
+
+	t.Run("update records only the changed fields", func(t *testing.T) {
+		moved := due.AddDate(0, 0, 7)
+		update := &models.Todo{ID: todo.ID, Title: "Renew passport", Priority: models.PriorityHigh, DueDate: &moved}
+		assert.NoError(t, service.UpdateTodo(testUserID, update))
+		assert.NoError(t, service.AddTag(testUserID, todo.ID, tag.ID))
+
+		entries := history(todo.ID)
+		assert.Len(t, entries, 3)
+		tagged, updated := entries[0], entries[1]
+		assert.Equal(t, models.AuditUpdated, updated.Action)
+		assert.Len(t, updated.Changes, 2)
+		assert.Equal(t, models.FieldChange{From: "low", To: "high"}, updated.Changes["priority"])
+		assert.Contains(t, updated.Changes, "due_date")
+		assert.Equal(t, update.Version, updated.Version)
+		assert.Len(t, tagged.Changes, 1)
+		assert.Contains(t, tagged.Changes, "tag_ids")
+	})
+
+	t.Run("toggle records the subtasks and parents it changes", func(t *testing.T) {
+		step := &models.Todo{Title: "Fill in form"}
+		service.CreateSubtask(testUserID, todo.ID, step)
+
+		assert.NoError(t, service.ToggleComplete(testUserID, step.ID, 0))
+
+		entries := history(step.ID)
+		assert.Equal(t, models.AuditToggled, entries[0].Action)
+		assert.Equal(t, models.FieldChange{From: false, To: true}, entries[0].Changes["completed"])
+		entries = history(todo.ID)
+		assert.Equal(t, models.AuditToggled, entries[0].Action) // Completed with its last subtask
+	})
+
+	t.Run("revert goes back to an earlier revision", func(t *testing.T) {
+		created := history(todo.ID)
+		first := created[len(created)-1]
+
+		reverted, err := service.RevertTodo(testUserID, todo.ID, first.ID, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, models.PriorityLow, reverted.Priority)
+		assert.True(t, reverted.DueDate.Equal(due))
+		assert.Empty(t, reverted.Tags)
+		assert.False(t, reverted.Completed)
+
+		entries := history(todo.ID)
+		assert.Equal(t, models.AuditReverted, entries[0].Action)
+		assert.Equal(t, models.FieldChange{From: "high", To: "low"}, entries[0].Changes["priority"])
+	})
+
+	t.Run("revert checks the entry and version", func(t *testing.T) {
+		other := &models.Todo{Title: "Other"}
+		service.CreateTodo(testUserID, other)
+		entries := history(other.ID)
+
+		var validationErr *ValidationError
+		_, err := service.RevertTodo(testUserID, todo.ID, entries[0].ID, 0)
+		assert.ErrorAs(t, err, &validationErr)
+		entries = history(todo.ID)
+		_, err = service.RevertTodo(testUserID, todo.ID, entries[0].ID, 1)
+		assert.ErrorIs(t, err, ErrVersionMismatch)
+	})
+
+	t.Run("delete and restore are recorded and filterable", func(t *testing.T) {
+		assert.NoError(t, service.DeleteTodo(testUserID, todo.ID, DeleteCascade, 0))
+		_, err := service.RestoreTodo(testUserID, todo.ID)
+		assert.NoError(t, err)
+
+		entries := history(todo.ID)
+		assert.Equal(t, models.AuditRestored, entries[0].Action)
+		assert.Equal(t, models.AuditDeleted, entries[1].Action)
+		assert.Equal(t, "Renew passport", entries[1].State["title"])
+
+		deleted, total, err := audit.GetAuditLog(testUserID, repository.AuditFilter{Action: models.AuditDeleted}, 1, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total) // The todo and its subtask
+		assert.Len(t, deleted, 2)
+		_, total, _ = audit.GetAuditLog(2, repository.AuditFilter{}, 1, 10)
+		assert.Zero(t, total)
+	})
+
+	t.Run("failed changes are not recorded", func(t *testing.T) {
+		before := history(todo.ID)
+		update := &models.Todo{ID: todo.ID, Title: "Stale", Version: 1}
+		assert.ErrorIs(t, service.UpdateTodo(testUserID, update), ErrVersionMismatch)
+		assert.Len(t, history(todo.ID), len(before))
+	})
+}
+
+func TestTodoService_RevertTodoDatabaseError(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+	todo := &models.Todo{Title: "Renew passport"}
+	service.CreateTodo(testUserID, todo)
+	db.Migrator().DropTable(&models.AuditEntry{})
+
+	_, err := service.RevertTodo(testUserID, todo.ID, 1, 0)
+
+	var validationErr *ValidationError
+	assert.Error(t, err)
+	assert.NotErrorAs(t, err, &validationErr) // A failing database is not a missing entry
+}
+
+func TestTodoService_Tags(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	backend := &models.Tag{UserID: testUserID, Name: "backend", Color: "#3B82F6"}
+	urgent := &models.Tag{UserID: testUserID, Name: "urgent-customer", Color: "#EF4444"}
+	docs := &models.Tag{UserID: testUserID, Name: "docs", Color: "#10B981"}
+	db.Create(backend)
+	db.Create(urgent)
+	db.Create(docs)
+
+	both := &models.Todo{Title: "Fix outage", TagIDs: []uint{backend.ID, urgent.ID}}
+	service.CreateTodo(testUserID, both)
+	onlyBackend := &models.Todo{Title: "Refactor repo", TagIDs: []uint{backend.ID}}
+	service.CreateTodo(testUserID, onlyBackend)
+	untagged := &models.Todo{Title: "Plan sprint"}
+	service.CreateTodo(testUserID, untagged)
+
+	t.Run("create attaches tags", func(t *testing.T) {
+		found, err := service.GetTodoByID(testUserID, both.ID)
+
+		assert.NoError(t, err)
+		assert.Len(t, found.Tags, 2)
+	})
+
+	t.Run("create with unknown tag fails", func(t *testing.T) {
+		err := service.CreateTodo(testUserID, &models.Todo{Title: "Bad", TagIDs: []uint{999}})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "tag not found")
+	})
+
+	t.Run("filter any of", func(t *testing.T) {
+		filters := map[string]interface{}{"tags_any": []uint{urgent.ID, docs.ID}}
+		todos, total, err := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", filters)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, both.ID, todos[0].ID)
+	})
+
+	t.Run("filter all of", func(t *testing.T) {
+		filters := map[string]interface{}{"tags_all": []uint{backend.ID, urgent.ID}}
+		todos, total, err := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", filters)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		assert.Equal(t, both.ID, todos[0].ID)
+	})
+
+	t.Run("filter none of", func(t *testing.T) {
+		filters := map[string]interface{}{"tags_none": []uint{urgent.ID}}
+		_, total, err := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", filters)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+	})
+
+	t.Run("update without tag_ids keeps tags", func(t *testing.T) {
+		update := &models.Todo{ID: onlyBackend.ID, Title: "Refactor repository"}
+		err := service.UpdateTodo(testUserID, update)
+
+		assert.NoError(t, err)
+		found, _ := service.GetTodoByID(testUserID, onlyBackend.ID)
+		assert.Len(t, found.Tags, 1)
+	})
+
+	t.Run("update with empty tag_ids clears tags", func(t *testing.T) {
+		update := &models.Todo{ID: onlyBackend.ID, Title: "Refactor repository", TagIDs: []uint{}}
+		err := service.UpdateTodo(testUserID, update)
+
+		assert.NoError(t, err)
+		found, _ := service.GetTodoByID(testUserID, onlyBackend.ID)
+		assert.Len(t, found.Tags, 0)
+	})
+
+	t.Run("attach and detach", func(t *testing.T) {
+		assert.NoError(t, service.AddTag(testUserID, untagged.ID, docs.ID))
+		found, _ := service.GetTodoByID(testUserID, untagged.ID)
+		assert.Len(t, found.Tags, 1)
+
+		assert.NoError(t, service.RemoveTag(testUserID, untagged.ID, docs.ID))
+		found, _ = service.GetTodoByID(testUserID, untagged.ID)
+		assert.Len(t, found.Tags, 0)
+	})
+}
+
+func TestTodoService_RecurringTodos(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	due := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC) // Monday
+	tag := &models.Tag{UserID: testUserID, Name: "chores", Color: "#10B981"}
+	db.Create(tag)
+
+	t.Run("validation error - invalid rule", func(t *testing.T) {
+		err := service.CreateTodo(testUserID, &models.Todo{Title: "Bins", DueDate: &due, Recurrence: "FREQ=HOURLY"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid recurrence")
+	})
+
+	t.Run("validation error - missing due date", func(t *testing.T) {
+		err := service.CreateTodo(testUserID, &models.Todo{Title: "Bins", Recurrence: "FREQ=WEEKLY"})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "recurring todos need a due date")
+	})
+
+	t.Run("completing spawns the next occurrence", func(t *testing.T) {
+		todo := &models.Todo{Title: "Take out bins", DueDate: &due, Recurrence: "freq=weekly;byday=mo,th;count=3", TagIDs: []uint{tag.ID}}
+		assert.NoError(t, service.CreateTodo(testUserID, todo))
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=3", todo.Recurrence)
+
+		assert.NoError(t, service.ToggleComplete(testUserID, todo.ID, 0))
+
+		completed, _ := service.GetTodoByID(testUserID, todo.ID)
+		assert.True(t, completed.Completed)
+		assert.Empty(t, completed.Recurrence)
+
+		todos, _, _ := service.GetTodos(testUserID, 1, 10, "", "created_at", "desc", map[string]interface{}{"completed": false})
+		assert.Len(t, todos, 1)
+		next := todos[0]
+		assert.Equal(t, "Take out bins", next.Title)
+		assert.Equal(t, time.Date(2026, 10, 22, 9, 0, 0, 0, time.UTC), next.DueDate.UTC())
+		assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=2", next.Recurrence)
+		assert.Len(t, next.Tags, 1)
+
+		// Reopening and completing the old instance again does not spawn twice
+		assert.NoError(t, service.ToggleComplete(testUserID, todo.ID, 0))
+		assert.NoError(t, service.ToggleComplete(testUserID, todo.ID, 0))
+		_, total, _ := service.GetTodos(testUserID, 1, 10, "Take out bins", "created_at", "desc", map[string]interface{}{})
+		assert.Equal(t, int64(2), total)
+	})
+
+	t.Run("last occurrence ends the series", func(t *testing.T) {
+		todo := &models.Todo{Title: "Renew passport", DueDate: &due, Recurrence: "FREQ=YEARLY;COUNT=1"}
+		service.CreateTodo(testUserID, todo)
+
+		assert.NoError(t, service.ToggleComplete(testUserID, todo.ID, 0))
+
+		_, total, _ := service.GetTodos(testUserID, 1, 10, "Renew passport", "created_at", "desc", map[string]interface{}{})
+		assert.Equal(t, int64(1), total)
+	})
+
+	t.Run("preview occurrences", func(t *testing.T) {
+		occurrences, err := service.PreviewRecurrence("FREQ=MONTHLY;INTERVAL=2", due, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Time{due, due.AddDate(0, 2, 0), due.AddDate(0, 4, 0)}, occurrences)
+	})
+}
+
+func TestTodoService_UserScoping(t *testing.T) {
+	db := setupTestDB()
+	service := NewTodoService(repository.NewTodoRepository(db), nil)
+
+	const otherUserID uint = 2
+	todo := &models.Todo{Title: "Private"}
+	service.CreateTodo(testUserID, todo)
+	otherCategory := &models.Category{UserID: otherUserID, Name: "Theirs", Color: "#3B82F6"}
+	db.Create(otherCategory)
+
+	t.Run("other users cannot read", func(t *testing.T) {
+		_, err := service.GetTodoByID(otherUserID, todo.ID)
+		assert.Error(t, err)
+
+		_, total, _ := service.GetTodos(otherUserID, 1, 10, "", "created_at", "desc", map[string]interface{}{})
+		assert.Equal(t, int64(0), total)
+	})
+
+	t.Run("other users cannot update, toggle or delete", func(t *testing.T) {
+		assert.Error(t, service.UpdateTodo(otherUserID, &models.Todo{ID: todo.ID, Title: "Mine now"}))
+		assert.Error(t, service.ToggleComplete(otherUserID, todo.ID, 0))
+		assert.NoError(t, service.DeleteTodo(otherUserID, todo.ID, DeleteCascade, 0))
+
+		found, err := service.GetTodoByID(testUserID, todo.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, "Private", found.Title)
+		assert.False(t, found.Completed)
+	})
+
+	t.Run("cannot use another user's category or parent", func(t *testing.T) {
+		err := service.CreateTodo(testUserID, &models.Todo{Title: "Sneaky", CategoryID: &otherCategory.ID})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "category not found")
+
+		err = service.CreateSubtask(otherUserID, todo.ID, &models.Todo{Title: "Sneaky"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "parent todo not found")
+	})
+}
+
+func TestTodoService_Events(t *testing.T) {
+	db := setupTestDB()
+	bus := events.NewBus(events.DefaultHistorySize)
+	service := NewTodoService(repository.NewTodoRepository(db), bus)
+	sub, _ := bus.Subscribe(testUserID, 0)
+	defer sub.Close()
+
+	next := func() events.Event {
+		select {
+		case event := <-sub.Events:
+			return event
+		default:
+			t.Fatal("no event published")
+			return events.Event{}
+		}
+	}
+
+	todo := &models.Todo{Title: "Watched"}
+	assert.NoError(t, service.CreateTodo(testUserID, todo))
+	event := next()
+	assert.Equal(t, events.TodoCreated, event.Type)
+	assert.Equal(t, todo.ID, event.Data.(*models.Todo).ID)
+
+	todo.Title = "Renamed"
+	assert.NoError(t, service.UpdateTodo(testUserID, todo))
+	event = next()
+	assert.Equal(t, events.TodoUpdated, event.Type)
+	assert.Equal(t, "Renamed", event.Data.(*models.Todo).Title)
+
+	assert.NoError(t, service.ToggleComplete(testUserID, todo.ID, 0))
+	event = next()
+	assert.Equal(t, events.TodoToggled, event.Type)
+	assert.True(t, event.Data.(*models.Todo).Completed)
+
+	assert.NoError(t, service.DeleteTodo(testUserID, todo.ID, DeleteCascade, 0))
+	event = next()
+	assert.Equal(t, events.TodoDeleted, event.Type)
+	assert.Equal(t, todo.ID, event.Data.(events.Deleted).ID)
+
+	t.Run("failed and no-op changes publish nothing", func(t *testing.T) {
+		assert.Error(t, service.CreateTodo(testUserID, &models.Todo{}))
+		assert.NoError(t, service.DeleteTodo(testUserID, todo.ID, DeleteCascade, 0))
+		assert.Empty(t, sub.Events)
+	})
+
+	t.Run("completing a recurring todo announces the next occurrence", func(t *testing.T) {
+		due := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+		recurring := &models.Todo{Title: "Standup", DueDate: &due, Recurrence: "FREQ=DAILY"}
+		assert.NoError(t, service.CreateTodo(testUserID, recurring))
+		next()
+
+		assert.NoError(t, service.ToggleComplete(testUserID, recurring.ID, 0))
+		assert.Equal(t, events.TodoToggled, next().Type)
+		created := next()
+		assert.Equal(t, events.TodoCreated, created.Type)
+		assert.NotEqual(t, recurring.ID, created.Data.(*models.Todo).ID)
+	})
+}
@@ -0,0 +1,529 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+	"todoListChallenge/internal/transfer"
+
+	"gorm.io/gorm"
+)
+
+// MaxImportRows is the most todos a single import may hold
+const MaxImportRows = 5000
+
+// importCategoryColor is the color of the categories an import creates
+// when the file does not give one
+const importCategoryColor = "#3B82F6"
+
+// DuplicateMode decides what happens to an imported todo with the same
+// title, ignoring case, as an existing todo in the same category
+type DuplicateMode string
+
+const (
+	// DuplicateSkip leaves the existing todo as it is
+	DuplicateSkip DuplicateMode = "skip"
+	// DuplicateOverwrite replaces the existing todo with the imported one
+	DuplicateOverwrite DuplicateMode = "overwrite"
+	// DuplicateCreate imports the todo next to the existing one
+	DuplicateCreate DuplicateMode = "duplicate"
+)
+
+// ImportOptions control an import
+type ImportOptions struct {
+	Format           string            // One of the transfer formats, like transfer.FormatCSV
+	FileName         string            // Name of the uploaded file, the project of a Todoist CSV export
+	Mapping          map[string]string // CSV column to read each field from, see transfer.ReadCSV
+	OnDuplicate      DuplicateMode     // DuplicateSkip when empty
+	CreateCategories bool              // Create categories that do not exist, else fail their todos
+	DryRun           bool              // Report what would happen without saving anything
+}
+
+// ImportStatus is the outcome of importing a single todo
+type ImportStatus string
+
+const (
+	ImportCreated ImportStatus = "created" // Created as a new todo
+	ImportUpdated ImportStatus = "updated" // Overwrote a duplicate
+	ImportSkipped ImportStatus = "skipped" // Left out as a duplicate
+	ImportFailed  ImportStatus = "failed"  // Not imported, see the error
+)
+
+// ImportRowResult is the outcome of the todo at Row of an imported file
+type ImportRowResult struct {
+	Row    int          `json:"row"`
+	Title  string       `json:"title,omitempty"`
+	Status ImportStatus `json:"status"`
+	ID     uint         `json:"id,omitempty"` // The created, updated or duplicate todo; left out for todos a dry run would create
+	Error  string       `json:"error,omitempty"`
+	Field  string       `json:"field,omitempty"` // Invalid field of a failed todo
+
+	// Todo is the todo as it was read from the file; only set by Preview
+	Todo *transfer.Todo `json:"todo,omitempty"`
+}
+
+// ImportResult is the outcome of an import. On a dry run nothing is saved,
+// and the result tells what the import would do.
+type ImportResult struct {
+	DryRun            bool              `json:"dry_run"`
+	Total             int               `json:"total"`
+	Created           int               `json:"created"`
+	Updated           int               `json:"updated"`
+	Skipped           int               `json:"skipped"`
+	Failed            int               `json:"failed"`
+	CategoriesCreated []string          `json:"categories_created"`
+	TagsCreated       []string          `json:"tags_created"`
+	Rows              []ImportRowResult `json:"rows"`
+}
+
+// errImportDryRun rolls back a dry run
+var errImportDryRun = errors.New("dry run")
+
+// TransferService exports a user's todos to files and imports them from
+// files. Todos and categories are created through the TodoService and
+// CategoryService, so the same validation and audit log apply as for the
+// REST API.
+type TransferService struct {
+	repo       *repository.TransferRepository
+	todos      *TodoService
+	categories *CategoryService
+	now        func() time.Time
+}
+
+// NewTransferService creates a new TransferService
+func NewTransferService(repo *repository.TransferRepository, todos *TodoService, categories *CategoryService) *TransferService {
+	return &TransferService{repo: repo, todos: todos, categories: categories, now: time.Now}
+}
+
+// Export writes a user's todos matching a search and filters, like
+// TodoService.EachTodo, to w as a file in the given format. The todos are
+// written as they are read, a batch at a time. Nothing is written before
+// the first batch, so an invalid format, search or filter can still be
+// reported; an invalid format returns a *ValidationError.
+func (s *TransferService) Export(w io.Writer, userID uint, format, searchText string, filters map[string]interface{}) error {
+	var out transfer.Writer
+	switch format {
+	case transfer.FormatJSON:
+		categories, err := s.categories.GetCategories(userID)
+		if err != nil {
+			return err
+		}
+		exported := make([]transfer.Category, len(categories))
+		for i, category := range categories {
+			exported[i] = transfer.Category{Name: category.Name, Color: category.Color}
+		}
+		out = transfer.NewJSONWriter(w, s.now(), exported)
+	case transfer.FormatCSV:
+		out = transfer.NewCSVWriter(w)
+	case transfer.FormatMarkdown:
+		out = transfer.NewMarkdownWriter(w)
+	case transfer.FormatTodoTxt:
+		out = transfer.NewTodoTxtWriter(w)
+	default:
+		return &ValidationError{Field: "format", Msg: "format must be json, csv, markdown or todotxt"}
+	}
+
+	err := s.todos.EachTodo(userID, searchText, filters, func(todos []models.Todo) error {
+		for i := range todos {
+			if err := out.Write(exportTodo(&todos[i])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// Import reads todos from a file and creates them for a user, along with
+// the categories and tags they name that do not exist yet. Each todo is
+// imported on its own: one that fails is reported in the result, and the
+// others are still imported. Subtasks are imported below the todo in the
+// file their parent_id names. A file that cannot be read at all, or invalid
+// options, return a *ValidationError.
+func (s *TransferService) Import(userID uint, file io.Reader, opts ImportOptions) (*ImportResult, error) {
+	data, err := readImport(file, &opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.importData(userID, data, opts)
+}
+
+// Preview reads a file like Import and returns what importing it would do,
+// like a dry run, with each todo as it was read from the file. This shows
+// how the file of another app was mapped before it is imported.
+func (s *TransferService) Preview(userID uint, file io.Reader, opts ImportOptions) (*ImportResult, error) {
+	data, err := readImport(file, &opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.DryRun = true
+	result, err := s.importData(userID, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	rows := make(map[int]*transfer.Todo, len(data.Todos))
+	for i := range data.Todos {
+		rows[data.Todos[i].Row] = &data.Todos[i]
+	}
+	for i := range result.Rows {
+		result.Rows[i].Todo = rows[result.Rows[i].Row]
+	}
+	return result, nil
+}
+
+// importData imports the todos read from a file
+func (s *TransferService) importData(userID uint, data *transfer.Data, opts ImportOptions) (*ImportResult, error) {
+	total := len(data.Todos) + len(data.Errors)
+	if total > MaxImportRows {
+		return nil, &ValidationError{Field: "file", Msg: fmt.Sprintf("the file holds %d todos, at most %d are allowed", total, MaxImportRows)}
+	}
+
+	result := &ImportResult{DryRun: opts.DryRun, Total: total, CategoriesCreated: []string{}, TagsCreated: []string{}, Rows: make([]ImportRowResult, 0, total)}
+	var run *importRun
+	err := s.repo.Transaction(func(tx *repository.TransferRepository) error {
+		run = &importRun{tx: tx, userID: userID, opts: opts, result: result, colors: make(map[string]string), ids: make(map[uint]uint)}
+		if err := run.load(data.Categories); err != nil {
+			return err
+		}
+		run.importTodos(data)
+		if opts.DryRun {
+			return errImportDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errImportDryRun) {
+		return nil, err
+	}
+
+	result.sortRows()
+	if opts.DryRun {
+		for i := range result.Rows {
+			if result.Rows[i].Status == ImportCreated {
+				result.Rows[i].ID = 0
+			}
+		}
+		return result, nil
+	}
+	for i := range run.createdCategories {
+		s.categories.bus.Publish(userID, events.CategoryCreated, &run.createdCategories[i])
+	}
+	s.todos.publishPending(run.pending)
+	return result, nil
+}
+
+// readImport checks the options of an import, filling in the defaults, and
+// reads the file in their format
+func readImport(file io.Reader, opts *ImportOptions) (*transfer.Data, error) {
+	if opts.OnDuplicate == "" {
+		opts.OnDuplicate = DuplicateSkip
+	}
+	if opts.OnDuplicate != DuplicateSkip && opts.OnDuplicate != DuplicateOverwrite && opts.OnDuplicate != DuplicateCreate {
+		return nil, &ValidationError{Field: "on_duplicate", Msg: "on_duplicate must be skip, overwrite or duplicate"}
+	}
+
+	var data *transfer.Data
+	var err error
+	switch opts.Format {
+	case transfer.FormatJSON:
+		data, err = transfer.ReadJSON(file)
+	case transfer.FormatCSV:
+		data, err = transfer.ReadCSV(file, opts.Mapping)
+	case transfer.FormatTodoist:
+		data, err = transfer.ReadTodoist(file, opts.FileName)
+	case transfer.FormatTodoTxt:
+		data, err = transfer.ReadTodoTxt(file)
+	case transfer.FormatTrello:
+		data, err = transfer.ReadTrello(file)
+	default:
+		return nil, &ValidationError{Field: "format", Msg: "format must be json, csv, todoist, todotxt or trello"}
+	}
+	var fileErr *transfer.Error
+	if errors.As(err, &fileErr) {
+		return nil, &ValidationError{Field: fileErr.Field, Msg: fileErr.Msg}
+	}
+	return data, err
+}
+
+// importRun imports the todos of one file in a transaction
+type importRun struct {
+	tx     *repository.TransferRepository
+	userID uint
+	opts   ImportOptions
+	result *ImportResult
+
+	categories map[string]uint   // Category IDs by lowercase name
+	colors     map[string]string // Colors of the categories in the file by lowercase name
+	tags       map[string]uint   // Tag IDs by lowercase name
+	ids        map[uint]uint     // IDs of the imported todos by their ID in the file
+
+	createdCategories []models.Category
+	pending           []pendingEvent
+}
+
+// load loads the user's categories and tags
+func (r *importRun) load(fileCategories []transfer.Category) error {
+	categories, err := r.tx.Categories().GetAll(r.userID)
+	if err != nil {
+		return err
+	}
+	r.categories = make(map[string]uint, len(categories))
+	for _, category := range categories {
+		if key := strings.ToLower(category.Name); r.categories[key] == 0 {
+			r.categories[key] = category.ID
+		}
+	}
+	for _, category := range fileCategories {
+		r.colors[strings.ToLower(strings.TrimSpace(category.Name))] = category.Color
+	}
+
+	tags, err := r.tx.Tags().GetAll(r.userID)
+	if err != nil {
+		return err
+	}
+	r.tags = make(map[string]uint, len(tags))
+	for _, tag := range tags {
+		if key := strings.ToLower(tag.Name); r.tags[key] == 0 {
+			r.tags[key] = tag.ID
+		}
+	}
+	return nil
+}
+
+// importTodos imports the todos of a file, parents before their subtasks,
+// and reports the todos that could not be read
+func (r *importRun) importTodos(data *transfer.Data) {
+	for _, rowErr := range data.Errors {
+		r.result.add(ImportRowResult{Row: rowErr.Row, Status: ImportFailed, Error: rowErr.Msg, Field: rowErr.Field})
+	}
+
+	inFile := make(map[uint]int, len(data.Todos))
+	for i, todo := range data.Todos {
+		if _, ok := inFile[todo.ID]; todo.ID != 0 && !ok {
+			inFile[todo.ID] = i
+		}
+	}
+	done := make([]bool, len(data.Todos))
+	var visit func(i int)
+	visit = func(i int) {
+		if done[i] {
+			return
+		}
+		done[i] = true // Before the parent, so a cycle in the file ends here
+		if parent, ok := inFile[data.Todos[i].ParentID]; ok && data.Todos[i].ParentID != 0 {
+			visit(parent)
+		}
+		r.result.add(r.importTodo(data.Todos[i], inFile))
+	}
+	for i := range data.Todos {
+		visit(i)
+	}
+}
+
+// importTodo imports a single todo
+func (r *importRun) importTodo(row transfer.Todo, inFile map[uint]int) ImportRowResult {
+	result := ImportRowResult{Row: row.Row, Title: row.Title}
+	todo, err := r.prepare(row, inFile)
+	if err == nil {
+		// A savepoint undoes a failed todo without the others
+		mark := len(r.pending)
+		err = r.tx.Transaction(func(tx *repository.TransferRepository) error {
+			return r.save(tx, todo, &result)
+		})
+		if err != nil {
+			r.pending = r.pending[:mark]
+		}
+	}
+	if err != nil {
+		result.Status = ImportFailed
+		result.Error, result.Field = itemError(err, "todo")
+		return result
+	}
+	if row.ID != 0 {
+		r.ids[row.ID] = result.ID
+	}
+	return result
+}
+
+// prepare returns the todo to save for a row, creating its category and
+// tags when they do not exist
+func (r *importRun) prepare(row transfer.Todo, inFile map[uint]int) (*models.Todo, error) {
+	todo := &models.Todo{
+		Title:       row.Title,
+		Description: row.Description,
+		Completed:   row.Completed,
+		Priority:    models.Priority(strings.ToLower(row.Priority)),
+		DueDate:     row.DueDate,
+		Recurrence:  row.Recurrence,
+	}
+	if row.ParentID != 0 {
+		if _, ok := inFile[row.ParentID]; !ok {
+			return nil, &ValidationError{Field: "parent_id", Msg: fmt.Sprintf("the file has no todo with id %d", row.ParentID)}
+		}
+		parentID, ok := r.ids[row.ParentID]
+		if !ok {
+			return nil, &ValidationError{Field: "parent_id", Msg: fmt.Sprintf("the parent todo %d was not imported", row.ParentID)}
+		}
+		todo.ParentID = &parentID
+	}
+
+	if name := strings.TrimSpace(row.Category); name != "" {
+		id, err := r.category(name)
+		if err != nil {
+			return nil, err
+		}
+		todo.CategoryID = &id
+	}
+	if row.Tags != nil {
+		todo.TagIDs = []uint{}
+		for _, name := range row.Tags {
+			id, err := r.tag(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			todo.TagIDs = append(todo.TagIDs, id)
+		}
+	}
+	return todo, nil
+}
+
+// save creates a todo, or handles the duplicate it has according to the
+// duplicate mode
+func (r *importRun) save(tx *repository.TransferRepository, todo *models.Todo, result *ImportRowResult) error {
+	todos := &TodoService{repo: tx.Todos(), pending: &r.pending}
+	if r.opts.OnDuplicate != DuplicateCreate {
+		existing, err := tx.FindDuplicate(r.userID, todo.Title, todo.CategoryID)
+		switch {
+		case err == nil && r.opts.OnDuplicate == DuplicateSkip:
+			result.Status, result.ID = ImportSkipped, existing.ID
+			return nil
+		case err == nil:
+			todo.ID, todo.Version = existing.ID, existing.Version
+			if todo.ParentID == nil {
+				todo.ParentID = existing.ParentID
+			}
+			if err := todos.UpdateTodo(r.userID, todo); err != nil {
+				return err
+			}
+			result.Status, result.ID = ImportUpdated, todo.ID
+			return nil
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+	}
+
+	if err := todos.CreateTodo(r.userID, todo); err != nil {
+		return err
+	}
+	result.Status, result.ID = ImportCreated, todo.ID
+	return nil
+}
+
+// category returns the ID of the user's category with a name, ignoring
+// case, creating it if it does not exist and the options allow it
+func (r *importRun) category(name string) (uint, error) {
+	key := strings.ToLower(name)
+	if id, ok := r.categories[key]; ok {
+		return id, nil
+	}
+	if !r.opts.CreateCategories {
+		return 0, &ValidationError{Field: "category", Msg: fmt.Sprintf("category %q not found", name)}
+	}
+
+	category := models.Category{Name: name, Color: r.colors[key]}
+	if category.Color == "" {
+		category.Color = importCategoryColor
+	}
+	// A savepoint keeps a failed category from aborting the import
+	err := r.tx.Transaction(func(tx *repository.TransferRepository) error {
+		return (&CategoryService{repo: tx.Categories()}).CreateCategory(r.userID, &category)
+	})
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return 0, &ValidationError{Field: "category", Msg: "invalid category: " + validationErr.Msg}
+	}
+	if err != nil {
+		return 0, err
+	}
+	r.categories[key] = category.ID
+	r.createdCategories = append(r.createdCategories, category)
+	r.result.CategoriesCreated = append(r.result.CategoriesCreated, category.Name)
+	return category.ID, nil
+}
+
+// tag returns the ID of the user's tag with a name, ignoring case, creating
+// it if it does not exist
+func (r *importRun) tag(name string) (uint, error) {
+	key := strings.ToLower(name)
+	if id, ok := r.tags[key]; ok {
+		return id, nil
+	}
+
+	tag := models.Tag{Name: name}
+	err := r.tx.Transaction(func(tx *repository.TransferRepository) error {
+		return NewTagService(tx.Tags()).CreateTag(r.userID, &tag)
+	})
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		return 0, &ValidationError{Field: "tags", Msg: "invalid tag: " + validationErr.Msg}
+	}
+	if err != nil {
+		return 0, err
+	}
+	r.tags[key] = tag.ID
+	r.result.TagsCreated = append(r.result.TagsCreated, tag.Name)
+	return tag.ID, nil
+}
+
+// add adds the outcome of a todo to the result
+func (r *ImportResult) add(row ImportRowResult) {
+	switch row.Status {
+	case ImportCreated:
+		r.Created++
+	case ImportUpdated:
+		r.Updated++
+	case ImportSkipped:
+		r.Skipped++
+	case ImportFailed:
+		r.Failed++
+	}
+	r.Rows = append(r.Rows, row)
+}
+
+// sortRows puts the outcomes in the order of the file
+func (r *ImportResult) sortRows() {
+	sort.SliceStable(r.Rows, func(i, j int) bool { return r.Rows[i].Row < r.Rows[j].Row })
+}
+
+// exportTodo returns a todo as it is exported
+func exportTodo(todo *models.Todo) transfer.Todo {
+	exported := transfer.Todo{
+		ID:          todo.ID,
+		Title:       todo.Title,
+		Description: todo.Description,
+		Completed:   todo.Completed,
+		Priority:    string(todo.Priority),
+		DueDate:     todo.DueDate,
+		Recurrence:  todo.Recurrence,
+		CreatedAt:   &todo.CreatedAt,
+		UpdatedAt:   &todo.UpdatedAt,
+	}
+	if todo.Category != nil {
+		exported.Category = todo.Category.Name
+	}
+	if todo.ParentID != nil {
+		exported.ParentID = *todo.ParentID
+	}
+	for _, tag := range todo.Tags {
+		exported.Tags = append(exported.Tags, tag.Name)
+	}
+	return exported
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+	"todoListChallenge/internal/transfer"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func setupTransferService() (*TransferService, *TodoService, *gorm.DB) {
+	db := setupTestDB()
+	todos := NewTodoService(repository.NewTodoRepository(db), nil)
+	categories := NewCategoryService(repository.NewCategoryRepository(db), nil)
+	service := NewTransferService(repository.NewTransferRepository(db), todos, categories)
+	service.now = func() time.Time { return time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC) }
+	return service, todos, db
+}
+
+func TestTransferService_Export(t *testing.T) {
+	service, todos, db := setupTransferService()
+
+	category := &models.Category{UserID: testUserID, Name: "Work", Color: "#FF0000"}
+	db.Create(category)
+	tag := &models.Tag{UserID: testUserID, Name: "q4", Color: "#6B7280"}
+	db.Create(tag)
+	parent := &models.Todo{Title: "Submit report", CategoryID: &category.ID, TagIDs: []uint{tag.ID}, Priority: models.PriorityHigh}
+	todos.CreateTodo(testUserID, parent)
+	todos.CreateSubtask(testUserID, parent.ID, &models.Todo{Title: "Attach figures"})
+	todos.CreateTodo(2, &models.Todo{Title: "Not mine"})
+
+	t.Run("json", func(t *testing.T) {
+		var out strings.Builder
+		err := service.Export(&out, testUserID, transfer.FormatJSON, "", map[string]interface{}{})
+
+		assert.NoError(t, err)
+		data, err := transfer.ReadJSON(strings.NewReader(out.String()))
+		assert.NoError(t, err)
+		assert.Equal(t, []transfer.Category{{Name: "Work", Color: "#FF0000"}}, data.Categories)
+		assert.Len(t, data.Todos, 2)
+		assert.Equal(t, "Work", data.Todos[0].Category)
+		assert.Equal(t, []string{"q4"}, data.Todos[0].Tags)
+		assert.Equal(t, "high", data.Todos[0].Priority)
+		assert.Equal(t, parent.ID, data.Todos[1].ParentID)
+		assert.NotContains(t, out.String(), "Not mine")
+	})
+
+	t.Run("csv with filters", func(t *testing.T) {
+		var out strings.Builder
+		err := service.Export(&out, testUserID, transfer.FormatCSV, "", map[string]interface{}{"top_level": true})
+
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, lines, 2)
+		assert.True(t, strings.HasPrefix(lines[1], "1,Submit report,,false,high,,,Work,q4,,"))
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		var out strings.Builder
+		err := service.Export(&out, testUserID, transfer.FormatMarkdown, "", map[string]interface{}{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "## Work\n\n### High priority\n\n- [ ] Submit report `q4`\n  - [ ] Attach figures\n", out.String())
+	})
+
+	t.Run("todo.txt", func(t *testing.T) {
+		var out strings.Builder
+		err := service.Export(&out, testUserID, transfer.FormatTodoTxt, "", map[string]interface{}{"top_level": true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "(A) "+parent.CreatedAt.UTC().Format("2006-01-02")+" Submit report +Work @q4\n", out.String())
+	})
+
+	t.Run("invalid format or search writes nothing", func(t *testing.T) {
+		var out strings.Builder
+
+		err := service.Export(&out, testUserID, "xml", "", map[string]interface{}{})
+		var validationErr *ValidationError
+		assert.ErrorAs(t, err, &validationErr)
+
+		err = service.Export(&out, testUserID, transfer.FormatJSON, `"unterminated`, map[string]interface{}{})
+		assert.Error(t, err)
+		assert.Empty(t, out.String())
+	})
+}
+
+func TestTransferService_Import(t *testing.T) {
+	t.Run("csv with categories, tags and row errors", func(t *testing.T) {
+		service, todos, db := setupTransferService()
+		db.Create(&models.Category{UserID: testUserID, Name: "Work", Color: "#FF0000"})
+		file := "Task,Category,Tags,Due,Priority\n" +
+			"Submit report,work,\"q4, finance\",2026-11-02,high\n" +
+			"Buy milk,Errands,,,\n" +
+			",Errands,,,\n" +
+			"Call Ada,,,someday,\n" +
+			"Renew passport,,,,urgent\n"
+
+		result, err := service.Import(testUserID, strings.NewReader(file), ImportOptions{
+			Format:           transfer.FormatCSV,
+			Mapping:          map[string]string{"title": "Task", "due_date": "Due"},
+			CreateCategories: true,
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 5, result.Total)
+		assert.Equal(t, 2, result.Created)
+		assert.Equal(t, 3, result.Failed)
+		assert.Equal(t, []string{"Errands"}, result.CategoriesCreated)
+		assert.Equal(t, []string{"q4", "finance"}, result.TagsCreated)
+		assert.Equal(t, []ImportRowResult{
+			{Row: 1, Title: "Submit report", Status: ImportCreated, ID: 1},
+			{Row: 2, Title: "Buy milk", Status: ImportCreated, ID: 2},
+			{Row: 3, Status: ImportFailed, Error: "title is required", Field: "title"},
+			{Row: 4, Status: ImportFailed, Error: result.Rows[3].Error, Field: "due_date"},
+			{Row: 5, Title: "Renew passport", Status: ImportFailed, Error: "invalid priority value", Field: "priority"},
+		}, result.Rows)
+
+		report, _ := todos.GetTodoByID(testUserID, 1)
+		assert.Equal(t, "Work", report.Category.Name)
+		assert.Len(t, report.Tags, 2)
+		assert.Equal(t, models.PriorityHigh, report.Priority)
+	})
+
+	t.Run("database errors are not reported", func(t *testing.T) {
+		service, _, db := setupTransferService()
+		db.Exec("CREATE TRIGGER fail_tags BEFORE INSERT ON tags BEGIN SELECT RAISE(ABORT, 'disk I/O error'); END")
+
+		result, err := service.Import(testUserID, strings.NewReader(`[{"title": "Water plants", "tags": ["home"]}]`), ImportOptions{Format: transfer.FormatJSON})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []ImportRowResult{{Row: 1, Title: "Water plants", Status: ImportFailed, Error: "internal error"}}, result.Rows)
+	})
+
+	t.Run("dry run saves nothing", func(t *testing.T) {
+		service, _, db := setupTransferService()
+		file := `{"categories": [{"name": "Home", "color": "#00FF00"}], "todos": [{"title": "Water plants", "category": "Home"}]}`
+
+		result, err := service.Import(testUserID, strings.NewReader(file), ImportOptions{Format: transfer.FormatJSON, CreateCategories: true, DryRun: true})
+
+		assert.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, 1, result.Created)
+		assert.Equal(t, []string{"Home"}, result.CategoriesCreated)
+		assert.Zero(t, result.Rows[0].ID)
+		var count int64
+		db.Model(&models.Todo{}).Count(&count)
+		assert.Zero(t, count)
+		db.Model(&models.Category{}).Count(&count)
+		assert.Zero(t, count)
+	})
+
+	t.Run("categories are not created unless allowed", func(t *testing.T) {
+		service, _, _ := setupTransferService()
+
+		result, err := service.Import(testUserID, strings.NewReader(`[{"title": "Water plants", "category": "Home"}]`), ImportOptions{Format: transfer.FormatJSON})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Failed)
+		assert.Equal(t, "category", result.Rows[0].Field)
+		assert.Empty(t, result.CategoriesCreated)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		file := `[{"title": "water plants ", "description": "Imported"}]`
+		cases := []struct {
+			mode        DuplicateMode
+			status      ImportStatus
+			todos       int64
+			description string
+		}{
+			{DuplicateSkip, ImportSkipped, 1, "Existing"},
+			{DuplicateOverwrite, ImportUpdated, 1, "Imported"},
+			{DuplicateCreate, ImportCreated, 2, "Existing"},
+		}
+		for _, tc := range cases {
+			service, todos, db := setupTransferService()
+			existing := &models.Todo{Title: "Water plants", Description: "Existing"}
+			todos.CreateTodo(testUserID, existing)
+
+			result, err := service.Import(testUserID, strings.NewReader(file), ImportOptions{Format: transfer.FormatJSON, OnDuplicate: tc.mode})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.status, result.Rows[0].Status)
+			var count int64
+			db.Model(&models.Todo{}).Count(&count)
+			assert.Equal(t, tc.todos, count)
+			found, _ := todos.GetTodoByID(testUserID, existing.ID)
+			assert.Equal(t, tc.description, found.Description)
+		}
+	})
+
+	t.Run("subtasks before their parent", func(t *testing.T) {
+		service, todos, _ := setupTransferService()
+		file := `[{"id": 9, "title": "Attach figures", "parent_id": 3}, {"id": 3, "title": "Submit report"}, {"title": "Orphan", "parent_id": 42}]`
+
+		result, err := service.Import(testUserID, strings.NewReader(file), ImportOptions{Format: transfer.FormatJSON})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.Created)
+		assert.Equal(t, "parent_id", result.Rows[2].Field)
+		subtask, _ := todos.GetTodoByID(testUserID, result.Rows[0].ID)
+		assert.Equal(t, result.Rows[1].ID, *subtask.ParentID)
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		service, todos, db := setupTransferService()
+		category := &models.Category{UserID: testUserID, Name: "Work", Color: "#FF0000"}
+		db.Create(category)
+		due := time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC)
+		parent := &models.Todo{Title: "Submit report", CategoryID: &category.ID, DueDate: &due, Recurrence: "FREQ=WEEKLY"}
+		todos.CreateTodo(testUserID, parent)
+		todos.CreateSubtask(testUserID, parent.ID, &models.Todo{Title: "Attach figures", Completed: true})
+		var out strings.Builder
+		service.Export(&out, testUserID, transfer.FormatJSON, "", map[string]interface{}{})
+
+		result, err := service.Import(2, strings.NewReader(out.String()), ImportOptions{Format: transfer.FormatJSON, CreateCategories: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.Created)
+		var imported []models.Todo
+		db.Preload("Category").Where("user_id = ?", 2).Order("id").Find(&imported)
+		assert.Equal(t, "#FF0000", imported[0].Category.Color)
+		assert.Equal(t, "FREQ=WEEKLY", imported[0].Recurrence)
+		assert.Equal(t, imported[0].ID, *imported[1].ParentID)
+		assert.True(t, imported[1].Completed)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		service, _, _ := setupTransferService()
+		cases := []struct {
+			opts  ImportOptions
+			file  string
+			field string
+		}{
+			{ImportOptions{Format: "xml"}, "[]", "format"},
+			{ImportOptions{Format: transfer.FormatJSON, OnDuplicate: "merge"}, "[]", "on_duplicate"},
+			{ImportOptions{Format: transfer.FormatJSON}, "{", "file"},
+			{ImportOptions{Format: transfer.FormatCSV, Mapping: map[string]string{"title": "Task"}}, "title\nx\n", "mapping"},
+		}
+		for _, tc := range cases {
+			_, err := service.Import(testUserID, strings.NewReader(tc.file), tc.opts)
+
+			var validationErr *ValidationError
+			assert.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tc.field, validationErr.Field)
+		}
+	})
+
+	t.Run("result as JSON", func(t *testing.T) {
+		service, _, _ := setupTransferService()
+
+		result, _ := service.Import(testUserID, strings.NewReader("[]"), ImportOptions{Format: transfer.FormatJSON})
+
+		body, _ := json.Marshal(result)
+		assert.JSONEq(t, `{"dry_run":false,"total":0,"created":0,"updated":0,"skipped":0,"failed":0,"categories_created":[],"tags_created":[],"rows":[]}`, string(body))
+	})
+}
+
+func TestTransferService_ImportFromOtherApps(t *testing.T) {
+	t.Run("trello board", func(t *testing.T) {
+		service, todos, db := setupTransferService()
+		file := `{
+			"lists": [{"id": "l1", "name": "To Do", "pos": 1}],
+			"cards": [{"id": "c1", "name": "Plan launch", "idList": "l1", "pos": 1, "labels": [{"name": "Marketing"}]}],
+			"checklists": [{"idCard": "c1", "checkItems": [{"name": "Book venue", "state": "complete"}]}]
+		}`
+
+		result, err := service.Import(testUserID, strings.NewReader(file), ImportOptions{Format: transfer.FormatTrello, CreateCategories: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.Created)
+		assert.Equal(t, []string{"To Do"}, result.CategoriesCreated)
+		assert.Equal(t, []string{"Marketing"}, result.TagsCreated)
+		subtask, _ := todos.GetTodoByID(testUserID, result.Rows[1].ID)
+		assert.Equal(t, result.Rows[0].ID, *subtask.ParentID)
+		assert.True(t, subtask.Completed)
+		var category models.Category
+		db.First(&category)
+		assert.Equal(t, importCategoryColor, category.Color)
+	})
+
+	t.Run("todoist project named by the file", func(t *testing.T) {
+		service, todos, _ := setupTransferService()
+		file := "TYPE,CONTENT,PRIORITY,INDENT\ntask,Submit report @q4,1,1\n"
+
+		result, err := service.Import(testUserID, strings.NewReader(file), ImportOptions{Format: transfer.FormatTodoist, FileName: "Work [2203306141].csv", CreateCategories: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Work"}, result.CategoriesCreated)
+		todo, _ := todos.GetTodoByID(testUserID, result.Rows[0].ID)
+		assert.Equal(t, models.PriorityHigh, todo.Priority)
+		assert.Equal(t, "q4", todo.Tags[0].Name)
+	})
+}
+
+func TestTransferService_Preview(t *testing.T) {
+	service, todos, db := setupTransferService()
+	todos.CreateTodo(testUserID, &models.Todo{Title: "Pay rent"})
+	file := "(A) Call Mom +Family @phone due:2026-11-02\nx Pay rent\nStretch rec:1w\n"
+
+	result, err := service.Preview(testUserID, strings.NewReader(file), ImportOptions{Format: transfer.FormatTodoTxt, CreateCategories: true})
+
+	assert.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, []string{"Family"}, result.CategoriesCreated)
+	assert.Equal(t, ImportCreated, result.Rows[0].Status)
+	assert.Zero(t, result.Rows[0].ID)
+	assert.Equal(t, &transfer.Todo{Row: 1, Title: "Call Mom", Priority: "high", DueDate: result.Rows[0].Todo.DueDate, Category: "Family", Tags: []string{"phone"}}, result.Rows[0].Todo)
+	assert.Equal(t, ImportSkipped, result.Rows[1].Status)
+	assert.NotZero(t, result.Rows[1].ID)
+	assert.Equal(t, ImportFailed, result.Rows[2].Status)
+	assert.Equal(t, "due_date", result.Rows[2].Field)
+	assert.Equal(t, "Stretch", result.Rows[2].Todo.Title)
+	var count int64
+	db.Model(&models.Todo{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	_, err = service.Preview(testUserID, strings.NewReader(file), ImportOptions{Format: "asana"})
+	var validationErr *ValidationError
+	assert.ErrorAs(t, err, &validationErr)
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// trashPurgeInterval is how often RunTrashPurge looks for expired trash
+const trashPurgeInterval = time.Hour
+
+// GetTrash gets a user's todos in the trash, most recently deleted first
+func (s *TodoService) GetTrash(userID uint) ([]models.Todo, error) {
+	return s.repo.GetTrash(userID)
+}
+
+// RestoreTodo takes a user's todo out of the trash along with the subtasks
+// that were deleted with it. A todo whose parent is no longer there is
+// restored at the top level.
+func (s *TodoService) RestoreTodo(userID, id uint) (*models.Todo, error) {
+	err := s.repo.Transaction(func(tx *repository.TodoRepository) error {
+		todo, err := tx.FindTrashed(userID, id)
+		if err != nil {
+			return err
+		}
+		since := todo.DeletedAt.Time
+		descendants, err := tx.GetTrashedDescendantIDs(userID, id, &since)
+		if err != nil {
+			return err
+		}
+		ids := append([]uint{id}, descendants...)
+		audit := newTodoAudit(tx, userID)
+		if err := audit.watch(ids...); err != nil {
+			return err
+		}
+		if err := tx.Restore(userID, ids...); err != nil {
+			return err
+		}
+
+		if todo.ParentID != nil {
+			_, err = tx.FindByID(userID, *todo.ParentID)
+			switch {
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				err = tx.SetParent(userID, id, nil)
+			case err == nil:
+				if err = audit.watchAncestors(todo.ParentID); err == nil {
+					err = syncAncestors(tx, userID, todo.ParentID)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return audit.record(models.AuditUpdated)
+	})
+	if err != nil {
+		return nil, dbError(err, "deleted todo")
+	}
+	s.publish(userID, events.TodoRestored, id)
+	return s.GetTodoByID(userID, id)
+}
+
+// PurgeTodo permanently deletes a user's todo in the trash along with its subtasks
+func (s *TodoService) PurgeTodo(userID, id uint) error {
+	err := s.repo.Transaction(func(tx *repository.TodoRepository) error {
+		if _, err := tx.FindTrashed(userID, id); err != nil {
+			return err
+		}
+		descendants, err := tx.GetTrashedDescendantIDs(userID, id, nil)
+		if err != nil {
+			return err
+		}
+		return tx.Purge(userID, append([]uint{id}, descendants...)...)
+	})
+	return dbError(err, "deleted todo")
+}
+
+// EmptyTrash permanently deletes all of a user's todos in the trash
+func (s *TodoService) EmptyTrash(userID uint) error {
+	return s.repo.Transaction(func(tx *repository.TodoRepository) error {
+		todos, err := tx.GetTrash(userID)
+		if err != nil {
+			return err
+		}
+		ids := make([]uint, len(todos))
+		for i, todo := range todos {
+			ids[i] = todo.ID
+		}
+		return tx.Purge(userID, ids...)
+	})
+}
+
+// PurgeExpired permanently deletes every todo moved to the trash before
+// cutoff and returns how many were deleted
+func (s *TodoService) PurgeExpired(cutoff time.Time) (int64, error) {
+	return s.repo.PurgeDeletedBefore(cutoff)
+}
+
+// GetTrash gets a user's categories in the trash, most recently deleted first
+func (s *CategoryService) GetTrash(userID uint) ([]models.Category, error) {
+	return s.repo.GetTrash(userID)
+}
+
+// RestoreCategory takes a user's category out of the trash and puts the
+// todos that belonged to it back into it. It fails with ErrNameTaken when
+// another category has the same name by now.
+func (s *CategoryService) RestoreCategory(userID, id uint) (*models.Category, error) {
+	category, err := s.repo.FindTrashed(userID, id)
+	if err != nil {
+		return nil, dbError(err, "deleted category")
+	}
+	taken, err := s.repo.NameTaken(userID, category.Name)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, ErrNameTaken
+	}
+
+	err = s.repo.Transaction(func(tx *repository.CategoryRepository) error {
+		if _, err := tx.Restore(userID, id); err != nil {
+			return err
+		}
+		restored, err := tx.GetByID(userID, id)
+		if err != nil {
+			return err
+		}
+		category = restored
+		return recordCategory(tx, userID, models.AuditRestored, nil, category)
+	})
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	s.bus.Publish(userID, events.CategoryRestored, category)
+	return category, nil
+}
+
+// PurgeCategory permanently deletes a user's category in the trash
+func (s *CategoryService) PurgeCategory(userID, id uint) error {
+	if _, err := s.repo.FindTrashed(userID, id); err != nil {
+		return dbError(err, "deleted category")
+	}
+	return s.repo.Purge(userID, id)
+}
+
+// EmptyTrash permanently deletes all of a user's categories in the trash
+func (s *CategoryService) EmptyTrash(userID uint) error {
+	categories, err := s.repo.GetTrash(userID)
+	if err != nil {
+		return err
+	}
+	ids := make([]uint, len(categories))
+	for i, category := range categories {
+		ids[i] = category.ID
+	}
+	return s.repo.Purge(userID, ids...)
+}
+
+// PurgeExpired permanently deletes every category moved to the trash before
+// cutoff and returns how many were deleted
+func (s *CategoryService) PurgeExpired(cutoff time.Time) (int64, error) {
+	return s.repo.PurgeDeletedBefore(cutoff)
+}
+
+// RunTrashPurge permanently deletes todos and categories once they have been
+// in the trash for longer than retention. It checks every hour until ctx is
+// done.
+func RunTrashPurge(ctx context.Context, retention time.Duration, todos *TodoService, categories *CategoryService) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().Add(-retention)
+		if n, err := todos.PurgeExpired(cutoff); err != nil {
+			log.Printf("Failed to purge expired todos from the trash: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d todos from the trash", n)
+		}
+		if n, err := categories.PurgeExpired(cutoff); err != nil {
+			log.Printf("Failed to purge expired categories from the trash: %v", err)
+		} else if n > 0 {
+			log.Printf("Purged %d categories from the trash", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+)
+
+// Headers sent with every webhook request
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature" // "sha256=" + hex HMAC-SHA256 of the body
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// allEventTypes subscribes a webhook to every event type
+const allEventTypes = "*"
+
+// ErrPrivateAddress is returned when a webhook request would connect to an
+// address that is not public
+var ErrPrivateAddress = errors.New("webhook receivers must have a public address")
+
+// sharedAddressSpace is the carrier-grade NAT range, private like those
+// netip.Addr.IsPrivate reports
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+const (
+	// webhookMaxAttempts is how often a delivery is tried before it is marked failed
+	webhookMaxAttempts = 8
+	// webhookBaseDelay is the wait after the first failed attempt; it doubles after each further one
+	webhookBaseDelay = 30 * time.Second
+	// webhookMaxDelay caps the wait between attempts
+	webhookMaxDelay = 6 * time.Hour
+	// webhookPollInterval is how often due retries are looked for
+	webhookPollInterval = 15 * time.Second
+	// webhookBatchSize is how many due deliveries are loaded at once
+	webhookBatchSize = 50
+)
+
+// WebhookService manages webhook subscriptions and delivers events to them
+type WebhookService struct {
+	repo        *repository.WebhookRepository
+	client      *http.Client
+	now         func() time.Time
+	maxAttempts int
+	baseDelay   time.Duration
+	wake        chan struct{}
+}
+
+// NewWebhookService creates a new WebhookService that sends requests with client
+func NewWebhookService(repo *repository.WebhookRepository, client *http.Client) *WebhookService {
+	return &WebhookService{
+		repo:        repo,
+		client:      client,
+		now:         time.Now,
+		maxAttempts: webhookMaxAttempts,
+		baseDelay:   webhookBaseDelay,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// CreateWebhook creates a new webhook for a user. A signing secret is
+// generated when none is given.
+func (s *WebhookService) CreateWebhook(userID uint, webhook *models.Webhook) error {
+	if err := s.validateWebhook(webhook); err != nil {
+		return err
+	}
+	if webhook.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return err
+		}
+		webhook.Secret = secret
+	}
+	webhook.UserID = userID
+	return dbError(s.repo.Create(webhook), "webhook")
+}
+
+// GetWebhooks gets all webhooks of a user
+func (s *WebhookService) GetWebhooks(userID uint) ([]models.Webhook, error) {
+	return s.repo.GetAll(userID)
+}
+
+// GetWebhookByID gets a user's webhook by ID
+func (s *WebhookService) GetWebhookByID(userID, id uint) (*models.Webhook, error) {
+	webhook, err := s.repo.GetByID(userID, id)
+	if err != nil {
+		return nil, dbError(err, "webhook")
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook updates a user's webhook. An empty secret keeps the current one.
+func (s *WebhookService) UpdateWebhook(userID uint, webhook *models.Webhook) error {
+	if err := s.validateWebhook(webhook); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetByID(userID, webhook.ID)
+	if err != nil {
+		return dbError(err, "webhook")
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = existing.Secret
+	}
+	webhook.UserID = userID
+	webhook.CreatedAt = existing.CreatedAt
+	return dbError(s.repo.Update(webhook), "webhook")
+}
+
+// DeleteWebhook deletes a user's webhook and its delivery log
+func (s *WebhookService) DeleteWebhook(userID, id uint) error {
+	return s.repo.Delete(userID, id)
+}
+
+// GetDeliveries gets the most recent deliveries of a user's webhook
+func (s *WebhookService) GetDeliveries(userID, webhookID uint, limit int) ([]models.WebhookDelivery, error) {
+	if _, err := s.repo.GetByID(userID, webhookID); err != nil {
+		return nil, dbError(err, "webhook")
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.GetDeliveries(webhookID, limit)
+}
+
+// Run queues a delivery for every published event that a webhook subscribes
+// to and sends queued deliveries until ctx is done. Deliveries still pending
+// from before a restart are picked up again.
+func (s *WebhookService) Run(ctx context.Context, bus *events.Bus) {
+	sub := bus.SubscribeAll()
+	defer func() { sub.Close() }()
+
+	go s.deliverLoop(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				log.Println("Webhook dispatcher fell behind the event bus; some events were not delivered")
+				sub = bus.SubscribeAll()
+				continue
+			}
+			if err := s.HandleEvent(event); err != nil {
+				log.Printf("Failed to queue webhook deliveries for event %d: %v", event.ID, err)
+				continue
+			}
+			select {
+			case s.wake <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// deliverLoop sends due deliveries whenever new ones are queued and
+// periodically for retries
+func (s *WebhookService) deliverLoop(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.DeliverDue(); err != nil {
+			log.Printf("Failed to deliver webhooks: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// HandleEvent queues a delivery of the event to each of the user's active
+// webhooks that subscribe to its type
+func (s *WebhookService) HandleEvent(event events.Event) error {
+	webhooks, err := s.repo.GetActive(event.UserID)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	var deliveries []models.WebhookDelivery
+	now := s.now()
+	for _, webhook := range webhooks {
+		if !subscribesTo(webhook, event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &now,
+		})
+	}
+	return s.repo.CreateDeliveries(deliveries)
+}
+
+// Send queues a delivery to the user's webhooks that subscribe to
+// eventType for an event that is not published on the bus. Such events
+// have no ID.
+func (s *WebhookService) Send(userID uint, eventType string, data interface{}) error {
+	event := events.Event{Type: eventType, UserID: userID, Data: data, Time: s.now()}
+	if err := s.HandleEvent(event); err != nil {
+		return err
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// DeliverDue attempts every pending delivery whose next attempt is due and
+// returns how many were attempted
+func (s *WebhookService) DeliverDue() (int, error) {
+	attempted := 0
+	for {
+		due, err := s.repo.GetDueDeliveries(s.now(), webhookBatchSize)
+		if err != nil {
+			return attempted, err
+		}
+		for i := range due {
+			if err := s.attempt(&due[i]); err != nil {
+				return attempted, err
+			}
+			attempted++
+		}
+		if len(due) < webhookBatchSize {
+			return attempted, nil
+		}
+	}
+}
+
+// attempt sends a delivery once and records the outcome, scheduling a retry
+// with exponential backoff after a failure
+func (s *WebhookService) attempt(delivery *models.WebhookDelivery) error {
+	webhook, err := s.repo.FindWebhook(delivery.WebhookID)
+	if err != nil {
+		return err
+	}
+
+	delivery.Attempts++
+	delivery.ResponseStatus = 0
+	delivery.LastError = ""
+	if webhook.Active {
+		delivery.ResponseStatus, err = s.send(webhook, delivery)
+	} else {
+		err = errors.New("webhook is disabled")
+		delivery.Attempts = s.maxAttempts
+	}
+
+	now := s.now()
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= s.maxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = nil
+	default:
+		next := now.Add(s.retryDelay(delivery.Attempts))
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = &next
+	}
+	return s.repo.UpdateDelivery(delivery)
+}
+
+// send posts the payload and returns the response status; any status other
+// than 2xx is an error
+func (s *WebhookService) send(webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "todoListChallenge-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryDelay returns the wait before the attempt following the given number
+// of failed attempts
+func (s *WebhookService) retryDelay(attempts int) time.Duration {
+	delay := s.baseDelay
+	for i := 1; i < attempts && delay < webhookMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > webhookMaxDelay {
+		delay = webhookMaxDelay
+	}
+	return delay
+}
+
+// SignWebhookPayload returns the signature header value for a payload, so
+// receivers can check that a request came from this server
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// PublicAddressesOnly is a net.Dialer Control function that refuses to
+// connect to loopback, private, link-local and other non-public addresses,
+// so that webhooks cannot reach the server's own network or a cloud
+// metadata service. It sees the resolved address, so host names pointing
+// at such addresses are refused as well.
+func PublicAddressesOnly(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	ip := addrPort.Addr().Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || sharedAddressSpace.Contains(ip) {
+		return ErrPrivateAddress
+	}
+	return nil
+}
+
+// subscribesTo reports whether the webhook wants events of the given type
+func subscribesTo(webhook models.Webhook, eventType string) bool {
+	for _, t := range webhook.EventTypes {
+		if t == allEventTypes || t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// newWebhookSecret generates a random signing secret
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// validateWebhook validates webhook fields
+func (s *WebhookService) validateWebhook(webhook *models.Webhook) error {
+	target, err := url.Parse(webhook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return &ValidationError{Field: "url", Msg: "url must be an absolute http or https URL"}
+	}
+	if len(webhook.URL) > 2048 {
+		return &ValidationError{Field: "url", Msg: "url must be at most 2048 characters"}
+	}
+	if len(webhook.Secret) > 255 {
+		return &ValidationError{Field: "secret", Msg: "secret must be at most 255 characters"}
+	}
+	if len(webhook.EventTypes) == 0 {
+		return &ValidationError{Field: "event_types", Msg: "event_types is required"}
+	}
+
+	known := map[string]bool{allEventTypes: true}
+	for _, t := range events.Types {
+		known[t] = true
+	}
+	seen := make(map[string]bool, len(webhook.EventTypes))
+	types := webhook.EventTypes[:0]
+	for _, t := range webhook.EventTypes {
+		if !known[t] {
+			return &ValidationError{Field: "event_types", Msg: fmt.Sprintf("unknown event type %q", t)}
+		}
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	webhook.EventTypes = types
+	return nil
+}
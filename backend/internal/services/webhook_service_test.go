@@ -0,0 +1,249 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"todoListChallenge/internal/events"
+	"todoListChallenge/internal/models"
+	"todoListChallenge/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupWebhookService() (*WebhookService, *gorm.DB) {
+	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{TranslateError: true})
+	db.AutoMigrate(&models.Webhook{}, &models.WebhookDelivery{})
+	return NewWebhookService(repository.NewWebhookRepository(db), http.DefaultClient), db
+}
+
+// receiver is a local webhook endpoint that records requests and answers
+// with the configured status
+type receiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T) (*receiver, *httptest.Server) {
+	r := &receiver{status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.requests = append(r.requests, req)
+		r.bodies = append(r.bodies, body)
+		w.WriteHeader(r.status)
+	}))
+	t.Cleanup(server.Close)
+	return r, server
+}
+
+func TestWebhookService_CRUD(t *testing.T) {
+	service, _ := setupWebhookService()
+
+	t.Run("generates a secret and dedupes event types", func(t *testing.T) {
+		webhook := &models.Webhook{
+			URL:        "https://example.com/hook",
+			EventTypes: []string{events.TodoToggled, events.TodoToggled},
+			Active:     true,
+		}
+		assert.NoError(t, service.CreateWebhook(testUserID, webhook))
+		assert.Len(t, webhook.Secret, 64)
+		assert.Equal(t, []string{events.TodoToggled}, webhook.EventTypes)
+
+		found, err := service.GetWebhookByID(testUserID, webhook.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{events.TodoToggled}, found.EventTypes)
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		cases := []struct {
+			webhook models.Webhook
+			err     string
+		}{
+			{models.Webhook{URL: "ftp://example.com", EventTypes: []string{"*"}}, "url must be"},
+			{models.Webhook{URL: "/relative", EventTypes: []string{"*"}}, "url must be"},
+			{models.Webhook{URL: "https://example.com"}, "event_types is required"},
+			{models.Webhook{URL: "https://example.com", EventTypes: []string{"todo.exploded"}}, "unknown event type"},
+		}
+		for _, tc := range cases {
+			err := service.CreateWebhook(testUserID, &tc.webhook)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		}
+	})
+
+	t.Run("update keeps the secret when none is given", func(t *testing.T) {
+		webhook := &models.Webhook{URL: "https://example.com/a", Secret: "s3cret", EventTypes: []string{"*"}, Active: true}
+		service.CreateWebhook(testUserID, webhook)
+
+		update := &models.Webhook{ID: webhook.ID, URL: "https://example.com/b", EventTypes: []string{"*"}}
+		assert.NoError(t, service.UpdateWebhook(testUserID, update))
+
+		found, _ := service.GetWebhookByID(testUserID, webhook.ID)
+		assert.Equal(t, "https://example.com/b", found.URL)
+		assert.Equal(t, "s3cret", found.Secret)
+		assert.False(t, found.Active)
+	})
+
+	t.Run("other users cannot see or change webhooks", func(t *testing.T) {
+		webhook := &models.Webhook{URL: "https://example.com", EventTypes: []string{"*"}}
+		service.CreateWebhook(testUserID, webhook)
+
+		_, err := service.GetWebhookByID(2, webhook.ID)
+		assert.Error(t, err)
+		_, err = service.GetDeliveries(2, webhook.ID, 10)
+		assert.Error(t, err)
+		assert.Error(t, service.UpdateWebhook(2, &models.Webhook{ID: webhook.ID, URL: "https://evil.test", EventTypes: []string{"*"}}))
+	})
+}
+
+func TestWebhookService_Delivery(t *testing.T) {
+	service, _ := setupWebhookService()
+	recv, server := newReceiver(t)
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	toggled := &models.Webhook{URL: server.URL, Secret: "topsecret", EventTypes: []string{events.TodoToggled}, Active: true}
+	everything := &models.Webhook{URL: server.URL, Secret: "other", EventTypes: []string{"*"}, Active: true}
+	disabled := &models.Webhook{URL: server.URL, EventTypes: []string{"*"}}
+	service.CreateWebhook(testUserID, toggled)
+	service.CreateWebhook(testUserID, everything)
+	service.CreateWebhook(testUserID, disabled)
+
+	event := events.Event{ID: 7, Type: events.TodoToggled, UserID: testUserID, Data: map[string]interface{}{"id": 3, "completed": true}, Time: now}
+	assert.NoError(t, service.HandleEvent(event))
+	assert.NoError(t, service.HandleEvent(events.Event{ID: 8, Type: events.TodoCreated, UserID: testUserID}))
+	assert.NoError(t, service.HandleEvent(events.Event{ID: 9, Type: events.TodoToggled, UserID: 2}))
+
+	attempted, err := service.DeliverDue()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempted)
+	assert.Len(t, recv.requests, 3)
+
+	t.Run("payload is signed with the webhook secret", func(t *testing.T) {
+		req, body := recv.requests[0], recv.bodies[0]
+		assert.Equal(t, events.TodoToggled, req.Header.Get(WebhookEventHeader))
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.NotEmpty(t, req.Header.Get(WebhookDeliveryHeader))
+		assert.Equal(t, SignWebhookPayload("topsecret", body), req.Header.Get(WebhookSignatureHeader))
+		assert.NotEqual(t, SignWebhookPayload("other", body), req.Header.Get(WebhookSignatureHeader))
+
+		var payload map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, events.TodoToggled, payload["type"])
+		assert.Equal(t, true, payload["data"].(map[string]interface{})["completed"])
+	})
+
+	t.Run("deliveries are logged", func(t *testing.T) {
+		deliveries, err := service.GetDeliveries(testUserID, toggled.ID, 10)
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+		assert.Equal(t, models.DeliverySucceeded, deliveries[0].Status)
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Equal(t, http.StatusOK, deliveries[0].ResponseStatus)
+		assert.NotNil(t, deliveries[0].DeliveredAt)
+
+		deliveries, _ = service.GetDeliveries(testUserID, everything.ID, 10)
+		assert.Len(t, deliveries, 2)
+		deliveries, _ = service.GetDeliveries(testUserID, disabled.ID, 10)
+		assert.Empty(t, deliveries)
+	})
+
+	t.Run("failures are retried with exponential backoff", func(t *testing.T) {
+		recv.status = http.StatusInternalServerError
+		service.HandleEvent(event)
+
+		service.DeliverDue()
+		deliveries, _ := service.GetDeliveries(testUserID, toggled.ID, 1)
+		failed := deliveries[0]
+		assert.Equal(t, models.DeliveryPending, failed.Status)
+		assert.Equal(t, 1, failed.Attempts)
+		assert.Equal(t, http.StatusInternalServerError, failed.ResponseStatus)
+		assert.Contains(t, failed.LastError, "500")
+		assert.Equal(t, now.Add(30*time.Second), failed.NextAttemptAt.UTC())
+
+		// Nothing is due before the backoff has passed
+		attempted, _ := service.DeliverDue()
+		assert.Equal(t, 0, attempted)
+
+		now = now.Add(30 * time.Second)
+		service.DeliverDue()
+		deliveries, _ = service.GetDeliveries(testUserID, toggled.ID, 1)
+		assert.Equal(t, 2, deliveries[0].Attempts)
+		assert.Equal(t, now.Add(time.Minute), deliveries[0].NextAttemptAt.UTC())
+
+		recv.status = http.StatusNoContent
+		now = now.Add(time.Minute)
+		service.DeliverDue()
+		deliveries, _ = service.GetDeliveries(testUserID, toggled.ID, 1)
+		assert.Equal(t, models.DeliverySucceeded, deliveries[0].Status)
+		assert.Equal(t, 3, deliveries[0].Attempts)
+		assert.Empty(t, deliveries[0].LastError)
+		assert.Nil(t, deliveries[0].NextAttemptAt)
+	})
+
+	t.Run("deliveries fail after the last attempt", func(t *testing.T) {
+		recv.status = http.StatusBadGateway
+		service.maxAttempts = 2
+		service.HandleEvent(events.Event{ID: 10, Type: events.TodoToggled, UserID: testUserID})
+
+		for i := 0; i < 3; i++ {
+			service.DeliverDue()
+			now = now.Add(time.Hour)
+		}
+		deliveries, _ := service.GetDeliveries(testUserID, toggled.ID, 1)
+		assert.Equal(t, models.DeliveryFailed, deliveries[0].Status)
+		assert.Equal(t, 2, deliveries[0].Attempts)
+		assert.Nil(t, deliveries[0].NextAttemptAt)
+	})
+
+	t.Run("deleting a webhook removes its deliveries", func(t *testing.T) {
+		assert.NoError(t, service.DeleteWebhook(testUserID, everything.ID))
+		_, err := service.GetDeliveries(testUserID, everything.ID, 10)
+		assert.Error(t, err)
+	})
+}
+
+func TestWebhookService_RetryDelay(t *testing.T) {
+	service, _ := setupWebhookService()
+	assert.Equal(t, 30*time.Second, service.retryDelay(1))
+	assert.Equal(t, time.Minute, service.retryDelay(2))
+	assert.Equal(t, 4*time.Minute, service.retryDelay(4))
+	assert.Equal(t, webhookMaxDelay, service.retryDelay(30))
+}
+
+func TestPublicAddressesOnly(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:5432", "[::1]:80", "10.0.0.8:80", "192.168.1.1:443", "169.254.169.254:80", "100.64.0.1:80", "0.0.0.0:80", "[fd00::1]:80", "[::ffff:127.0.0.1]:80"} {
+		assert.ErrorIs(t, PublicAddressesOnly("tcp", address, nil), ErrPrivateAddress, address)
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:80"} {
+		assert.NoError(t, PublicAddressesOnly("tcp", address, nil), address)
+	}
+
+	t.Run("deliveries to local receivers fail", func(t *testing.T) {
+		service, _ := setupWebhookService()
+		recv, server := newReceiver(t)
+		dialer := &net.Dialer{Control: PublicAddressesOnly}
+		service.client = &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext}}
+		webhook := &models.Webhook{URL: server.URL, EventTypes: []string{"*"}, Active: true}
+		service.CreateWebhook(testUserID, webhook)
+
+		service.HandleEvent(events.Event{ID: 1, Type: events.TodoCreated, UserID: testUserID})
+		service.DeliverDue()
+
+		assert.Empty(t, recv.requests)
+		deliveries, _ := service.GetDeliveries(testUserID, webhook.ID, 1)
+		assert.Contains(t, deliveries[0].LastError, ErrPrivateAddress.Error())
+		assert.Zero(t, deliveries[0].ResponseStatus)
+	})
+}
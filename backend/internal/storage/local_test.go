@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalStorage(t.TempDir())
+	assert.NoError(t, err)
+	key := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	t.Run("put and get", func(t *testing.T) {
+		assert.NoError(t, store.Put(ctx, key, strings.NewReader("hello"), 5, "text/plain"))
+
+		exists, err := store.Exists(ctx, key)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		r, err := store.Get(ctx, key)
+		assert.NoError(t, err)
+		content, _ := io.ReadAll(r)
+		r.Close()
+		assert.Equal(t, "hello", string(content))
+	})
+
+	t.Run("short write is rejected", func(t *testing.T) {
+		err := store.Put(ctx, "short", strings.NewReader("abc"), 5, "")
+
+		assert.Error(t, err)
+		exists, _ := store.Exists(ctx, "short")
+		assert.False(t, exists)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assert.NoError(t, store.Delete(ctx, key))
+		assert.NoError(t, store.Delete(ctx, key))
+
+		_, err := store.Get(ctx, key)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("keys cannot leave the directory", func(t *testing.T) {
+		for _, key := range []string{"", "..", "../etc", "a/b"} {
+			assert.Error(t, store.Put(ctx, key, strings.NewReader(""), 0, ""), key)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+// Package storage keeps the files attached to todos. Files are stored under
+// keys chosen by the caller, the hex SHA-256 of their content, on the local
+// file system or in an S3-compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned when no file is stored under a key
+var ErrNotFound = errors.New("file not found in storage")
+
+// Storage stores files by key. Putting a key that is already stored
+// replaces the file.
+type Storage interface {
+	// Put stores size bytes read from r under key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the file stored under key; the caller closes it
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Exists reports whether a file is stored under key
+	Exists(ctx context.Context, key string) (bool, error)
+	// Delete removes the file stored under key; a missing file is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// validKey reports whether a key is safe to use as a file name or object
+// name: letters, digits, dots, hyphens and underscores only
+func validKey(key string) bool {
+	if key == "" || strings.Trim(key, ".") == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// errInvalidKey is returned for keys validKey rejects
+var errInvalidKey = errors.New("invalid storage key")
@@ -0,0 +1,239 @@
+package transfer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Columns are the columns of an exported CSV file, in order
+var Columns = []string{"id", "title", "description", "completed", "priority", "due_date", "recurrence", "category", "tags", "parent_id", "created_at", "updated_at"}
+
+// Fields are the fields a column of an imported CSV file can be read into
+var Fields = []string{"id", "title", "description", "completed", "priority", "due_date", "recurrence", "category", "tags", "parent_id"}
+
+// tagSeparator separates the tags in the tags column
+const tagSeparator = ","
+
+// CSVWriter writes todos as CSV, one row per todo after a header row
+type CSVWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewCSVWriter creates a CSVWriter that writes to w
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+// Write writes a todo as a row
+func (w *CSVWriter) Write(todo Todo) error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	return w.w.Write([]string{
+		formatID(todo.ID),
+		todo.Title,
+		todo.Description,
+		strconv.FormatBool(todo.Completed),
+		todo.Priority,
+		formatTime(todo.DueDate),
+		todo.Recurrence,
+		todo.Category,
+		strings.Join(todo.Tags, tagSeparator+" "),
+		formatID(todo.ParentID),
+		formatTime(todo.CreatedAt),
+		formatTime(todo.UpdatedAt),
+	})
+}
+
+// Close writes the header row if no todo was written and flushes the output
+func (w *CSVWriter) Close() error {
+	if err := w.writeHeader(); err != nil {
+		return err
+	}
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// writeHeader writes the header row unless it has been written
+func (w *CSVWriter) writeHeader() error {
+	if w.header {
+		return nil
+	}
+	w.header = true
+	return w.w.Write(Columns)
+}
+
+// ReadCSV reads todos from a CSV file with a header row. Columns are read
+// into the field of the same name, ignoring case; mapping sets the column
+// for a field instead, such as "Task Name" for title. Columns that are not
+// read into a field are ignored. A file without a title column, or a
+// mapping to a field or column that does not exist, returns an *Error.
+func ReadCSV(r io.Reader, mapping map[string]string) (*Data, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, &Error{Field: "file", Msg: "the file is empty"}
+	}
+	if err != nil {
+		return nil, csvError(err)
+	}
+	columns, err := csvColumns(header, mapping)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &Data{}
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return data, nil
+		}
+		if err != nil {
+			return nil, csvError(err)
+		}
+		todo, rowErr := csvTodo(record, columns)
+		if rowErr != nil {
+			rowErr.Row = row
+			data.Errors = append(data.Errors, *rowErr)
+			continue
+		}
+		todo.Row = row
+		data.Todos = append(data.Todos, todo)
+	}
+}
+
+// csvError returns an *Error for malformed CSV, and other errors, like
+// those of reading the file, as they are
+func csvError(err error) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &Error{Field: "file", Msg: "invalid CSV: " + err.Error()}
+	}
+	return err
+}
+
+// csvColumns returns the index of the column each field is read from
+func csvColumns(header []string, mapping map[string]string) (map[string]int, error) {
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff") // Byte order mark of files saved by Excel
+	}
+	index := func(name string) int {
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), strings.TrimSpace(name)) {
+				return i
+			}
+		}
+		return -1
+	}
+
+	columns := make(map[string]int)
+	for _, field := range Fields {
+		if i := index(field); i >= 0 {
+			columns[field] = i
+		}
+	}
+	fields := make([]string, 0, len(mapping))
+	for field := range mapping {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		if !isField(field) {
+			return nil, &Error{Field: "mapping", Msg: "unknown field " + strconv.Quote(field) + ", expected one of " + strings.Join(Fields, ", ")}
+		}
+		i := index(mapping[field])
+		if i < 0 {
+			return nil, &Error{Field: "mapping", Msg: "the file has no column " + strconv.Quote(mapping[field]) + " for " + field}
+		}
+		columns[field] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, &Error{Field: "mapping", Msg: "the file has no title column; map one to the title field"}
+	}
+	return columns, nil
+}
+
+// csvTodo reads a todo from a row
+func csvTodo(record []string, columns map[string]int) (Todo, *Error) {
+	value := func(field string) (string, bool) {
+		i, ok := columns[field]
+		if !ok {
+			return "", false
+		}
+		if i >= len(record) {
+			return "", true
+		}
+		return strings.TrimSpace(record[i]), true
+	}
+
+	var todo Todo
+	var err error
+	todo.Title, _ = value("title")
+	todo.Description, _ = value("description")
+	priority, _ := value("priority")
+	todo.Priority = strings.ToLower(priority)
+	todo.Recurrence, _ = value("recurrence")
+	todo.Category, _ = value("category")
+	if tags, ok := value("tags"); ok {
+		todo.Tags = []string{}
+		for _, tag := range strings.Split(tags, tagSeparator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				todo.Tags = append(todo.Tags, tag)
+			}
+		}
+	}
+
+	completed, _ := value("completed")
+	if todo.Completed, err = parseBool(completed); err != nil {
+		return todo, &Error{Field: "completed", Msg: err.Error()}
+	}
+	if due, _ := value("due_date"); due != "" {
+		t, err := parseTime(due)
+		if err != nil {
+			return todo, &Error{Field: "due_date", Msg: err.Error()}
+		}
+		todo.DueDate = &t
+	}
+	id, _ := value("id")
+	if todo.ID, err = parseID(id); err != nil {
+		return todo, &Error{Field: "id", Msg: err.Error()}
+	}
+	parentID, _ := value("parent_id")
+	if todo.ParentID, err = parseID(parentID); err != nil {
+		return todo, &Error{Field: "parent_id", Msg: err.Error()}
+	}
+	return todo, nil
+}
+
+// isField reports whether a CSV column can be read into the field
+func isField(field string) bool {
+	for _, f := range Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// formatID formats an optional ID
+func formatID(id uint) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// formatTime formats an optional time in UTC
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,139 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// jsonVersion is the version of the JSON export format
+const jsonVersion = 1
+
+// JSONWriter writes todos as a JSON object holding the categories and then
+// the todos, one per line so the file can be written as the todos are read
+type JSONWriter struct {
+	w          *bufio.Writer
+	exportedAt time.Time
+	categories []Category
+	started    bool
+	count      int
+	err        error
+}
+
+// NewJSONWriter creates a JSONWriter that writes to w. Nothing is written
+// before the first todo or Close.
+func NewJSONWriter(w io.Writer, exportedAt time.Time, categories []Category) *JSONWriter {
+	if categories == nil {
+		categories = []Category{}
+	}
+	return &JSONWriter{w: bufio.NewWriter(w), exportedAt: exportedAt, categories: categories}
+}
+
+// Write writes a todo
+func (w *JSONWriter) Write(todo Todo) error {
+	w.start()
+	if w.count > 0 {
+		w.write([]byte(",\n"))
+	} else {
+		w.write([]byte("\n"))
+	}
+	w.count++
+	w.encode(todo)
+	return w.err
+}
+
+// Close writes the end of the object and flushes the output
+func (w *JSONWriter) Close() error {
+	w.start()
+	w.write([]byte("\n]}\n"))
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	return w.err
+}
+
+// start writes the object up to the opening of the todos array
+func (w *JSONWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	w.write([]byte(`{"version":`))
+	w.encode(jsonVersion)
+	w.write([]byte(`,"exported_at":`))
+	w.encode(w.exportedAt.UTC())
+	w.write([]byte(`,"categories":`))
+	w.encode(w.categories)
+	w.write([]byte(`,"todos":[`))
+}
+
+// encode writes a value as JSON
+func (w *JSONWriter) encode(v interface{}) {
+	if w.err != nil {
+		return
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		w.err = err
+		return
+	}
+	w.write(b)
+}
+
+// write writes raw output unless an earlier write failed
+func (w *JSONWriter) write(b []byte) {
+	if w.err == nil {
+		_, w.err = w.w.Write(b)
+	}
+}
+
+// jsonFile is an exported JSON file
+type jsonFile struct {
+	Categories []Category        `json:"categories"`
+	Todos      []json.RawMessage `json:"todos"`
+}
+
+// ReadJSON reads todos from a JSON file in the form JSONWriter writes, or
+// from an array of todos. A todo that does not match the format, like one
+// with a number for a title, is reported in Data.Errors; a file that is not
+// JSON returns an *Error.
+func ReadJSON(r io.Reader) (*Data, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\ufeff")))
+	if len(body) == 0 {
+		return nil, &Error{Field: "file", Msg: "the file is empty"}
+	}
+
+	var file jsonFile
+	if body[0] == '[' {
+		err = json.Unmarshal(body, &file.Todos)
+	} else {
+		err = json.Unmarshal(body, &file)
+	}
+	if err != nil {
+		return nil, &Error{Field: "file", Msg: "invalid JSON: " + err.Error()}
+	}
+
+	data := &Data{Categories: file.Categories}
+	for i, raw := range file.Todos {
+		var todo Todo
+		if err := json.Unmarshal(raw, &todo); err != nil {
+			rowErr := Error{Row: i + 1, Msg: "invalid todo: " + err.Error()}
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				rowErr.Field = typeErr.Field
+			}
+			data.Errors = append(data.Errors, rowErr)
+			continue
+		}
+		todo.Row = i + 1
+		data.Todos = append(data.Todos, todo)
+	}
+	return data, nil
+}
@@ -0,0 +1,142 @@
+package transfer
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// markdownPriorities are the priorities in the order their groups are
+// written, with their headings
+var markdownPriorities = []struct{ value, heading string }{
+	{"high", "High priority"},
+	{"medium", "Medium priority"},
+	{"low", "Low priority"},
+	{"", "No priority"},
+}
+
+// markdownEscaper escapes the characters of a title that Markdown would
+// read as formatting
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "<", `\<`, "\n", " ", "\r", "",
+)
+
+// MarkdownWriter writes todos as a GitHub-flavored Markdown task list, under
+// a heading for each category and then for each priority. Subtasks are
+// nested below their parent when it is written too. Since the todos are
+// grouped, they are held until Close.
+type MarkdownWriter struct {
+	w     io.Writer
+	todos []Todo
+}
+
+// NewMarkdownWriter creates a MarkdownWriter that writes to w
+func NewMarkdownWriter(w io.Writer) *MarkdownWriter {
+	return &MarkdownWriter{w: w}
+}
+
+// Write adds a todo to the list
+func (w *MarkdownWriter) Write(todo Todo) error {
+	w.todos = append(w.todos, todo)
+	return nil
+}
+
+// Close writes the list
+func (w *MarkdownWriter) Close() error {
+	out := bufio.NewWriter(w.w)
+	if len(w.todos) == 0 {
+		out.WriteString("_No todos_\n")
+		return out.Flush()
+	}
+
+	listed := make(map[uint]bool, len(w.todos))
+	for _, todo := range w.todos {
+		if todo.ID != 0 {
+			listed[todo.ID] = true
+		}
+	}
+	subtasks := make(map[uint][]Todo)
+	groups := make(map[string]map[string][]Todo) // Top-level todos by category and priority
+	for _, todo := range w.todos {
+		if todo.ParentID != 0 && listed[todo.ParentID] {
+			subtasks[todo.ParentID] = append(subtasks[todo.ParentID], todo)
+			continue
+		}
+		if groups[todo.Category] == nil {
+			groups[todo.Category] = make(map[string][]Todo)
+		}
+		priority := strings.ToLower(todo.Priority)
+		if priority != "high" && priority != "medium" && priority != "low" {
+			priority = ""
+		}
+		groups[todo.Category][priority] = append(groups[todo.Category][priority], todo)
+	}
+
+	categories := make([]string, 0, len(groups))
+	for category := range groups {
+		categories = append(categories, category)
+	}
+	// By name, with the todos without a category last
+	sort.Slice(categories, func(i, j int) bool {
+		if (categories[i] == "") != (categories[j] == "") {
+			return categories[j] == ""
+		}
+		return strings.ToLower(categories[i]) < strings.ToLower(categories[j])
+	})
+
+	var write func(todo Todo, depth int)
+	write = func(todo Todo, depth int) {
+		out.WriteString(strings.Repeat("  ", depth))
+		out.WriteString(markdownItem(todo))
+		out.WriteByte('\n')
+		for _, subtask := range subtasks[todo.ID] {
+			write(subtask, depth+1)
+		}
+	}
+	for i, category := range categories {
+		if i > 0 {
+			out.WriteByte('\n')
+		}
+		heading := "No category"
+		if category != "" {
+			heading = markdownEscaper.Replace(category)
+		}
+		out.WriteString("## " + heading + "\n")
+		for _, priority := range markdownPriorities {
+			todos := groups[category][priority.value]
+			if len(todos) == 0 {
+				continue
+			}
+			out.WriteString("\n### " + priority.heading + "\n\n")
+			for _, todo := range todos {
+				write(todo, 0)
+			}
+		}
+	}
+	return out.Flush()
+}
+
+// markdownItem returns the task list item of a todo
+func markdownItem(todo Todo) string {
+	var b strings.Builder
+	if todo.Completed {
+		b.WriteString("- [x] ")
+	} else {
+		b.WriteString("- [ ] ")
+	}
+	b.WriteString(markdownEscaper.Replace(todo.Title))
+	if todo.DueDate != nil {
+		b.WriteString(" (due " + formatDate(todo.DueDate) + ")")
+	}
+	for _, tag := range todo.Tags {
+		b.WriteString(" `" + strings.ReplaceAll(tag, "`", "'") + "`")
+	}
+	return b.String()
+}
+
+// formatDate formats a date, or the day of a time in UTC
+func formatDate(t *time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
@@ -0,0 +1,430 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxTodoistBackup is the most a Todoist backup may hold once unzipped
+const maxTodoistBackup = 50 << 20
+
+var (
+	// todoistBackupName matches the name of a project in a backup, like
+	// "Work [2203306141].csv"
+	todoistBackupName = regexp.MustCompile(`\s*\[\d+\]$`)
+	todoistEvery      = regexp.MustCompile(`^every (?:(\d+) )?(day|week|month|year)s?$`)
+)
+
+// todoistColors are the hex values of Todoist's named colors
+var todoistColors = map[string]string{
+	"berry_red": "#B8256F", "red": "#DB4035", "orange": "#FF9933", "yellow": "#FAD000",
+	"olive_green": "#AFB83B", "lime_green": "#7ECC49", "green": "#299438", "mint_green": "#6ACCBC",
+	"teal": "#158FAD", "sky_blue": "#14AAF5", "light_blue": "#96C3EB", "blue": "#4073FF",
+	"grape": "#884DFF", "violet": "#AF38EB", "lavender": "#EB96EB", "magenta": "#E05194",
+	"salmon": "#FF8D85", "charcoal": "#808080", "grey": "#B8B8B8", "taupe": "#CCAC93",
+}
+
+// todoistFrequencies are the RRULE frequencies of the units of a Todoist
+// recurring date
+var todoistFrequencies = map[string]string{"day": "DAILY", "week": "WEEKLY", "month": "MONTHLY", "year": "YEARLY"}
+
+// todoistWeekdays are the RRULE codes of weekdays as Todoist writes them
+var todoistWeekdays = map[string]string{
+	"mon": "MO", "monday": "MO", "tue": "TU", "tuesday": "TU", "wed": "WE", "wednesday": "WE",
+	"thu": "TH", "thursday": "TH", "fri": "FR", "friday": "FR", "sat": "SA", "saturday": "SA",
+	"sun": "SU", "sunday": "SU",
+}
+
+// ReadTodoist reads todos from a Todoist export, which is one of:
+//
+//   - the JSON of a Sync API request for all resources, where projects are
+//     categories, labels tags and subtasks are kept;
+//   - a project exported as CSV, whose category is the project in the file
+//     name, like "Work [2203306141].csv", with labels read from the @words
+//     of a task and subtasks from its indent;
+//   - a backup, which is a zip file of those CSVs, one for each project.
+//
+// Tasks in the inbox have no category. Priority p1 is high, p2 medium and
+// the others low. A due date or recurrence that cannot be read, like
+// "every 3rd friday", is added to the description instead, so it is not
+// lost. Sections are left out.
+func ReadTodoist(r io.Reader, fileName string) (*Data, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	body = bytes.TrimPrefix(body, []byte("\ufeff"))
+	switch {
+	case bytes.HasPrefix(body, []byte("PK\x03\x04")):
+		return readTodoistBackup(body)
+	case bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")):
+		return readTodoistJSON(body)
+	}
+	data := &Data{}
+	if _, err := readTodoistCSV(bytes.NewReader(body), fileName, data, 0); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// todoistID is the ID of a Todoist object, which is a string in the Sync
+// API v9 and a number before it
+type todoistID string
+
+func (id *todoistID) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		*id = ""
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*id = todoistID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return err
+	}
+	*id = todoistID(n)
+	return nil
+}
+
+// todoistExport is the JSON of a Sync API request for all resources
+type todoistExport struct {
+	Projects []struct {
+		ID           todoistID `json:"id"`
+		Name         string    `json:"name"`
+		Color        string    `json:"color"`
+		InboxProject bool      `json:"inbox_project"`
+		IsDeleted    bool      `json:"is_deleted"`
+	} `json:"projects"`
+	Items []json.RawMessage `json:"items"`
+}
+
+// todoistItem is a task of a todoistExport
+type todoistItem struct {
+	ID          todoistID   `json:"id"`
+	ProjectID   todoistID   `json:"project_id"`
+	ParentID    todoistID   `json:"parent_id"`
+	Content     string      `json:"content"`
+	Description string      `json:"description"`
+	Priority    int         `json:"priority"` // 4 is p1, the highest
+	Due         *todoistDue `json:"due"`
+	Labels      []string    `json:"labels"`
+	Checked     bool        `json:"checked"`
+	IsDeleted   bool        `json:"is_deleted"`
+}
+
+// todoistDue is the due date of a todoistItem
+type todoistDue struct {
+	Date        string `json:"date"`
+	IsRecurring bool   `json:"is_recurring"`
+	String      string `json:"string"`
+}
+
+// readTodoistJSON reads the JSON of a Sync API request
+func readTodoistJSON(body []byte) (*Data, error) {
+	var export todoistExport
+	if err := json.Unmarshal(body, &export); err != nil {
+		return nil, &Error{Field: "file", Msg: "invalid JSON: " + err.Error()}
+	}
+	if export.Items == nil {
+		return nil, &Error{Field: "file", Msg: "the file has no items; export all resources of the Sync API"}
+	}
+
+	data := &Data{}
+	projects := make(map[todoistID]string, len(export.Projects))
+	for _, project := range export.Projects {
+		if project.IsDeleted || project.InboxProject {
+			continue
+		}
+		projects[project.ID] = project.Name
+		data.Categories = append(data.Categories, Category{Name: project.Name, Color: todoistColors[project.Color]})
+	}
+
+	items := make([]todoistItem, len(export.Items))
+	ids := make(map[todoistID]uint, len(export.Items))
+	for i, raw := range export.Items {
+		if err := json.Unmarshal(raw, &items[i]); err != nil {
+			rowErr := Error{Row: i + 1, Msg: "invalid item: " + err.Error()}
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				rowErr.Field = typeErr.Field
+			}
+			data.Errors = append(data.Errors, rowErr)
+			items[i].IsDeleted = true
+			continue
+		}
+		if items[i].ID != "" {
+			ids[items[i].ID] = uint(i + 1)
+		}
+	}
+	for i, item := range items {
+		if item.IsDeleted {
+			continue
+		}
+		todo := Todo{
+			Row:         i + 1,
+			ID:          uint(i + 1),
+			Title:       item.Content,
+			Description: item.Description,
+			Completed:   item.Checked,
+			Priority:    todoistPriority(5 - item.Priority),
+			Category:    projects[item.ProjectID],
+			Tags:        item.Labels,
+			ParentID:    ids[item.ParentID],
+		}
+		if item.Due != nil {
+			todoistDate(&todo, item.Due.Date, item.Due.String, item.Due.IsRecurring)
+		}
+		data.Todos = append(data.Todos, todo)
+	}
+	return data, nil
+}
+
+// readTodoistBackup reads a zip file of projects exported as CSV, in the
+// order of their names. Rows are numbered on from one project to the next.
+func readTodoistBackup(body []byte) (*Data, error) {
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return nil, &Error{Field: "file", Msg: "invalid zip file: " + err.Error()}
+	}
+	files := make([]*zip.File, 0, len(archive.File))
+	for _, file := range archive.File {
+		if strings.EqualFold(path.Ext(file.Name), ".csv") {
+			files = append(files, file)
+		}
+	}
+	if len(files) == 0 {
+		return nil, &Error{Field: "file", Msg: "the zip file has no CSV files"}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	data := &Data{}
+	row := 0
+	remaining := int64(maxTodoistBackup)
+	for _, file := range files {
+		rc, err := file.Open()
+		if err != nil {
+			return nil, &Error{Field: "file", Msg: "invalid zip file: " + err.Error()}
+		}
+		content, err := io.ReadAll(io.LimitReader(rc, remaining+1))
+		rc.Close()
+		if err != nil {
+			return nil, &Error{Field: "file", Msg: "invalid zip file: " + err.Error()}
+		}
+		if remaining -= int64(len(content)); remaining < 0 {
+			return nil, &Error{Field: "file", Msg: fmt.Sprintf("the backup holds more than %d MiB", maxTodoistBackup>>20)}
+		}
+		content = bytes.TrimPrefix(content, []byte("\ufeff"))
+		if row, err = readTodoistCSV(bytes.NewReader(content), file.Name, data, row); err != nil {
+			var fileErr *Error
+			if errors.As(err, &fileErr) {
+				fileErr.Msg = file.Name + ": " + fileErr.Msg
+			}
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// readTodoistCSV reads a project exported as CSV into data, numbering its
+// rows after row, and returns the number of its last row
+func readTodoistCSV(r io.Reader, fileName string, data *Data, row int) (int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return row, &Error{Field: "file", Msg: "the file is empty"}
+	}
+	if err != nil {
+		return row, csvError(err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, column := range header {
+		columns[strings.ToUpper(strings.TrimSpace(column))] = i
+	}
+	for _, column := range []string{"TYPE", "CONTENT"} {
+		if _, ok := columns[column]; !ok {
+			return row, &Error{Field: "file", Msg: "the file has no " + column + " column; it is not a Todoist CSV export"}
+		}
+	}
+
+	project := strings.TrimSuffix(path.Base(fileName), path.Ext(fileName))
+	project = strings.TrimSpace(todoistBackupName.ReplaceAllString(project, ""))
+	if strings.EqualFold(project, "inbox") {
+		project = ""
+	}
+	if project != "" {
+		data.Categories = append(data.Categories, Category{Name: project})
+	}
+
+	first := row + 1
+	var parents []uint // IDs of the last task at each indent
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return row, nil
+		}
+		if err != nil {
+			return row, csvError(err)
+		}
+		row++
+		value := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		switch strings.ToLower(value("TYPE")) {
+		case "task":
+		case "note":
+			// A comment on the task before it
+			if last := len(data.Todos) - 1; last >= 0 && data.Todos[last].Row >= first {
+				data.Todos[last].Description = appendLine(data.Todos[last].Description, value("CONTENT"))
+			}
+			continue
+		default:
+			continue
+		}
+
+		todo := Todo{Row: row, ID: uint(row), Category: project, Description: value("DESCRIPTION")}
+		var title []string
+		for _, word := range strings.Fields(value("CONTENT")) {
+			if len(word) > 1 && word[0] == '@' {
+				todo.Tags = append(todo.Tags, word[1:])
+			} else {
+				title = append(title, word)
+			}
+		}
+		todo.Title = strings.Join(title, " ")
+		if priority := value("PRIORITY"); priority != "" {
+			p, err := strconv.Atoi(priority)
+			if err != nil {
+				data.Errors = append(data.Errors, Error{Row: row, Field: "priority", Msg: fmt.Sprintf("invalid priority %q, expected 1 to 4", priority)})
+				continue
+			}
+			todo.Priority = todoistPriority(p)
+		}
+		if date := value("DATE"); date != "" {
+			todoistDate(&todo, date, date, strings.HasPrefix(strings.ToLower(date), "every"))
+		}
+
+		indent := 1
+		if s := value("INDENT"); s != "" {
+			if indent, err = strconv.Atoi(s); err != nil || indent < 1 {
+				data.Errors = append(data.Errors, Error{Row: row, Field: "parent_id", Msg: fmt.Sprintf("invalid indent %q", s)})
+				continue
+			}
+		}
+		if indent > len(parents)+1 {
+			indent = len(parents) + 1
+		}
+		if indent > 1 {
+			todo.ParentID = parents[indent-2]
+		}
+		parents = append(parents[:indent-1], todo.ID)
+		data.Todos = append(data.Todos, todo)
+	}
+}
+
+// todoistPriority returns the priority of Todoist's p1 to p4
+func todoistPriority(p int) string {
+	switch p {
+	case 1:
+		return "high"
+	case 2:
+		return "medium"
+	}
+	return "low"
+}
+
+// todoistDate sets the due date and recurrence of a todo from a Todoist
+// date and the text it was entered as. What cannot be read is added to the
+// description.
+func todoistDate(todo *Todo, date, text string, recurring bool) {
+	due, err := parseTime(date)
+	if err != nil {
+		todo.Description = appendLine(todo.Description, "Due: "+text)
+		return
+	}
+	todo.DueDate = &due
+	if !recurring {
+		return
+	}
+	if rule, ok := todoistRule(text); ok {
+		todo.Recurrence = rule
+	} else {
+		todo.Description = appendLine(todo.Description, "Repeats: "+text)
+	}
+}
+
+// todoistRule returns the recurrence rule of the simple forms of a Todoist
+// recurring date: "every day", "every 2 weeks", "every weekday" and
+// "every mon, fri", optionally with a time like "at 9am"
+func todoistRule(text string) (string, bool) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.Replace(text, "every!", "every", 1) // Repeats from completion
+	if i := strings.Index(text, " at "); i >= 0 {
+		text = text[:i]
+	}
+	switch text {
+	case "daily":
+		return "FREQ=DAILY", true
+	case "weekly":
+		return "FREQ=WEEKLY", true
+	case "monthly":
+		return "FREQ=MONTHLY", true
+	case "yearly":
+		return "FREQ=YEARLY", true
+	case "every weekday", "every workday":
+		return "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", true
+	}
+	if match := todoistEvery.FindStringSubmatch(text); match != nil {
+		rule := "FREQ=" + todoistFrequencies[match[2]]
+		if interval, _ := strconv.Atoi(match[1]); interval > 1 {
+			rule += ";INTERVAL=" + match[1]
+		}
+		return rule, true
+	}
+
+	days, ok := strings.CutPrefix(text, "every ")
+	if !ok {
+		return "", false
+	}
+	var codes []string
+	for _, day := range strings.FieldsFunc(days, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if day == "and" {
+			continue
+		}
+		code, ok := todoistWeekdays[day]
+		if !ok {
+			return "", false
+		}
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return "", false
+	}
+	return "FREQ=WEEKLY;BYDAY=" + strings.Join(codes, ","), true
+}
+
+// appendLine adds a line to the end of a text
+func appendLine(text, line string) string {
+	if text == "" {
+		return line
+	}
+	return text + "\n\n" + line
+}
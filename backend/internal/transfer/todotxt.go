@@ -0,0 +1,254 @@
+package transfer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTodoTxtLine is the longest line of a todo.txt file that can be read
+const maxTodoTxtLine = 1 << 20
+
+var (
+	todoTxtPriority   = regexp.MustCompile(`^\(([A-Z])\)$`)
+	todoTxtRecurrence = regexp.MustCompile(`^\+?(\d*)([dwmy])$`)
+)
+
+// todoTxtFrequencies are the RRULE frequencies of the units of a rec: tag
+var todoTxtFrequencies = map[string]string{"d": "DAILY", "w": "WEEKLY", "m": "MONTHLY", "y": "YEARLY"}
+
+// todoTxtUnits are the units of a rec: tag by RRULE frequency
+var todoTxtUnits = map[string]string{"DAILY": "d", "WEEKLY": "w", "MONTHLY": "m", "YEARLY": "y"}
+
+// TodoTxtWriter writes todos as todo.txt lines, in the form ReadTodoTxt
+// reads. A completed todo starts with "x" and the day it was last changed
+// as its completion date; others start with their priority, (A) for high,
+// (B) for medium and (C) for low. The creation date comes next, and the
+// category and tags follow the title as +project and @contexts, with
+// spaces replaced by underscores.
+type TodoTxtWriter struct {
+	w *bufio.Writer
+}
+
+// NewTodoTxtWriter creates a TodoTxtWriter that writes to w
+func NewTodoTxtWriter(w io.Writer) *TodoTxtWriter {
+	return &TodoTxtWriter{w: bufio.NewWriter(w)}
+}
+
+// Write writes a todo as a line
+func (w *TodoTxtWriter) Write(todo Todo) error {
+	_, err := w.w.WriteString(todoTxtLine(todo) + "\n")
+	return err
+}
+
+// Close flushes the output
+func (w *TodoTxtWriter) Close() error {
+	return w.w.Flush()
+}
+
+// todoTxtLine returns the todo.txt line of a todo
+func todoTxtLine(todo Todo) string {
+	var words []string
+	letter := todoTxtLetter(todo.Priority)
+	if todo.Completed {
+		words = append(words, "x")
+		// The completion date must come first when there is a creation date
+		if todo.UpdatedAt != nil && todo.CreatedAt != nil {
+			words = append(words, formatDate(todo.UpdatedAt))
+		}
+	} else if letter != "" {
+		words = append(words, "("+letter+")")
+	}
+	if todo.CreatedAt != nil && (!todo.Completed || todo.UpdatedAt != nil) {
+		words = append(words, formatDate(todo.CreatedAt))
+	}
+
+	words = append(words, strings.Fields(todo.Title)...)
+	if todo.Category != "" {
+		words = append(words, "+"+todoTxtName(todo.Category))
+	}
+	for _, tag := range todo.Tags {
+		words = append(words, "@"+todoTxtName(tag))
+	}
+	if todo.DueDate != nil {
+		words = append(words, "due:"+formatDate(todo.DueDate))
+	}
+	if rec := todoTxtRec(todo.Recurrence); rec != "" {
+		words = append(words, "rec:"+rec)
+	}
+	if todo.Completed && letter != "" {
+		words = append(words, "pri:"+letter)
+	}
+	return strings.Join(words, " ")
+}
+
+// todoTxtLetter returns the todo.txt priority letter of a priority
+func todoTxtLetter(priority string) string {
+	switch strings.ToLower(priority) {
+	case "high":
+		return "A"
+	case "medium":
+		return "B"
+	case "low":
+		return "C"
+	}
+	return ""
+}
+
+// todoTxtName returns a category or tag name as a single word
+func todoTxtName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// todoTxtRec returns the rec: value of a recurrence rule, or "" when the
+// rule has more than a frequency and interval, which rec: cannot express
+func todoTxtRec(rule string) string {
+	unit, interval := "", "1"
+	for _, part := range strings.Split(strings.TrimPrefix(rule, "RRULE:"), ";") {
+		name, value, _ := strings.Cut(part, "=")
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			unit = todoTxtUnits[strings.ToUpper(value)]
+		case "INTERVAL":
+			interval = value
+		default:
+			return ""
+		}
+	}
+	if unit == "" {
+		return ""
+	}
+	return interval + unit
+}
+
+// ReadTodoTxt reads todos from a todo.txt file, one per line. A line that
+// starts with "x " is completed, and a priority of (A) is high, (B) medium
+// and any other low. The first +project is the category, and @contexts and
+// any further projects are tags. The due: tag sets the due date, and a
+// rec: tag like rec:1w or rec:+2d the recurrence. Rows are the line
+// numbers, and blank lines are skipped.
+func ReadTodoTxt(r io.Reader) (*Data, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxTodoTxtLine)
+
+	data := &Data{}
+	for row := 1; scanner.Scan(); row++ {
+		line := scanner.Text()
+		if row == 1 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		todo, rowErr := todoTxtTodo(line)
+		if rowErr != nil {
+			rowErr.Row = row
+			data.Errors = append(data.Errors, *rowErr)
+			continue
+		}
+		todo.Row = row
+		data.Todos = append(data.Todos, todo)
+	}
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, &Error{Field: "file", Msg: fmt.Sprintf("a line is longer than %d bytes", maxTodoTxtLine)}
+		}
+		return nil, err
+	}
+	if len(data.Todos) == 0 && len(data.Errors) == 0 {
+		return nil, &Error{Field: "file", Msg: "the file is empty"}
+	}
+	return data, nil
+}
+
+// todoTxtTodo reads a todo from a line
+func todoTxtTodo(line string) (Todo, *Error) {
+	var todo Todo
+	words := strings.Fields(line)
+	if words[0] == "x" {
+		todo.Completed = true
+		words = words[1:]
+		// The completion date, then the creation date
+		for i := 0; i < 2 && len(words) > 0 && isTodoTxtDate(words[0]); i++ {
+			words = words[1:]
+		}
+	} else {
+		if len(words) > 0 {
+			if match := todoTxtPriority.FindStringSubmatch(words[0]); match != nil {
+				todo.Priority = todoTxtPriorityValue(match[1])
+				words = words[1:]
+			}
+		}
+		if len(words) > 0 && isTodoTxtDate(words[0]) {
+			words = words[1:]
+		}
+	}
+
+	var title, projects []string
+	for _, word := range words {
+		switch {
+		case len(word) > 1 && word[0] == '+':
+			projects = append(projects, word[1:])
+		case len(word) > 1 && word[0] == '@':
+			todo.Tags = append(todo.Tags, word[1:])
+		case strings.HasPrefix(word, "due:") && len(word) > len("due:"):
+			due, err := parseTime(strings.TrimPrefix(word, "due:"))
+			if err != nil {
+				return todo, &Error{Field: "due_date", Msg: err.Error()}
+			}
+			todo.DueDate = &due
+		case strings.HasPrefix(word, "rec:") && len(word) > len("rec:"):
+			rule, err := todoTxtRule(strings.TrimPrefix(word, "rec:"))
+			if err != nil {
+				return todo, &Error{Field: "recurrence", Msg: err.Error()}
+			}
+			todo.Recurrence = rule
+		case strings.HasPrefix(word, "pri:") && len(word) == len("pri:A"):
+			// The priority of a completed todo
+			todo.Priority = todoTxtPriorityValue(strings.ToUpper(word[len("pri:"):]))
+		default:
+			title = append(title, word)
+		}
+	}
+	if len(projects) > 0 {
+		todo.Category = projects[0]
+		todo.Tags = append(todo.Tags, projects[1:]...)
+	}
+	todo.Title = strings.Join(title, " ")
+	return todo, nil
+}
+
+// todoTxtPriorityValue returns the priority of a todo.txt priority letter
+func todoTxtPriorityValue(letter string) string {
+	switch letter {
+	case "A":
+		return "high"
+	case "B":
+		return "medium"
+	}
+	return "low"
+}
+
+// todoTxtRule returns the recurrence rule of a rec: tag
+func todoTxtRule(value string) (string, error) {
+	match := todoTxtRecurrence.FindStringSubmatch(strings.ToLower(value))
+	if match == nil {
+		return "", fmt.Errorf("invalid rec value %q, expected a form like 1w or +2d", value)
+	}
+	rule := "FREQ=" + todoTxtFrequencies[match[2]]
+	if interval, _ := strconv.Atoi(match[1]); interval > 1 {
+		rule += ";INTERVAL=" + match[1]
+	}
+	return rule, nil
+}
+
+// isTodoTxtDate reports whether a word is a date like 2006-01-02
+func isTodoTxtDate(word string) bool {
+	_, err := time.Parse("2006-01-02", word)
+	return err == nil
+}
@@ -0,0 +1,134 @@
+// Package transfer reads and writes the files todos are exported to and
+// imported from: JSON, which keeps categories and their colors, and CSV for
+// spreadsheets. It also writes Markdown task lists and todo.txt, and reads
+// the exports of other task managers: Todoist, todo.txt and Trello.
+package transfer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of export and import files
+const (
+	FormatJSON    = "json"
+	FormatCSV     = "csv"
+	FormatTodoTxt = "todotxt"
+
+	// Formats that are only exported
+	FormatMarkdown = "markdown"
+
+	// Formats that are only imported
+	FormatTodoist = "todoist"
+	FormatTrello  = "trello"
+)
+
+// Category is an exported category
+type Category struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+// Todo is an exported todo. Its category and tags are given by name, and
+// its parent by the ID of another todo in the same file.
+type Todo struct {
+	ID          uint       `json:"id,omitempty"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	Completed   bool       `json:"completed"`
+	Priority    string     `json:"priority,omitempty"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Recurrence  string     `json:"recurrence,omitempty"`
+	Category    string     `json:"category,omitempty"`
+	Tags        []string   `json:"tags,omitempty"` // Left out of a CSV row when it has no tags column
+	ParentID    uint       `json:"parent_id,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"` // Only exported
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"` // Only exported
+
+	// Row is the position of the todo in an imported file, starting at 1
+	Row int `json:"-"`
+}
+
+// Data is the content of an imported file. Todos that could not be read are
+// left out of Todos and reported in Errors instead.
+type Data struct {
+	Categories []Category
+	Todos      []Todo
+	Errors     []Error
+}
+
+// Error reports a problem with a file. Row is the position of the todo it
+// is about, starting at 1, or 0 for the file as a whole.
+type Error struct {
+	Row   int
+	Field string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	if e.Row == 0 {
+		return e.Msg
+	}
+	return fmt.Sprintf("row %d: %s", e.Row, e.Msg)
+}
+
+// Writer writes todos to an export file
+type Writer interface {
+	Write(todo Todo) error
+	// Close writes the end of the file. It must be called after the last
+	// todo, even when there are none.
+	Close() error
+}
+
+// timeLayouts are the forms of dates accepted in CSV files, tried in order.
+// Times without a zone are taken to be in UTC.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseTime parses a date with or without a time of day
+func parseTime(value string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected a form like 2006-01-02 or 2006-01-02T15:04:05Z", value)
+}
+
+// parseBool parses a completion status. Besides the forms of
+// strconv.ParseBool, yes, no and x are accepted; empty is false.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return false, nil
+	case "yes", "y", "x", "done":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid completed value %q, expected true or false", value)
+	}
+	return b, nil
+}
+
+// parseID parses the ID of a todo, which may be empty
+func parseID(value string) (uint, error) {
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid id %q", value)
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,407 @@
+package transfer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVWriter(t *testing.T) {
+	t.Run("header and rows", func(t *testing.T) {
+		var out strings.Builder
+		due := time.Date(2026, 11, 2, 9, 30, 0, 0, time.FixedZone("CET", 3600))
+		w := NewCSVWriter(&out)
+
+		assert.NoError(t, w.Write(Todo{ID: 7, Title: "Submit report, final", Description: "Line one\nline two", Completed: true, Priority: "high", DueDate: &due, Category: "Work", Tags: []string{"q4", "finance"}}))
+		assert.NoError(t, w.Write(Todo{ID: 8, Title: "Attach figures", ParentID: 7}))
+		assert.NoError(t, w.Close())
+
+		lines := strings.SplitN(out.String(), "\n", 2)
+		assert.Equal(t, "id,title,description,completed,priority,due_date,recurrence,category,tags,parent_id,created_at,updated_at", lines[0])
+		assert.Equal(t, "7,\"Submit report, final\",\"Line one\nline two\",true,high,2026-11-02T08:30:00Z,,Work,\"q4, finance\",,,\n8,Attach figures,,false,,,,,,7,,\n", lines[1])
+	})
+
+	t.Run("header without rows", func(t *testing.T) {
+		var out strings.Builder
+		w := NewCSVWriter(&out)
+
+		assert.NoError(t, w.Close())
+		assert.Equal(t, strings.Join(Columns, ",")+"\n", out.String())
+	})
+}
+
+func TestReadCSV(t *testing.T) {
+	t.Run("columns by name", func(t *testing.T) {
+		file := "\ufeffTitle,Completed,Due_Date,Category,Tags,Notes\n" +
+			"Submit report,yes,2026-11-02,Work,\"q4, finance\",ignored\n" +
+			"\"Call \"\"Ada\"\"\",,2026-11-02 09:30,,,\n"
+
+		data, err := ReadCSV(strings.NewReader(file), nil)
+
+		assert.NoError(t, err)
+		assert.Empty(t, data.Errors)
+		assert.Len(t, data.Todos, 2)
+		assert.Equal(t, Todo{Row: 1, Title: "Submit report", Completed: true, DueDate: data.Todos[0].DueDate, Category: "Work", Tags: []string{"q4", "finance"}}, data.Todos[0])
+		assert.Equal(t, time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC), *data.Todos[0].DueDate)
+		assert.Equal(t, `Call "Ada"`, data.Todos[1].Title)
+		assert.Equal(t, []string{}, data.Todos[1].Tags)
+		assert.Equal(t, time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC), *data.Todos[1].DueDate)
+	})
+
+	t.Run("mapping", func(t *testing.T) {
+		file := "Task Name,Deadline,Priority\nWater plants,2026-11-02T08:00:00+01:00,HIGH\n"
+
+		data, err := ReadCSV(strings.NewReader(file), map[string]string{"title": "task name", "due_date": "Deadline"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Water plants", data.Todos[0].Title)
+		assert.Equal(t, "high", data.Todos[0].Priority)
+		assert.Equal(t, time.Date(2026, 11, 2, 7, 0, 0, 0, time.UTC), data.Todos[0].DueDate.UTC())
+		assert.Nil(t, data.Todos[0].Tags)
+	})
+
+	t.Run("row errors", func(t *testing.T) {
+		file := "title,completed,due_date,parent_id\nBad date,,tomorrow,\nBad status,maybe,,\nBad parent,,,-1\nFine,,,\n"
+
+		data, err := ReadCSV(strings.NewReader(file), nil)
+
+		assert.NoError(t, err)
+		assert.Len(t, data.Todos, 1)
+		assert.Equal(t, 4, data.Todos[0].Row)
+		assert.Len(t, data.Errors, 3)
+		assert.Equal(t, Error{Row: 1, Field: "due_date", Msg: data.Errors[0].Msg}, data.Errors[0])
+		assert.Equal(t, "completed", data.Errors[1].Field)
+		assert.Equal(t, "parent_id", data.Errors[2].Field)
+	})
+
+	t.Run("file errors", func(t *testing.T) {
+		cases := []struct {
+			file    string
+			mapping map[string]string
+			field   string
+		}{
+			{"", nil, "file"},
+			{"name,notes\nx,y\n", nil, "mapping"},
+			{"title\nx\n", map[string]string{"owner": "title"}, "mapping"},
+			{"title\nx\n", map[string]string{"description": "Notes"}, "mapping"},
+			{"title\n\"unterminated\n", nil, "file"},
+		}
+		for _, tc := range cases {
+			_, err := ReadCSV(strings.NewReader(tc.file), tc.mapping)
+
+			var fileErr *Error
+			assert.ErrorAs(t, err, &fileErr)
+			assert.Equal(t, tc.field, fileErr.Field)
+		}
+	})
+}
+
+func TestJSON(t *testing.T) {
+	exportedAt := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	t.Run("round trip", func(t *testing.T) {
+		var out strings.Builder
+		due := time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC)
+		w := NewJSONWriter(&out, exportedAt, []Category{{Name: "Work", Color: "#FF0000"}})
+		assert.NoError(t, w.Write(Todo{ID: 1, Title: "Submit report", DueDate: &due, Category: "Work", Tags: []string{"q4"}}))
+		assert.NoError(t, w.Write(Todo{ID: 2, Title: "Attach figures", ParentID: 1}))
+		assert.NoError(t, w.Close())
+
+		var file map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(out.String()), &file))
+		assert.Equal(t, float64(1), file["version"])
+		assert.Equal(t, "2026-10-18T09:00:00Z", file["exported_at"])
+
+		data, err := ReadJSON(strings.NewReader(out.String()))
+		assert.NoError(t, err)
+		assert.Equal(t, []Category{{Name: "Work", Color: "#FF0000"}}, data.Categories)
+		assert.Len(t, data.Todos, 2)
+		assert.Equal(t, Todo{Row: 1, ID: 1, Title: "Submit report", DueDate: &due, Category: "Work", Tags: []string{"q4"}}, data.Todos[0])
+		assert.Equal(t, uint(1), data.Todos[1].ParentID)
+	})
+
+	t.Run("empty export", func(t *testing.T) {
+		var out strings.Builder
+		w := NewJSONWriter(&out, exportedAt, nil)
+
+		assert.NoError(t, w.Close())
+		assert.Equal(t, "{\"version\":1,\"exported_at\":\"2026-10-18T09:00:00Z\",\"categories\":[],\"todos\":[\n]}\n", out.String())
+	})
+
+	t.Run("array of todos with a bad one", func(t *testing.T) {
+		data, err := ReadJSON(strings.NewReader(`[{"title": "Fine"}, {"title": 42}, {"title": "Late", "due_date": "soon"}]`))
+
+		assert.NoError(t, err)
+		assert.Len(t, data.Todos, 1)
+		assert.Equal(t, 1, data.Todos[0].Row)
+		assert.Len(t, data.Errors, 2)
+		assert.Equal(t, 2, data.Errors[0].Row)
+		assert.Equal(t, "title", data.Errors[0].Field)
+		assert.Equal(t, 3, data.Errors[1].Row)
+	})
+
+	t.Run("not JSON", func(t *testing.T) {
+		_, err := ReadJSON(strings.NewReader("title\nx\n"))
+
+		var fileErr *Error
+		assert.ErrorAs(t, err, &fileErr)
+		assert.Equal(t, "file", fileErr.Field)
+	})
+}
+
+func TestReadTodoTxt(t *testing.T) {
+	file := "\ufeff(A) 2026-10-01 Call Mom +Family @phone due:2026-11-02 rec:+1w\n" +
+		"\n" +
+		"x 2026-10-05 2026-10-01 Pay rent +Home +Bills pri:B\n" +
+		"Read https://example.com/article @web\n" +
+		"Renew passport due:soon\n" +
+		"Water plants rec:3d due:2026-11-02\n" +
+		"Stretch rec:fortnightly due:2026-11-02\n"
+
+	data, err := ReadTodoTxt(strings.NewReader(file))
+
+	assert.NoError(t, err)
+	due := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, []Todo{
+		{Row: 1, Title: "Call Mom", Priority: "high", DueDate: &due, Recurrence: "FREQ=WEEKLY", Category: "Family", Tags: []string{"phone"}},
+		{Row: 3, Title: "Pay rent", Completed: true, Priority: "medium", Category: "Home", Tags: []string{"Bills"}},
+		{Row: 4, Title: "Read https://example.com/article", Tags: []string{"web"}},
+		{Row: 6, Title: "Water plants", DueDate: &due, Recurrence: "FREQ=DAILY;INTERVAL=3"},
+	}, data.Todos)
+	assert.Len(t, data.Errors, 2)
+	assert.Equal(t, Error{Row: 5, Field: "due_date", Msg: data.Errors[0].Msg}, data.Errors[0])
+	assert.Equal(t, Error{Row: 7, Field: "recurrence", Msg: data.Errors[1].Msg}, data.Errors[1])
+
+	_, err = ReadTodoTxt(strings.NewReader("\n\n"))
+	var fileErr *Error
+	assert.ErrorAs(t, err, &fileErr)
+}
+
+func TestReadTodoist(t *testing.T) {
+	t.Run("sync API JSON", func(t *testing.T) {
+		file := `{
+			"projects": [
+				{"id": "100", "name": "Inbox", "color": "grey", "inbox_project": true},
+				{"id": "200", "name": "Work", "color": "berry_red"}
+			],
+			"items": [
+				{"id": "1", "project_id": "200", "content": "Submit report", "priority": 4, "labels": ["q4"], "due": {"date": "2026-11-02T09:30:00", "is_recurring": true, "string": "every 2 weeks at 9:30"}},
+				{"id": "2", "project_id": "200", "parent_id": "1", "content": "Attach figures", "priority": 1, "checked": true},
+				{"id": "3", "project_id": "100", "content": "Buy milk", "priority": 3, "due": {"date": "2026-11-02", "is_recurring": true, "string": "every 3rd friday"}},
+				{"id": 4, "project_id": 100, "content": 42}
+			]
+		}`
+
+		data, err := ReadTodoist(strings.NewReader(file), "")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Category{{Name: "Work", Color: "#B8256F"}}, data.Categories)
+		assert.Len(t, data.Todos, 3)
+		due := time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC)
+		assert.Equal(t, Todo{Row: 1, ID: 1, Title: "Submit report", Priority: "high", DueDate: &due, Recurrence: "FREQ=WEEKLY;INTERVAL=2", Category: "Work", Tags: []string{"q4"}}, data.Todos[0])
+		assert.Equal(t, Todo{Row: 2, ID: 2, Title: "Attach figures", Completed: true, Priority: "low", Category: "Work", ParentID: 1}, data.Todos[1])
+		assert.Equal(t, "Buy milk", data.Todos[2].Title)
+		assert.Equal(t, "medium", data.Todos[2].Priority)
+		assert.Empty(t, data.Todos[2].Category)
+		assert.Empty(t, data.Todos[2].Recurrence)
+		assert.Equal(t, "Repeats: every 3rd friday", data.Todos[2].Description)
+		assert.Equal(t, []Error{{Row: 4, Field: "content", Msg: data.Errors[0].Msg}}, data.Errors)
+	})
+
+	t.Run("project CSV", func(t *testing.T) {
+		file := "TYPE,CONTENT,DESCRIPTION,PRIORITY,INDENT,AUTHOR,RESPONSIBLE,DATE,DATE_LANG,TIMEZONE\n" +
+			"section,Planning,,,,,,,,\n" +
+			"task,Submit report @q4 @finance,Final version,1,1,Ada (1),,2026-11-02,en,UTC\n" +
+			"note,Ask Bob for figures,,,,,,,,\n" +
+			"task,Attach figures,,4,2,Ada (1),,,en,UTC\n" +
+			"task,Check totals,,3,3,Ada (1),,every monday,en,UTC\n" +
+			"task,Book venue,,x,1,Ada (1),,,en,UTC\n" +
+			"task,Send invites,,2,1,Ada (1),,next week,en,UTC\n"
+
+		data, err := ReadTodoist(strings.NewReader(file), "Work [2203306141].csv")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Category{{Name: "Work"}}, data.Categories)
+		assert.Len(t, data.Todos, 4)
+		due := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, Todo{Row: 2, ID: 2, Title: "Submit report", Description: "Final version\n\nAsk Bob for figures", Priority: "high", DueDate: &due, Category: "Work", Tags: []string{"q4", "finance"}}, data.Todos[0])
+		assert.Equal(t, uint(2), data.Todos[1].ParentID)
+		assert.Equal(t, "low", data.Todos[1].Priority)
+		assert.Equal(t, uint(4), data.Todos[2].ParentID)
+		assert.Equal(t, "Due: every monday", data.Todos[2].Description)
+		assert.Nil(t, data.Todos[2].DueDate)
+		assert.Equal(t, "Send invites", data.Todos[3].Title)
+		assert.Zero(t, data.Todos[3].ParentID)
+		assert.Equal(t, []Error{{Row: 6, Field: "priority", Msg: data.Errors[0].Msg}}, data.Errors)
+	})
+
+	t.Run("backup", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		for name, content := range map[string]string{
+			"Inbox [1].csv":    "TYPE,CONTENT,PRIORITY,INDENT\ntask,Buy milk,4,1\n",
+			"Work [2].csv":     "TYPE,CONTENT,PRIORITY,INDENT\nsection,Planning,,\ntask,Submit report,1,1\ntask,Attach figures,4,2\n",
+			"attachments.json": "{}",
+		} {
+			w, _ := archive.Create(name)
+			w.Write([]byte(content))
+		}
+		archive.Close()
+
+		data, err := ReadTodoist(&buf, "backup.zip")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []Category{{Name: "Work"}}, data.Categories)
+		assert.Len(t, data.Todos, 3)
+		assert.Equal(t, Todo{Row: 1, ID: 1, Title: "Buy milk", Priority: "low"}, data.Todos[0])
+		assert.Equal(t, Todo{Row: 3, ID: 3, Title: "Submit report", Priority: "high", Category: "Work"}, data.Todos[1])
+		assert.Equal(t, uint(3), data.Todos[2].ParentID)
+	})
+
+	t.Run("not an export", func(t *testing.T) {
+		for _, file := range []string{"title\nx\n", `{"projects": []}`, "PK\x03\x04 broken"} {
+			_, err := ReadTodoist(strings.NewReader(file), "")
+
+			var fileErr *Error
+			assert.ErrorAs(t, err, &fileErr)
+			assert.Equal(t, "file", fileErr.Field)
+		}
+	})
+}
+
+func TestTodoistRule(t *testing.T) {
+	cases := map[string]string{
+		"every day":          "FREQ=DAILY",
+		"Every! 3 months":    "FREQ=MONTHLY;INTERVAL=3",
+		"weekly":             "FREQ=WEEKLY",
+		"every weekday":      "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		"every mon, fri":     "FREQ=WEEKLY;BYDAY=MO,FR",
+		"every tue and thu":  "FREQ=WEEKLY;BYDAY=TU,TH",
+		"every year at 9am":  "FREQ=YEARLY",
+		"every 3rd friday":   "",
+		"every other week":   "",
+		"after every sprint": "",
+	}
+	for text, want := range cases {
+		rule, ok := todoistRule(text)
+
+		assert.Equal(t, want != "", ok, text)
+		assert.Equal(t, want, rule, text)
+	}
+}
+
+func TestReadTrello(t *testing.T) {
+	file := `{
+		"name": "Launch",
+		"lists": [
+			{"id": "l2", "name": "Doing", "pos": 2},
+			{"id": "l1", "name": "To Do", "pos": 1},
+			{"id": "l3", "name": "Old", "pos": 3, "closed": true}
+		],
+		"cards": [
+			{"id": "c2", "name": "Write copy", "idList": "l2", "pos": 1, "due": "2026-11-02T09:30:00.000Z", "dueComplete": true},
+			{"id": "c1", "name": "Plan launch", "desc": "Q4", "idList": "l1", "pos": 2, "labels": [{"name": "Marketing", "color": "green"}, {"name": "", "color": "red"}]},
+			{"id": "c0", "name": "Pick date", "idList": "l1", "pos": 1},
+			{"id": "c3", "name": "Archived", "idList": "l1", "pos": 3, "closed": true},
+			{"id": "c4", "name": "In archived list", "idList": "l3", "pos": 1}
+		],
+		"checklists": [
+			{"id": "k2", "idCard": "c1", "pos": 2, "checkItems": [{"name": "Book venue", "state": "incomplete", "pos": 1}]},
+			{"id": "k1", "idCard": "c1", "pos": 1, "checkItems": [{"name": "Draft budget", "state": "complete", "pos": 2}, {"name": "List goals", "state": "incomplete", "pos": 1}]}
+		]
+	}`
+
+	data, err := ReadTrello(strings.NewReader(file))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Category{{Name: "To Do"}, {Name: "Doing"}}, data.Categories)
+	titles := make([]string, len(data.Todos))
+	for i, todo := range data.Todos {
+		titles[i] = todo.Title
+	}
+	assert.Equal(t, []string{"Pick date", "Plan launch", "List goals", "Draft budget", "Book venue", "Write copy"}, titles)
+	assert.Equal(t, Todo{Row: 2, ID: 2, Title: "Plan launch", Description: "Q4", Category: "To Do", Tags: []string{"Marketing", "red"}}, data.Todos[1])
+	assert.Equal(t, Todo{Row: 4, ID: 4, Title: "Draft budget", Completed: true, Category: "To Do", ParentID: 2}, data.Todos[3])
+	assert.True(t, data.Todos[5].Completed)
+	assert.Equal(t, time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC), data.Todos[5].DueDate.UTC())
+
+	for _, file := range []string{"", "[]", `{"name": "Not a board"}`} {
+		_, err := ReadTrello(strings.NewReader(file))
+
+		var fileErr *Error
+		assert.ErrorAs(t, err, &fileErr)
+	}
+}
+
+func TestMarkdownWriter(t *testing.T) {
+	t.Run("grouped by category and priority", func(t *testing.T) {
+		var out strings.Builder
+		due := time.Date(2026, 11, 2, 23, 30, 0, 0, time.FixedZone("EST", -5*3600))
+		w := NewMarkdownWriter(&out)
+		for _, todo := range []Todo{
+			{ID: 1, Title: "Water plants", Priority: "low"},
+			{ID: 2, Title: "Submit *final* report", Priority: "high", Category: "Work", DueDate: &due, Tags: []string{"q4", "finance"}},
+			{ID: 3, Title: "Attach figures", Completed: true, Priority: "low", Category: "Work", ParentID: 2},
+			{ID: 4, Title: "Check totals", Priority: "medium", Category: "Work", ParentID: 3},
+			{ID: 5, Title: "Book venue", Priority: "medium", Category: "events"},
+			{ID: 6, Title: "Orphan", Priority: "low", Category: "Work", ParentID: 42},
+		} {
+			assert.NoError(t, w.Write(todo))
+		}
+		assert.NoError(t, w.Close())
+
+		assert.Equal(t, "## events\n"+
+			"\n### Medium priority\n\n"+
+			"- [ ] Book venue\n"+
+			"\n## Work\n"+
+			"\n### High priority\n\n"+
+			"- [ ] Submit \\*final\\* report (due 2026-11-03) `q4` `finance`\n"+
+			"  - [x] Attach figures\n"+
+			"    - [ ] Check totals\n"+
+			"\n### Low priority\n\n"+
+			"- [ ] Orphan\n"+
+			"\n## No category\n"+
+			"\n### Low priority\n\n"+
+			"- [ ] Water plants\n", out.String())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var out strings.Builder
+		w := NewMarkdownWriter(&out)
+
+		assert.NoError(t, w.Close())
+		assert.Equal(t, "_No todos_\n", out.String())
+	})
+}
+
+func TestTodoTxtWriter(t *testing.T) {
+	var out strings.Builder
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	updated := time.Date(2026, 10, 5, 12, 0, 0, 0, time.UTC)
+	due := time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC)
+	w := NewTodoTxtWriter(&out)
+	for _, todo := range []Todo{
+		{Title: "Call Mom", Priority: "high", DueDate: &due, Recurrence: "FREQ=WEEKLY", Category: "Family", Tags: []string{"phone"}, CreatedAt: &created, UpdatedAt: &updated},
+		{Title: "Pay rent", Completed: true, Priority: "medium", Category: "Home Office", CreatedAt: &created, UpdatedAt: &updated},
+		{Title: "Stretch\ndaily", DueDate: &due, Recurrence: "FREQ=DAILY;INTERVAL=2"},
+		{Title: "Stand-up", Priority: "low", DueDate: &due, Recurrence: "FREQ=WEEKLY;BYDAY=MO,WE"},
+	} {
+		assert.NoError(t, w.Write(todo))
+	}
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, "(A) 2026-10-01 Call Mom +Family @phone due:2026-11-02 rec:1w\n"+
+		"x 2026-10-05 2026-10-01 Pay rent +Home_Office pri:B\n"+
+		"Stretch daily due:2026-11-02 rec:2d\n"+
+		"(C) Stand-up due:2026-11-02\n", out.String())
+
+	data, err := ReadTodoTxt(strings.NewReader(out.String()))
+	assert.NoError(t, err)
+	assert.Equal(t, Todo{Row: 1, Title: "Call Mom", Priority: "high", DueDate: data.Todos[0].DueDate, Recurrence: "FREQ=WEEKLY", Category: "Family", Tags: []string{"phone"}}, data.Todos[0])
+	assert.Equal(t, Todo{Row: 2, Title: "Pay rent", Completed: true, Priority: "medium", Category: "Home_Office"}, data.Todos[1])
+}
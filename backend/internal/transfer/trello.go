@@ -0,0 +1,137 @@
+package transfer
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+)
+
+// trelloBoard is a board exported as JSON from Trello
+type trelloBoard struct {
+	Lists []struct {
+		ID     string  `json:"id"`
+		Name   string  `json:"name"`
+		Closed bool    `json:"closed"`
+		Pos    float64 `json:"pos"`
+	} `json:"lists"`
+	Cards []struct {
+		ID          string     `json:"id"`
+		Name        string     `json:"name"`
+		Desc        string     `json:"desc"`
+		IDList      string     `json:"idList"`
+		Closed      bool       `json:"closed"`
+		Due         *time.Time `json:"due"`
+		DueComplete bool       `json:"dueComplete"`
+		Pos         float64    `json:"pos"`
+		Labels      []struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		} `json:"labels"`
+	} `json:"cards"`
+	Checklists []trelloChecklist `json:"checklists"`
+}
+
+// trelloChecklist is a checklist of a card
+type trelloChecklist struct {
+	IDCard     string  `json:"idCard"`
+	Pos        float64 `json:"pos"`
+	CheckItems []struct {
+		Name  string     `json:"name"`
+		State string     `json:"state"`
+		Due   *time.Time `json:"due"`
+		Pos   float64    `json:"pos"`
+	} `json:"checkItems"`
+}
+
+// ReadTrello reads todos from a Trello board exported as JSON. Lists are
+// categories and cards todos, with their labels as tags, and a card is
+// completed when its due date is marked complete. The items of a card's
+// checklists are its subtasks. Archived lists and cards are left out.
+// Rows number the cards and checklist items in board order.
+func ReadTrello(r io.Reader) (*Data, error) {
+	var board trelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		if err == io.EOF {
+			return nil, &Error{Field: "file", Msg: "the file is empty"}
+		}
+		return nil, &Error{Field: "file", Msg: "invalid JSON: " + err.Error()}
+	}
+	if board.Lists == nil || board.Cards == nil {
+		return nil, &Error{Field: "file", Msg: "the file has no lists or cards; it is not a Trello board export"}
+	}
+
+	data := &Data{}
+	lists := make(map[string]string, len(board.Lists))
+	listPos := make(map[string]float64, len(board.Lists))
+	sort.SliceStable(board.Lists, func(i, j int) bool { return board.Lists[i].Pos < board.Lists[j].Pos })
+	for _, list := range board.Lists {
+		if list.Closed {
+			continue
+		}
+		lists[list.ID] = list.Name
+		listPos[list.ID] = list.Pos
+		data.Categories = append(data.Categories, Category{Name: list.Name})
+	}
+
+	checklists := make(map[string][]trelloChecklist)
+	for _, checklist := range board.Checklists {
+		checklists[checklist.IDCard] = append(checklists[checklist.IDCard], checklist)
+	}
+
+	cards := board.Cards
+	sort.SliceStable(cards, func(i, j int) bool {
+		if listPos[cards[i].IDList] != listPos[cards[j].IDList] {
+			return listPos[cards[i].IDList] < listPos[cards[j].IDList]
+		}
+		return cards[i].Pos < cards[j].Pos
+	})
+	row := 0
+	for _, card := range cards {
+		list, ok := lists[card.IDList]
+		if card.Closed || !ok {
+			continue
+		}
+		row++
+		todo := Todo{
+			Row:         row,
+			ID:          uint(row),
+			Title:       card.Name,
+			Description: card.Desc,
+			Completed:   card.DueComplete,
+			DueDate:     card.Due,
+			Category:    list,
+		}
+		for _, label := range card.Labels {
+			name := label.Name
+			if name == "" {
+				name = label.Color // An unnamed label is known by its color
+			}
+			if name != "" {
+				todo.Tags = append(todo.Tags, name)
+			}
+		}
+		data.Todos = append(data.Todos, todo)
+
+		parent := todo.ID
+		cardChecklists := checklists[card.ID]
+		sort.SliceStable(cardChecklists, func(i, j int) bool { return cardChecklists[i].Pos < cardChecklists[j].Pos })
+		for _, checklist := range cardChecklists {
+			items := checklist.CheckItems
+			sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+			for _, item := range items {
+				row++
+				data.Todos = append(data.Todos, Todo{
+					Row:       row,
+					ID:        uint(row),
+					Title:     item.Name,
+					Completed: item.State == "complete",
+					DueDate:   item.Due,
+					Category:  list,
+					ParentID:  parent,
+				})
+			}
+		}
+	}
+	return data, nil
+}
@@ -1,6 +1,7 @@
 import { useState } from "react"
-import { Layout, Typography, Tabs, FloatButton } from "antd"
+import { Layout, Typography, Tabs, FloatButton, Button } from "antd"
 import {
+  LogoutOutlined,
   QuestionCircleOutlined,
   UnorderedListOutlined,
   FolderOutlined,
@@ -8,6 +9,7 @@ import {
 import { useTodoContext } from "./context/useTodoContext"
 import { TodoList } from "./features/todos"
 import { CategoryManager } from "./features/categories"
+import { authApi } from "./services/api"
 import "./App.css"
 
 const { Header, Content } = Layout
@@ -24,11 +26,17 @@ function App() {
           backgroundColor: "#fff",
           padding: "0 24px",
           boxShadow: "0 2px 8px rgba(0,0,0,0.06)",
+          display: "flex",
+          alignItems: "center",
+          justifyContent: "space-between",
         }}
       >
         <Title level={2} style={{ margin: "16px 0" }}>
           Industrix Todo App
         </Title>
+        <Button icon={<LogoutOutlined />} onClick={() => authApi.logout()}>
+          Log out
+        </Button>
       </Header>
 
       <Content
@@ -1,6 +1,6 @@
 import { useState, useEffect, useCallback, type ReactNode } from "react"
 import { message } from "antd"
-import { todoApi, categoryApi } from "../services/api"
+import { todoApi, categoryApi, authApi, eventsUrl } from "../services/api"
 import type { Todo, TodoInput, Category, TodoStatistics } from "../types/todos"
 import { TodoContext, type TodoContextType } from "./TodoContextType"
 
@@ -51,7 +51,8 @@ export const TodoProvider = ({ children }: TodoProviderProps) => {
         page: currentPage,
         limit: pageSize,
         search: searchQuery,
-        sort_by: "created_at",
+        // Rank search results by relevance
+        sort_by: searchQuery ? "relevance" : "created_at",
         sort_order: "desc",
         completed: filterCompleted,
         category_id: filterCategoryId,
@@ -142,6 +143,39 @@ export const TodoProvider = ({ children }: TodoProviderProps) => {
     fetchTodos()
   }, [fetchTodos])
 
+  // Live updates: reload when the change feed reports edits from other sessions
+  useEffect(() => {
+    let source: EventSource | null = null
+    let retry: ReturnType<typeof setTimeout> | undefined
+
+    const connect = () => {
+      source = new EventSource(eventsUrl())
+      source.onmessage = (message) => {
+        const event = JSON.parse(message.data) as { type: string }
+        if (event.type.startsWith("category.") || event.type === "reset") {
+          fetchCategories()
+        }
+        fetchTodos()
+      }
+      source.onerror = () => {
+        if (source?.readyState !== EventSource.CLOSED) {
+          return // The browser reconnects on its own
+        }
+        // The stream was refused, most likely because the access token
+        // expired; refresh it and reconnect
+        retry = setTimeout(() => {
+          authApi.me().then(connect).catch(() => undefined)
+        }, 5000)
+      }
+    }
+
+    connect()
+    return () => {
+      source?.close()
+      clearTimeout(retry)
+    }
+  }, [fetchTodos])
+
   const value: TodoContextType = {
     todos,
     categories,
@@ -0,0 +1,27 @@
+import { useEffect, useState, type ReactNode } from "react"
+import { AUTH_LOGOUT_EVENT, tokenStorage } from "../../services/api"
+import LoginPage from "./LoginPage"
+
+interface AuthGateProps {
+  children: ReactNode
+}
+
+// Shows the login page until the user has a session
+const AuthGate = ({ children }: AuthGateProps) => {
+  const [authenticated, setAuthenticated] = useState(
+    () => tokenStorage.getRefreshToken() !== null
+  )
+
+  useEffect(() => {
+    const handleLogout = () => setAuthenticated(false)
+    window.addEventListener(AUTH_LOGOUT_EVENT, handleLogout)
+    return () => window.removeEventListener(AUTH_LOGOUT_EVENT, handleLogout)
+  }, [])
+
+  if (!authenticated) {
+    return <LoginPage onAuthenticated={() => setAuthenticated(true)} />
+  }
+  return <>{children}</>
+}
+
+export default AuthGate
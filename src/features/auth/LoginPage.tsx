@@ -0,0 +1,99 @@
+import { useState } from "react"
+import { Card, Form, Input, Button, Tabs, Typography, message } from "antd"
+import { LockOutlined, MailOutlined, UserOutlined } from "@ant-design/icons"
+import { isAxiosError } from "axios"
+import { authApi } from "../../services/api"
+
+const { Title } = Typography
+
+interface LoginPageProps {
+  onAuthenticated: () => void
+}
+
+interface AuthFormValues {
+  email: string
+  name?: string
+  password: string
+}
+
+const LoginPage: React.FC<LoginPageProps> = ({ onAuthenticated }) => {
+  const [mode, setMode] = useState<"login" | "register">("login")
+  const [loading, setLoading] = useState(false)
+
+  const handleSubmit = async (values: AuthFormValues) => {
+    setLoading(true)
+    try {
+      if (mode === "login") {
+        await authApi.login(values.email, values.password)
+      } else {
+        await authApi.register(values.email, values.name ?? "", values.password)
+      }
+      onAuthenticated()
+    } catch (error) {
+      const text = isAxiosError(error) ? error.response?.data?.error : undefined
+      message.error(text ?? "Authentication failed")
+    } finally {
+      setLoading(false)
+    }
+  }
+
+  return (
+    <div
+      style={{
+        minHeight: "100vh",
+        display: "flex",
+        alignItems: "center",
+        justifyContent: "center",
+        backgroundColor: "#f5f5f5",
+      }}
+    >
+      <Card style={{ width: 380 }}>
+        <Title level={3} style={{ textAlign: "center" }}>
+          Industrix Todo App
+        </Title>
+        <Tabs
+          activeKey={mode}
+          onChange={(key) => setMode(key as "login" | "register")}
+          centered
+          items={[
+            { key: "login", label: "Log in" },
+            { key: "register", label: "Register" },
+          ]}
+        />
+        <Form layout="vertical" onFinish={handleSubmit} key={mode}>
+          <Form.Item
+            name="email"
+            rules={[{ required: true, type: "email", message: "Enter a valid email" }]}
+          >
+            <Input prefix={<MailOutlined />} placeholder="Email" autoComplete="email" />
+          </Form.Item>
+          {mode === "register" && (
+            <Form.Item name="name">
+              <Input prefix={<UserOutlined />} placeholder="Name" autoComplete="name" />
+            </Form.Item>
+          )}
+          <Form.Item
+            name="password"
+            rules={[
+              { required: true, message: "Enter your password" },
+              ...(mode === "register"
+                ? [{ min: 8, message: "Password must be at least 8 characters" }]
+                : []),
+            ]}
+          >
+            <Input.Password
+              prefix={<LockOutlined />}
+              placeholder="Password"
+              autoComplete={mode === "login" ? "current-password" : "new-password"}
+            />
+          </Form.Item>
+          <Button type="primary" htmlType="submit" loading={loading} block>
+            {mode === "login" ? "Log in" : "Create account"}
+          </Button>
+        </Form>
+      </Card>
+    </div>
+  )
+}
+
+export default LoginPage
@@ -0,0 +1,2 @@
+export { default as AuthGate } from "./AuthGate"
+export { default as LoginPage } from "./LoginPage"
@@ -43,7 +43,12 @@ const TodoItem: React.FC<TodoItemProps> = ({
                 color: todo.completed ? "#8c8c8c" : "#262626",
               }}
             >
-              {todo.title}
+              {todo.search ? (
+                // Server-escaped HTML with the search matches in <mark> tags
+                <span dangerouslySetInnerHTML={{ __html: todo.search.title }} />
+              ) : (
+                todo.title
+              )}
             </Text>
           </Space>
           <Space>
@@ -79,7 +84,11 @@ const TodoItem: React.FC<TodoItemProps> = ({
             }}
             ellipsis={{ rows: 2, expandable: false }}
           >
-            {todo.description}
+            {todo.search?.description ? (
+              <span dangerouslySetInnerHTML={{ __html: todo.search.description }} />
+            ) : (
+              todo.description
+            )}
           </Paragraph>
         )}
 
@@ -3,11 +3,14 @@ import { createRoot } from "react-dom/client"
 import "./index.css"
 import App from "./App.tsx"
 import { TodoProvider } from "./context/TodoContext"
+import { AuthGate } from "./features/auth"
 
 createRoot(document.getElementById("root")!).render(
   <StrictMode>
-    <TodoProvider>
-      <App />
-    </TodoProvider>
+    <AuthGate>
+      <TodoProvider>
+        <App />
+      </TodoProvider>
+    </AuthGate>
   </StrictMode>
 )
@@ -1,8 +1,36 @@
-import axios from "axios"
-import type { Todo, TodoInput, TodosResponse, Category } from "../types/todos"
+import axios, { type InternalAxiosRequestConfig } from "axios"
+import type {
+  Todo,
+  TodoInput,
+  TodosResponse,
+  Category,
+  AuthResponse,
+  AuthTokens,
+  User,
+} from "../types/todos"
 
 const API_BASE_URL = import.meta.env.VITE_API_URL || "http://localhost:8080/api"
 
+// Event dispatched on window when the session can no longer be refreshed
+export const AUTH_LOGOUT_EVENT = "auth:logout"
+
+const ACCESS_TOKEN_KEY = "access_token"
+const REFRESH_TOKEN_KEY = "refresh_token"
+
+// Token storage in localStorage
+export const tokenStorage = {
+  getAccessToken: () => localStorage.getItem(ACCESS_TOKEN_KEY),
+  getRefreshToken: () => localStorage.getItem(REFRESH_TOKEN_KEY),
+  save: (tokens: AuthTokens) => {
+    localStorage.setItem(ACCESS_TOKEN_KEY, tokens.access_token)
+    localStorage.setItem(REFRESH_TOKEN_KEY, tokens.refresh_token)
+  },
+  clear: () => {
+    localStorage.removeItem(ACCESS_TOKEN_KEY)
+    localStorage.removeItem(REFRESH_TOKEN_KEY)
+  },
+}
+
 const apiClient = axios.create({
   baseURL: API_BASE_URL,
   headers: {
@@ -10,6 +38,104 @@ const apiClient = axios.create({
   },
 })
 
+// Attach the access token to every request
+apiClient.interceptors.request.use((config) => {
+  const token = tokenStorage.getAccessToken()
+  if (token) {
+    config.headers.Authorization = `Bearer ${token}`
+  }
+  return config
+})
+
+// Endpoints whose 401 means bad credentials rather than an expired access token
+const SESSION_ENDPOINTS = ["/auth/login", "/auth/register", "/auth/refresh", "/auth/logout"]
+
+// Shared refresh so parallel 401s only rotate the refresh token once
+let refreshing: Promise<AuthTokens> | null = null
+
+const refreshTokens = async () => {
+  const refreshToken = tokenStorage.getRefreshToken()
+  if (!refreshToken) {
+    throw new Error("no refresh token")
+  }
+  const response = await axios.post<AuthTokens>(`${API_BASE_URL}/auth/refresh`, {
+    refresh_token: refreshToken,
+  })
+  tokenStorage.save(response.data)
+  return response.data
+}
+
+// On 401, refresh the access token once and retry the request
+apiClient.interceptors.response.use(
+  (response) => response,
+  async (error) => {
+    const original = error.config as InternalAxiosRequestConfig & { _retry?: boolean }
+    if (
+      error.response?.status !== 401 ||
+      original._retry ||
+      SESSION_ENDPOINTS.includes(original.url ?? "")
+    ) {
+      return Promise.reject(error)
+    }
+
+    original._retry = true
+    try {
+      refreshing = refreshing ?? refreshTokens()
+      const tokens = await refreshing
+      original.headers.Authorization = `Bearer ${tokens.access_token}`
+      return apiClient(original)
+    } catch {
+      tokenStorage.clear()
+      window.dispatchEvent(new Event(AUTH_LOGOUT_EVENT))
+      return Promise.reject(error)
+    } finally {
+      refreshing = null
+    }
+  }
+)
+
+// URL of the Server-Sent Events change feed. EventSource cannot send headers,
+// so the access token goes in the query string.
+export const eventsUrl = () =>
+  `${API_BASE_URL}/events?access_token=${encodeURIComponent(tokenStorage.getAccessToken() ?? "")}`
+
+// Auth API
+export const authApi = {
+  // Log in with email and password
+  login: async (email: string, password: string) => {
+    const response = await apiClient.post<AuthResponse>("/auth/login", { email, password })
+    tokenStorage.save(response.data)
+    return response.data
+  },
+
+  // Create a new account
+  register: async (email: string, name: string, password: string) => {
+    const response = await apiClient.post<AuthResponse>("/auth/register", {
+      email,
+      name,
+      password,
+    })
+    tokenStorage.save(response.data)
+    return response.data
+  },
+
+  // Get the current user; refreshes the access token when it has expired
+  me: async () => {
+    const response = await apiClient.get<User>("/auth/me")
+    return response.data
+  },
+
+  // Revoke the refresh token and forget the session
+  logout: async () => {
+    const refreshToken = tokenStorage.getRefreshToken()
+    tokenStorage.clear()
+    if (refreshToken) {
+      await apiClient.post("/auth/logout", { refresh_token: refreshToken }).catch(() => undefined)
+    }
+    window.dispatchEvent(new Event(AUTH_LOGOUT_EVENT))
+  },
+}
+
 // Todo API
 export const todoApi = {
   // Get all todos with pagination and filters
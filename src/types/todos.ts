@@ -4,7 +4,9 @@ export interface Category {
   id: number
   name: string
   color: string
+  version: number
   created_at: string
+  deleted_at?: string | null
 }
 
 export interface Todo {
@@ -15,9 +17,71 @@ export interface Todo {
   category_id?: number
   priority: Priority
   due_date?: string
+  version: number
   created_at: string
   updated_at: string
+  deleted_at?: string | null
   category?: Category
+  search?: SearchMatch
+  comment_count?: number
+}
+
+export interface Comment {
+  id: number
+  todo_id: number
+  user_id: number
+  body: string
+  mentions: string[]
+  edited_at: string | null
+  created_at: string
+}
+
+export interface Attachment {
+  id: number
+  todo_id: number
+  user_id: number
+  file_name: string
+  content_type: string
+  size: number
+  sha256: string
+  created_at: string
+}
+
+export type ReminderChannel = "in_app" | "email" | "webhook"
+
+export interface Reminder {
+  id: number
+  todo_id: number
+  user_id: number
+  remind_at: string | null
+  offset_minutes: number | null
+  channels: ReminderChannel[]
+  status: "pending" | "sent" | "dismissed"
+  fire_at: string | null
+  sent_at: string | null
+  created_at: string
+  updated_at: string
+}
+
+export interface Notification {
+  id: number
+  user_id: number
+  todo_id: number
+  reminder_id: number
+  channel: ReminderChannel
+  title: string
+  message: string
+  status: "pending" | "sent" | "failed"
+  sent_at: string | null
+  read_at: string | null
+  created_at: string
+}
+
+// Set on search results; title and description are escaped HTML with <mark> tags
+export interface SearchMatch {
+  rank: number
+  title: string
+  description?: string
 }
 
 export interface TodoInput {
@@ -47,3 +111,21 @@ export interface TodoStatistics {
   completed: number
   progress: number
 }
+
+export interface User {
+  id: number
+  email: string
+  name: string
+  created_at: string
+}
+
+export interface AuthTokens {
+  access_token: string
+  refresh_token: string
+  token_type: string
+  expires_in: number
+}
+
+export interface AuthResponse extends AuthTokens {
+  user: User
+}